@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// errNotFound is returned by API calls addressing a panel that does not exist.
+var errNotFound = errors.New("not found")
+
+// errLockHeld is returned when a client asks for a cell lock someone else
+// holds.
+var errLockHeld = errors.New("cell is being edited")
+
+// apiMaxCells bounds the panels the API creates and how far a write may
+// grow one, so a single request cannot make a grid too big to save or
+// scan. Panels loaded from larger files can still be written inside.
+const apiMaxCells = 1000000
+
+// apiGridOK reports whether a cols x rows panel is within apiMaxCells.
+func apiGridOK(cols, rows int) bool {
+	return cols <= apiMaxCells && rows <= apiMaxCells && cols*rows <= apiMaxCells
+}
+
+// apiCall is a unit of work an HTTP handler hands to the game loop. The
+// canvas is only ever touched from Update, so handlers never access it
+// directly; they block on reply instead.
+type apiCall struct {
+	fn    func(g *Game) (any, error)
+	reply chan apiReply
+}
+
+type apiReply struct {
+	v   any
+	err error
+}
+
+// APIServer exposes a small REST API for reading and writing cells on the
+// running instance:
+//
+//	GET    /api/panels                           list panels
+//	POST   /api/panels                           add a panel {"x","y","cols","rows","name"}
+//	GET    /api/panels/{panel}/cells             all non-empty cells of a panel
+//	GET    /api/panels/{panel}/cells/{ref}       one cell value (and formula)
+//	PUT    /api/panels/{panel}/cells/{ref}       set a cell; body is the raw value
+//	PUT    /api/panels/{panel}/cells/{ref}/lock  announce editing a cell
+//	DELETE /api/panels/{panel}/cells/{ref}/lock  stop editing it
+//	GET    /api/locks                            cells being edited, and by whom
+//	POST   /api/save                             save the workspace state file
+//
+// {panel} is either the 1-based panel number shown in the header or the
+// panel's name. Writing outside a panel grows it to fit, up to apiMaxCells.
+// Derived, SQL and preview panels cannot be written, as in the UI.
+//
+// Clients name their user in the X-User header (the remote address is used
+// otherwise). A lock is a hint that lasts cellLockTTL unless renewed; taking
+// one held by someone else fails with 409 Conflict, but locks never refuse
+// a write. Writing a cell someone else is editing overwrites it, reports
+// "conflict" in the reply and records the conflict in the cell history.
+type APIServer struct {
+	calls chan apiCall
+	srv   *http.Server
+}
+
+// apiPanel is the JSON view of a panel returned by the API.
+type apiPanel struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Filename string `json:"file,omitempty"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Cols     int    `json:"cols"`
+	Rows     int    `json:"rows"`
+	Loaded   bool   `json:"loaded"`
+}
+
+// NewAPIServer creates a server listening on addr. Call Start to begin
+// serving and Drain from the game loop to execute queued calls.
+func NewAPIServer(addr string) *APIServer {
+	s := &APIServer{calls: make(chan apiCall)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/panels", s.handleListPanels)
+	mux.HandleFunc("POST /api/panels", s.handleAddPanel)
+	mux.HandleFunc("GET /api/panels/{panel}/cells", s.handleGetCells)
+	mux.HandleFunc("GET /api/panels/{panel}/cells/{ref}", s.handleGetCell)
+	mux.HandleFunc("PUT /api/panels/{panel}/cells/{ref}", s.handleSetCell)
+	mux.HandleFunc("PUT /api/panels/{panel}/cells/{ref}/lock", s.handleLockCell)
+	mux.HandleFunc("DELETE /api/panels/{panel}/cells/{ref}/lock", s.handleUnlockCell)
+	mux.HandleFunc("GET /api/locks", s.handleListLocks)
+	mux.HandleFunc("POST /api/save", s.handleSave)
+	s.srv = &http.Server{Addr: addr, Handler: mux}
+	return s
+}
+
+// Start serves in a background goroutine.
+func (s *APIServer) Start() {
+	go func() {
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("api server: %v", err)
+		}
+	}()
+	log.Printf("api server listening on %s", s.srv.Addr)
+}
+
+// Drain runs every call queued by HTTP handlers and reports whether there
+// were any. It must be called from Update; a nil server is a no-op.
+func (s *APIServer) Drain(g *Game) bool {
+	if s == nil {
+		return false
+	}
+	ran := false
+	for {
+		select {
+		case c := <-s.calls:
+			ran = true
+			v, err := c.fn(g)
+			c.reply <- apiReply{v: v, err: err}
+		default:
+			return ran
+		}
+	}
+}
+
+// do queues fn on the game loop and writes its result as JSON.
+func (s *APIServer) do(w http.ResponseWriter, r *http.Request, fn func(g *Game) (any, error)) {
+	c := apiCall{fn: fn, reply: make(chan apiReply, 1)}
+	select {
+	case s.calls <- c:
+	case <-r.Context().Done():
+		return
+	}
+	rep := <-c.reply
+	if rep.err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(rep.err, errNotFound):
+			status = http.StatusNotFound
+		case errors.Is(rep.err, errLockHeld):
+			status = http.StatusConflict
+		}
+		http.Error(w, rep.err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rep.v); err != nil {
+		log.Printf("api server: %v", err)
+	}
+}
+
+// resolvePanel maps a {panel} path value to a panel index.
+func resolvePanel(c *Canvas, key string) (int, error) {
+	for i := range c.Panels {
+		if c.Panels[i].Name != "" && c.Panels[i].Name == key {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(c.Panels) {
+		return n - 1, nil
+	}
+	return -1, errNotFound
+}
+
+func toAPIPanel(i int, p *cellcanvas.Panel) apiPanel {
+	return apiPanel{ID: i + 1, Name: p.Name, Filename: p.Filename, X: p.X, Y: p.Y, Cols: p.Cols, Rows: p.Rows, Loaded: p.Loaded}
+}
+
+func (s *APIServer) handleListPanels(w http.ResponseWriter, r *http.Request) {
+	s.do(w, r, func(g *Game) (any, error) {
+		out := []apiPanel{}
+		for i := range g.canvas.Panels {
+			out = append(out, toAPIPanel(i, &g.canvas.Panels[i]))
+		}
+		return out, nil
+	})
+}
+
+func (s *APIServer) handleAddPanel(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		X, Y       int
+		Cols, Rows int
+		Name       string
+	}{Cols: 5, Rows: 5}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Cols < 1 || req.Rows < 1 {
+		http.Error(w, "cols and rows must be positive", http.StatusBadRequest)
+		return
+	}
+	if !apiGridOK(req.Cols, req.Rows) {
+		http.Error(w, fmt.Sprintf("panel larger than %d cells", apiMaxCells), http.StatusBadRequest)
+		return
+	}
+	s.do(w, r, func(g *Game) (any, error) {
+		p := cellcanvas.NewBlankPanel(req.X, req.Y, req.Cols, req.Rows)
+		p.Name = req.Name
+		i := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: i})
+		return toAPIPanel(i, &g.canvas.Panels[i]), nil
+	})
+}
+
+func (s *APIServer) handleGetCells(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("panel")
+	s.do(w, r, func(g *Game) (any, error) {
+		i, err := resolvePanel(g.canvas, key)
+		if err != nil {
+			return nil, err
+		}
+		p := &g.canvas.Panels[i]
+		if p.Paged != nil {
+			return nil, errors.New("panel is paged to disk; read cells individually")
+		}
+		cells := make(map[string]string, len(p.Cells))
+		for k, v := range p.Cells {
+			cells[k] = v
+		}
+		return cells, nil
+	})
+}
+
+func (s *APIServer) handleGetCell(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("panel")
+	col, row, err := cellcanvas.ParseCellRef(r.PathValue("ref"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.do(w, r, func(g *Game) (any, error) {
+		i, err := resolvePanel(g.canvas, key)
+		if err != nil {
+			return nil, err
+		}
+		p := &g.canvas.Panels[i]
+		res := map[string]string{"ref": cellcanvas.CellRef(col, row), "value": p.GetCell(col, row)}
+		if raw := p.RawCell(col, row); cellcanvas.IsFormula(raw) {
+			res["formula"] = raw
+		}
+		return res, nil
+	})
+}
+
+func (s *APIServer) handleSetCell(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("panel")
+	col, row, err := cellcanvas.ParseCellRef(r.PathValue("ref"))
+	if err != nil || col < 0 || row < 0 {
+		http.Error(w, "invalid cell reference", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	val := strings.TrimRight(string(body), "\r\n")
+	user := apiUser(r)
+	s.do(w, r, func(g *Game) (any, error) {
+		i, err := resolvePanel(g.canvas, key)
+		if err != nil {
+			return nil, err
+		}
+		p := &g.canvas.Panels[i]
+		switch {
+		case !p.Loaded:
+			return nil, errors.New("panel is still loading")
+		case p.Query != nil || p.SQL != nil:
+			// the next run of the query would overwrite the write
+			return nil, errors.New("panel shows a query; change the query instead")
+		case p.SampleRows > 0:
+			return nil, errors.New("panel is a preview; load the full file to write it")
+		}
+		cols, rows := max(p.Cols, col+1), max(p.Rows, row+1)
+		if (cols > p.Cols || rows > p.Rows) && !apiGridOK(cols, rows) {
+			return nil, fmt.Errorf("%s would grow the panel past %d cells", cellcanvas.CellRef(col, row), apiMaxCells)
+		}
+		val, err := p.UnitValue(col, row, val)
+		if err != nil {
+			return nil, err
+		}
+		p.Cols, p.Rows = cols, rows
+		old := p.RawCell(col, row)
+		p.SetCell(col, row, val)
+		// last write wins; whoever else was editing is noted in the history
+		other := conflictWith(g, i, col, row, user)
+		g.locks.Unlock(i, col, row, user)
+		if old != val {
+			g.events.Publish(CellChanged{Panel: i, Col: col, Row: row, Old: old, New: val, By: user, Conflict: other})
+		}
+		res := map[string]string{"ref": cellcanvas.CellRef(col, row), "value": val}
+		if other != "" {
+			res["conflict"] = other
+			g.events.Publish(LogMessage{Text: lockMessage(user, other, i, col, row)})
+		}
+		return res, nil
+	})
+}
+
+// apiUser returns the user a request was made by: its X-User header, or
+// else the host it came from.
+func apiUser(r *http.Request) string {
+	if u := strings.TrimSpace(r.Header.Get("X-User")); u != "" {
+		return u
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// lockTarget resolves the panel and cell of a lock request.
+func lockTarget(c *Canvas, r *http.Request) (panel, col, row int, err error) {
+	col, row, err = cellcanvas.ParseCellRef(r.PathValue("ref"))
+	if err != nil || col < 0 || row < 0 {
+		return 0, 0, 0, errors.New("invalid cell reference")
+	}
+	panel, err = resolvePanel(c, r.PathValue("panel"))
+	return panel, col, row, err
+}
+
+func (s *APIServer) handleLockCell(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	s.do(w, r, func(g *Game) (any, error) {
+		i, col, row, err := lockTarget(g.canvas, r)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if other := conflictWith(g, i, col, row, user); other == hostUser {
+			return nil, fmt.Errorf("%w by %s", errLockHeld, other)
+		}
+		if holder, ok := g.locks.Lock(i, col, row, user, now); !ok {
+			return nil, fmt.Errorf("%w by %s", errLockHeld, holder)
+		}
+		g.power.redraw = true
+		return apiLock{Panel: i + 1, Ref: cellcanvas.CellRef(col, row), User: user, Expires: now.Add(cellLockTTL)}, nil
+	})
+}
+
+func (s *APIServer) handleUnlockCell(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	s.do(w, r, func(g *Game) (any, error) {
+		i, col, row, err := lockTarget(g.canvas, r)
+		if err != nil {
+			return nil, err
+		}
+		g.locks.Unlock(i, col, row, user)
+		g.power.redraw = true
+		return map[string]string{"ref": cellcanvas.CellRef(col, row), "released": user}, nil
+	})
+}
+
+func (s *APIServer) handleListLocks(w http.ResponseWriter, r *http.Request) {
+	s.do(w, r, func(g *Game) (any, error) {
+		return g.locks.List(g), nil
+	})
+}
+
+func (s *APIServer) handleSave(w http.ResponseWriter, r *http.Request) {
+	s.do(w, r, func(g *Game) (any, error) {
+		if err := g.canvas.SaveState(defaultStatePath); err != nil {
+			return nil, err
+		}
+		g.events.Publish(WorkspaceSaved{Path: defaultStatePath})
+		return map[string]string{"saved": defaultStatePath}, nil
+	})
+}
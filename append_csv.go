@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// appendModes are the ways a file's columns can be lined up with a panel's.
+var appendModes = []string{"Match columns by header", "Append by column position"}
+
+// appendCSVToPanel asks for a CSV file and how to line up its columns, then
+// appends its rows below panel pi's data.
+func appendCSVToPanel(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "Right-click a panel to append to it"})
+		return
+	}
+	path, err := dialog.File().Filter("CSV", csvFileTypes...).Title("Append CSV to Panel").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	g.picker.Open("Append "+filepath.Base(path), appendModes, func(g *Game, mode int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		src := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+		if err := cellcanvas.LoadPanelCSV(path, &src); err != nil {
+			reportError(g, "Append CSV", err)
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		if !p.Loaded {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is still loading", pi+1)})
+			return
+		}
+		first, n := p.AppendPanel(&src, mode == 0)
+		p.AddRowSource(first, n, path, time.Now())
+		g.events.Publish(RowsAppended{Panel: pi, First: first, Count: n, Source: filepath.Base(path)})
+		showCSVProblems(g, pi, path, src.Problems)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("appended %d row(s) from %s to Panel %d", n, filepath.Base(path), pi+1)})
+	})
+}
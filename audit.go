@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// auditFile is the append-only audit trail kept next to the state file
+// when started with -audit.
+const auditFile = "audit.csv"
+
+var auditHeader = []string{"time", "user", "action", "panel", "name", "cell", "from", "to"}
+
+// AuditLog appends one CSV row per data-changing event (cell edits, panels
+// added, removed or resized, and one row per bulk edit such as a large
+// paste) to the workspace audit file. Rows are flushed immediately and the
+// file is only ever appended to. Data a panel reads from its own file, a
+// live feed or a query is not audited, and while the workspace is encrypted
+// edits are logged without their old and new values.
+type AuditLog struct {
+	f    *os.File
+	w    *csv.Writer
+	user string
+}
+
+// OpenAuditLog opens (or creates) the audit file in dir.
+func OpenAuditLog(dir string) (*AuditLog, error) {
+	path := filepath.Join(dir, auditFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	a := &AuditLog{f: f, w: csv.NewWriter(f), user: currentUser()}
+	if st, err := f.Stat(); err == nil && st.Size() == 0 {
+		a.write(auditHeader)
+	}
+	return a, nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Observe records e if it changes data. A nil log ignores everything, so
+// auditing can stay subscribed unconditionally.
+func (a *AuditLog) Observe(e Event, c *Canvas) {
+	if a == nil {
+		return
+	}
+	switch ev := e.(type) {
+	case CellChanged:
+		if c.password != "" {
+			// the values stay in the archive; audit.csv is plain text
+			a.record(c, "edit", ev.Panel, cellcanvas.CellRef(ev.Col, ev.Row), "", "")
+			break
+		}
+		a.record(c, "edit", ev.Panel, cellcanvas.CellRef(ev.Col, ev.Row), ev.Old, ev.New)
+	case PanelAdded:
+		a.record(c, "add panel", ev.Panel, "", "", "")
+	case PanelRemoved:
+		// the panel is already gone from the canvas
+		a.write([]string{a.now(), a.user, "remove panel", strconv.Itoa(ev.Panel + 1), "", "", "", ""})
+	case RowsAppended:
+		a.record(c, "append rows", ev.Panel, cellcanvas.CellRef(0, ev.First), "", fmt.Sprintf("%d rows from %s", ev.Count, ev.Source))
+	case PanelResized:
+		a.record(c, "resize panel", ev.Panel, "", "", strconv.Itoa(ev.Cols)+"x"+strconv.Itoa(ev.Rows))
+	case BulkEdit:
+		a.record(c, ev.Action, ev.Panel, ev.Cell, "", ev.Detail)
+	}
+}
+
+func (a *AuditLog) record(c *Canvas, action string, panel int, cell, from, to string) {
+	name := ""
+	if panel >= 0 && panel < len(c.Panels) {
+		name = c.Panels[panel].Name
+		if name == "" {
+			name = c.Panels[panel].Filename
+		}
+	}
+	a.write([]string{a.now(), a.user, action, strconv.Itoa(panel + 1), name, cell, from, to})
+}
+
+func (a *AuditLog) now() string {
+	return time.Now().Format(time.RFC3339)
+}
+
+func (a *AuditLog) write(rec []string) {
+	_ = a.w.Write(rec)
+	a.w.Flush()
+}
+
+// Path returns the audit file location.
+func (a *AuditLog) Path() string {
+	return a.f.Name()
+}
+
+// Export copies the audit trail to path as a standalone CSV file. A failed
+// export leaves whatever was at path before.
+func (a *AuditLog) Export(path string) error {
+	src, err := os.Open(a.Path())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return cellcanvas.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import "math"
+
+// cameraMargin is how much of the panels, in pixels, the camera constraint
+// keeps on screen, and the gap Home leaves around them.
+const cameraMargin = 48
+
+// panelsBox returns the world rectangle holding every panel, headers and
+// padding included; ok is false when there are no panels.
+func panelsBox(c *Canvas) (x0, y0, x1, y1 int, ok bool) {
+	for i := range c.Panels {
+		p := &c.Panels[i]
+		px0, py0 := p.X-PanelPaddingX, p.Y-PanelHeaderHeight
+		px1, py1 := p.X+p.Width()+PanelPaddingX, p.Y+p.Height()+PanelPaddingY*2
+		if !ok {
+			x0, y0, x1, y1, ok = px0, py0, px1, py1, true
+			continue
+		}
+		x0, y0, x1, y1 = min(x0, px0), min(y0, py0), max(x1, px1), max(y1, py1)
+	}
+	return x0, y0, x1, y1, ok
+}
+
+// goHome moves the camera back to the origin, or with all set to the
+// panels: centered when they fit on screen, else their top-left corner.
+func goHome(g *Game, all bool) {
+	x0, y0, x1, y1, ok := panelsBox(g.canvas)
+	if !all || !ok {
+		g.canvas.CamX, g.canvas.CamY = 0, 0
+		return
+	}
+	w, h := g.viewSize()
+	g.canvas.CamX = homeOffset(x0, x1, w)
+	g.canvas.CamY = homeOffset(y0, y1, h)
+}
+
+// homeOffset is the camera offset along one axis that centers the span
+// lo..hi in size pixels, or puts lo near the edge when it does not fit.
+func homeOffset(lo, hi, size int) float64 {
+	if hi-lo <= size-2*cameraMargin {
+		return float64(size/2 - (lo+hi)/2)
+	}
+	return float64(cameraMargin - lo)
+}
+
+// constrainCamera keeps at least cameraMargin pixels of the panels on
+// screen, so panning can never lose them all.
+func constrainCamera(g *Game) {
+	x0, y0, x1, y1, ok := panelsBox(g.canvas)
+	if !ok {
+		return
+	}
+	c := g.canvas
+	w, h := g.viewSize()
+	c.CamX = math.Min(math.Max(c.CamX, float64(cameraMargin-x1)), float64(w-cameraMargin-x0))
+	c.CamY = math.Min(math.Max(c.CamY, float64(cameraMargin-y1)), float64(h-cameraMargin-y0))
+}
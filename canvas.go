@@ -3,22 +3,9 @@ package main
 import (
 	"path/filepath"
 
-	"github.com/hajimehoshi/ebiten/v2"
-	"golang.org/x/image/font"
+	"github.com/example/cellchain/cellcanvas"
 )
 
-type Panel struct {
-	X, Y     int
-	Cols     int
-	Rows     int
-	CellW    int
-	CellH    int
-	Cells    map[string]string // sparse map of cells keyed A1-style
-	Filename string
-	Loaded   bool
-	Name     string
-}
-
 // panelGap is the minimum spacing (in pixels) to keep between panels.
 // This uses PanelPaddingX to calculate spacing.
 const panelGap = PanelPaddingX
@@ -30,89 +17,80 @@ const panelGap = PanelPaddingX
 // a violation larger by this many pixels before we switch to it.
 const axisHysteresis = 2
 
-// Canvas contains panels and camera + interaction state for moving/resizing
+// Canvas is the on-screen view of a cellcanvas.Workspace: the panels and
+// camera come from the embedded workspace, and Canvas adds background
+// loading, overlap resolution and drawing.
 type Canvas struct {
-	panels     []Panel
-	camX, camY float64
+	cellcanvas.Workspace
 	// SaveManager manages background CSV loads and applies them on the UI thread.
 	saveManager *SaveManager
-}
-
-// CanvasDrawState encapsulates all external state required to render the canvas.
-// This decouples the Canvas.Draw method from the monolithic Game struct.
-type CanvasDrawState struct {
-	Face             font.Face
-	ActivePanel      int
-	SelRow, SelCol   int
-	Editing          bool
-	EditBuffer       string
-	EditingPanelName bool
-	EditPanelIndex   int
-	EditPanelBuffer  string
-	CaretVisible     bool
-	EditCursor       int
-	EditPanelCursor  int
+	// events receives panel and log notifications; may be nil.
+	events *EventBus
+	// password, when set, makes SaveState write an encrypted archive
+	// instead of plain state.yml and CSV files.
+	password string
+	// readOnly blocks SaveState while another instance owns the workspace.
+	readOnly bool
+	// changedOnDisk lists the workspace files changed by another program
+	// since they were loaded or saved; SaveState refuses until the user
+	// chooses what to do about them (see SyncWatch).
+	changedOnDisk []string
+	// scheduler spreads deferred work (applying loads) over frames.
+	scheduler *FrameScheduler
 }
 
 func NewCanvas() *Canvas {
 	c := &Canvas{}
 	// Start with no sample/demo panels by default. Panels will be created
 	// by state load or user actions.
-	c.panels = []Panel{}
+	c.Panels = []cellcanvas.Panel{}
 	c.saveManager = NewSaveManager()
+	c.scheduler = NewFrameScheduler()
 	return c
 }
 
-// RemovePanelAt removes the panel at index i from the canvas if valid.
-// It does not delete any files on disk; it simply drops the panel from
-// the canvas slice so SaveState will no longer reference it.
-func (c *Canvas) RemovePanelAt(i int) {
-	if i < 0 || i >= len(c.panels) {
-		return
-	}
-	c.panels = append(c.panels[:i], c.panels[i+1:]...)
-}
-
 // Update handles background loads and overlap resolution.
 // Interaction logic has been moved to InputManager.HandleCanvasInteraction.
-func (c *Canvas) Update(g *Game, lockedPanels map[int]bool) {
+// It reports whether anything changed that needs a redraw.
+func (c *Canvas) Update(lockedPanels map[int]bool) bool {
 	// Process background loads completed by SaveManager (keeps canvas free
 	// of channel handling).
 	if c.saveManager != nil {
-		c.saveManager.ApplyPending(c, func(msg string) {
-			if g.ui != nil {
-				g.ui.addClickLog(msg)
-			}
+		c.saveManager.ApplyPending(c, c.scheduler, func(op string, err error) {
+			c.events.Publish(ErrorReported{Op: op, Err: err})
 		})
 	}
+	busy := c.scheduler.Busy()
+	c.scheduler.Run()
 
 	// resolve a single overlapping panel pair by moving one panel one pixel
 	// (skip panels currently being moved/resized by the user)
-	c.resolveOneOverlap(lockedPanels)
+	return c.resolveOneOverlap(lockedPanels) || busy
 }
 
 // resolveOneOverlap finds the first overlapping panel pair (not being
 // actively moved/resized) and moves one panel by a single pixel away from
 // the other along the axis of least overlap. This helps separate multiple
-// overlapping panels gradually (one pair, one pixel per update).
-func (c *Canvas) resolveOneOverlap(lockedPanels map[int]bool) {
-	for i := 0; i < len(c.panels); i++ {
+// overlapping panels gradually (one pair, one pixel per update). It reports
+// whether a panel was moved.
+func (c *Canvas) resolveOneOverlap(lockedPanels map[int]bool) bool {
+	for i := 0; i < len(c.Panels); i++ {
 		// skip if this panel is being interacted with
 		if lockedPanels[i] {
 			continue
 		}
-		a := c.panels[i]
+		a := c.Panels[i]
 		aLeft := a.X - PanelPaddingX
-		aW := a.Cols*a.CellW + PanelPaddingX*2
+		aW := a.Width() + PanelPaddingX*2
 
-		for j := i + 1; j < len(c.panels); j++ {
+		for j := i + 1; j < len(c.Panels); j++ {
 			// skip if this panel is being interacted with
 			if lockedPanels[j] {
 				continue
 			}
-			b := c.panels[j]
+			b := c.Panels[j]
 			bLeft := b.X - PanelPaddingX
-			bW := b.Cols*b.CellW + PanelPaddingX*2
+			bW := b.Width() + PanelPaddingX*2
 
 			// Only perform horizontal separation: compute X overlap/gap and
 			// move panel j by 1 pixel along X away from panel i when the
@@ -149,9 +127,9 @@ func (c *Canvas) resolveOneOverlap(lockedPanels map[int]bool) {
 			// Only separate panels that actually overlap vertically as well.
 			// Compute vertical bounds used elsewhere when drawing (title/header included)
 			aTop := a.Y - PanelHeaderHeight
-			aH := a.Rows*a.CellH + PanelHeaderHeight + PanelPaddingY*2
+			aH := a.Height() + PanelHeaderHeight + PanelPaddingY*2
 			bTop := b.Y - PanelHeaderHeight
-			bH := b.Rows*b.CellH + PanelHeaderHeight + PanelPaddingY*2
+			bH := b.Height() + PanelHeaderHeight + PanelPaddingY*2
 			overlapY := min(aTop+aH, bTop+bH) - max(aTop, bTop)
 			if overlapY <= 0 {
 				// panels are vertically separated (one is above/below the other)
@@ -165,15 +143,16 @@ func (c *Canvas) resolveOneOverlap(lockedPanels map[int]bool) {
 
 			// move along X by 1 pixel away from A
 			if aCx < bCx {
-				c.panels[j].X += 1
+				c.Panels[j].X += 1
 			} else {
-				c.panels[j].X -= 1
+				c.Panels[j].X -= 1
 			}
 
 			// Only move a single pair by a single pixel per Update
-			return
+			return true
 		}
 	}
+	return false
 }
 
 func min(a, b int) int {
@@ -190,72 +169,18 @@ func max(a, b int) int {
 	return b
 }
 
-// Draw renders the panels; selection overlay and editing are now handled by InputManager
-func (c *Canvas) Draw(screen *ebiten.Image, im *InputManager) {
-	// Delegate drawing to the Renderer
-	renderer := NewRenderer()
-	renderer.DrawCanvas(screen, c, im)
-}
-
-func NewPanel(x, y, cols, rows int) Panel {
-	// NewPanel creates a panel with default empty content. We no longer
-	// pre-fill sample values so newly created panels are blank.
-	return Panel{X: x, Y: y, Cols: cols, Rows: rows, CellW: defaultCellW, CellH: defaultCellH, Cells: make(map[string]string), Filename: "", Loaded: true}
-}
-
-// NewBlankPanel creates a panel with provided dimensions but no cell content
-// (empty map). Use this for freshly created blank panels via the UI.
-func NewBlankPanel(x, y, cols, rows int) Panel {
-	return Panel{X: x, Y: y, Cols: cols, Rows: rows, CellW: defaultCellW, CellH: defaultCellH, Cells: make(map[string]string), Filename: "", Loaded: true}
-}
-
-// GetCell returns the string stored at the given col,row (zero-based).
-// Returns empty string when not present.
-func (p *Panel) GetCell(col, row int) string {
-	if p == nil {
-		return ""
-	}
-	if p.Cells == nil {
-		return ""
-	}
-	key := CellRef(col, row)
-	if v, ok := p.Cells[key]; ok {
-		return v
-	}
-	return ""
-}
-
-// SetCell writes a value at the given col,row. Empty values remove the entry
-// to keep the structure sparse.
-func (p *Panel) SetCell(col, row int, val string) {
-	if p == nil {
-		return
-	}
-	if p.Cells == nil {
-		p.Cells = make(map[string]string)
-	}
-	key := CellRef(col, row)
-	if val == "" {
-		delete(p.Cells, key)
-	} else {
-		p.Cells[key] = val
-	}
-}
-
 // AddPanelAt appends a new blank panel positioned at given world coordinates
 func (c *Canvas) AddPanelAt(x, y int) {
 	// new panels are 5x5 blank by default
-	p := NewBlankPanel(x, y, 5, 5)
-	p.X = x
-	p.Y = y
-	c.panels = append(c.panels, p)
+	p := cellcanvas.NewBlankPanel(x, y, 5, 5)
+	c.events.Publish(PanelAdded{Panel: c.AddPanel(p)})
 }
 
 // AddPanelFromCSV loads a CSV file into a new panel positioned at x,y.
 // Returns an error if loading the CSV fails.
 func (c *Canvas) AddPanelFromCSV(path string, x, y int) error {
-	p := NewBlankPanel(x, y, 1, 1)
-	if err := loadPanelCSV(path, &p); err != nil {
+	p := cellcanvas.NewBlankPanel(x, y, 1, 1)
+	if err := cellcanvas.LoadPanelCSV(path, &p); err != nil {
 		return err
 	}
 	// store filename as base name
@@ -263,9 +188,30 @@ func (c *Canvas) AddPanelFromCSV(path string, x, y int) error {
 	p.X = x
 	p.Y = y
 	p.Loaded = true
-	c.panels = append(c.panels, p)
+	c.events.Publish(PanelAdded{Panel: c.AddPanel(p)})
 	return nil
 }
 
-// scheduleBackgroundLoad is implemented in model.go so CSV loading
-// and related data handling remain grouped with the model I/O code.
+// AddPanelFromRecords adds a panel positioned at x,y holding recs (as parsed
+// from the clipboard), sized to fit them. It returns the panel's index.
+func (c *Canvas) AddPanelFromRecords(recs [][]string, x, y int) (int, error) {
+	p, err := cellcanvas.NewPanelFromRecords(x, y, recs)
+	if err != nil {
+		return -1, err
+	}
+	i := c.AddPanel(p)
+	c.events.Publish(PanelAdded{Panel: i})
+	return i, nil
+}
+
+// LoadState replaces the workspace with the state file at statePath. Panel
+// CSVs are loaded in the background by the SaveManager when there is one.
+func (c *Canvas) LoadState(statePath string) error {
+	var schedule func(int, string)
+	if c.saveManager != nil {
+		schedule = func(i int, path string) {
+			c.saveManager.ScheduleChecked(i, path, c.Panels[i].SampleRows, c.Panels[i].Checksum, c.Panels[i].Delimiter)
+		}
+	}
+	return c.Workspace.LoadState(statePath, schedule)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// maxBorderCells caps how many cells one border edit may touch; borders
+// are stored per cell, so styling a whole huge panel would bloat state.yml.
+const maxBorderCells = 10000
+
+// cellBorderStyles lists the border styles in picker order with their
+// labels; the empty style removes borders.
+var cellBorderStyles = []struct{ style, label string }{
+	{"", "None"},
+	{cellcanvas.BorderBox, "Box"},
+	{cellcanvas.BorderBoxThick, "Thick box"},
+	{cellcanvas.BorderTop, "Top"},
+	{cellcanvas.BorderBottom, "Bottom"},
+	{cellcanvas.BorderBottomThick, "Thick bottom"},
+	{cellcanvas.BorderBottomDouble, "Double bottom"},
+}
+
+// drawCellBorders draws the explicit borders of the visible cells over the
+// gridlines. Borders are few, so the map is walked instead of every cell.
+func (r *Renderer) drawCellBorders(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, firstRow, lastRow, firstCol, lastCol int) {
+	for ref, style := range p.Borders {
+		col, row, err := cellcanvas.ParseCellRef(ref)
+		if err != nil || col < firstCol || col >= lastCol || row < firstRow || row >= lastRow {
+			continue
+		}
+		x := float64(b.GridX + p.ColX(col))
+		y := float64(b.GridY + p.RowY(row))
+		w := float64(p.ColWidth(col))
+		h := float64(p.RowHeight(row))
+		switch style {
+		case cellcanvas.BorderBox:
+			r.strokeRect(screen, x, y, w, h, 1)
+		case cellcanvas.BorderBoxThick:
+			r.strokeRect(screen, x, y, w, h, 2)
+		case cellcanvas.BorderTop:
+			r.fillRect(screen, x, y, w, 1, ColorCellBorder)
+		case cellcanvas.BorderBottom:
+			r.fillRect(screen, x, y+h-1, w, 1, ColorCellBorder)
+		case cellcanvas.BorderBottomThick:
+			r.fillRect(screen, x, y+h-2, w, 2, ColorCellBorder)
+		case cellcanvas.BorderBottomDouble:
+			r.fillRect(screen, x, y+h-4, w, 1, ColorCellBorder)
+			r.fillRect(screen, x, y+h-1, w, 1, ColorCellBorder)
+		}
+	}
+}
+
+// strokeRect outlines a rectangle with lines t pixels thick drawn inside it.
+func (r *Renderer) strokeRect(screen *ebiten.Image, x, y, w, h, t float64) {
+	r.fillRect(screen, x, y, w, t, ColorCellBorder)
+	r.fillRect(screen, x, y+h-t, w, t, ColorCellBorder)
+	r.fillRect(screen, x, y, t, h, ColorCellBorder)
+	r.fillRect(screen, x+w-t, y, t, h, ColorCellBorder)
+}
+
+// pickCellBorder lets the user choose a border style for the cells from
+// col0, row0 to col1, row1 of panel pi.
+func pickCellBorder(g *Game, pi, col0, row0, col1, row1 int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) || col0 < 0 || row0 < 0 {
+		return
+	}
+	if (col1-col0+1)*(row1-row0+1) > maxBorderCells {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("borders: select at most %d cells", maxBorderCells)})
+		return
+	}
+	items := make([]string, len(cellBorderStyles))
+	for i, s := range cellBorderStyles {
+		items[i] = s.label
+	}
+	where := cellcanvas.CellRef(col0, row0)
+	if col1 != col0 || row1 != row0 {
+		where += ":" + cellcanvas.CellRef(col1, row1)
+	}
+	g.picker.Open("Border for "+where, items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		for row := row0; row <= row1; row++ {
+			for col := col0; col <= col1; col++ {
+				p.SetBorder(col, row, cellBorderStyles[i].style)
+			}
+		}
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("border %s on %s", cellBorderStyles[i].label, where)})
+	})
+}
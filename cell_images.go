@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	_ "golang.org/x/image/webp"
+)
+
+// Cell images: a panel with Show Images on draws cells naming an image file
+// or URL as a thumbnail fitted to the cell. Images are read and decoded off
+// the game loop and kept in a small cache; Ctrl+click (or View Image) shows
+// one at full size.
+const (
+	// thumbSize is the longest side images are cached at for cells
+	thumbSize = 128
+	// fullImageSize is the longest side of an image in the viewer
+	fullImageSize = 2048
+	// maxThumbs is how many thumbnails are cached before the oldest go
+	maxThumbs = 512
+	// maxImageLoads is how many images are read at the same time
+	maxImageLoads = 4
+	// maxImageBytes and maxImagePixels bound what is decoded, so a huge
+	// file cannot exhaust memory
+	maxImageBytes  = 32 << 20
+	maxImagePixels = 50_000_000
+	imageTimeout   = 15 * time.Second
+)
+
+// cellImage is a cached thumbnail; img stays nil when loading failed.
+type cellImage struct {
+	img *ebiten.Image
+}
+
+// imageLoad is a finished read of src, for the viewer when full is set.
+type imageLoad struct {
+	src  string
+	full bool
+	img  image.Image
+	err  error
+}
+
+// CellImages keeps the thumbnails of image cells and the full-size image
+// of the viewer.
+type CellImages struct {
+	thumbs map[string]*cellImage
+	// order lists the cached sources oldest first
+	order   []string
+	running int
+	done    chan imageLoad
+
+	// the viewer opened by Ctrl+click
+	visible bool
+	src     string
+	full    *ebiten.Image
+	msg     string
+}
+
+func NewCellImages() *CellImages {
+	return &CellImages{thumbs: make(map[string]*cellImage), done: make(chan imageLoad, maxImageLoads+1)}
+}
+
+// Thumb returns the thumbnail of src, or nil while it loads or when it
+// failed to. A source seen for the first time starts loading unless
+// maxImageLoads are running; it is asked for again on the next repaint.
+func (ci *CellImages) Thumb(src string) *ebiten.Image {
+	if t, ok := ci.thumbs[src]; ok {
+		return t.img
+	}
+	if ci.running >= maxImageLoads {
+		return nil
+	}
+	ci.thumbs[src] = &cellImage{}
+	ci.order = append(ci.order, src)
+	if len(ci.order) > maxThumbs {
+		old := ci.order[0]
+		ci.order = ci.order[1:]
+		if t := ci.thumbs[old]; t.img != nil {
+			t.img.Deallocate()
+		}
+		delete(ci.thumbs, old)
+	}
+	ci.start(src, false)
+	return nil
+}
+
+// start reads src in the background.
+func (ci *CellImages) start(src string, full bool) {
+	ci.running++
+	size := thumbSize
+	if full {
+		size = fullImageSize
+	}
+	go func() {
+		img, err := loadImage(src, size)
+		ci.done <- imageLoad{src: src, full: full, img: img, err: err}
+	}()
+}
+
+// Update turns finished reads into images and reports whether any
+// arrived, so the canvas is repainted with them.
+func (ci *CellImages) Update() bool {
+	arrived := false
+	for {
+		select {
+		case l := <-ci.done:
+			ci.running--
+			arrived = true
+			switch {
+			case l.full:
+				if !ci.visible || l.src != ci.src {
+					break
+				}
+				if l.err != nil {
+					ci.msg = l.err.Error()
+				} else {
+					ci.full, ci.msg = ebiten.NewImageFromImage(l.img), ""
+				}
+			case l.err == nil:
+				if t, ok := ci.thumbs[l.src]; ok {
+					t.img = ebiten.NewImageFromImage(l.img)
+				}
+			}
+		default:
+			return arrived
+		}
+	}
+}
+
+// loadImage reads and decodes the image at src, a file path or URL, and
+// shrinks it to at most size pixels on its longest side.
+func loadImage(src string, size int) (image.Image, error) {
+	var r io.ReadCloser
+	if cellcanvas.IsImageURL(src) {
+		client := http.Client{Timeout: imageTimeout}
+		resp, err := client.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", src, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", src, maxImageBytes>>20)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%s is %d x %d pixels, too large to show", src, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	b := img.Bounds()
+	if b.Dx() <= size && b.Dy() <= size {
+		return img, nil
+	}
+	s := float64(size) / float64(max(b.Dx(), b.Dy()))
+	dst := image.NewRGBA(image.Rect(0, 0, max(1, int(float64(b.Dx())*s)), max(1, int(float64(b.Dy())*s))))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst, nil
+}
+
+// drawCellImage draws img fitted into the cell at x, y of size w x h,
+// keeping its aspect ratio.
+func (r *Renderer) drawCellImage(screen, img *ebiten.Image, x, y float64, w, h int) {
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	bw, bh := float64(w-4), float64(h-4)
+	if bw <= 0 || bh <= 0 {
+		return
+	}
+	s := math.Min(bw/iw, bh/ih)
+	var op ebiten.DrawImageOptions
+	op.GeoM.Scale(s, s)
+	op.GeoM.Translate(x+2, y+2+(bh-ih*s)/2)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(img, &op)
+}
+
+// toggleImages switches image thumbnails for panel pi.
+func toggleImages(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	p.Images = !p.Images
+	state := "off"
+	if p.Images {
+		state = "on"
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: images %s", pi+1, state)})
+}
+
+// OpenCell shows the image named by cell col,row of panel pi in the viewer.
+// It reports whether the cell names an image.
+func (ci *CellImages) OpenCell(g *Game, pi, col, row int) bool {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return false
+	}
+	p := &g.canvas.Panels[pi]
+	src, ok := p.ImageSource(p.GetCell(col, row))
+	if !ok {
+		return false
+	}
+	if ci.full != nil {
+		ci.full.Deallocate()
+	}
+	ci.visible, ci.src, ci.full, ci.msg = true, src, nil, "loading..."
+	ci.start(src, true)
+	g.power.redraw = true
+	return true
+}
+
+// HandleInput closes the viewer on any click or Esc.
+func (ci *CellImages) HandleInput(g *Game) {
+	if !ci.visible {
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) || g.frame.MouseJustPressed(ebiten.MouseButtonLeft) || g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
+		ci.visible = false
+		if ci.full != nil {
+			ci.full.Deallocate()
+			ci.full = nil
+		}
+		g.power.redraw = true
+	}
+}
+
+// Draw shows the viewer over the dimmed screen: the image fitted to the
+// screen, or the progress or error of reading it, above its source.
+func (ci *CellImages) Draw(screen *ebiten.Image, face font.Face) {
+	if !ci.visible {
+		return
+	}
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	ebitenutil.DrawRect(screen, 0, 0, float64(sw), float64(sh), ColorImageBackdrop)
+	lh := lineHeight(face)
+	if ci.full != nil {
+		iw, ih := float64(ci.full.Bounds().Dx()), float64(ci.full.Bounds().Dy())
+		bw, bh := float64(sw)*0.9, float64(sh)*0.9-float64(lh)
+		s := math.Min(math.Min(bw/iw, bh/ih), 1)
+		var op ebiten.DrawImageOptions
+		op.GeoM.Scale(s, s)
+		op.GeoM.Translate((float64(sw)-iw*s)/2, (float64(sh)-float64(lh)-ih*s)/2)
+		op.Filter = ebiten.FilterLinear
+		screen.DrawImage(ci.full, &op)
+	} else {
+		drawTextAt(screen, face, ci.msg, (sw-textWidth(face, ci.msg))/2, sh/2, ColorText)
+	}
+	drawTextAt(screen, face, ci.src, (sw-textWidth(face, ci.src))/2, sh-lh-PanelInnerPadding, ColorTextDim)
+}
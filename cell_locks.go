@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"sort"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+// cellLockTTL is how long a cell lock lasts unless it is renewed; a client
+// that goes away without releasing its locks loses them after this.
+const cellLockTTL = 30 * time.Second
+
+// hostUser names the person at this instance's keyboard in lock listings
+// and conflict messages.
+const hostUser = "host"
+
+// cellLock is a remote user's hint that they are editing a cell.
+type cellLock struct {
+	User    string
+	Expires time.Time
+}
+
+// CellLocks holds the cells API clients have announced they are editing
+// (see the /lock endpoints of APIServer). Locks are optimistic: they are
+// shown on the canvas and reported to other clients, but never block a
+// write. A write to a cell someone else holds still wins and is recorded
+// in the cell history as a conflict.
+type CellLocks struct {
+	locks map[historyKey]cellLock
+}
+
+func NewCellLocks() *CellLocks {
+	return &CellLocks{locks: make(map[historyKey]cellLock)}
+}
+
+// Observe moves locks along with their panels and drops them with the
+// workspace.
+func (l *CellLocks) Observe(e Event) {
+	switch ev := e.(type) {
+	case PanelRemoved:
+		moved := make(map[historyKey]cellLock, len(l.locks))
+		for k, lk := range l.locks {
+			switch {
+			case k.panel == ev.Panel:
+				continue
+			case k.panel > ev.Panel:
+				k.panel--
+			}
+			moved[k] = lk
+		}
+		l.locks = moved
+	case WorkspaceLoaded:
+		l.locks = make(map[historyKey]cellLock)
+	}
+}
+
+// Lock gives user the lock on a cell until now+cellLockTTL, or renews it.
+// When another user holds it, it is left alone and their name returned.
+func (l *CellLocks) Lock(panel, col, row int, user string, now time.Time) (holder string, ok bool) {
+	k := historyKey{panel, col, row}
+	if lk, held := l.locks[k]; held && lk.User != user && now.Before(lk.Expires) {
+		return lk.User, false
+	}
+	l.locks[k] = cellLock{User: user, Expires: now.Add(cellLockTTL)}
+	return user, true
+}
+
+// Unlock releases user's lock on a cell, if they hold it.
+func (l *CellLocks) Unlock(panel, col, row int, user string) {
+	k := historyKey{panel, col, row}
+	if lk, ok := l.locks[k]; ok && lk.User == user {
+		delete(l.locks, k)
+	}
+}
+
+// Holder returns who holds the lock on a cell, or "".
+func (l *CellLocks) Holder(panel, col, row int, now time.Time) string {
+	if lk, ok := l.locks[historyKey{panel, col, row}]; ok && now.Before(lk.Expires) {
+		return lk.User
+	}
+	return ""
+}
+
+// Update drops expired locks, redrawing when any went.
+func (l *CellLocks) Update(g *Game) {
+	now := time.Now()
+	for k, lk := range l.locks {
+		if !now.Before(lk.Expires) {
+			delete(l.locks, k)
+			g.power.redraw = true
+		}
+	}
+}
+
+// apiLock is the JSON view of a lock returned by the API.
+type apiLock struct {
+	Panel   int       `json:"panel"`
+	Ref     string    `json:"ref"`
+	User    string    `json:"user"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// List returns the live locks and, as held by hostUser, the cell being
+// edited on this instance, ordered by panel and cell.
+func (l *CellLocks) List(g *Game) []apiLock {
+	now := time.Now()
+	out := []apiLock{}
+	for k, lk := range l.locks {
+		if now.Before(lk.Expires) {
+			out = append(out, apiLock{Panel: k.panel + 1, Ref: cellcanvas.CellRef(k.col, k.row), User: lk.User, Expires: lk.Expires})
+		}
+	}
+	if im := g.input; im.editing && im.activePanel >= 0 {
+		out = append(out, apiLock{Panel: im.activePanel + 1, Ref: cellcanvas.CellRef(im.selCol, im.selRow), User: hostUser})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Panel != out[j].Panel {
+			return out[i].Panel < out[j].Panel
+		}
+		return out[i].Ref < out[j].Ref
+	})
+	return out
+}
+
+// conflictWith returns who else was editing a cell that user is writing:
+// the remote holder of its lock, or hostUser while the cell is being
+// edited here. It is "" when nobody was.
+func conflictWith(g *Game, panel, col, row int, user string) string {
+	if h := g.locks.Holder(panel, col, row, time.Now()); h != "" && h != user {
+		return h
+	}
+	im := g.input
+	if user != hostUser && im.editing && im.activePanel == panel && im.selCol == col && im.selRow == row {
+		return hostUser
+	}
+	return ""
+}
+
+// lockColor picks a stable color for user from the accent palette.
+func lockColor(user string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(user))
+	c, _ := parseHexColor(AccentPalette[h.Sum32()%uint32(len(AccentPalette))].Hex)
+	return c
+}
+
+// Draw outlines every locked cell in its user's color with their name
+// above it.
+func (l *CellLocks) Draw(screen *ebiten.Image, face font.Face, c *Canvas) {
+	now := time.Now()
+	for k, lk := range l.locks {
+		if k.panel >= len(c.Panels) || !now.Before(lk.Expires) {
+			continue
+		}
+		p := &c.Panels[k.panel]
+		if !p.CellVisible(k.col, k.row) {
+			continue
+		}
+		b := c.Bounds(p)
+		x, y := b.GridX+p.ColX(k.col), b.GridY+p.RowY(k.row)
+		w, h := p.ColWidth(k.col), p.RowHeight(k.row)
+		clr := lockColor(lk.User)
+		vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 2, clr, false)
+		label := lk.User
+		lh := lineHeight(face)
+		ebitenutil.DrawRect(screen, float64(x), float64(y-lh-2), float64(textWidth(face, label)+4), float64(lh+2), clr)
+		drawTextAt(screen, face, label, x+2, y-lh-1, ColorBackground)
+	}
+}
+
+// lockMessage describes a write to panel's cell that overwrote other's
+// pending edit.
+func lockMessage(by, other string, panel, col, row int) string {
+	return fmt.Sprintf("Panel %d %s: %s overwrote %s's edit (kept in the cell history)", panel+1, cellcanvas.CellRef(col, row), by, other)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+const (
+	// moveBorderSlop is how many pixels either side of the selection's
+	// outline a press grabs it for a drag-move
+	moveBorderSlop = 3
+	// maxMoveUndo is how many moves Ctrl+Z can undo
+	maxMoveUndo = 20
+)
+
+// cellMove is one cell written by a drag-move: the text it held before and
+// the text the move wrote.
+type cellMove struct {
+	panel, col, row int
+	old, new        string
+}
+
+// CellMoves keeps the drag-moves that Ctrl+Z undoes, most recent last.
+// Removing a panel or loading a workspace forgets them, and reloading a
+// panel forgets the moves that touched it.
+type CellMoves struct {
+	undo [][]cellMove
+}
+
+func NewCellMoves() *CellMoves {
+	return &CellMoves{}
+}
+
+// Observe forgets moves whose panel indexes or contents were replaced.
+func (m *CellMoves) Observe(e Event) {
+	switch ev := e.(type) {
+	case PanelRemoved, WorkspaceLoaded:
+		m.undo = nil
+	case PanelLoaded:
+		kept := m.undo[:0]
+		for _, step := range m.undo {
+			if !touches(step, ev.Panel) {
+				kept = append(kept, step)
+			}
+		}
+		m.undo = kept
+	}
+}
+
+// touches reports whether step wrote a cell of panel pi.
+func touches(step []cellMove, pi int) bool {
+	for _, cm := range step {
+		if cm.panel == pi {
+			return true
+		}
+	}
+	return false
+}
+
+// grabSelectionBorder starts a drag-move of the selected range of panel pi
+// when view position mx, my is on its outline. It reports whether it did.
+func (im *InputManager) grabSelectionBorder(g *Game, pi int, b PanelBounds, mx, my int) bool {
+	if pi != im.activePanel || g.input.editing {
+		return false
+	}
+	p := &g.canvas.Panels[pi]
+	col0, row0, col1, row1 := im.selectionRange()
+	x0, y0 := b.GridX+p.ColX(col0), b.GridY+p.RowY(row0)
+	x1, y1 := b.GridX+p.ColX(col1+1), b.GridY+p.RowY(row1+1)
+	near := mx >= x0-moveBorderSlop && mx <= x1+moveBorderSlop && my >= y0-moveBorderSlop && my <= y1+moveBorderSlop
+	inside := mx > x0+moveBorderSlop && mx < x1-moveBorderSlop && my > y0+moveBorderSlop && my < y1-moveBorderSlop
+	if !near || inside {
+		return false
+	}
+	// the grabbed cell, kept under the cursor while dragging
+	grabCol := min(max(p.ColAt(mx-b.GridX), col0), col1)
+	grabRow := min(max(p.RowAt(my-b.GridY), row0), row1)
+	im.movingCells = true
+	im.moveGrabCol, im.moveGrabRow = grabCol-col0, grabRow-row0
+	im.moveToPanel, im.moveToCol, im.moveToRow = pi, col0, row0
+	return true
+}
+
+// dragCells follows the cursor while the selection is dragged, and moves
+// the cells on release, or drops the rows when whole rows go to another
+// panel (see draggingRows). Escape cancels.
+func (im *InputManager) dragCells(g *Game) {
+	if !im.movingCells {
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) {
+		im.movingCells = false
+		g.events.Publish(LogMessage{Text: "move cancelled"})
+		return
+	}
+	if pi, col, row, ok := g.canvas.CellAt(g.frame.CursorPosition()); ok {
+		im.moveToPanel = pi
+		im.moveToCol = max(col-im.moveGrabCol, 0)
+		im.moveToRow = max(row-im.moveGrabRow, 0)
+		if im.draggingRows(g) {
+			// rows go in between rows, at the edge nearest the cursor
+			_, vy := g.canvas.ToView(g.frame.CursorPosition())
+			im.moveToRow = max(rowDropAt(g, pi, vy)-im.moveGrabRow, 0)
+		}
+	}
+	if g.frame.MouseJustReleased(ebiten.MouseButtonLeft) {
+		if im.draggingRows(g) {
+			im.movingCells = false
+			dropRows(g, im.moveToPanel, im.moveToRow, g.frame.CtrlPressed())
+			return
+		}
+		im.movingCells = false
+		col0, row0, _, _ := im.selectionRange()
+		if im.moveToPanel != im.activePanel || im.moveToCol != col0 || im.moveToRow != row0 {
+			moveCells(g, im.moveToPanel, im.moveToCol, im.moveToRow)
+		}
+	}
+}
+
+// drawMoveGhost outlines where a drag-move in progress puts the selection.
+func (im *InputManager) drawMoveGhost(screen *ebiten.Image, face font.Face, g *Game) {
+	if !im.movingCells || im.moveToPanel < 0 || im.moveToPanel >= len(g.canvas.Panels) {
+		return
+	}
+	if im.draggingRows(g) {
+		im.drawRowDropGhost(screen, face, g)
+		return
+	}
+	col0, row0, col1, row1 := im.selectionRange()
+	p := &g.canvas.Panels[im.moveToPanel]
+	b := g.canvas.Bounds(p)
+	c, r := im.moveToCol, im.moveToRow
+	x, y := float64(b.GridX+p.ColX(c)), float64(b.GridY+p.RowY(r))
+	w := float64(p.ColX(c+col1-col0+1) - p.ColX(c))
+	h := float64(p.RowY(r+row1-row0+1) - p.RowY(r))
+	ebitenutil.DrawRect(screen, x, y, w, 2, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x, y+h-2, w, 2, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x, y, 2, h, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x+w-2, y, 2, h, ColorResizeGhost)
+
+	label := cellcanvas.CellRef(c, r)
+	if im.moveToPanel != im.activePanel {
+		label = fmt.Sprintf("Panel %d!%s", im.moveToPanel+1, label)
+	}
+	lx, ly := int(x+w)+6, int(y+h)+4
+	ebitenutil.DrawRect(screen, float64(lx), float64(ly), float64(textWidth(face, label)+PanelInnerPadding*2), float64(lineHeight(face)+PanelInnerPadding), ColorMenuBg)
+	drawTextAt(screen, face, label, lx+PanelInnerPadding, ly+PanelInnerPadding/2, ColorText)
+}
+
+// moveCells moves the contents of the selected range to col, row of panel
+// dst, which grows to fit, leaving the source cells empty, and selects the
+// moved range there. Every cell written is published as an edit; the move
+// as a whole can be undone with Ctrl+Z. Formulas move as they are written.
+// Nothing moves when a value is not a number for a column with a unit.
+func moveCells(g *Game, dst, col, row int) {
+	im := g.input
+	src := im.activePanel
+	if src < 0 || src >= len(g.canvas.Panels) || dst < 0 || dst >= len(g.canvas.Panels) {
+		return
+	}
+	for _, pi := range []int{src, dst} {
+		if !g.canvas.Panels[pi].Loaded || previewBlocksEdit(g, pi) || queryBlocksEdit(g, pi) {
+			return
+		}
+	}
+	col0, row0, col1, row1 := im.selectionRange()
+	sp, dp := &g.canvas.Panels[src], &g.canvas.Panels[dst]
+	cw, ch := col1-col0+1, row1-row0+1
+
+	// collect the writes first, so that overlapping ranges move intact
+	var w cellWrites
+	vals := make([]string, 0, cw*ch)
+	for r := row0; r <= row1; r++ {
+		for c := col0; c <= col1; c++ {
+			vals = append(vals, sp.RawCell(c, r))
+			w.set(src, c, r, "")
+		}
+	}
+	for i, v := range vals {
+		v, err := unitWrite(g, src, col0+i%cw, dst, col+i%cw, row+i/cw, v)
+		if err != nil {
+			g.events.Publish(LogMessage{Text: err.Error() + "; nothing moved"})
+			return
+		}
+		w.set(dst, col+i%cw, row+i/cw, v)
+	}
+
+	cols, rows := dp.Cols, dp.Rows
+	dp.Cols, dp.Rows = max(dp.Cols, col+cw), max(dp.Rows, row+ch)
+	w.apply(g)
+	if dp.Cols != cols || dp.Rows != rows {
+		g.events.Publish(PanelResized{Panel: dst, Cols: dp.Cols, Rows: dp.Rows})
+	}
+	if dst != src {
+		im.switchToPanel(g, dst)
+	}
+	im.selectRange(g, dst, row, col, row+ch-1, col+cw-1)
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("moved %s:%s to %s of Panel %d (Ctrl+Z undoes)",
+		cellcanvas.CellRef(col0, row0), cellcanvas.CellRef(col1, row1), cellcanvas.CellRef(col, row), dst+1)})
+}
+
+// unitWrite returns v, taken from column from of panel src, as it is
+// written at col,row of panel dst: see Panel.UnitValue. A value staying in
+// its column is kept as it is, even if it predates the column's unit.
+func unitWrite(g *Game, src, from, dst, col, row int, v string) (string, error) {
+	if src == dst && from == col {
+		return v, nil
+	}
+	return g.canvas.Panels[dst].UnitValue(col, row, v)
+}
+
+// cellWrites collects the cells a move writes before any is written, so
+// that every value is read from the cells as they were. A cell set twice
+// keeps the last value.
+type cellWrites struct {
+	order [][3]int
+	vals  map[[3]int]string
+}
+
+// set writes v to cell col,row of panel pi.
+func (w *cellWrites) set(pi, col, row int, v string) {
+	k := [3]int{pi, col, row}
+	if w.vals == nil {
+		w.vals = make(map[[3]int]string)
+	}
+	if _, ok := w.vals[k]; !ok {
+		w.order = append(w.order, k)
+	}
+	w.vals[k] = v
+}
+
+// apply writes the cells that change, publishing each as an edit, and keeps
+// them as one step for Ctrl+Z. It returns the number of cells written.
+func (w *cellWrites) apply(g *Game) int {
+	var step []cellMove
+	for _, k := range w.order {
+		p := &g.canvas.Panels[k[0]]
+		old, v := p.RawCell(k[1], k[2]), w.vals[k]
+		if old == v {
+			continue
+		}
+		p.SetCell(k[1], k[2], v)
+		step = append(step, cellMove{panel: k[0], col: k[1], row: k[2], old: old, new: v})
+		g.events.Publish(CellChanged{Panel: k[0], Col: k[1], Row: k[2], Old: old, New: v})
+	}
+	if len(step) > 0 {
+		m := g.moves
+		m.undo = append(m.undo, step)
+		if len(m.undo) > maxMoveUndo {
+			m.undo = m.undo[1:]
+		}
+	}
+	return len(step)
+}
+
+// undoMove restores the cells of the last drag-move, unless one of them
+// was edited since.
+func undoMove(g *Game) {
+	m := g.moves
+	if len(m.undo) == 0 {
+		g.events.Publish(LogMessage{Text: "nothing to undo"})
+		return
+	}
+	step := m.undo[len(m.undo)-1]
+	m.undo = m.undo[:len(m.undo)-1]
+	for _, cm := range step {
+		if cm.panel >= len(g.canvas.Panels) || g.canvas.Panels[cm.panel].RawCell(cm.col, cm.row) != cm.new {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("%s was edited after the move; the move is not undone", cellcanvas.CellRef(cm.col, cm.row))})
+			return
+		}
+	}
+	for _, cm := range step {
+		g.canvas.Panels[cm.panel].SetCell(cm.col, cm.row, cm.old)
+		g.events.Publish(CellChanged{Panel: cm.panel, Col: cm.col, Row: cm.row, Old: cm.new, New: cm.old})
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("undid the move of %d cells", len(step))})
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+const (
+	// starGap is the space between the stars of a rating
+	starGap = 2
+	// chipPad is the space around a tag chip's text, and chipGap between
+	// chips
+	chipPad   = 3
+	chipGap   = 3
+	chipAlpha = 0.45
+	// qrQuiet is the light margin around a QR code, in modules
+	qrQuiet = 2
+	// maxQRCodes is how many QR codes are cached before the cache is
+	// cleared
+	maxQRCodes = 512
+)
+
+// columnRenderers are the choices of pickColumnRenderer.
+var columnRenderers = []struct {
+	label string
+	kind  string
+}{
+	{"Text", ""},
+	{fmt.Sprintf("Star rating (0-%d)", cellcanvas.MaxStars), cellcanvas.RenderStars},
+	{"Tag chips (comma-separated)", cellcanvas.RenderTags},
+	{"QR code", cellcanvas.RenderQR},
+}
+
+// drawRenderedCell draws cell value v of a column with renderer kind in the
+// cell at x, y of size w x h, and reports whether it did; values the
+// renderer cannot draw are left to be drawn as text.
+func (r *Renderer) drawRenderedCell(screen *ebiten.Image, face font.Face, kind, v string, x, y float64, w, h int) bool {
+	switch kind {
+	case cellcanvas.RenderStars:
+		n, ok := cellcanvas.Stars(v)
+		if !ok {
+			return false
+		}
+		r.drawStars(screen, n, x+PanelInnerPadding, y, w-PanelInnerPadding, h)
+		return true
+	case cellcanvas.RenderTags:
+		tags := cellcanvas.Tags(v)
+		if len(tags) == 0 {
+			return false
+		}
+		r.drawTagChips(screen, face, tags, x+PanelInnerPadding/2, y, w-PanelInnerPadding/2, h)
+		return true
+	case cellcanvas.RenderQR:
+		img := r.qrCode(v)
+		if img == nil {
+			return false
+		}
+		return r.drawQRCode(screen, img, x, y, w, h)
+	}
+	return false
+}
+
+// drawStars draws a rating of n out of MaxStars, filled stars first, in the
+// space at x, y of size w x h.
+func (r *Renderer) drawStars(screen *ebiten.Image, n int, x, y float64, w, h int) {
+	s := min(min(h-2*starGap, (w-starGap*cellcanvas.MaxStars)/cellcanvas.MaxStars), iconSize+4)
+	if s < 4 {
+		return
+	}
+	top := float32(y) + float32(h-s)/2
+	for i := range cellcanvas.MaxStars {
+		path := &r.icon
+		path.Reset()
+		starPath(path, float32(x)+float32(i*(s+starGap)), top, float32(s))
+		path.Close()
+		var op vector.DrawPathOptions
+		op.AntiAlias = true
+		if i < n {
+			op.ColorScale.ScaleWithColor(ColorStarFilled)
+		} else {
+			op.ColorScale.ScaleWithColor(ColorStarEmpty)
+		}
+		vector.FillPath(screen, path, nil, &op)
+	}
+}
+
+// drawTagChips draws tags as chips left to right in the space at x, y of
+// size w x h, as many as fit. Each tag has a color of its own, the same
+// wherever it appears.
+func (r *Renderer) drawTagChips(screen *ebiten.Image, face font.Face, tags []string, x, y float64, w, h int) {
+	lh := lineHeight(face)
+	ch := float64(min(lh+chipPad, h-2))
+	cy := y + (float64(h)-ch)/2
+	end := x + float64(w)
+	for _, t := range tags {
+		cw := float64(textWidth(face, t) + 2*chipPad)
+		if x+cw > end {
+			break
+		}
+		r.tintRect(screen, x, cy, cw, ch, tagColor(t), chipAlpha)
+		drawTextAt(screen, face, t, int(x)+chipPad, int(cy+(ch-float64(lh))/2), ColorText)
+		x += cw + chipGap
+	}
+}
+
+// tagColor picks the chip color of tag t from the accent palette.
+func tagColor(t string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(t))
+	c, _ := parseHexColor(AccentPalette[h.Sum32()%uint32(len(AccentPalette))].Hex)
+	return c
+}
+
+// qrCode returns the QR code of v, one pixel per module with its quiet
+// zone, or nil when v is empty or too long for one.
+func (r *Renderer) qrCode(v string) *ebiten.Image {
+	if img, ok := r.qrCodes[v]; ok {
+		return img
+	}
+	if len(r.qrCodes) >= maxQRCodes || r.qrCodes == nil {
+		for _, img := range r.qrCodes {
+			if img != nil {
+				img.Deallocate()
+			}
+		}
+		r.qrCodes = make(map[string]*ebiten.Image)
+	}
+	var img *ebiten.Image
+	if mods, err := cellcanvas.QRCode(v); err == nil && v != "" {
+		n := len(mods) + 2*qrQuiet
+		pix := image.NewGray(image.Rect(0, 0, n, n))
+		for i := range pix.Pix {
+			pix.Pix[i] = 0xff
+		}
+		for y, row := range mods {
+			for x, dark := range row {
+				if dark {
+					pix.SetGray(x+qrQuiet, y+qrQuiet, color.Gray{})
+				}
+			}
+		}
+		img = ebiten.NewImageFromImage(pix)
+	}
+	r.qrCodes[v] = img
+	return img
+}
+
+// drawQRCode draws QR code img as the largest square that fits the cell at
+// x, y of size w x h, at a whole number of pixels per module so the modules
+// stay sharp. It reports whether the cell is large enough for the code to
+// be drawn at all: below one pixel per module, modules would be dropped
+// and the code could not be scanned.
+func (r *Renderer) drawQRCode(screen, img *ebiten.Image, x, y float64, w, h int) bool {
+	side := min(w, h) - 4
+	n := img.Bounds().Dx()
+	if side < n {
+		return false
+	}
+	s := float64(side / n)
+	var op ebiten.DrawImageOptions
+	op.GeoM.Scale(s, s)
+	op.GeoM.Translate(math.Round(x+2), math.Round(y+(float64(h)-float64(n)*s)/2))
+	op.Filter = ebiten.FilterNearest
+	screen.DrawImage(img, &op)
+	return true
+}
+
+// pickColumnRenderer asks how the cells of column col of panel pi are
+// drawn: as text, a star rating, tag chips or QR codes.
+func pickColumnRenderer(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := make([]string, len(columnRenderers))
+	for i, cr := range columnRenderers {
+		items[i] = cr.label
+	}
+	letters := cellcanvas.ColToLetters(col)
+	g.picker.Open(fmt.Sprintf("Draw Panel %d column %s as", pi+1, letters), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		g.canvas.Panels[pi].SetRenderer(col, columnRenderers[i].kind)
+		g.power.redraw = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s: %s", letters, columnRenderers[i].label)})
+	})
+}
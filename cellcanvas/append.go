@@ -0,0 +1,97 @@
+package cellcanvas
+
+import "strings"
+
+// UsedRows returns the number of rows up to and including the last one
+// holding a value.
+func (p *Panel) UsedRows() int {
+	n := 0
+	if p.Paged != nil {
+		n = p.Paged.Rows()
+	}
+	for ref, v := range p.Cells {
+		if v == "" {
+			continue
+		}
+		if _, row, err := ParseCellRef(ref); err == nil && row >= n {
+			n = row + 1
+		}
+	}
+	return n
+}
+
+// UsedCols returns the number of columns up to and including the last one
+// holding a value.
+func (p *Panel) UsedCols() int {
+	n := 0
+	if p.Paged != nil {
+		n = p.Paged.Cols()
+	}
+	for ref, v := range p.Cells {
+		if v == "" {
+			continue
+		}
+		if col, _, err := ParseCellRef(ref); err == nil && col >= n {
+			n = col + 1
+		}
+	}
+	return n
+}
+
+// AppendPanel copies the rows of src below the data of p and returns the
+// first appended row and the number of rows appended.
+//
+// With byHeader the first row of each panel is a header: src columns are
+// appended under the p column with the same (case-insensitive) header,
+// headers p lacks become new columns after its last used one, and the src header row itself is
+// skipped unless p is still empty. Otherwise rows are appended by position.
+func (p *Panel) AppendPanel(src *Panel, byHeader bool) (first, n int) {
+	first = p.UsedRows()
+	srcRows := src.UsedRows()
+	colMap := make([]int, src.Cols)
+	for c := range colMap {
+		colMap[c] = c
+	}
+	start := 0
+	if byHeader && first > 0 && srcRows > 0 {
+		start = 1
+		headers := make(map[string]int)
+		for c := 0; c < p.Cols; c++ {
+			if h := headerKey(p.GetCell(c, 0)); h != "" {
+				if _, dup := headers[h]; !dup {
+					headers[h] = c
+				}
+			}
+		}
+		next := p.UsedCols()
+		for c := range colMap {
+			h := headerKey(src.GetCell(c, 0))
+			if dst, ok := headers[h]; ok && h != "" {
+				colMap[c] = dst
+				continue
+			}
+			// a new column, headed like in src
+			colMap[c] = next
+			p.SetCell(next, 0, src.RawCell(c, 0))
+			next++
+		}
+		p.Cols = max(p.Cols, next)
+	} else {
+		p.Cols = max(p.Cols, src.Cols)
+	}
+	for r := start; r < srcRows; r++ {
+		row := first + n
+		for c, dst := range colMap {
+			if v := src.RawCell(c, r); v != "" {
+				p.SetCell(dst, row, v)
+			}
+		}
+		n++
+	}
+	p.Rows = max(p.Rows, first+n)
+	return first, n
+}
+
+func headerKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,142 @@
+package cellcanvas
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fileTxn writes a group of files so that either all of them are replaced
+// or none are. Each file is first written and synced to a temporary file in
+// its target directory; commit then renames them over their targets, which
+// replaces each one atomically, keeping a second link to (or a copy of) the
+// previous versions until every rename succeeded so they can be restored.
+// A target is never missing, even for a moment.
+type fileTxn struct {
+	files []txnFile
+}
+
+type txnFile struct {
+	path, tmp string
+}
+
+// write stages path with the content produced by fn. On error the staged
+// temporaries are discarded.
+func (t *fileTxn) write(path string, perm os.FileMode, fn func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.abort()
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		t.abort()
+		return err
+	}
+	t.files = append(t.files, txnFile{path: path, tmp: f.Name()})
+	err = fn(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err != nil {
+		t.abort()
+	}
+	return err
+}
+
+// commit renames every staged file into place in the order they were
+// written. If a rename fails, files already replaced are rolled back to
+// their previous content.
+func (t *fileTxn) commit() error {
+	type swapped struct {
+		path, backup string
+	}
+	var done []swapped
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			if done[i].backup != "" {
+				os.Rename(done[i].backup, done[i].path)
+			} else {
+				os.Remove(done[i].path)
+			}
+		}
+	}
+	for i, f := range t.files {
+		backup := ""
+		if _, err := os.Stat(f.path); err == nil {
+			backup = f.tmp + ".bak"
+			if err := keepBackup(f.path, backup); err != nil {
+				os.Remove(backup)
+				rollback()
+				t.files = t.files[i:]
+				t.abort()
+				return err
+			}
+		}
+		if err := os.Rename(f.tmp, f.path); err != nil {
+			// f.path was not touched
+			if backup != "" {
+				os.Remove(backup)
+			}
+			rollback()
+			t.files = t.files[i:]
+			t.abort()
+			return err
+		}
+		done = append(done, swapped{f.path, backup})
+	}
+	for _, d := range done {
+		if d.backup != "" {
+			// a leftover backup is harmless; the save itself succeeded
+			os.Remove(d.backup)
+		}
+	}
+	t.files = nil
+	return nil
+}
+
+// keepBackup makes backup a hard link to path, so the rename that replaces
+// path leaves its old content there, or a copy of it where the file system
+// has no hard links.
+func keepBackup(path, backup string) error {
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(backup)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// abort removes any staged temporaries.
+func (t *fileTxn) abort() {
+	for _, f := range t.files {
+		os.Remove(f.tmp)
+	}
+	t.files = nil
+}
+
+// WriteFileAtomic replaces path with the content produced by fn, so readers
+// (and a crash mid-write) only ever see the old or the new file.
+func WriteFileAtomic(path string, perm os.FileMode, fn func(w io.Writer) error) error {
+	var t fileTxn
+	if err := t.write(path, perm, fn); err != nil {
+		return err
+	}
+	return t.commit()
+}
@@ -0,0 +1,33 @@
+package cellcanvas
+
+// Cell border styles stored in Panel.Borders.
+const (
+	BorderBox          = "box"
+	BorderBoxThick     = "box-thick"
+	BorderTop          = "top"
+	BorderBottom       = "bottom"
+	BorderBottomThick  = "bottom-thick"
+	BorderBottomDouble = "bottom-double"
+)
+
+// Border returns the border style of the cell at col, row, or "" for none.
+func (p *Panel) Border(col, row int) string {
+	if len(p.Borders) == 0 {
+		return ""
+	}
+	var buf [24]byte
+	return p.Borders[string(AppendCellRef(buf[:0], col, row))]
+}
+
+// SetBorder sets the border style of the cell at col, row; "" removes it.
+func (p *Panel) SetBorder(col, row int, style string) {
+	ref := CellRef(col, row)
+	if style == "" {
+		delete(p.Borders, ref)
+		return
+	}
+	if p.Borders == nil {
+		p.Borders = make(map[string]string)
+	}
+	p.Borders[ref] = style
+}
@@ -0,0 +1,69 @@
+package cellcanvas
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Cell renderers of a ColumnRenderer: how the cells of a column are drawn
+// instead of as text.
+const (
+	// RenderStars draws whole numbers from 0 to MaxStars as a star rating
+	RenderStars = "stars"
+	// RenderTags draws comma-separated values as colored chips
+	RenderTags = "tags"
+)
+
+// MaxStars is the rating a RenderStars column draws out of.
+const MaxStars = 5
+
+// ColumnRenderer draws the cells of column Col below the header with
+// Kind, one of the Render constants. Cells it cannot draw, such as a
+// rating out of range, are shown as text; the stored values are unchanged.
+type ColumnRenderer struct {
+	Col  int    `yaml:"col"`
+	Kind string `yaml:"kind"`
+}
+
+// RendererAt returns col's renderer, or "" for text.
+func (p *Panel) RendererAt(col int) string {
+	for _, cr := range p.Renderers {
+		if cr.Col == col {
+			return cr.Kind
+		}
+	}
+	return ""
+}
+
+// SetRenderer sets or, with an empty kind, removes col's renderer.
+func (p *Panel) SetRenderer(col int, kind string) {
+	for i, cr := range p.Renderers {
+		if cr.Col == col {
+			p.Renderers = append(p.Renderers[:i], p.Renderers[i+1:]...)
+			break
+		}
+	}
+	if kind != "" {
+		p.Renderers = append(p.Renderers, ColumnRenderer{Col: col, Kind: kind})
+	}
+}
+
+// Stars returns the rating v holds: a whole number from 0 to MaxStars.
+func Stars(v string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 || n > MaxStars {
+		return 0, false
+	}
+	return n, true
+}
+
+// Tags splits v at commas into its non-empty tags, trimmed.
+func Tags(v string) []string {
+	var tags []string
+	for _, t := range strings.Split(v, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -1,4 +1,4 @@
-package main
+package cellcanvas
 
 import (
 	"fmt"
@@ -45,7 +45,26 @@ func LettersToCol(s string) (int, error) {
 
 // CellRef returns a string like "A1" for the given zero-based col and row.
 func CellRef(col, row int) string {
-	return fmt.Sprintf("%s%d", ColToLetters(col), row+1)
+	var buf [24]byte
+	return string(AppendCellRef(buf[:0], col, row))
+}
+
+// AppendCellRef appends the A1-style reference of col, row to dst. Together
+// with a stack buffer it lets hot paths look cells up without allocating.
+func AppendCellRef(dst []byte, col, row int) []byte {
+	if col < 0 {
+		dst = append(dst, '?')
+	} else {
+		// letters come out least significant first; reverse them in place
+		start := len(dst)
+		for n := col; n >= 0; n = n/26 - 1 {
+			dst = append(dst, byte('A'+n%26))
+		}
+		for i, j := start, len(dst)-1; i < j; i, j = i+1, j-1 {
+			dst[i], dst[j] = dst[j], dst[i]
+		}
+	}
+	return strconv.AppendInt(dst, int64(row+1), 10)
 }
 
 // ParseCellRef parses a cell reference like "A1" or "BC23" into zero-based
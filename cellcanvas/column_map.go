@@ -0,0 +1,70 @@
+package cellcanvas
+
+// HasHeader reports whether row 0 of p holds any text, taken as the header
+// names of its columns.
+func (p *Panel) HasHeader() bool {
+	for col := 0; col < p.Cols; col++ {
+		if p.RawCell(col, 0) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchColumns maps each column of src to the column of p with the same
+// header (compared case-insensitively), or -1 when p has none. Of several
+// equal headers in p the first is used.
+func MatchColumns(p, src *Panel) []int {
+	headers := make(map[string]int)
+	for col := p.Cols - 1; col >= 0; col-- {
+		if h := headerKey(p.RawCell(col, 0)); h != "" {
+			headers[h] = col
+		}
+	}
+	colMap := make([]int, src.Cols)
+	for c := range colMap {
+		colMap[c] = -1
+		if dst, ok := headers[headerKey(src.RawCell(c, 0))]; ok {
+			colMap[c] = dst
+		}
+	}
+	return colMap
+}
+
+// SameColumns reports whether colMap puts every column of src in the same
+// place, so loading it by position would give the same result.
+func SameColumns(colMap []int) bool {
+	for c, dst := range colMap {
+		if dst != c {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplaceMapped replaces the data of p with the rows of src below its
+// header, src column c going to p column colMap[c] and columns mapped to -1
+// being skipped. p keeps its header row and size, growing to fit; a column
+// without a header takes the one from src, and columns nothing maps to are
+// left empty.
+func (p *Panel) ReplaceMapped(src *Panel, colMap []int) {
+	rows := src.UsedRows()
+	tmp := NewBlankPanel(0, 0, p.Cols, max(p.Rows, rows, 1))
+	for col := 0; col < p.Cols; col++ {
+		tmp.SetCell(col, 0, p.RawCell(col, 0))
+	}
+	for c, dst := range colMap {
+		if dst < 0 || c >= src.Cols {
+			continue
+		}
+		tmp.Cols = max(tmp.Cols, dst+1)
+		if tmp.RawCell(dst, 0) == "" {
+			tmp.SetCell(dst, 0, src.RawCell(c, 0))
+		}
+		for row := 1; row < rows; row++ {
+			tmp.SetCell(dst, row, src.RawCell(c, row))
+		}
+	}
+	tmp.Problems = src.Problems
+	p.ReplaceContent(&tmp)
+}
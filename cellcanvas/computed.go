@@ -0,0 +1,140 @@
+package cellcanvas
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ComputedColumn fills column Col from row 1 down with Expr, an arithmetic
+// expression over the other columns named by their header (row 0), e.g.
+// "price * qty".
+type ComputedColumn struct {
+	Col  int    `yaml:"col"`
+	Expr string `yaml:"expr"`
+}
+
+// Values written into computed cells that cannot be evaluated.
+const (
+	ComputedValueError = "#VALUE!"
+	ComputedDivError   = "#DIV/0!"
+	ComputedNameError  = "#NAME?"
+)
+
+// compiledColumn is a ComputedColumn with its names resolved to columns.
+type compiledColumn struct {
+	col  int
+	expr *Expr
+	// cols maps each name in expr to its column, -1 when no header has it
+	cols map[string]int
+}
+
+// ComputedAt returns the index in p.Computed of the definition of col, or
+// -1.
+func (p *Panel) ComputedAt(col int) int {
+	for i, cc := range p.Computed {
+		if cc.Col == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetComputed defines column col as expr; ComputeRows then fills it. An
+// empty expr turns the column back into plain cells, keeping their last
+// values. Names must match a column header other than col's own.
+func (p *Panel) SetComputed(col int, expr string) error {
+	expr = strings.TrimSpace(expr)
+	i := p.ComputedAt(col)
+	if expr == "" {
+		if i >= 0 {
+			p.Computed = append(p.Computed[:i], p.Computed[i+1:]...)
+		}
+		return nil
+	}
+	cc := ComputedColumn{Col: col, Expr: expr}
+	c, err := p.compile(cc)
+	if err != nil {
+		return err
+	}
+	for name, ref := range c.cols {
+		switch ref {
+		case -1:
+			return fmt.Errorf("no column is called %q", name)
+		case col:
+			return fmt.Errorf("%q is the computed column itself", name)
+		}
+	}
+	if i >= 0 {
+		p.Computed[i] = cc
+	} else {
+		p.Computed = append(p.Computed, cc)
+	}
+	return nil
+}
+
+// compile parses cc and resolves its names against the header row. Names
+// match headers case-insensitively.
+func (p *Panel) compile(cc ComputedColumn) (*compiledColumn, error) {
+	e, err := ParseExpr(cc.Expr)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiledColumn{col: cc.Col, expr: e, cols: make(map[string]int)}
+	for _, name := range e.Names() {
+		c.cols[name] = -1
+		for col := 0; col < p.Cols; col++ {
+			if strings.EqualFold(strings.TrimSpace(p.GetCell(col, 0)), name) {
+				c.cols[name] = col
+				break
+			}
+		}
+	}
+	return c, nil
+}
+
+// ComputeRows refills the computed columns in rows [from, to). Columns are
+// computed in definition order, so one may use another defined before it.
+// A definition whose expression no longer parses is left alone.
+func (p *Panel) ComputeRows(from, to int) {
+	for _, cc := range p.Computed {
+		c, err := p.compile(cc)
+		if err != nil {
+			continue
+		}
+		for row := max(from, 1); row < min(to, p.Rows); row++ {
+			p.SetCell(c.col, row, c.value(p, row))
+		}
+	}
+}
+
+// value evaluates c in row. Empty cells count as 0; a row whose inputs are
+// all empty stays empty.
+func (c *compiledColumn) value(p *Panel, row int) string {
+	filled := false
+	v, err := c.expr.Eval(func(name string) (float64, error) {
+		col := c.cols[name]
+		if col < 0 {
+			return 0, errComputedName
+		}
+		s := strings.TrimSpace(p.GetCell(col, row))
+		if s == "" {
+			return 0, nil
+		}
+		filled = true
+		return p.Numbers().Parse(s)
+	})
+	switch {
+	case errors.Is(err, errComputedName):
+		return ComputedNameError
+	case !filled && len(c.cols) > 0:
+		return ""
+	case errors.Is(err, ErrDivByZero):
+		return ComputedDivError
+	case err != nil:
+		return ComputedValueError
+	}
+	return p.Numbers().Format(v, 12)
+}
+
+var errComputedName = errors.New("unknown column")
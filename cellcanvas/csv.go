@@ -0,0 +1,215 @@
+package cellcanvas
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+)
+
+// SavePanelCSV writes the panel grid to path as CSV with the panel's
+// delimiter, creating parent directories as needed. The file is replaced
+// atomically.
+func SavePanelCSV(path string, p *Panel) error {
+	return fileError(path, WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return WritePanelCSV(w, p)
+	}))
+}
+
+// WritePanelCSV writes the panel grid to w as CSV with the panel's
+// delimiter.
+func WritePanelCSV(out io.Writer, p *Panel) error {
+	return writePanelCSV(out, p, nil)
+}
+
+// writePanelCSV writes p as CSV, appending the values returned by extra (if
+// non-nil) to each row.
+func writePanelCSV(out io.Writer, p *Panel, extra func(row int) []string) error {
+	w := csv.NewWriter(out)
+	w.Comma = p.comma()
+	// values left outside the panel by Resize are saved too
+	cols, rows := p.DataExtent()
+	// Determine the last row that contains any non-empty data. We will
+	// write rows up to and including that index. This prevents saving
+	// trailing empty rows at the bottom of the CSV while preserving
+	// intermediate empty rows.
+	lastRow := -1
+	for r := 0; r < rows; r++ {
+		for cidx := 0; cidx < cols; cidx++ {
+			if p.RawCell(cidx, r) != "" {
+				lastRow = r
+				break
+			}
+		}
+	}
+
+	if lastRow == -1 {
+		// no data at all; write an empty file
+		return nil
+	}
+
+	for r := 0; r <= lastRow; r++ {
+		row := make([]string, cols)
+		for cidx := 0; cidx < cols; cidx++ {
+			row[cidx] = p.RawCell(cidx, r)
+		}
+		if extra != nil {
+			row = append(row, extra(r)...)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// LoadPanelCSV replaces the grid size and cells of p with the contents of the
+// CSV file at path, read with p's delimiter, which is sniffed from the file
+// when p has none (see SniffDelimiter).
+//
+// Mid-sized files are read whole and parsed on several goroutines (see
+// ParseCSVParallel); others, and files that do not parse cleanly, are
+// streamed by ReadPanelCSV. Failures are returned as a *FileError.
+func LoadPanelCSV(path string, p *Panel) error {
+	return fileError(path, loadPanelCSV(path, p))
+}
+
+func loadPanelCSV(path string, p *Panel) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := sniffPanelDelimiter(f, p); err != nil {
+		return err
+	}
+	if st, err := f.Stat(); err == nil && st.Size() >= parallelCSVMinBytes && st.Size() <= parallelCSVMaxBytes {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		records, err := parseCSVParallel(data, 0, p.comma())
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			// recover what ReadPanelCSV can and note the rest
+			return ReadPanelCSV(bytes.NewReader(data), p)
+		}
+		if err != nil {
+			return err
+		}
+		p.Problems = nil
+		return setPanelRecords(p, records, PagingThreshold)
+	}
+	return ReadPanelCSV(f, p)
+}
+
+// ReadPanelCSV replaces the grid size and cells of p with CSV read from in
+// with p's delimiter. Records are streamed; once more than PagingThreshold rows have been read
+// the panel's data moves to a PagedStore instead of the Cells map.
+//
+// Records that cannot be parsed are skipped and ragged ones fitted per
+// RaggedRows; p.Problems lists them.
+func ReadPanelCSV(in io.Reader, p *Panel) error {
+	return readPanelCSV(in, p, PagingThreshold)
+}
+
+// readPanelCSV is ReadPanelCSV paging past threshold rows; zero never
+// pages.
+func readPanelCSV(in io.Reader, p *Panel, threshold int) error {
+	r := newCSVRecovery(in, RaggedRows, p.comma())
+	defer func() { p.Problems = r.report() }()
+	var records [][]string
+	var store *PagedStore
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if store != nil {
+			if err := store.appendRow(rec); err != nil {
+				return err
+			}
+			continue
+		}
+		records = append(records, rec)
+		if threshold > 0 && len(records) > threshold {
+			if store, err = newPagedStore(); err != nil {
+				return err
+			}
+			for _, rec := range records {
+				if err := store.appendRow(rec); err != nil {
+					return err
+				}
+			}
+			records = nil
+		}
+	}
+	if store != nil {
+		return setPanelStore(p, store)
+	}
+	return setPanelRecords(p, records, threshold)
+}
+
+func setPanelStore(p *Panel, store *PagedStore) error {
+	if err := store.finish(); err != nil {
+		return err
+	}
+	p.Rows = store.Rows()
+	p.Cols = store.Cols()
+	p.Cells = map[string]string{}
+	p.Paged = store
+	return nil
+}
+
+// setPanelRecords fills p from parsed records, paging them when there are
+// more than threshold.
+func setPanelRecords(p *Panel, records [][]string, threshold int) error {
+	if threshold > 0 && len(records) > threshold {
+		store, err := newPagedStore()
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if err := store.appendRow(rec); err != nil {
+				return err
+			}
+		}
+		return setPanelStore(p, store)
+	}
+	p.Paged = nil
+	if len(records) == 0 {
+		// empty file -> zero-sized panel
+		p.Rows = 0
+		p.Cols = 0
+		p.Cells = map[string]string{}
+		return nil
+	}
+	cols := 0
+	for _, rec := range records {
+		if len(rec) > cols {
+			cols = len(rec)
+		}
+	}
+	rows := len(records)
+	cells := make(map[string]string)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			v := ""
+			if j < len(records[i]) {
+				v = records[i][j]
+			}
+			if v != "" {
+				cells[CellRef(j, i)] = v
+			}
+		}
+	}
+	p.Rows = rows
+	p.Cols = cols
+	p.Cells = cells
+	return nil
+}
@@ -0,0 +1,168 @@
+package cellcanvas
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Files between these sizes are parsed in parallel by LoadPanelCSV. Small
+// files are not worth the goroutines; larger ones stream instead so they can
+// be paged without first reading the whole file into memory.
+const (
+	parallelCSVMinBytes = 1 << 20
+	parallelCSVMaxBytes = 256 << 20
+)
+
+// ParseCSVParallel parses data as CSV using up to workers goroutines
+// (GOMAXPROCS when workers <= 0) and returns the records in order, exactly
+// as csv.Reader.ReadAll would, and its errors with the same lines.
+//
+// The data is cut into chunks at record boundaries: a newline only ends a
+// record when it is outside a quoted field, which is known from the parity
+// of the quote characters before it ("" escapes count twice and keep the
+// parity). Quote counts per chunk are gathered in parallel, so finding the
+// boundaries costs one extra pass over the bytes.
+func ParseCSVParallel(data []byte, workers int) ([][]string, error) {
+	return parseCSVParallel(data, workers, ',')
+}
+
+// parseCSVParallel is ParseCSVParallel for fields separated by comma.
+func parseCSVParallel(data []byte, workers int, comma rune) ([][]string, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || len(data) < 64<<10 {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.Comma = comma
+		return r.ReadAll()
+	}
+
+	// 1. quote counts of equal-sized raw slices
+	size := (len(data) + workers - 1) / workers
+	quotes := make([]int, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lo, hi := min(i*size, len(data)), min((i+1)*size, len(data))
+			quotes[i] = bytes.Count(data[lo:hi], []byte{'"'})
+		}()
+	}
+	wg.Wait()
+
+	// 2. move every slice start forward to the next newline outside quotes
+	starts := []int{0}
+	inQuotes := false
+	for i := 1; i < workers; i++ {
+		inQuotes = inQuotes != (quotes[i-1]%2 == 1)
+		lo := i * size
+		if lo >= len(data) || lo <= starts[len(starts)-1] {
+			continue
+		}
+		q := inQuotes
+		pos := -1
+		for j := lo; j < len(data); j++ {
+			switch data[j] {
+			case '"':
+				q = !q
+			case '\n':
+				if !q {
+					pos = j + 1
+				}
+			}
+			if pos >= 0 {
+				break
+			}
+		}
+		if pos < 0 || pos >= len(data) {
+			break
+		}
+		starts = append(starts, pos)
+	}
+	starts = append(starts, len(data))
+
+	// 3. parse chunks concurrently, keeping the records read before an
+	// error so field counts are checked up to it like ReadAll does
+	results := make([][][]string, len(starts)-1)
+	errs := make([]error, len(starts)-1)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := csv.NewReader(bytes.NewReader(data[starts[i]:starts[i+1]]))
+			r.Comma = comma
+			r.FieldsPerRecord = -1 // checked across chunks below
+			for {
+				rec, err := r.Read()
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				results[i] = append(results[i], rec)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 4. report the first error in file order: ReadAll requires every
+	// record to have as many fields as the first
+	total, fields := 0, -1
+	for i, part := range results {
+		for j, rec := range part {
+			if fields < 0 {
+				fields = len(rec)
+			}
+			if len(rec) != fields {
+				line := chunkLines(data, starts[i]) + recordLine(data[starts[i]:starts[i+1]], comma, j)
+				return nil, &csv.ParseError{StartLine: line, Line: line, Column: 1, Err: csv.ErrFieldCount}
+			}
+		}
+		var pe *csv.ParseError
+		if errors.As(errs[i], &pe) {
+			// report the line in the whole file, not the chunk
+			lines := chunkLines(data, starts[i])
+			pe.StartLine += lines
+			pe.Line += lines
+			return nil, pe
+		}
+		if errs[i] != nil {
+			return nil, fmt.Errorf("csv chunk at byte %d: %w", starts[i], errs[i])
+		}
+		total += len(part)
+	}
+	records := make([][]string, 0, total)
+	for _, part := range results {
+		records = append(records, part...)
+	}
+	return records, nil
+}
+
+// chunkLines returns the number of lines before the chunk at byte start.
+func chunkLines(data []byte, start int) int {
+	return bytes.Count(data[:start], []byte{'\n'})
+}
+
+// recordLine returns the line of chunk, counting from 1, that record j of
+// it starts on. Quoted fields may span lines, so the chunk is read again
+// up to the record.
+func recordLine(chunk []byte, comma rune, j int) int {
+	r := csv.NewReader(bytes.NewReader(chunk))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	for range j + 1 {
+		if _, err := r.Read(); err != nil {
+			return 0
+		}
+	}
+	line, _ := r.FieldPos(0)
+	return line
+}
@@ -0,0 +1,121 @@
+package cellcanvas
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleCSV returns rows records of four fields, every seventh with a
+// quoted field spanning lines and "" escapes, lines ending in eol.
+func sampleCSV(rows int, eol string) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.UseCRLF = eol == "\r\n"
+	for i := range rows {
+		note := fmt.Sprintf("row %d", i)
+		if i%7 == 0 {
+			note = fmt.Sprintf("multi\nline, \"quoted\" note %d", i)
+		}
+		w.Write([]string{fmt.Sprint(i), fmt.Sprintf("item-%d", i%1000), fmt.Sprintf("%.2f", float64(i)*0.37), note})
+	}
+	w.Flush()
+	return b.Bytes()
+}
+
+func readAll(data []byte, comma rune) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	return r.ReadAll()
+}
+
+func TestParseCSVParallelMatchesReadAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		comma rune
+	}{
+		{"quoted newlines", sampleCSV(20000, "\n"), ','},
+		{"CRLF", sampleCSV(20000, "\r\n"), ','},
+		{"escaped quotes", bytes.Repeat([]byte("\"a \"\"b\"\"\",\"\"\"\"\n\"\"\"x\",y\n"), 10000), ','},
+		{"semicolons", bytes.ReplaceAll(sampleCSV(20000, "\n"), []byte(","), []byte(";")), ';'},
+		{"no trailing newline", bytes.TrimSuffix(sampleCSV(20000, "\n"), []byte("\n")), ','},
+	}
+	for _, tt := range tests {
+		want, err := readAll(tt.data, tt.comma)
+		if err != nil {
+			t.Fatalf("%s: ReadAll: %v", tt.name, err)
+		}
+		for _, workers := range []int{2, 3, 7, 16} {
+			got, err := parseCSVParallel(tt.data, workers, tt.comma)
+			if err != nil {
+				t.Errorf("%s, %d workers: %v", tt.name, workers, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, %d workers: %d records differ from ReadAll's %d", tt.name, workers, len(got), len(want))
+			}
+		}
+	}
+}
+
+func TestParseCSVParallelErrors(t *testing.T) {
+	data := sampleCSV(50000, "\n")
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"field count", append(bytes.Clone(data), "1,2\n"...)},
+		{"field count early", append([]byte("a,b,c,d\na,b\n"), data...)},
+		{"bare quote", append(bytes.Clone(data), "1,2,x\"y,4\n"...)},
+		{"field count before bare quote", append(append(bytes.Clone(data[:len(data)/3]), "1,2\n"...), append(bytes.Clone(data[len(data)/3:]), "1,2,x\"y,4\n"...)...)},
+	}
+	for _, tt := range tests {
+		_, want := readAll(tt.data, ',')
+		var wantPE *csv.ParseError
+		if !errors.As(want, &wantPE) {
+			t.Fatalf("%s: ReadAll returned %v", tt.name, want)
+		}
+		for _, workers := range []int{2, 3, 7, 16} {
+			_, err := parseCSVParallel(tt.data, workers, ',')
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) || *pe != *wantPE {
+				t.Errorf("%s, %d workers: got %v, want %v", tt.name, workers, err, want)
+			}
+		}
+	}
+}
+
+func TestParseCSVParallelFieldCountLine(t *testing.T) {
+	// every record spans two lines, so records and lines are numbered apart
+	data := []byte(strings.Repeat("1,\"two\nlines\"\n", 50000) + "x\n")
+	_, err := parseCSVParallel(data, 4, ',')
+	var pe *csv.ParseError
+	if !errors.As(err, &pe) || pe.Line != 100001 {
+		t.Errorf("got %v, want the error on line 100001", err)
+	}
+}
+
+func BenchmarkReadAll(b *testing.B) {
+	data := sampleCSV(200000, "\n")
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if _, err := readAll(data, ','); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseCSVParallel(b *testing.B) {
+	data := sampleCSV(200000, "\n")
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if _, err := ParseCSVParallel(data, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
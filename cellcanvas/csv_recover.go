@@ -0,0 +1,156 @@
+package cellcanvas
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RaggedPolicy says what the CSV loaders do with a record whose field count
+// differs from the first record's.
+type RaggedPolicy string
+
+const (
+	// RaggedPad keeps every field; short records are padded with empty
+	// cells and long ones widen the panel.
+	RaggedPad RaggedPolicy = "pad"
+	// RaggedTruncate pads short records and cuts long ones to the width
+	// of the first record.
+	RaggedTruncate RaggedPolicy = "truncate"
+	// RaggedSkip drops ragged records.
+	RaggedSkip RaggedPolicy = "skip"
+)
+
+// RaggedRows is the policy used by LoadPanelCSV, ReadPanelCSV and
+// LoadPanelCSVHead.
+var RaggedRows = RaggedPad
+
+// maxCSVProblems caps the problems noted per load; the rest are counted.
+const maxCSVProblems = 1000
+
+// CSVProblem is a line of a CSV file that did not load cleanly. Line is 0
+// for the note counting problems past maxCSVProblems.
+type CSVProblem struct {
+	Line   int
+	Reason string
+}
+
+// csvRecovery reads records leniently: records with bad quoting are
+// skipped and ragged ones fitted per policy, each noted in problems.
+type csvRecovery struct {
+	r        *csv.Reader
+	policy   RaggedPolicy
+	width    int // fields in the first record; -1 before it is read
+	problems []CSVProblem
+	more     int // problems past maxCSVProblems
+}
+
+func newCSVRecovery(in io.Reader, policy RaggedPolicy, comma rune) *csvRecovery {
+	r := csv.NewReader(in)
+	r.Comma = comma
+	r.FieldsPerRecord = -1 // checked by fit
+	return &csvRecovery{r: r, policy: policy, width: -1}
+}
+
+// Read returns the next loadable record, or io.EOF. Errors other than CSV
+// parse errors end the read.
+func (cr *csvRecovery) Read() ([]string, error) {
+	for {
+		rec, err := cr.r.Read()
+		var pe *csv.ParseError
+		switch {
+		case err == io.EOF:
+			return nil, err
+		case errors.As(err, &pe):
+			cr.note(parseProblem(pe))
+			continue
+		case err != nil:
+			return nil, err
+		}
+		line, _ := cr.r.FieldPos(0)
+		if rec, ok := cr.fit(rec, line); ok {
+			return rec, nil
+		}
+	}
+}
+
+// fit applies the ragged row policy to rec, read at line. ok is false when
+// the record is dropped.
+func (cr *csvRecovery) fit(rec []string, line int) (_ []string, ok bool) {
+	if cr.width < 0 {
+		cr.width = len(rec)
+	}
+	if len(rec) == cr.width {
+		return rec, true
+	}
+	var action string
+	switch {
+	case cr.policy == RaggedSkip:
+		action = "skipped"
+	case len(rec) < cr.width:
+		action = "padded"
+	case cr.policy == RaggedTruncate:
+		action = "truncated"
+	default:
+		action = "kept"
+	}
+	cr.note(CSVProblem{
+		Line:   line,
+		Reason: fmt.Sprintf("%d fields, expected %d; %s", len(rec), cr.width, action),
+	})
+	switch action {
+	case "skipped":
+		return nil, false
+	case "truncated":
+		return rec[:cr.width], true
+	}
+	// setPanelRecords pads short records to the panel width
+	return rec, true
+}
+
+func (cr *csvRecovery) note(pb CSVProblem) {
+	if len(cr.problems) < maxCSVProblems {
+		cr.problems = append(cr.problems, pb)
+	} else {
+		cr.more++
+	}
+}
+
+// report returns the problems noted so far.
+func (cr *csvRecovery) report() []CSVProblem {
+	if cr.more == 0 {
+		return cr.problems
+	}
+	return append(cr.problems, CSVProblem{Reason: fmt.Sprintf("... and %d more", cr.more)})
+}
+
+// ProblemsPanel lists problems as a new panel at x, y: the line and reason
+// of each under a header row.
+func ProblemsPanel(problems []CSVProblem, x, y int) Panel {
+	out := NewBlankPanel(x, y, 2, len(problems)+1)
+	out.SetCell(0, 0, "Line")
+	out.SetCell(1, 0, "Problem")
+	for i, pb := range problems {
+		if pb.Line > 0 {
+			out.SetCell(0, i+1, strconv.Itoa(pb.Line))
+		}
+		out.SetCell(1, i+1, pb.Reason)
+	}
+	return out
+}
+
+// parseProblem describes a record dropped because it could not be parsed.
+func parseProblem(pe *csv.ParseError) CSVProblem {
+	reason := pe.Err.Error()
+	switch {
+	case errors.Is(pe.Err, csv.ErrBareQuote):
+		reason = `stray " in an unquoted field`
+	case errors.Is(pe.Err, csv.ErrQuote) && pe.Line > pe.StartLine:
+		reason = fmt.Sprintf("unterminated quoted field (through line %d)", pe.Line)
+	case errors.Is(pe.Err, csv.ErrQuote):
+		reason = `stray " in a quoted field`
+	}
+	return CSVProblem{Line: pe.StartLine, Reason: reason + "; skipped"}
+}
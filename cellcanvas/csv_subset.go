@@ -0,0 +1,41 @@
+package cellcanvas
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// SaveRangeCSV writes the cells of p inside r to path as CSV, replacing it
+// atomically. Cells are written as shown, formulas by their result, since
+// the cells a formula refers to may be left out.
+func SaveRangeCSV(path string, p *Panel, r CellRange) error {
+	rows := make([]int, 0, r.Row1-r.Row0+1)
+	for row := r.Row0; row <= r.Row1; row++ {
+		rows = append(rows, row)
+	}
+	return saveSubsetCSV(path, p, rows, r.Col0, r.Col1)
+}
+
+// SaveRowsCSV writes the listed rows of p, every column, to path as CSV in
+// the order given, replacing it atomically. Like SaveRangeCSV it writes the
+// values as shown.
+func SaveRowsCSV(path string, p *Panel, rows []int) error {
+	return saveSubsetCSV(path, p, rows, 0, p.Cols-1)
+}
+
+func saveSubsetCSV(path string, p *Panel, rows []int, col0, col1 int) error {
+	return fileError(path, WriteFileAtomic(path, 0644, func(out io.Writer) error {
+		w := csv.NewWriter(out)
+		for _, row := range rows {
+			rec := make([]string, 0, col1-col0+1)
+			for col := col0; col <= col1; col++ {
+				rec = append(rec, p.GetCell(col, row))
+			}
+			if err := w.Write(rec); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}))
+}
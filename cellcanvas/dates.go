@@ -0,0 +1,178 @@
+package cellcanvas
+
+import (
+	"strings"
+	"time"
+)
+
+// DateColumn marks column Col as holding dates written with Layout, a
+// time.Parse layout. Its cells are normalized to ISO dates whenever the
+// panel's data is loaded (see NormalizeDates).
+type DateColumn struct {
+	Col    int    `yaml:"col"`
+	Layout string `yaml:"layout"`
+}
+
+// The normalized forms of dates: ISO 8601, which sorts as text in date
+// order, without or with a time of day.
+const (
+	ISODate     = "2006-01-02"
+	ISODateTime = "2006-01-02 15:04:05"
+)
+
+// DateLayouts are the date formats DetectDateLayout tries, in order. Where
+// two read the same text (01/02/2006 and 02/01/2006), the first wins.
+var DateLayouts = []string{ISODate, ISODateTime, time.RFC3339, "2006-01-02T15:04:05", "01/02/2006", "02/01/2006", "02.01.2006", "2006/01/02", "02-Jan-2006", "2 Jan 2006", "Jan 2, 2006", "01/02/2006 15:04", "02.01.2006 15:04"}
+
+// dateSpec turns the field names of DateSpecLayout into time.Parse
+// placeholders; longer names come first so YYYY is not read as YY twice.
+var dateSpec = strings.NewReplacer("YYYY", "2006", "YY", "06", "MMM", "Jan", "MM", "01", "DD", "02", "hh", "15", "mm", "04", "ss", "05")
+
+// DateSpecLayout converts a format written with YYYY, YY, MM, MMM, DD, hh,
+// mm and ss, like "DD.MM.YYYY", to a time.Parse layout. Other text is kept,
+// so a time.Parse layout passes through unchanged.
+func DateSpecLayout(spec string) string {
+	return dateSpec.Replace(strings.TrimSpace(spec))
+}
+
+// DateExample writes a fixed sample date with layout, for showing formats.
+func DateExample(layout string) string {
+	return time.Date(2024, 12, 31, 18, 30, 0, 0, time.UTC).Format(layout)
+}
+
+// hasClock reports whether layout includes a time of day.
+func hasClock(layout string) bool {
+	return strings.Contains(layout, "15") || strings.Contains(layout, "03") || strings.Contains(layout, "3:")
+}
+
+// NormalizeDate rewrites s, a date written with layout, as ISODate, or as
+// ISODateTime (in UTC for layouts with a zone) when layout has a time of
+// day. Text already in the normalized form is returned as it is.
+func NormalizeDate(s, layout string) (string, bool) {
+	s = strings.TrimSpace(s)
+	out := ISODate
+	if hasClock(layout) {
+		out = ISODateTime
+	}
+	if _, err := time.Parse(out, s); err == nil {
+		return s, true
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return "", false
+	}
+	if strings.Contains(layout, "Z07") || strings.Contains(layout, "MST") {
+		t = t.UTC()
+	}
+	return t.Format(out), true
+}
+
+// DetectDateLayout returns the first of DateLayouts that reads every
+// non-empty cell in rows 1 up to 1000 of column col, and any later layouts
+// that read them all too (ambiguous day/month order, for instance).
+func (p *Panel) DetectDateLayout(col int) (layout string, alternatives []string, ok bool) {
+	var vals []string
+	for row := 1; row < min(p.Rows, 1001); row++ {
+		if v := strings.TrimSpace(p.GetCell(col, row)); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	if len(vals) == 0 {
+		return "", nil, false
+	}
+	for _, l := range DateLayouts {
+		all := true
+		for _, v := range vals {
+			if _, err := time.Parse(l, v); err != nil {
+				all = false
+				break
+			}
+		}
+		if !all {
+			continue
+		}
+		if !ok {
+			layout, ok = l, true
+		} else {
+			alternatives = append(alternatives, l)
+		}
+	}
+	return layout, alternatives, ok
+}
+
+// DateColumnAt returns the index in p.DateColumns of col's entry, or -1.
+func (p *Panel) DateColumnAt(col int) int {
+	for i, dc := range p.DateColumns {
+		if dc.Col == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetDateColumn makes col a date column written with layout, replacing any
+// earlier layout, and normalizes it. An empty layout makes it a plain
+// column again, leaving its cells as they are.
+func (p *Panel) SetDateColumn(col int, layout string) (normalized, failed int) {
+	i := p.DateColumnAt(col)
+	if layout == "" {
+		if i >= 0 {
+			p.DateColumns = append(p.DateColumns[:i], p.DateColumns[i+1:]...)
+		}
+		return 0, 0
+	}
+	if i >= 0 {
+		p.DateColumns[i].Layout = layout
+	} else {
+		p.DateColumns = append(p.DateColumns, DateColumn{Col: col, Layout: layout})
+	}
+	return p.normalizeColumn(DateColumn{Col: col, Layout: layout})
+}
+
+// NormalizeDates rewrites the cells of every date column below the header
+// in the normalized form, so they sort and compare in date order and
+// formulas can do date math on them. Cells that do not match the column's
+// layout are left as they are and counted as failed.
+func (p *Panel) NormalizeDates() (normalized, failed int) {
+	for _, dc := range p.DateColumns {
+		n, f := p.normalizeColumn(dc)
+		normalized += n
+		failed += f
+	}
+	return normalized, failed
+}
+
+func (p *Panel) normalizeColumn(dc DateColumn) (normalized, failed int) {
+	for row := 1; row < p.Rows; row++ {
+		v := p.RawCell(dc.Col, row)
+		if strings.TrimSpace(v) == "" || IsFormula(v) {
+			continue
+		}
+		d, ok := NormalizeDate(v, dc.Layout)
+		if !ok {
+			failed++
+			continue
+		}
+		if d != v {
+			p.SetCell(dc.Col, row, d)
+			normalized++
+		}
+	}
+	return normalized, failed
+}
+
+// dateEpoch is day 0 of date serials, as in spreadsheets, so that
+// =B2-A2 over two dates gives the days between them.
+var dateEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// DateSerial returns the days since dateEpoch of s, a normalized date or
+// date and time; the time of day is the fraction.
+func DateSerial(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	for _, l := range []string{ISODate, ISODateTime} {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.Sub(dateEpoch).Hours() / 24, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,135 @@
+package cellcanvas
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Field delimiters a panel's CSV can use. A panel without one is read and
+// written with commas; loading a file into it first sniffs the file's
+// delimiter and keeps it, so TSV, semicolon- and pipe-delimited files load
+// as they are and are saved back the same way.
+const (
+	DelimiterComma     = ","
+	DelimiterTab       = "\t"
+	DelimiterSemicolon = ";"
+	DelimiterPipe      = "|"
+)
+
+// Delimiters lists the delimiters in the order they are offered and
+// preferred when sniffing ties.
+var Delimiters = []struct {
+	Name  string
+	Value string
+}{
+	{"comma", DelimiterComma},
+	{"tab", DelimiterTab},
+	{"semicolon", DelimiterSemicolon},
+	{"pipe", DelimiterPipe},
+}
+
+const (
+	// sniffBytes is how much of a file SniffDelimiter is given
+	sniffBytes = 64 << 10
+	// sniffLines is how many records it compares
+	sniffLines = 20
+)
+
+// DelimiterName names delimiter d for messages, "" as comma.
+func DelimiterName(d string) string {
+	for _, c := range Delimiters {
+		if c.Value == d {
+			return c.Name
+		}
+	}
+	if d == "" {
+		return "comma"
+	}
+	return "'" + d + "'"
+}
+
+// comma returns the delimiter p's CSV is read and written with.
+func (p *Panel) comma() rune {
+	if r, _ := utf8.DecodeRuneInString(p.Delimiter); p.Delimiter != "" && r != utf8.RuneError {
+		return r
+	}
+	return ','
+}
+
+// SniffDelimiter guesses the delimiter of CSV data from its first records:
+// the candidate found outside quotes in the first record that appears the
+// same number of times in most of the others, preferring more fields, then
+// the order of Delimiters. Data it cannot tell is taken as comma-separated.
+func SniffDelimiter(data []byte) string {
+	if len(data) == sniffBytes {
+		// the last line may be cut short
+		if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+			data = data[:i]
+		}
+	}
+	counts := make([][]int, len(Delimiters))
+	line := make([]int, len(Delimiters))
+	quoted := false
+	end := func() {
+		for i := range Delimiters {
+			counts[i] = append(counts[i], line[i])
+			line[i] = 0
+		}
+	}
+	for _, c := range data {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '\n':
+			end()
+		case c != '\r':
+			for i, d := range Delimiters {
+				if c == d.Value[0] {
+					line[i]++
+				}
+			}
+		}
+		if len(counts[0]) == sniffLines {
+			break
+		}
+	}
+	if len(counts[0]) < sniffLines {
+		end()
+	}
+	best, bestMatch, bestFields := DelimiterComma, 0, 0
+	for i, d := range Delimiters {
+		first := counts[i][0]
+		if first == 0 {
+			continue
+		}
+		match := 0
+		for _, n := range counts[i] {
+			if n == first {
+				match++
+			}
+		}
+		if match > bestMatch || match == bestMatch && first > bestFields {
+			best, bestMatch, bestFields = d.Value, match, first
+		}
+	}
+	return best
+}
+
+// sniffPanelDelimiter sets the delimiter of p from the start of f when p
+// has none, then rewinds f.
+func sniffPanelDelimiter(f *os.File, p *Panel) error {
+	if p.Delimiter != "" {
+		return nil
+	}
+	buf := make([]byte, sniffBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	p.Delimiter = SniffDelimiter(buf[:n])
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
@@ -0,0 +1,150 @@
+package cellcanvas
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Display styles of a ColumnFormat.
+const (
+	// DisplayFixed rounds to Decimals places
+	DisplayFixed = "fixed"
+	// DisplaySci writes every number in scientific notation (1.23e+06)
+	DisplaySci = "sci"
+	// DisplayAuto writes numbers in scientific notation only when they are
+	// very large or very small (see autoSciLarge, autoSciSmall), rounding
+	// the others like DisplayFixed
+	DisplayAuto = "auto"
+	// DisplaySI writes numbers with an SI suffix (1.23M, 4.5m)
+	DisplaySI = "si"
+)
+
+// Numbers at least autoSciLarge or, other than 0, smaller than
+// autoSciSmall in magnitude are shown in scientific notation by DisplayAuto.
+const (
+	autoSciLarge = 1e9
+	autoSciSmall = 1e-4
+)
+
+// ColumnFormat sets how the numbers of column Col are shown: in Style with
+// Decimals digits after the point. It changes the display only; cells keep
+// their stored value, which is what is edited, saved and computed with.
+type ColumnFormat struct {
+	Col      int    `yaml:"col"`
+	Style    string `yaml:"style"`
+	Decimals int    `yaml:"decimals"`
+}
+
+// ColumnFormatAt returns the index in p.ColumnFormats of col's format, or
+// -1.
+func (p *Panel) ColumnFormatAt(col int) int {
+	for i, cf := range p.ColumnFormats {
+		if cf.Col == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetColumnFormat sets or, with an empty style, removes col's format.
+func (p *Panel) SetColumnFormat(col int, style string, decimals int) {
+	i := p.ColumnFormatAt(col)
+	switch {
+	case style == "" && i >= 0:
+		p.ColumnFormats = append(p.ColumnFormats[:i], p.ColumnFormats[i+1:]...)
+	case style == "":
+	case i >= 0:
+		p.ColumnFormats[i] = ColumnFormat{Col: col, Style: style, Decimals: decimals}
+	default:
+		p.ColumnFormats = append(p.ColumnFormats, ColumnFormat{Col: col, Style: style, Decimals: decimals})
+	}
+}
+
+// DisplayCell returns the text shown for col,row: GetCell's value, with
+// numbers in a formatted column written in its format and followed by the
+// column's unit, if any. The header row and text are shown as they are.
+func (p *Panel) DisplayCell(col, row int) string {
+	v := p.GetCell(col, row)
+	if v == "" || row == 0 || len(p.ColumnFormats) == 0 && len(p.Units) == 0 {
+		return v
+	}
+	i, unit := p.ColumnFormatAt(col), p.UnitAt(col)
+	if i < 0 && unit == "" {
+		return v
+	}
+	num := p.Numbers()
+	f, err := num.Parse(v)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return v
+	}
+	if i >= 0 {
+		v = p.ColumnFormats[i].format(f, num)
+	}
+	if unit != "" {
+		v += " " + unit
+	}
+	return v
+}
+
+func (cf ColumnFormat) format(f float64, num NumberLocale) string {
+	d := max(cf.Decimals, 0)
+	switch cf.Style {
+	case DisplaySci:
+		return sciText(f, d, num)
+	case DisplayAuto:
+		if a := math.Abs(f); a >= autoSciLarge || (a != 0 && a < autoSciSmall) {
+			return sciText(f, d, num)
+		}
+	case DisplaySI:
+		return siText(f, d, num)
+	}
+	return fixedText(f, d, num)
+}
+
+// fixedText rounds f to d decimals, written in num.
+func fixedText(f float64, d int, num NumberLocale) string {
+	plain := strconv.FormatFloat(f, 'f', d, 64)
+	if num.plain() {
+		return plain
+	}
+	sign := ""
+	if strings.HasPrefix(plain, "-") {
+		sign, plain = "-", plain[1:]
+	}
+	whole, frac, _ := strings.Cut(plain, ".")
+	s := sign + groupDigits(whole, num.Group)
+	if frac != "" {
+		s += string(num.Decimal) + frac
+	}
+	return s
+}
+
+// sciText writes f in scientific notation with d decimals.
+func sciText(f float64, d int, num NumberLocale) string {
+	s := strconv.FormatFloat(f, 'e', d, 64)
+	if num.Decimal != '.' {
+		s = strings.Replace(s, ".", string(num.Decimal), 1)
+	}
+	return s
+}
+
+// siPrefixes are the SI suffixes siText uses, from 10^-12 to 10^15. "u"
+// stands in for micro (µ), which the default font cannot draw.
+var siPrefixes = []string{"p", "n", "u", "m", "", "k", "M", "G", "T", "P"}
+
+// siText writes f with d decimals and an SI suffix, 1234567 as 1.23M.
+func siText(f float64, d int, num NumberLocale) string {
+	if f == 0 {
+		return fixedText(0, d, num)
+	}
+	exp := int(math.Floor(math.Log10(math.Abs(f)) / 3))
+	exp = min(max(exp, -4), len(siPrefixes)-5)
+	scaled := f / math.Pow(1000, float64(exp))
+	// rounding can carry into the next prefix (999.996k with 2 decimals)
+	if r := math.Pow(10, float64(d)); math.Abs(math.Round(scaled*r)/r) >= 1000 && exp < len(siPrefixes)-5 {
+		exp++
+		scaled /= 1000
+	}
+	return fixedText(scaled, d, num) + siPrefixes[exp+4]
+}
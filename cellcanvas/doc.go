@@ -0,0 +1,17 @@
+// Package cellcanvas is the data layer of CellCanvas: panels of sparse,
+// A1-addressed cells, the workspace that positions them on a canvas, and the
+// on-disk format (a state.yml describing camera and panel placement, plus one
+// CSV file per panel).
+//
+// It has no UI dependencies, so other Go programs can read and write
+// CellCanvas workspaces directly:
+//
+//	ws, err := cellcanvas.Open("state.yml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ws.Panels[0].SetCell(0, 0, "hello")
+//	if err := ws.SaveState("state.yml"); err != nil {
+//		log.Fatal(err)
+//	}
+package cellcanvas
@@ -0,0 +1,199 @@
+package cellcanvas
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// EncryptedExt is appended to the state file name for an encrypted
+// workspace archive, e.g. state.yml.enc.
+const EncryptedExt = ".enc"
+
+// ErrWrongPassword is returned by OpenEncrypted when the password does not
+// decrypt the archive (or the file was tampered with).
+var ErrWrongPassword = errors.New("wrong password or corrupted file")
+
+// Archive layout: magic, PBKDF2 salt, GCM nonce, then the sealed zip holding
+// state.yml and one CSV per panel.
+const (
+	encMagic      = "CCENC1"
+	encSaltLen    = 16
+	encIterations = 600000
+)
+
+func deriveKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, encIterations, 32)
+}
+
+// SaveEncrypted writes the whole workspace (state and every panel's cells)
+// into a single AES-GCM encrypted archive at path. Nothing is written in
+// plain text. Scratch panels are left out. It fails while a panel is still
+// loading, as the archive would hold its empty placeholder.
+func (w *Workspace) SaveEncrypted(path, password string) error {
+	if i := w.LoadingPanel(); i >= 0 {
+		return fmt.Errorf("panel %d is still loading %s; save again once it has loaded", i+1, filepath.Base(w.Panels[i].Filename))
+	}
+	w, _ = w.withoutScratch()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	sf := StateFile{CamX: w.CamX, CamY: w.CamY, Zoom: w.Zoom, Layouts: w.Layouts, Watches: w.Watches, Scenarios: w.Scenarios, ActivePanel: w.ActivePanel}
+	// used maps archive entries to the panel that wrote them
+	used := make(map[string]int)
+	for i := range w.Panels {
+		p := &w.Panels[i]
+		if p.SampleRows > 0 {
+			return fmt.Errorf("panel %d is a preview of %s; load the full file first", i+1, p.Filename)
+		}
+		name := filepath.Base(p.Filename)
+		if j, ok := used[name]; ok && linked(p, &w.Panels[j]) {
+			// a view shares the entry of the first panel
+			sf.Panels = append(sf.Panels, NewStatePanel(p, name))
+			continue
+		} else if p.Filename == "" || ok {
+			name = fmt.Sprintf("panel_%d.csv", i+1)
+		}
+		used[name] = i
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := WritePanelCSV(f, p); err != nil {
+			return err
+		}
+		sf.Panels = append(sf.Panels, NewStatePanel(p, name))
+	}
+	f, err := zw.Create("state.yml")
+	if err != nil {
+		return err
+	}
+	if err := encodeStateFile(f, &sf); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	salt := make([]byte, encSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(password, salt)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := append([]byte(encMagic), salt...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, buf.Bytes(), []byte(encMagic))
+
+	return WriteFileAtomic(path, 0600, func(w io.Writer) error {
+		_, err := w.Write(out)
+		return err
+	})
+}
+
+// OpenEncrypted decrypts an archive written by SaveEncrypted. All panels are
+// returned fully loaded and held in memory; none is paged to disk however
+// large it is.
+func OpenEncrypted(path, password string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(encMagic)+encSaltLen || string(data[:len(encMagic)]) != encMagic {
+		return nil, fmt.Errorf("%s: not an encrypted workspace", filepath.Base(path))
+	}
+	salt := data[len(encMagic) : len(encMagic)+encSaltLen]
+	key, err := deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(encMagic)+encSaltLen:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrWrongPassword
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], []byte(encMagic))
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	readAll := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("archive has no %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	b, err := readAll("state.yml")
+	if err != nil {
+		return nil, err
+	}
+	sf, err := decodeStateFile("state.yml", b)
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{CamX: sf.CamX, CamY: sf.CamY, Zoom: sf.Zoom, Layouts: sf.Layouts, Watches: sf.Watches, Scenarios: sf.Scenarios, ActivePanel: sf.ActivePanel}
+	for _, sp := range sf.Panels {
+		p := NewBlankPanel(sp.X, sp.Y, 1, 1)
+		p.Delimiter = sp.Delimiter
+		b, err := readAll(sp.Filename)
+		if err != nil {
+			return nil, err
+		}
+		// never paged: the page file would hold the cells in plain text
+		if err := readPanelCSV(bytes.NewReader(b), &p, 0); err != nil {
+			return nil, err
+		}
+		if p.Rows == 0 || p.Cols == 0 {
+			p.Rows, p.Cols = 5, 5
+		}
+		p.Filename = sp.Filename
+		sp.apply(&p)
+		p.Loaded = true
+		w.Panels = append(w.Panels, p)
+	}
+	w.syncAllViews()
+	return w, nil
+}
@@ -0,0 +1,83 @@
+package cellcanvas
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Code classifies an error so the frontend can explain it and suggest a
+// fix.
+type Code string
+
+// Error codes returned by CodeOf.
+const (
+	CodeNotFound      Code = "not-found"
+	CodePermission    Code = "permission"
+	CodeParse         Code = "parse"
+	CodeWrongPassword Code = "wrong-password"
+	CodeNewerVersion  Code = "newer-version"
+	CodeUnknown       Code = "error"
+)
+
+// FileError is a failure reading or writing a workspace file. Line is the
+// 1-based line of a CSV parse failure, 0 otherwise.
+type FileError struct {
+	Code Code
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// fileError wraps err from reading or writing path in a FileError. CSV
+// parse errors keep their line; the path of fs errors is not repeated.
+func fileError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var fe *FileError
+	if errors.As(err, &fe) {
+		return err
+	}
+	e := &FileError{Code: CodeOf(err), Path: path, Err: err}
+	var pe *csv.ParseError
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &pe):
+		e.Line, e.Err = pe.Line, pe.Err
+	case errors.As(err, &pathErr):
+		e.Err = pathErr.Err
+	}
+	return e
+}
+
+// CodeOf classifies err.
+func CodeOf(err error) Code {
+	var fe *FileError
+	var pe *csv.ParseError
+	switch {
+	case errors.As(err, &fe):
+		return fe.Code
+	case errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return CodePermission
+	case errors.Is(err, ErrWrongPassword):
+		return CodeWrongPassword
+	case errors.As(err, &pe):
+		return CodeParse
+	}
+	return CodeUnknown
+}
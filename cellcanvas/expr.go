@@ -0,0 +1,212 @@
+package cellcanvas
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrDivByZero is returned by Expr.Eval for a division by zero.
+var ErrDivByZero = errors.New("division by zero")
+
+// Expr is a parsed arithmetic expression over named values: numbers,
+// names, + - * /, unary minus and parentheses. A name is a run of letters,
+// digits, '_' and '.', or any text in square brackets ([unit price]).
+type Expr struct {
+	root  exprNode
+	names []string
+}
+
+type exprNode interface {
+	eval(lookup func(name string) (float64, error)) (float64, error)
+}
+
+type (
+	numNode  float64
+	nameNode string
+	negNode  struct{ x exprNode }
+	binNode  struct {
+		op   byte
+		l, r exprNode
+	}
+)
+
+func (n numNode) eval(func(string) (float64, error)) (float64, error) { return float64(n), nil }
+
+func (n nameNode) eval(lookup func(string) (float64, error)) (float64, error) {
+	return lookup(string(n))
+}
+
+func (n negNode) eval(lookup func(string) (float64, error)) (float64, error) {
+	v, err := n.x.eval(lookup)
+	return -v, err
+}
+
+func (n binNode) eval(lookup func(string) (float64, error)) (float64, error) {
+	l, err := n.l.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	}
+	if r == 0 {
+		return 0, ErrDivByZero
+	}
+	return l / r, nil
+}
+
+// ParseExpr parses s.
+func ParseExpr(s string) (*Expr, error) {
+	ps := &exprParser{src: s}
+	root, err := ps.sum()
+	if err != nil {
+		return nil, err
+	}
+	if ps.skipSpace(); ps.pos < len(ps.src) {
+		return nil, fmt.Errorf("unexpected %q at %d", ps.src[ps.pos:], ps.pos+1)
+	}
+	return &Expr{root: root, names: ps.names}, nil
+}
+
+// Names returns the names used by e, in order of first use.
+func (e *Expr) Names() []string {
+	return e.names
+}
+
+// Eval computes e, resolving names with lookup.
+func (e *Expr) Eval(lookup func(name string) (float64, error)) (float64, error) {
+	return e.root.eval(lookup)
+}
+
+type exprParser struct {
+	src   string
+	pos   int
+	names []string
+}
+
+func (ps *exprParser) skipSpace() {
+	for ps.pos < len(ps.src) && ps.src[ps.pos] == ' ' {
+		ps.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end.
+func (ps *exprParser) peek() byte {
+	if ps.skipSpace(); ps.pos < len(ps.src) {
+		return ps.src[ps.pos]
+	}
+	return 0
+}
+
+func (ps *exprParser) sum() (exprNode, error) {
+	l, err := ps.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := ps.peek(); op == '+' || op == '-'; op = ps.peek() {
+		ps.pos++
+		r, err := ps.product()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (ps *exprParser) product() (exprNode, error) {
+	l, err := ps.unary()
+	if err != nil {
+		return nil, err
+	}
+	for op := ps.peek(); op == '*' || op == '/'; op = ps.peek() {
+		ps.pos++
+		r, err := ps.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (ps *exprParser) unary() (exprNode, error) {
+	switch ps.peek() {
+	case '-':
+		ps.pos++
+		x, err := ps.unary()
+		return negNode{x}, err
+	case '+':
+		ps.pos++
+		return ps.unary()
+	}
+	return ps.operand()
+}
+
+func (ps *exprParser) operand() (exprNode, error) {
+	c := ps.peek()
+	start := ps.pos
+	switch {
+	case c == 0:
+		return nil, errors.New("unexpected end of expression")
+	case c == '(':
+		ps.pos++
+		x, err := ps.sum()
+		if err != nil {
+			return nil, err
+		}
+		if ps.peek() != ')' {
+			return nil, fmt.Errorf("missing ) for ( at %d", start+1)
+		}
+		ps.pos++
+		return x, nil
+	case c == '[':
+		end := strings.IndexByte(ps.src[start:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("missing ] for [ at %d", start+1)
+		}
+		ps.pos = start + end + 1
+		return ps.name(strings.TrimSpace(ps.src[start+1 : start+end])), nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		for ps.pos < len(ps.src) && (ps.src[ps.pos] == '.' || ps.src[ps.pos] >= '0' && ps.src[ps.pos] <= '9') {
+			ps.pos++
+		}
+		f, err := strconv.ParseFloat(ps.src[start:ps.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", ps.src[start:ps.pos])
+		}
+		return numNode(f), nil
+	}
+	for _, r := range ps.src[start:] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			break
+		}
+		ps.pos += len(string(r))
+	}
+	if ps.pos == start {
+		return nil, fmt.Errorf("unexpected %q at %d", []rune(ps.src[start:])[0], start+1)
+	}
+	return ps.name(ps.src[start:ps.pos]), nil
+}
+
+func (ps *exprParser) name(n string) exprNode {
+	for _, seen := range ps.names {
+		if seen == n {
+			return nameNode(n)
+		}
+	}
+	ps.names = append(ps.names, n)
+	return nameNode(n)
+}
@@ -0,0 +1,81 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator produces one sample value per call.
+type Generator func(r *rand.Rand) string
+
+var (
+	sampleFirstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Edsger", "Radia", "Donald", "Hedy", "John", "Karen", "Niklaus", "Sophie", "Tim", "Joan", "Guido"}
+	sampleLastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Allen", "Dijkstra", "Perlman", "Knuth", "Lamarr", "Backus", "Jones", "Wirth", "Wilson", "Berners-Lee", "Clarke", "van Rossum"}
+)
+
+// NameGenerator returns random "First Last" names.
+func NameGenerator() Generator {
+	return func(r *rand.Rand) string {
+		return sampleFirstNames[r.IntN(len(sampleFirstNames))] + " " + sampleLastNames[r.IntN(len(sampleLastNames))]
+	}
+}
+
+// DateGenerator returns random YYYY-MM-DD dates from from to to, both
+// included.
+func DateGenerator(from, to time.Time) Generator {
+	if to.Before(from) {
+		from, to = to, from
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	return func(r *rand.Rand) string {
+		return from.AddDate(0, 0, r.IntN(days)).Format("2006-01-02")
+	}
+}
+
+// NumberGenerator returns random numbers from lo to hi. When both bounds
+// are whole numbers the values are integers, otherwise they keep as many
+// decimals as the more precise bound.
+func NumberGenerator(lo, hi string) (Generator, error) {
+	a, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", lo)
+	}
+	b, err := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", hi)
+	}
+	if b < a {
+		a, b = b, a
+	}
+	decimals := max(decimalPlaces(lo), decimalPlaces(hi))
+	if decimals == 0 {
+		n := int64(b-a) + 1
+		return func(r *rand.Rand) string {
+			return strconv.FormatInt(int64(a)+r.Int64N(n), 10)
+		}, nil
+	}
+	scale := math.Pow(10, float64(decimals))
+	return func(r *rand.Rand) string {
+		v := math.Round((a+r.Float64()*(b-a))*scale) / scale
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}, nil
+}
+
+func decimalPlaces(s string) int {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// ListGenerator returns values picked at random from items.
+func ListGenerator(items []string) Generator {
+	return func(r *rand.Rand) string {
+		return items[r.IntN(len(items))]
+	}
+}
@@ -0,0 +1,399 @@
+package cellcanvas
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Results shown in place of a formula that cannot be computed, besides the
+// Computed*Error values shared with computed columns.
+const (
+	FormulaRefError    = "#REF!"
+	FormulaCycleError  = "#CYCLE!"
+	FormulaSyntaxError = "#ERROR!"
+)
+
+// IsFormula reports whether v is a formula: text starting with "=", like
+// "=A1*2" or "=SUM(B2:B10)". A lone "=" is plain text.
+func IsFormula(v string) bool {
+	return len(v) > 1 && v[0] == '='
+}
+
+// formulaError is a formula's failure, carrying the text shown for it.
+type formulaError string
+
+func (e formulaError) Error() string { return string(e) }
+
+// formulaResult is a computed formula cell; pending is set while it is
+// being computed, so meeting it again means the formula refers to itself.
+type formulaResult struct {
+	val     string
+	err     error
+	pending bool
+}
+
+// formulaEval computes one top-level formula. Values of the formula cells
+// it refers to are remembered for the rest of the evaluation, so a range
+// over a column of running totals costs one pass, not one per total.
+type formulaEval struct {
+	p    *Panel
+	memo map[[2]int]formulaResult
+}
+
+// formula returns the value of the formula v stored at col,row. Formulas
+// are computed each time they are read, so they always reflect the cells
+// as they are now.
+func (p *Panel) formula(col, row int, v string) string {
+	fe := &formulaEval{p: p, memo: make(map[[2]int]formulaResult)}
+	val, err := fe.cell(col, row, v)
+	if err != nil {
+		return err.Error()
+	}
+	return val
+}
+
+// cell returns the value of the formula v stored at col,row.
+func (fe *formulaEval) cell(col, row int, v string) (string, error) {
+	k := [2]int{col, row}
+	if m, ok := fe.memo[k]; ok {
+		if m.pending {
+			return "", formulaError(FormulaCycleError)
+		}
+		return m.val, m.err
+	}
+	fe.memo[k] = formulaResult{pending: true}
+	val, err := fe.compute(strings.TrimSpace(v[1:]))
+	fe.memo[k] = formulaResult{val: val, err: err}
+	return val, err
+}
+
+// compute evaluates the body of a formula. A formula that is just a cell
+// reference shows that cell as it is, text included, and QRCODE(value)
+// shows its value, a reference or text as written, for drawing as a QR
+// code; anything else is arithmetic and shows a number.
+func (fe *formulaEval) compute(body string) (string, error) {
+	if arg, ok := qrCodeArg(body); ok {
+		fs := &formulaScan{fe: fe, src: arg}
+		if col, row, ok := fs.ref(); ok && fs.peek() == 0 {
+			return fe.value(col, row)
+		}
+		return strings.Trim(arg, `"`), nil
+	}
+	fs := &formulaScan{fe: fe, src: body}
+	if col, row, ok := fs.ref(); ok && fs.peek() == 0 {
+		return fe.value(col, row)
+	}
+	fs.pos = 0
+	x, err := fs.sum()
+	if err != nil {
+		return "", err
+	}
+	if fs.peek() != 0 {
+		return "", formulaError(FormulaSyntaxError)
+	}
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return "", formulaError(ComputedValueError)
+	}
+	return fe.p.Numbers().Format(x, 12), nil
+}
+
+// value returns the shown value of the cell at col,row.
+func (fe *formulaEval) value(col, row int) (string, error) {
+	p := fe.p
+	if col >= p.Cols || row >= p.Rows {
+		return "", formulaError(FormulaRefError)
+	}
+	v := p.RawCell(col, row)
+	if IsFormula(v) {
+		return fe.cell(col, row, v)
+	}
+	return v, nil
+}
+
+// number returns the cell at col,row as a number. Empty cells count as 0
+// and ISO dates as their DateSerial, so =B2-A2 counts days; other text that
+// is not a number is a #VALUE! error, or skipped (ok false) when
+// skipText is set, as aggregates over ranges do.
+func (fe *formulaEval) number(col, row int, skipText bool) (f float64, ok bool, err error) {
+	v, err := fe.value(col, row)
+	if err != nil {
+		return 0, false, err
+	}
+	s := strings.TrimSpace(v)
+	if s == "" {
+		return 0, !skipText, nil
+	}
+	f, perr := fe.p.Numbers().Parse(s)
+	if perr != nil {
+		if d, ok := DateSerial(s); ok {
+			return d, true, nil
+		}
+		if skipText {
+			return 0, false, nil
+		}
+		return 0, false, formulaError(ComputedValueError)
+	}
+	return f, true, nil
+}
+
+// formulaScan parses and evaluates a formula body in one pass: numbers
+// (with an optional exponent, as in 1e3), cell references, + - * /, unary minus, parentheses and the functions
+// SUM, AVG (or AVERAGE), MIN and MAX over values and A1:B3 ranges, and
+// TODAY() for the DateSerial of the current day.
+type formulaScan struct {
+	fe  *formulaEval
+	src string
+	pos int
+}
+
+func (fs *formulaScan) peek() byte {
+	for fs.pos < len(fs.src) && fs.src[fs.pos] == ' ' {
+		fs.pos++
+	}
+	if fs.pos < len(fs.src) {
+		return fs.src[fs.pos]
+	}
+	return 0
+}
+
+func (fs *formulaScan) sum() (float64, error) {
+	l, err := fs.product()
+	if err != nil {
+		return 0, err
+	}
+	for op := fs.peek(); op == '+' || op == '-'; op = fs.peek() {
+		fs.pos++
+		r, err := fs.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			l += r
+		} else {
+			l -= r
+		}
+	}
+	return l, nil
+}
+
+func (fs *formulaScan) product() (float64, error) {
+	l, err := fs.unary()
+	if err != nil {
+		return 0, err
+	}
+	for op := fs.peek(); op == '*' || op == '/'; op = fs.peek() {
+		fs.pos++
+		r, err := fs.unary()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			l *= r
+		} else if r == 0 {
+			return 0, formulaError(ComputedDivError)
+		} else {
+			l /= r
+		}
+	}
+	return l, nil
+}
+
+func (fs *formulaScan) unary() (float64, error) {
+	switch fs.peek() {
+	case '-':
+		fs.pos++
+		x, err := fs.unary()
+		return -x, err
+	case '+':
+		fs.pos++
+		return fs.unary()
+	}
+	return fs.operand()
+}
+
+func (fs *formulaScan) operand() (float64, error) {
+	c := fs.peek()
+	start := fs.pos
+	switch {
+	case c == '(':
+		fs.pos++
+		x, err := fs.sum()
+		if err != nil {
+			return 0, err
+		}
+		if fs.peek() != ')' {
+			return 0, formulaError(FormulaSyntaxError)
+		}
+		fs.pos++
+		return x, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		for fs.pos < len(fs.src) && (fs.src[fs.pos] == '.' || isDigit(fs.src[fs.pos])) {
+			fs.pos++
+		}
+		// an exponent, as in 1e3 or 2.5E-4
+		if i := fs.pos + 1; i < len(fs.src) && (fs.src[fs.pos] == 'e' || fs.src[fs.pos] == 'E') {
+			if fs.src[i] == '+' || fs.src[i] == '-' {
+				i++
+			}
+			for i < len(fs.src) && isDigit(fs.src[i]) {
+				i++
+				fs.pos = i
+			}
+		}
+		f, err := strconv.ParseFloat(fs.src[start:fs.pos], 64)
+		if err != nil {
+			return 0, formulaError(FormulaSyntaxError)
+		}
+		return f, nil
+	case isLetter(c):
+		if col, row, ok := fs.ref(); ok {
+			if fs.peek() == ':' {
+				// a range is only meaningful inside a function
+				return 0, formulaError(ComputedValueError)
+			}
+			f, _, err := fs.fe.number(col, row, false)
+			return f, err
+		}
+		for fs.pos < len(fs.src) && isLetter(fs.src[fs.pos]) {
+			fs.pos++
+		}
+		name := strings.ToUpper(fs.src[start:fs.pos])
+		if fs.peek() != '(' {
+			return 0, formulaError(ComputedNameError)
+		}
+		fs.pos++
+		return fs.call(name)
+	}
+	return 0, formulaError(FormulaSyntaxError)
+}
+
+// ref reads a cell reference like B12 at the current position. On failure
+// the position is left where it was.
+func (fs *formulaScan) ref() (col, row int, ok bool) {
+	fs.peek()
+	start := fs.pos
+	i := start
+	for i < len(fs.src) && isLetter(fs.src[i]) {
+		i++
+	}
+	letters := i - start
+	for i < len(fs.src) && fs.src[i] >= '0' && fs.src[i] <= '9' {
+		i++
+	}
+	if letters == 0 || letters > 3 || i == start+letters || (i < len(fs.src) && (isLetter(fs.src[i]) || fs.src[i] == '(')) {
+		return 0, 0, false
+	}
+	col, row, err := ParseCellRef(fs.src[start:i])
+	if err != nil {
+		return 0, 0, false
+	}
+	fs.pos = i
+	return col, row, true
+}
+
+// call evaluates the arguments of function name up to the closing ")".
+func (fs *formulaScan) call(name string) (float64, error) {
+	switch name {
+	case "SUM", "AVG", "AVERAGE", "MIN", "MAX":
+	case "TODAY":
+		if fs.peek() != ')' {
+			return 0, formulaError(FormulaSyntaxError)
+		}
+		fs.pos++
+		d, _ := DateSerial(time.Now().Format(ISODate))
+		return d, nil
+	default:
+		return 0, formulaError(ComputedNameError)
+	}
+	var vals []float64
+	for fs.peek() != ')' {
+		if err := fs.arg(&vals); err != nil {
+			return 0, err
+		}
+		if fs.peek() == ',' {
+			fs.pos++
+			continue
+		}
+		if fs.peek() != ')' {
+			return 0, formulaError(FormulaSyntaxError)
+		}
+	}
+	fs.pos++
+	switch name {
+	case "SUM":
+		s := 0.0
+		for _, v := range vals {
+			s += v
+		}
+		return s, nil
+	case "AVG", "AVERAGE":
+		if len(vals) == 0 {
+			return 0, formulaError(ComputedDivError)
+		}
+		s := 0.0
+		for _, v := range vals {
+			s += v
+		}
+		return s / float64(len(vals)), nil
+	}
+	if len(vals) == 0 {
+		return 0, nil
+	}
+	m := vals[0]
+	for _, v := range vals[1:] {
+		if name == "MIN" {
+			m = math.Min(m, v)
+		} else {
+			m = math.Max(m, v)
+		}
+	}
+	return m, nil
+}
+
+// arg appends the values of one function argument to vals: every number in
+// a range, or the value of an expression.
+func (fs *formulaScan) arg(vals *[]float64) error {
+	start := fs.pos
+	if c0, r0, ok := fs.ref(); ok && fs.peek() == ':' {
+		fs.pos++
+		c1, r1, ok := fs.ref()
+		if !ok {
+			return formulaError(FormulaSyntaxError)
+		}
+		p := fs.fe.p
+		c0, c1 = min(c0, c1), min(max(c0, c1), p.Cols-1)
+		r0, r1 = min(r0, r1), min(max(r0, r1), p.Rows-1)
+		if c0 > c1 || r0 > r1 {
+			return formulaError(FormulaRefError)
+		}
+		for r := r0; r <= r1; r++ {
+			for c := c0; c <= c1; c++ {
+				f, ok, err := fs.fe.number(c, r, true)
+				if err != nil {
+					return err
+				}
+				if ok {
+					*vals = append(*vals, f)
+				}
+			}
+		}
+		return nil
+	}
+	fs.pos = start
+	x, err := fs.sum()
+	if err != nil {
+		return err
+	}
+	*vals = append(*vals, x)
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,120 @@
+package cellcanvas
+
+import "testing"
+
+// formulaPanel returns a 4 x 6 panel holding 1 to 4 in A1:A4, text in B1,
+// 1e3 in C1 and the formulas cells sets, with everything else empty.
+func formulaPanel(locale string, cells map[string]string) *Panel {
+	p := NewBlankPanel(0, 0, 4, 6)
+	p.NumberLocale = locale
+	for ref, v := range map[string]string{"A1": "1", "A2": "2", "A3": "3", "A4": "4", "B1": "text", "C1": "1e3"} {
+		p.Cells[ref] = v
+	}
+	for ref, v := range cells {
+		p.Cells[ref] = v
+	}
+	return &p
+}
+
+func TestFormula(t *testing.T) {
+	tests := []struct {
+		formula, want string
+	}{
+		// precedence and associativity
+		{"=1+2*3", "7"},
+		{"=(1+2)*3", "9"},
+		{"=2*3-4/2", "4"},
+		{"=10-4-3", "3"},
+		{"=8/4/2", "1"},
+		{"=-2*-3", "6"},
+		{"=-(1+2)", "-3"},
+		{"= 1 +  2 ", "3"},
+		{"=1/3", "0.333333333333"},
+		{"=1+", FormulaSyntaxError},
+		{"=(1+2", FormulaSyntaxError},
+		{"=1 2", FormulaSyntaxError},
+
+		// number literals
+		{"=1e3", "1000"},
+		{"=2.5E-2*4", "0.1"},
+		{"=1e+2+1", "101"},
+		{"=.5e1", "5"},
+		{"=1e", FormulaSyntaxError},
+		{"=1.2.3", FormulaSyntaxError},
+
+		// references
+		{"=A1", "1"},
+		{"=B1", "text"},
+		{"=a2*b2", "0"},
+		{"=A1+C1", "1001"},
+		{"=A1+B1", ComputedValueError},
+		{"=E1", FormulaRefError},
+		{"=A7", FormulaRefError},
+		{"=C2*2", "6"},
+
+		// functions and ranges
+		{"=SUM(A1:A4)", "10"},
+		{"=SUM(A4:A1)", "10"},
+		{"=SUM(A1:A99)", "10"},
+		{"=sum(A1:A2, 10, A3*2)", "19"},
+		{"=AVG(A1:B4)", "2.5"},
+		{"=AVERAGE(A1:A4)", "2.5"},
+		{"=MIN(A1:A4)", "1"},
+		{"=MAX(A1:A4, 10)", "10"},
+		{"=MAX()", "0"},
+		{"=SUM(C1:C1)", "1000"},
+		{"=SUM(A1:A4)/SUM(A1:A2)", "3.33333333333"},
+		{"=SUM(E1:F2)", FormulaRefError},
+		{"=A1:A2", ComputedValueError},
+		{"=SUM(A1:)", FormulaSyntaxError},
+		{"=FOO(1)", ComputedNameError},
+		{"=FOO", ComputedNameError},
+
+		// division by zero
+		{"=1/0", ComputedDivError},
+		{"=1/(A1-1)", ComputedDivError},
+		{"=AVG()", ComputedDivError},
+		{"=AVG(B1:B4)", ComputedDivError},
+
+		// cycles, the formula being in D6
+		{"=D6+1", FormulaCycleError},
+		{"=D1", FormulaCycleError},
+		{"=SUM(D1:D6)", FormulaCycleError},
+		{"=D3", "12"},
+	}
+	for _, tt := range tests {
+		p := formulaPanel("plain", map[string]string{
+			"C2": "=A1+A2",
+			"D1": "=D2", "D2": "=D1",
+			"D3": "=D4*2", "D4": "=SUM(A1:A3)",
+			"D6": tt.formula,
+		})
+		if got := p.GetCell(3, 5); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.formula, got, tt.want)
+		}
+	}
+}
+
+func TestFormulaLocale(t *testing.T) {
+	tests := []struct {
+		locale, a1, formula, want string
+	}{
+		{"plain", "1234.5", "=A1*2", "2469"},
+		{"en", "1,234.5", "=A1*2", "2,469"},
+		{"en", "1,234.5", "=A1/4", "308.625"},
+		{"de", "1.234,5", "=A1/4", "308,625"},
+		{"de", "1.234,5", "=-A1*1000", "-1.234.500"},
+		{"fr", "1 234,5", "=A1*1000", "1 234 500"},
+		{"ch", "1'234.5", "=A1*2", "2'469"},
+		// exponent form is never grouped
+		{"en", "1", "=A1*1e15", "1e+15"},
+		// text the locale does not read as a number
+		{"de", "1.5", "=A1*2", ComputedValueError},
+	}
+	for _, tt := range tests {
+		p := formulaPanel(tt.locale, map[string]string{"A1": tt.a1, "D6": tt.formula})
+		if got := p.GetCell(3, 5); got != tt.want {
+			t.Errorf("%s, A1 %q: %s: got %q, want %q", tt.locale, tt.a1, tt.formula, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cellcanvas
+
+import "sort"
+
+// Column geometry. Columns are CellW pixels wide unless ColWidths overrides
+// them; ColWidths is indexed by column and a zero (or missing) entry means
+// the default width. Offsets are relative to the panel's content origin.
+
+// ColWidth returns the width of column col in pixels.
+func (p *Panel) ColWidth(col int) int {
+	if col >= 0 && col < len(p.ColWidths) && p.ColWidths[col] > 0 {
+		return p.ColWidths[col]
+	}
+	return p.CellW
+}
+
+// SetColWidth overrides the width of column col; w <= 0 restores the
+// default.
+func (p *Panel) SetColWidth(col, w int) {
+	if col < 0 {
+		return
+	}
+	if w <= 0 || w == p.CellW {
+		if col < len(p.ColWidths) {
+			p.ColWidths[col] = 0
+		}
+		return
+	}
+	for len(p.ColWidths) <= col {
+		p.ColWidths = append(p.ColWidths, 0)
+	}
+	p.ColWidths[col] = w
+}
+
+// UniformCols reports whether every column has the default width.
+func (p *Panel) UniformCols() bool {
+	for _, w := range p.ColWidths {
+		if w > 0 && w != p.CellW {
+			return false
+		}
+	}
+	return true
+}
+
+// ColX returns the offset of the left edge of column col.
+func (p *Panel) ColX(col int) int {
+	x := 0
+	n := min(col, len(p.ColWidths))
+	for c := 0; c < n; c++ {
+		x += p.ColWidth(c)
+	}
+	return x + (col-n)*p.CellW
+}
+
+// ColAt returns the column containing offset x. Offsets left of the panel
+// give -1 and offsets past the last custom width keep counting default
+// columns, so the result may be >= Cols.
+func (p *Panel) ColAt(x int) int {
+	if x < 0 {
+		return -1
+	}
+	for c := 0; c < len(p.ColWidths); c++ {
+		w := p.ColWidth(c)
+		if x < w {
+			return c
+		}
+		x -= w
+	}
+	return len(p.ColWidths) + x/p.CellW
+}
+
+// ColEdgeNear returns the column whose right edge lies within slop pixels
+// of offset x, or -1.
+func (p *Panel) ColEdgeNear(x, slop int) int {
+	edge := 0
+	for c := 0; c < p.Cols; c++ {
+		edge += p.ColWidth(c)
+		if x >= edge-slop && x <= edge+slop {
+			return c
+		}
+		if edge > x+slop {
+			break
+		}
+	}
+	return -1
+}
+
+// Width returns the width of the panel's content area, the columns it
+// shows.
+func (p *Panel) Width() int {
+	first, last := p.VisibleCols()
+	return p.ColX(last) - p.ColX(first)
+}
+
+// Row geometry. Rows are CellH pixels tall unless SetRowHeights recorded
+// taller ones (the frontend does this for word-wrapped panels); rows past
+// the recorded heights use the default.
+
+// SetRowHeights replaces the recorded row heights; entries <= 0 mean the
+// default height. A prefix sum is kept so RowY and RowAt stay fast on
+// panels with many rows.
+func (p *Panel) SetRowHeights(h []int) {
+	p.rowHeights = h
+	p.rowTops = nil
+	if len(h) == 0 {
+		return
+	}
+	tops := make([]int, len(h)+1)
+	for i := range h {
+		tops[i+1] = tops[i] + p.RowHeight(i)
+	}
+	p.rowTops = tops
+}
+
+// RowHeight returns the height of row in pixels.
+func (p *Panel) RowHeight(row int) int {
+	if row >= 0 && row < len(p.rowHeights) && p.rowHeights[row] > 0 {
+		return p.rowHeights[row]
+	}
+	return p.CellH
+}
+
+// UniformRows reports whether every row has the default height.
+func (p *Panel) UniformRows() bool {
+	for _, h := range p.rowHeights {
+		if h > 0 && h != p.CellH {
+			return false
+		}
+	}
+	return true
+}
+
+// RowY returns the offset of the top edge of row.
+func (p *Panel) RowY(row int) int {
+	n := len(p.rowTops) - 1
+	if n < 0 || row <= 0 {
+		return row * p.CellH
+	}
+	if row <= n {
+		return p.rowTops[row]
+	}
+	return p.rowTops[n] + (row-n)*p.CellH
+}
+
+// RowAt returns the row containing offset y, -1 above the panel; like ColAt
+// it may return a row >= Rows.
+func (p *Panel) RowAt(y int) int {
+	if y < 0 {
+		return -1
+	}
+	n := len(p.rowTops) - 1
+	if n < 0 {
+		return y / p.CellH
+	}
+	if y >= p.rowTops[n] {
+		return n + (y-p.rowTops[n])/p.CellH
+	}
+	// last row whose top is <= y
+	return sort.SearchInts(p.rowTops, y+1) - 1
+}
+
+// Height returns the height of the panel's content area, the rows it
+// shows.
+func (p *Panel) Height() int {
+	first, last := p.VisibleRows()
+	return p.RowY(last) - p.RowY(first)
+}
@@ -0,0 +1,76 @@
+package cellcanvas
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// goalSeekSteps caps the iterations of GoalSeek.
+const goalSeekSteps = 100
+
+// Goal seek failures.
+var (
+	ErrNoDependence     = errors.New("the cell does not change with the input")
+	ErrGoalNotConverged = errors.New("no input value found that reaches the target")
+)
+
+// GoalSeek adjusts the number in cell inCol,inRow until the value of cell
+// col,row equals target, starting from the input's current value, and
+// returns the input found. Each step writes the input with SetCell and reads
+// the result back with GetCell. On failure the input gets its original text
+// back.
+func (p *Panel) GoalSeek(col, row, inCol, inRow int, target float64) (float64, error) {
+	orig := p.RawCell(inCol, inRow)
+	num := p.Numbers()
+	eval := func(x float64) (float64, error) {
+		p.SetCell(inCol, inRow, num.Format(x, -1))
+		v := strings.TrimSpace(p.GetCell(col, row))
+		f, err := num.Parse(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a number: %q", CellRef(col, row), v)
+		}
+		return f - target, nil
+	}
+	fail := func(err error) (float64, error) {
+		p.SetCell(inCol, inRow, orig)
+		return 0, err
+	}
+
+	x0, _ := num.Parse(orig)
+	x1 := x0 + math.Max(math.Abs(x0)*0.01, 1)
+	f0, err := eval(x0)
+	if err != nil {
+		return fail(err)
+	}
+	tol := 1e-9 * math.Max(1, math.Abs(target))
+	if math.Abs(f0) <= tol {
+		return x0, nil
+	}
+	f1, err := eval(x1)
+	if err != nil {
+		return fail(err)
+	}
+	if f1 == f0 {
+		return fail(ErrNoDependence)
+	}
+	// secant steps
+	for i := 0; i < goalSeekSteps; i++ {
+		if math.Abs(f1) <= tol {
+			return x1, nil
+		}
+		if f1 == f0 {
+			break
+		}
+		x0, x1 = x1, x1-f1*(x1-x0)/(f1-f0)
+		if math.IsNaN(x1) || math.IsInf(x1, 0) {
+			break
+		}
+		f0 = f1
+		if f1, err = eval(x1); err != nil {
+			return fail(err)
+		}
+	}
+	return fail(ErrGoalNotConverged)
+}
@@ -0,0 +1,132 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of problems reported by Check.
+const (
+	IssueMissingFile   = "missing"   // a panel's CSV does not exist
+	IssueDuplicateName = "duplicate" // different CSVs with the same base name
+	IssueSharedFile    = "shared"    // unlinked panels save to one CSV
+	IssueOrphanFile    = "orphan"    // a CSV next to the state file no panel uses
+	IssueBadReference  = "ref"       // a formula refers to a cell off the panel
+)
+
+// Issue is one problem found by Check. Panel is -1 for orphaned files;
+// Col and Row locate formula problems.
+type Issue struct {
+	Kind     string
+	Panel    int
+	Col, Row int
+	File     string
+	Detail   string
+}
+
+// formulaRef matches A1-style references inside a formula.
+var formulaRef = regexp.MustCompile(`\b[A-Za-z]{1,3}[0-9]+\b`)
+
+// formulaQuoted matches quoted text inside a formula.
+var formulaQuoted = regexp.MustCompile(`"[^"]*"`)
+
+// formulaRefText returns the part of formula v that may hold references:
+// v without its quoted text, so =QRCODE("SKU123") refers to nothing, and
+// of a QRCODE formula only an argument that is a reference, as QRCODE
+// reads anything else as text.
+func formulaRefText(v string) string {
+	body := strings.TrimSpace(strings.TrimPrefix(v, "="))
+	if arg, ok := qrCodeArg(body); ok {
+		fs := &formulaScan{src: arg}
+		if _, _, ok := fs.ref(); ok && fs.peek() == 0 {
+			return arg
+		}
+		return ""
+	}
+	return formulaQuoted.ReplaceAllString(body, `""`)
+}
+
+// Check scans the workspace saved at statePath for broken file and cell
+// references: panels whose CSV is missing, CSVs that share a base name
+// (they collide in encrypted archives and recovery snapshots), panels
+// saving to the same CSV without being linked views, CSVs next to the state file that no panel uses,
+// and formulas ("=..." cells) that refer to cells outside their panel or
+// contain #REF!.
+func (w *Workspace) Check(statePath string) []Issue {
+	dir := filepath.Dir(statePath)
+	var issues []Issue
+	used := make(map[string]int)      // resolved path -> first panel
+	byName := make(map[string]string) // base name -> first resolved path
+	for i := range w.Panels {
+		p := &w.Panels[i]
+		if p.Filename == "" {
+			continue
+		}
+		path := p.Filename
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path = filepath.Clean(path)
+		if _, err := os.Stat(path); err != nil {
+			issues = append(issues, Issue{Kind: IssueMissingFile, Panel: i, File: p.Filename, Detail: "file not found"})
+		}
+		if first, ok := used[path]; ok && !linked(p, &w.Panels[first]) {
+			issues = append(issues, Issue{Kind: IssueSharedFile, Panel: i, File: p.Filename, Detail: fmt.Sprintf("same file as panel %d", first+1)})
+		} else if !ok {
+			used[path] = i
+		}
+		base := strings.ToLower(filepath.Base(path))
+		if other, ok := byName[base]; ok && other != path {
+			issues = append(issues, Issue{Kind: IssueDuplicateName, Panel: i, File: p.Filename, Detail: "name also used by " + other})
+		} else if !ok {
+			byName[base] = path
+		}
+	}
+	for i := range w.Panels {
+		issues = append(issues, checkFormulas(&w.Panels[i], i)...)
+	}
+	csvs, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	sort.Strings(csvs)
+	for _, path := range csvs {
+		if _, ok := used[filepath.Clean(path)]; !ok {
+			issues = append(issues, Issue{Kind: IssueOrphanFile, Panel: -1, File: path, Detail: "not used by any panel"})
+		}
+	}
+	return issues
+}
+
+// checkFormulas reports formula cells of panel pi that refer outside it.
+func checkFormulas(p *Panel, pi int) []Issue {
+	var issues []Issue
+	for ref, v := range p.Cells {
+		if !strings.HasPrefix(v, "=") {
+			continue
+		}
+		col, row, err := ParseCellRef(ref)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(strings.ToUpper(v), "#REF!") {
+			issues = append(issues, Issue{Kind: IssueBadReference, Panel: pi, Col: col, Row: row, Detail: ref + " contains #REF!"})
+			continue
+		}
+		for _, m := range formulaRef.FindAllString(formulaRefText(v), -1) {
+			c, r, err := ParseCellRef(m)
+			if err != nil || c >= p.Cols || r >= p.Rows || r < 0 {
+				issues = append(issues, Issue{Kind: IssueBadReference, Panel: pi, Col: col, Row: row, Detail: fmt.Sprintf("%s refers to %s outside the panel", ref, m)})
+				break
+			}
+		}
+	}
+	sort.Slice(issues, func(a, b int) bool {
+		if issues[a].Row != issues[b].Row {
+			return issues[a].Row < issues[b].Row
+		}
+		return issues[a].Col < issues[b].Col
+	})
+	return issues
+}
@@ -0,0 +1,93 @@
+package cellcanvas
+
+import (
+	"strings"
+)
+
+// Histogram counts the numeric values of one column in equal-width bins
+// between the column's minimum and maximum. Non-numeric cells (such as a
+// header) are skipped. Rows are scanned in steps, first for the range and
+// then for the counts, so a caller can spread a huge panel over several
+// frames.
+type Histogram struct {
+	Col      int
+	Bins     []int
+	Min, Max float64
+	// Count is the number of numeric values seen
+	Count int
+	// binning is set once the range pass is done
+	binning    bool
+	next, rows int
+}
+
+// NewHistogram prepares a histogram of column col of p with n bins.
+func NewHistogram(p *Panel, col, n int) *Histogram {
+	return &Histogram{Col: col, Bins: make([]int, max(n, 1)), rows: p.UsedRows()}
+}
+
+// Step scans up to n more rows of p and reports whether the histogram is
+// complete.
+func (h *Histogram) Step(p *Panel, n int) bool {
+	end := min(h.next+n, h.rows)
+	for ; h.next < end; h.next++ {
+		f, ok := h.value(p, h.next)
+		if !ok {
+			continue
+		}
+		if !h.binning {
+			if h.Count == 0 || f < h.Min {
+				h.Min = f
+			}
+			if h.Count == 0 || f > h.Max {
+				h.Max = f
+			}
+			h.Count++
+			continue
+		}
+		h.Bins[h.bin(f)]++
+	}
+	if h.next < h.rows {
+		return false
+	}
+	if h.binning || h.Count == 0 {
+		return true
+	}
+	h.binning = true
+	h.next = 0
+	return false
+}
+
+// Progress returns the fraction of the scan done so far.
+func (h *Histogram) Progress() float64 {
+	if h.rows == 0 {
+		return 1
+	}
+	done := float64(h.next) / float64(h.rows)
+	if h.binning {
+		return 0.5 + done/2
+	}
+	return done / 2
+}
+
+// BinRange returns the lower and upper bound of bin i.
+func (h *Histogram) BinRange(i int) (lo, hi float64) {
+	w := (h.Max - h.Min) / float64(len(h.Bins))
+	return h.Min + float64(i)*w, h.Min + float64(i+1)*w
+}
+
+func (h *Histogram) value(p *Panel, row int) (float64, bool) {
+	v := strings.TrimSpace(p.GetCell(h.Col, row))
+	if v == "" {
+		return 0, false
+	}
+	f, err := p.Numbers().Parse(v)
+	return f, err == nil
+}
+
+func (h *Histogram) bin(f float64) int {
+	if h.Max == h.Min {
+		return 0
+	}
+	i := int((f - h.Min) / (h.Max - h.Min) * float64(len(h.Bins)))
+	return max(0, min(i, len(h.Bins)-1))
+}
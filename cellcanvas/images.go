@@ -0,0 +1,37 @@
+package cellcanvas
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// imageExts are the file extensions of the images a cell can show.
+var imageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".webp"}
+
+// IsImageURL reports whether src, as returned by ImageSource, is fetched
+// over HTTP rather than read from a file.
+func IsImageURL(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// ImageSource returns the image that cell value v names: an http(s) URL,
+// or a file path, a relative one taken from the directory of p's CSV. ok is
+// false when v does not end in an image file extension (a URL's query
+// aside).
+func (p *Panel) ImageSource(v string) (src string, ok bool) {
+	v = strings.TrimSpace(v)
+	name := strings.ToLower(v)
+	url := IsImageURL(v)
+	if i := strings.IndexAny(name, "?#"); url && i >= 0 {
+		name = name[:i]
+	}
+	if !slices.ContainsFunc(imageExts, func(ext string) bool { return strings.HasSuffix(name, ext) }) {
+		return "", false
+	}
+	if url || filepath.IsAbs(v) || p.Filename == "" {
+		return v, true
+	}
+	return filepath.Join(filepath.Dir(p.Filename), v), true
+}
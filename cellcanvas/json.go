@@ -0,0 +1,250 @@
+package cellcanvas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JSON files hold a panel as an array of objects, one per row. Keys are
+// the header row, in the order they are first seen; nested objects are
+// flattened into dotted keys ("address.city") on reading and rebuilt from
+// them on writing. Arrays are kept in their cell as JSON text.
+
+// ReadJSON loads a .json file whose top level is an array of objects as a
+// panel named after the file. Its position is left at zero for the caller
+// to arrange, and it has no file name, so SaveState gives it a CSV of its
+// own.
+func ReadJSON(path string) (Panel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Panel{}, err
+	}
+	defer f.Close()
+	recs, err := parseJSONRecords(f)
+	if err != nil {
+		return Panel{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	p, err := NewPanelFromRecords(0, 0, recs)
+	if err != nil {
+		return Panel{}, err
+	}
+	p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return p, nil
+}
+
+// parseJSONRecords reads an array of objects as records, header first.
+func parseJSONRecords(r io.Reader) ([][]string, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("the top level is not an array")
+	}
+	cols := make(map[string]int)
+	var header []string
+	var rows [][]string
+	for n := 1; dec.More(); n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			return nil, fmt.Errorf("element %d is not an object", n)
+		}
+		var rec []string
+		err := flattenJSON(raw, "", func(key, v string) {
+			col, ok := cols[key]
+			if !ok {
+				col = len(header)
+				cols[key] = col
+				header = append(header, key)
+			}
+			for len(rec) <= col {
+				rec = append(rec, "")
+			}
+			rec[col] = v
+		})
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", n, err)
+		}
+		rows = append(rows, rec)
+	}
+	if len(header) == 0 {
+		return nil, errors.New("no object has any keys")
+	}
+	return append([][]string{header}, rows...), nil
+}
+
+// flattenJSON calls add with the dotted key and cell text of every value of
+// the object raw, in order, prefixing keys with prefix.
+func flattenJSON(raw json.RawMessage, prefix string, add func(key, v string)) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := prefix + tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		v = bytes.TrimSpace(v)
+		switch v[0] {
+		case '{':
+			if err := flattenJSON(v, key+".", add); err != nil {
+				return err
+			}
+		case '"':
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			add(key, s)
+		case 'n':
+			add(key, "")
+		default:
+			// numbers and booleans as written; arrays as compact JSON
+			var b bytes.Buffer
+			if err := json.Compact(&b, v); err != nil {
+				return err
+			}
+			add(key, b.String())
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the data rows of p to path as an array of objects keyed
+// by the header row (column letters where a header is empty) and rebuilds
+// the objects that dotted keys were flattened from. Numbers are written as
+// numbers, true and false as booleans and cells holding a JSON array or
+// object as that value; empty cells are left out of their row's object.
+func WriteJSON(path string, p *Panel) error {
+	keys := make([]string, p.Cols)
+	for col := range keys {
+		keys[col] = strings.TrimSpace(p.GetCell(col, 0))
+		if keys[col] == "" {
+			keys[col] = ColToLetters(col)
+		}
+	}
+	tree := newJSONTree(keys)
+	num := p.Numbers()
+	return WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		var b bytes.Buffer
+		b.WriteString("[")
+		for row := 1; row < p.UsedRows(); row++ {
+			if row > 1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n  ")
+			tree.write(&b, func(col int) string { return jsonValue(p.GetCell(col, row), num) })
+		}
+		b.WriteString("\n]\n")
+		_, err := w.Write(b.Bytes())
+		return err
+	})
+}
+
+// jsonValue returns cell text v as a JSON value, or "" for an empty cell.
+func jsonValue(v string, num NumberLocale) string {
+	s := strings.TrimSpace(v)
+	switch {
+	case s == "":
+		return ""
+	case s == "true" || s == "false":
+		return s
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{"):
+		if json.Valid([]byte(s)) {
+			return s
+		}
+	}
+	if c, ok := num.Canonical(s); ok && json.Valid([]byte(c)) {
+		return c
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// jsonTree is the object shape dotted keys describe: each entry is a
+// column or a nested object, in the order of the first column under it.
+type jsonTree struct {
+	names []string
+	cols  []int // the column of each entry, or -1 for a nested object
+	kids  map[string]*jsonTree
+}
+
+// newJSONTree builds the tree of keys, one per column. A key that is both
+// a value and the start of longer ones ("a" and "a.b") stays flat.
+func newJSONTree(keys []string) *jsonTree {
+	leaf := make(map[string]bool)
+	for _, k := range keys {
+		leaf[k] = true
+	}
+	root := &jsonTree{kids: make(map[string]*jsonTree)}
+	for col, k := range keys {
+		t, path := root, strings.Split(k, ".")
+		for i := 1; i < len(path); i++ {
+			if leaf[strings.Join(path[:i], ".")] || path[i-1] == "" {
+				// keep the rest of the key as one name
+				path = append(path[:i-1], strings.Join(path[i-1:], "."))
+				break
+			}
+		}
+		for _, name := range path[:len(path)-1] {
+			kid, ok := t.kids[name]
+			if !ok {
+				kid = &jsonTree{kids: make(map[string]*jsonTree)}
+				t.kids[name] = kid
+				t.names = append(t.names, name)
+				t.cols = append(t.cols, -1)
+			}
+			t = kid
+		}
+		t.names = append(t.names, path[len(path)-1])
+		t.cols = append(t.cols, col)
+	}
+	return root
+}
+
+// write writes the object for one row, value giving each column's JSON
+// value; entries whose value is empty, and objects left empty, are left
+// out.
+func (t *jsonTree) write(b *bytes.Buffer, value func(col int) string) {
+	b.WriteString("{")
+	first := true
+	for i, name := range t.names {
+		var v string
+		if t.cols[i] >= 0 {
+			if v = value(t.cols[i]); v == "" {
+				continue
+			}
+		} else {
+			var kb bytes.Buffer
+			t.kids[name].write(&kb, value)
+			if v = kb.String(); v == "{}" {
+				continue
+			}
+		}
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		k, _ := json.Marshal(name)
+		b.Write(k)
+		b.WriteString(": ")
+		b.WriteString(v)
+	}
+	b.WriteString("}")
+}
@@ -0,0 +1,59 @@
+package cellcanvas
+
+// Layout is a named arrangement of the workspace: the camera and the
+// position of every panel, indexed like Workspace.Panels. Panels added after
+// the layout was saved have no entry and stay where they are.
+type Layout struct {
+	Name   string      `yaml:"name"`
+	CamX   float64     `yaml:"cam_x"`
+	CamY   float64     `yaml:"cam_y"`
+	Panels []LayoutPos `yaml:"panels"`
+}
+
+// LayoutPos is a panel's position in a Layout.
+type LayoutPos struct {
+	X int `yaml:"x"`
+	Y int `yaml:"y"`
+}
+
+// FindLayout returns the index of the layout called name, or -1.
+func (w *Workspace) FindLayout(name string) int {
+	for i := range w.Layouts {
+		if w.Layouts[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SaveLayout records the current camera and panel positions as name,
+// replacing a layout of the same name.
+func (w *Workspace) SaveLayout(name string) {
+	l := Layout{Name: name, CamX: w.CamX, CamY: w.CamY, Panels: make([]LayoutPos, len(w.Panels))}
+	for i := range w.Panels {
+		l.Panels[i] = LayoutPos{X: w.Panels[i].X, Y: w.Panels[i].Y}
+	}
+	if i := w.FindLayout(name); i >= 0 {
+		w.Layouts[i] = l
+		return
+	}
+	w.Layouts = append(w.Layouts, l)
+}
+
+// DeleteLayout removes the layout called name if there is one.
+func (w *Workspace) DeleteLayout(name string) {
+	if i := w.FindLayout(name); i >= 0 {
+		w.Layouts = append(w.Layouts[:i], w.Layouts[i+1:]...)
+	}
+}
+
+// dropLayoutPanel forgets panel i in every layout so later panels keep
+// their saved positions after it is removed.
+func (w *Workspace) dropLayoutPanel(i int) {
+	for li := range w.Layouts {
+		l := &w.Layouts[li]
+		if i < len(l.Panels) {
+			l.Panels = append(l.Panels[:i], l.Panels[i+1:]...)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cellcanvas
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// NumberLocale is how numbers are written in cell text: the decimal
+// separator and the thousands separator ("" for none). Cells keep the text
+// as typed or loaded; the locale says how to read it as a number, and how
+// to write the numbers formulas and computed columns produce.
+type NumberLocale struct {
+	Name    string
+	Label   string
+	Decimal byte
+	Group   string
+}
+
+// NumberLocales lists the supported locales, by name.
+var NumberLocales = []NumberLocale{
+	{Name: "plain", Label: "1234.5", Decimal: '.'},
+	{Name: "en", Label: "1,234.5", Decimal: '.', Group: ","},
+	{Name: "de", Label: "1.234,5", Decimal: ',', Group: "."},
+	{Name: "fr", Label: "1 234,5", Decimal: ',', Group: " "},
+	{Name: "ch", Label: "1'234.5", Decimal: '.', Group: "'"},
+}
+
+// DefaultNumberLocale applies to panels without a locale of their own;
+// settings.yml's number_locale sets it.
+var DefaultNumberLocale = NumberLocales[0]
+
+// LookupNumberLocale returns the locale called name (case-insensitive).
+func LookupNumberLocale(name string) (NumberLocale, bool) {
+	for _, l := range NumberLocales {
+		if strings.EqualFold(l.Name, name) {
+			return l, true
+		}
+	}
+	return NumberLocale{}, false
+}
+
+// Numbers returns the locale p's numbers are written in: its NumberLocale,
+// or DefaultNumberLocale when that is empty or unknown.
+func (p *Panel) Numbers() NumberLocale {
+	if l, ok := LookupNumberLocale(p.NumberLocale); ok {
+		return l
+	}
+	return DefaultNumberLocale
+}
+
+var errNotLocaleNumber = errors.New("not a number")
+
+// plain reports whether l writes numbers the way strconv does.
+func (l NumberLocale) plain() bool {
+	return l.Decimal == '.' && l.Group == ""
+}
+
+// Canonical returns s, surrounding space removed, in the form strconv
+// parses when it is a number written in l, and ok false otherwise.
+// Thousands separators must group the integer digits by three; "1.5" is
+// not a number in a locale with a decimal comma.
+func (l NumberLocale) Canonical(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if l.plain() {
+		_, err := strconv.ParseFloat(s, 64)
+		return s, err == nil
+	}
+	if l.Group == " " {
+		// French text often groups with (narrow) no-break spaces
+		s = strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(s)
+	}
+	whole, frac, hasFrac := s, "", false
+	if i := strings.LastIndexByte(s, l.Decimal); i >= 0 {
+		whole, frac, hasFrac = s[:i], s[i+1:], true
+	}
+	if l.Decimal != '.' && strings.Contains(s, ".") && l.Group != "." {
+		return "", false
+	}
+	if l.Group != "" && strings.Contains(whole, l.Group) {
+		groups := strings.Split(whole, l.Group)
+		first := strings.TrimLeft(groups[0], "+-")
+		if len(first) < 1 || len(first) > 3 || !allDigits(first) {
+			return "", false
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 || !allDigits(g) {
+				return "", false
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+	if strings.IndexByte(whole, l.Decimal) >= 0 || (l.Group != "" && strings.Contains(frac, l.Group)) {
+		return "", false
+	}
+	out := whole
+	if hasFrac {
+		out += "." + frac
+	}
+	if _, err := strconv.ParseFloat(out, 64); err != nil {
+		return "", false
+	}
+	return out, true
+}
+
+// Parse reads s, a number written in l.
+func (l NumberLocale) Parse(s string) (float64, error) {
+	if l.plain() {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	c, ok := l.Canonical(s)
+	if !ok {
+		return 0, errNotLocaleNumber
+	}
+	return strconv.ParseFloat(c, 64)
+}
+
+// Format writes f in l with up to prec significant digits, like
+// strconv.FormatFloat(f, 'g', prec, 64), grouping the integer digits
+// unless the number comes out in exponent form.
+func (l NumberLocale) Format(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'g', prec, 64)
+	if l.plain() || strings.ContainsAny(s, "eEIN") {
+		return s
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	sign := ""
+	if strings.HasPrefix(whole, "-") {
+		sign, whole = "-", whole[1:]
+	}
+	out := sign + groupDigits(whole, l.Group)
+	if frac != "" {
+		out += string(l.Decimal) + frac
+	}
+	return out
+}
+
+// groupDigits puts sep between every three digits of whole, counting from
+// the right; an empty sep leaves it as it is.
+func groupDigits(whole, sep string) string {
+	if sep == "" {
+		return whole
+	}
+	var b strings.Builder
+	for i := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteByte(whole[i])
+	}
+	return b.String()
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
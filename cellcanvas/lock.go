@@ -0,0 +1,200 @@
+package cellcanvas
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockExt is appended to the state file name for its lock file, e.g.
+// state.yml.lock.
+const LockExt = ".lock"
+
+// LockStaleAfter is how old a lock's heartbeat may get before the lock is
+// considered abandoned (its owner crashed or was killed). Owners should
+// Refresh well within this interval.
+const LockStaleAfter = 30 * time.Second
+
+var (
+	// ErrLocked is returned by AcquireLock when another live instance holds
+	// the workspace.
+	ErrLocked = errors.New("workspace is locked by another instance")
+	// ErrLockLost is returned by Refresh when another instance took the lock
+	// over.
+	ErrLockLost = errors.New("workspace lock was taken over")
+)
+
+// LockInfo is the content of a lock file and identifies its owner.
+type LockInfo struct {
+	Token   string    `yaml:"token"`
+	PID     int       `yaml:"pid"`
+	Host    string    `yaml:"host"`
+	User    string    `yaml:"user"`
+	Updated time.Time `yaml:"updated"`
+}
+
+// String describes the owner for display, e.g. "alice@box (pid 123)".
+func (li *LockInfo) String() string {
+	if li.Token == "" {
+		return "an instance that is still starting"
+	}
+	return fmt.Sprintf("%s@%s (pid %d)", li.User, li.Host, li.PID)
+}
+
+// Stale reports whether the owner stopped refreshing the lock.
+func (li *LockInfo) Stale() bool {
+	return time.Since(li.Updated) > LockStaleAfter
+}
+
+// WorkspaceLock is a held single-writer lock on a state file.
+type WorkspaceLock struct {
+	statePath string
+	info      LockInfo
+}
+
+// ReadLock returns the current lock on statePath, or nil if there is none.
+// A lock file that is empty or cannot be parsed, such as one another
+// instance has just created and not yet written, is returned without an
+// owner and dated by its file, so it counts as held until it goes stale.
+func ReadLock(statePath string) (*LockInfo, error) {
+	path := statePath + LockExt
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var li LockInfo
+	if err := yaml.Unmarshal(b, &li); err != nil || li.Token == "" {
+		st, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &LockInfo{Updated: st.ModTime()}, nil
+	}
+	return &li, nil
+}
+
+// AcquireLock takes the lock on statePath. If another instance holds a
+// fresh lock, AcquireLock returns ErrLocked together with the holder's info
+// unless takeover is true, in which case the lock is overwritten and the
+// other instance finds out on its next Refresh.
+func AcquireLock(statePath string, takeover bool) (*WorkspaceLock, *LockInfo, error) {
+	holder, err := ReadLock(statePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if holder != nil && !holder.Stale() && !takeover {
+		return nil, holder, ErrLocked
+	}
+	tok := make([]byte, 8)
+	if _, err := rand.Read(tok); err != nil {
+		return nil, nil, err
+	}
+	host, _ := os.Hostname()
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	l := &WorkspaceLock{
+		statePath: statePath,
+		info:      LockInfo{Token: hex.EncodeToString(tok), PID: os.Getpid(), Host: host, User: name},
+	}
+	if holder != nil {
+		if err := l.write(); err != nil {
+			return nil, nil, err
+		}
+		return l, nil, nil
+	}
+	// claim a missing lock exclusively so two instances starting at the
+	// same moment cannot both win; the loser reads the file as held even
+	// before the winner's info is in it (see ReadLock)
+	f, err := os.OpenFile(statePath+LockExt, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			if holder, _ = ReadLock(statePath); holder == nil {
+				holder = &LockInfo{Updated: time.Now()}
+			}
+			return nil, holder, ErrLocked
+		}
+		return nil, nil, err
+	}
+	b, err := l.marshal()
+	if err == nil {
+		_, err = f.Write(b)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(statePath + LockExt)
+		return nil, nil, err
+	}
+	return l, nil, nil
+}
+
+// marshal stamps the heartbeat and returns the lock file content.
+func (l *WorkspaceLock) marshal() ([]byte, error) {
+	l.info.Updated = time.Now()
+	return yaml.Marshal(&l.info)
+}
+
+// write replaces the lock file with l's info. The content is staged in a
+// temporary file of its own, so instances writing at once never share one.
+func (l *WorkspaceLock) write() error {
+	b, err := l.marshal()
+	if err != nil {
+		return err
+	}
+	path := l.statePath + LockExt
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Refresh updates the heartbeat. It returns ErrLockLost with the new
+// owner's info if another instance took the lock over.
+func (l *WorkspaceLock) Refresh() (*LockInfo, error) {
+	cur, err := ReadLock(l.statePath)
+	if err != nil {
+		return nil, err
+	}
+	if cur != nil && cur.Token != l.info.Token {
+		return cur, ErrLockLost
+	}
+	return nil, l.write()
+}
+
+// Release removes the lock file if it is still ours. A nil lock is a no-op.
+func (l *WorkspaceLock) Release() {
+	if l == nil {
+		return
+	}
+	cur, err := ReadLock(l.statePath)
+	if err == nil && cur != nil && cur.Token == l.info.Token {
+		os.Remove(l.statePath + LockExt)
+	}
+}
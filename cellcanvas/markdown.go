@@ -0,0 +1,193 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markdown tables in the GitHub-flavored form: a header row, a delimiter
+// row of dashes with optional colons for alignment, then the body rows,
+// cells separated by | with \| for a pipe inside a cell.
+//
+//	| item   | qty |
+//	|--------|----:|
+//	| apples |  12 |
+
+// ParseMarkdownTable returns the records of the first Markdown table in
+// text, header first, and whether text has one.
+func ParseMarkdownTable(text string) ([][]string, bool) {
+	recs, start := markdownTable(text)
+	return recs, start >= 0
+}
+
+// markdownTable returns the records of the first Markdown table in text
+// and the line its header is on, or -1 without a table.
+func markdownTable(text string) ([][]string, int) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		header := markdownCells(lines[i])
+		if header == nil || !isMarkdownDelimiter(lines[i+1]) {
+			continue
+		}
+		recs := [][]string{header}
+		for _, line := range lines[i+2:] {
+			rec := markdownCells(line)
+			if rec == nil {
+				break
+			}
+			recs = append(recs, rec)
+		}
+		return recs, i
+	}
+	return nil, -1
+}
+
+// markdownCells splits a table row into its cells, or returns nil when
+// line is not a row: blank or without a |.
+func markdownCells(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || !strings.Contains(line, "|") {
+		return nil
+	}
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			b.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, markdownCell(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(line[i])
+		}
+	}
+	return append(cells, markdownCell(b.String()))
+}
+
+// markdownCell returns the value of a cell as written in a table: trimmed,
+// with <br> as a line break.
+func markdownCell(s string) string {
+	s = strings.TrimSpace(s)
+	for _, br := range []string{"<br>", "<br/>", "<br />"} {
+		s = strings.ReplaceAll(s, br, "\n")
+	}
+	return s
+}
+
+// isMarkdownDelimiter reports whether line is the delimiter row under a
+// table's header, like |---|:---:|--:|.
+func isMarkdownDelimiter(line string) bool {
+	cells := markdownCells(line)
+	if cells == nil {
+		return false
+	}
+	for _, c := range cells {
+		c = strings.TrimSuffix(strings.TrimPrefix(c, ":"), ":")
+		if c == "" || strings.Trim(c, "-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadMarkdown loads the first table of a Markdown file as a panel named
+// after the file. Its position is left at zero for the caller to arrange.
+func ReadMarkdown(path string) (Panel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Panel{}, err
+	}
+	recs, ok := ParseMarkdownTable(string(data))
+	if !ok {
+		return Panel{}, fmt.Errorf("%s: no Markdown table found", filepath.Base(path))
+	}
+	p, err := NewPanelFromRecords(0, 0, recs)
+	if err != nil {
+		return Panel{}, err
+	}
+	p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return p, nil
+}
+
+// MarkdownTable writes range r of p as a Markdown table of the values its
+// cells show. The first row of r is the header, or the panel's header row
+// over the selected columns when r starts below it. Columns whose values
+// are all numbers are right-aligned, and the columns are padded to line up.
+func MarkdownTable(p *Panel, r CellRange) string {
+	var recs [][]string
+	if r.Row0 > 0 {
+		recs = append(recs, markdownRow(p, r, 0))
+	}
+	for row := r.Row0; row <= r.Row1; row++ {
+		recs = append(recs, markdownRow(p, r, row))
+	}
+	if len(recs) == 1 {
+		// a header with no rows below still needs one to be a table
+		recs = append(recs, make([]string, r.Col1-r.Col0+1))
+	}
+	num := p.Numbers()
+	widths := make([]int, r.Col1-r.Col0+1)
+	right := make([]bool, len(widths))
+	for i := range widths {
+		widths[i] = 3
+		right[i] = true
+		seen := false
+		for j, rec := range recs {
+			widths[i] = max(widths[i], len([]rune(rec[i])))
+			if j == 0 || rec[i] == "" {
+				continue
+			}
+			seen = true
+			if _, err := num.Parse(rec[i]); err != nil {
+				right[i] = false
+			}
+		}
+		right[i] = right[i] && seen
+	}
+	var b strings.Builder
+	line := func(cells []string, pad func(i int, s string) string) {
+		b.WriteString("|")
+		for i, c := range cells {
+			b.WriteString(" " + pad(i, c) + " |")
+		}
+		b.WriteString("\n")
+	}
+	pad := func(i int, s string) string {
+		fill := strings.Repeat(" ", widths[i]-len([]rune(s)))
+		if right[i] {
+			return fill + s
+		}
+		return s + fill
+	}
+	line(recs[0], pad)
+	line(make([]string, len(widths)), func(i int, _ string) string {
+		if right[i] {
+			return strings.Repeat("-", widths[i]-1) + ":"
+		}
+		return strings.Repeat("-", widths[i])
+	})
+	for _, rec := range recs[1:] {
+		line(rec, pad)
+	}
+	return b.String()
+}
+
+// markdownRow returns the cells of row in the columns of r, escaped for a
+// table.
+func markdownRow(p *Panel, r CellRange, row int) []string {
+	rec := make([]string, 0, r.Col1-r.Col0+1)
+	for col := r.Col0; col <= r.Col1; col++ {
+		v := strings.ReplaceAll(p.DisplayCell(col, row), "|", `\|`)
+		v = strings.ReplaceAll(strings.ReplaceAll(v, "\r\n", "\n"), "\n", "<br>")
+		rec = append(rec, v)
+	}
+	return rec
+}
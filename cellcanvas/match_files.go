@@ -0,0 +1,42 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MatchFiles returns the regular files in dir whose names match pattern,
+// sorted by name. A pattern between slashes (/day-\d+\.csv/) is a regular
+// expression matched against the file name; anything else is a glob as
+// accepted by filepath.Match.
+func MatchFiles(dir, pattern string) ([]string, error) {
+	match := func(name string) (bool, error) { return filepath.Match(pattern, name) }
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %s: %w", pattern, err)
+		}
+		match = func(name string) (bool, error) { return re.MatchString(name), nil }
+	} else if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("bad pattern %s: %w", pattern, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if ok, _ := match(e.Name()); ok {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,319 @@
+package cellcanvas
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OpenDocument namespaces used when reading and writing .ods files.
+const (
+	odsNSOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsNSTable  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsNSText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+)
+
+// ReadODS loads every sheet of an OpenDocument spreadsheet as a panel. Each
+// panel is named after its sheet; positions are left at zero for the caller
+// to arrange. Numeric, date and boolean cells keep their raw value rather
+// than the locale-formatted display text.
+func ReadODS(path string) ([]Panel, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		panels, err := parseODSContent(rc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		return panels, nil
+	}
+	return nil, fmt.Errorf("%s: no content.xml (not an ODS file?)", filepath.Base(path))
+}
+
+func odsAttr(se xml.StartElement, space, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func odsRepeat(se xml.StartElement, local string) int {
+	n, err := strconv.Atoi(odsAttr(se, odsNSTable, local))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// parseODSContent walks content.xml. Repeated empty rows/columns (which
+// LibreOffice emits up to the sheet limits) only advance the position; the
+// panel is sized to the last non-empty cell.
+func parseODSContent(r io.Reader) ([]Panel, error) {
+	dec := xml.NewDecoder(r)
+	var panels []Panel
+	var cur *Panel
+	row, col := 0, 0
+	rowRepeat := 1
+
+	// state of the cell being read
+	inCell := false
+	cellRepeat := 1
+	cellValue := ""
+	hasValue := false
+	var text strings.Builder
+	paragraphs := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsNSTable && t.Name.Local == "table":
+				p := NewBlankPanel(0, 0, 0, 0)
+				p.Name = odsAttr(t, odsNSTable, "name")
+				panels = append(panels, p)
+				cur = &panels[len(panels)-1]
+				row = 0
+			case cur != nil && t.Name.Space == odsNSTable && t.Name.Local == "table-row":
+				col = 0
+				rowRepeat = odsRepeat(t, "number-rows-repeated")
+			case cur != nil && t.Name.Space == odsNSTable && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				inCell = true
+				cellRepeat = odsRepeat(t, "number-columns-repeated")
+				text.Reset()
+				paragraphs = 0
+				hasValue = false
+				switch odsAttr(t, odsNSOffice, "value-type") {
+				case "float", "percentage", "currency":
+					cellValue, hasValue = odsAttr(t, odsNSOffice, "value"), true
+				case "date":
+					cellValue, hasValue = odsAttr(t, odsNSOffice, "date-value"), true
+				case "time":
+					cellValue, hasValue = odsAttr(t, odsNSOffice, "time-value"), true
+				case "boolean":
+					cellValue, hasValue = odsAttr(t, odsNSOffice, "boolean-value"), true
+				}
+			case inCell && t.Name.Space == odsNSText && t.Name.Local == "p":
+				if paragraphs > 0 {
+					text.WriteByte('\n')
+				}
+				paragraphs++
+			case inCell && t.Name.Space == odsNSText && t.Name.Local == "s":
+				n, err := strconv.Atoi(odsAttr(t, odsNSText, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				text.WriteString(strings.Repeat(" ", n))
+			case inCell && t.Name.Space == odsNSText && t.Name.Local == "tab":
+				text.WriteByte('\t')
+			case inCell && t.Name.Space == odsNSText && t.Name.Local == "line-break":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inCell && paragraphs > 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Space == odsNSTable && t.Name.Local == "table":
+				cur = nil
+			case cur != nil && t.Name.Space == odsNSTable && t.Name.Local == "table-row":
+				row += rowRepeat
+			case inCell && t.Name.Space == odsNSTable && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				inCell = false
+				v := text.String()
+				if hasValue && cellValue != "" {
+					v = cellValue
+				}
+				if v != "" {
+					// a non-empty row may itself be repeated
+					for rr := 0; rr < rowRepeat; rr++ {
+						for c := 0; c < cellRepeat; c++ {
+							cur.SetCell(col+c, row+rr, v)
+						}
+					}
+					cur.Cols = max(cur.Cols, col+cellRepeat)
+					cur.Rows = max(cur.Rows, row+rowRepeat)
+				}
+				col += cellRepeat
+			}
+		}
+	}
+	for i := range panels {
+		if panels[i].Rows == 0 || panels[i].Cols == 0 {
+			panels[i].Rows, panels[i].Cols = 1, 1
+		}
+	}
+	return panels, nil
+}
+
+// SheetName returns a spreadsheet sheet name for the panel at index i: its
+// Name, else its file name without extension, else "Panel N".
+func SheetName(p *Panel, i int) string {
+	name := p.Name
+	if name == "" && p.Filename != "" {
+		name = strings.TrimSuffix(filepath.Base(p.Filename), filepath.Ext(p.Filename))
+	}
+	if name == "" {
+		name = fmt.Sprintf("Panel %d", i+1)
+	}
+	// characters spreadsheet applications reject in sheet names
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]*?:/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if rs := []rune(name); len(rs) > 31 {
+		name = string(rs[:31])
+	}
+	return name
+}
+
+// uniqueSheetNames assigns each panel a distinct sheet name.
+func uniqueSheetNames(panels []Panel) []string {
+	names := make([]string, len(panels))
+	seen := make(map[string]bool)
+	for i := range panels {
+		base := SheetName(&panels[i], i)
+		name := base
+		for n := 2; seen[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)", base, n)
+		}
+		seen[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// WriteODS writes panels to an OpenDocument spreadsheet, one sheet per
+// panel. Cells that parse as numbers are stored as floats.
+func WriteODS(path string, panels []Panel) error {
+	return WriteFileAtomic(path, 0644, func(f io.Writer) error {
+		return writeODSZip(f, panels)
+	})
+}
+
+func writeODSZip(f io.Writer, panels []Panel) error {
+	zw := zip.NewWriter(f)
+
+	// the mimetype entry must come first and be stored uncompressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, odsMimeType); err != nil {
+		return err
+	}
+
+	manifest := `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + odsMimeType + `"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+	w, err := zw.Create("META-INF/manifest.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, manifest); err != nil {
+		return err
+	}
+
+	w, err = zw.Create("content.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeODSContent(w, panels); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeODSContent(w io.Writer, panels []Panel) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="` + odsNSOffice + `" xmlns:table="` + odsNSTable + `" xmlns:text="` + odsNSText + `" office:version="1.2">
+<office:body><office:spreadsheet>
+`)
+	names := uniqueSheetNames(panels)
+	for i := range panels {
+		p := &panels[i]
+		fmt.Fprintf(&b, `<table:table table:name="%s">`, xmlEscape(names[i]))
+		if p.Cols > 0 {
+			fmt.Fprintf(&b, `<table:table-column table:number-columns-repeated="%d"/>`, p.Cols)
+		}
+		for r := 0; r < p.Rows; r++ {
+			b.WriteString("<table:table-row>")
+			for c := 0; c < p.Cols; c++ {
+				v := p.GetCell(c, r)
+				num, isNum := exportNumber(p, v)
+				switch {
+				case v == "":
+					b.WriteString("<table:table-cell/>")
+				case isNum:
+					fmt.Fprintf(&b, `<table:table-cell office:value-type="float" office:value="%s"><text:p>%s</text:p></table:table-cell>`, num, xmlEscape(v))
+				default:
+					b.WriteString(`<table:table-cell office:value-type="string">`)
+					for _, line := range strings.Split(v, "\n") {
+						fmt.Fprintf(&b, "<text:p>%s</text:p>", xmlEscape(line))
+					}
+					b.WriteString("</table:table-cell>")
+				}
+			}
+			b.WriteString("</table:table-row>\n")
+		}
+		b.WriteString("</table:table>\n")
+	}
+	b.WriteString("</office:spreadsheet></office:body></office:document-content>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportNumber returns v as a plain decimal number when it is a number in
+// p's locale, so spreadsheets get the value whatever separators it was
+// written with.
+func exportNumber(p *Panel, v string) (string, bool) {
+	c, ok := p.Numbers().Canonical(v)
+	return c, ok && isPlainNumber(c)
+}
+
+// isPlainNumber reports whether s is a plain decimal number (no hex, NaN or
+// Inf spellings, which ParseFloat also accepts).
+func isPlainNumber(s string) bool {
+	if strings.Trim(s, "0123456789+-.eE") != "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
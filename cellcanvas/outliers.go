@@ -0,0 +1,84 @@
+package cellcanvas
+
+import (
+	"math"
+	"strings"
+)
+
+// DefaultOutlierK is the outlier threshold in standard deviations used
+// when none is configured.
+const DefaultOutlierK = 3
+
+// Outlier is a numeric cell far from its column mean. Z is its distance
+// from the mean in standard deviations (negative below the mean).
+type Outlier struct {
+	Col, Row int
+	Value, Z float64
+}
+
+// columnMoments accumulates the running mean and variance of a column
+// (Welford's method).
+type columnMoments struct {
+	n        int
+	mean, m2 float64
+}
+
+// OutlierScan finds the numeric cells of a panel more than K standard
+// deviations from the mean of their column. Non-numeric cells are ignored.
+// Rows are scanned in steps, first for the column statistics and then for
+// the outliers, so a caller can spread a huge panel over several frames.
+type OutlierScan struct {
+	K        float64
+	Outliers []Outlier
+	cols     []columnMoments
+	// flagging is set once the statistics pass is done
+	flagging   bool
+	next, rows int
+}
+
+// NewOutlierScan prepares a scan of p with threshold k.
+func NewOutlierScan(p *Panel, k float64) *OutlierScan {
+	return &OutlierScan{K: k, rows: p.UsedRows(), cols: make([]columnMoments, p.UsedCols())}
+}
+
+// Step scans up to n more rows of p and reports whether the scan is
+// complete.
+func (s *OutlierScan) Step(p *Panel, n int) bool {
+	end := min(s.next+n, s.rows)
+	for ; s.next < end; s.next++ {
+		for c := range s.cols {
+			v := strings.TrimSpace(p.GetCell(c, s.next))
+			if v == "" {
+				continue
+			}
+			f, err := p.Numbers().Parse(v)
+			if err != nil {
+				continue
+			}
+			m := &s.cols[c]
+			if !s.flagging {
+				m.n++
+				d := f - m.mean
+				m.mean += d / float64(m.n)
+				m.m2 += d * (f - m.mean)
+				continue
+			}
+			if m.n < 2 {
+				continue
+			}
+			sd := math.Sqrt(m.m2 / float64(m.n-1))
+			if z := (f - m.mean) / sd; sd > 0 && math.Abs(z) > s.K {
+				s.Outliers = append(s.Outliers, Outlier{Col: c, Row: s.next, Value: f, Z: z})
+			}
+		}
+	}
+	if s.next < s.rows {
+		return false
+	}
+	if s.flagging {
+		return true
+	}
+	s.flagging = true
+	s.next = 0
+	return false
+}
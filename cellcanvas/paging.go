@@ -0,0 +1,202 @@
+package cellcanvas
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+)
+
+// PagingThreshold is the row count above which LoadPanelCSV pages a panel's
+// data to a temporary file instead of holding every cell in memory. Zero
+// disables paging. Panels of an encrypted workspace are never paged, since
+// the temporary file is plain text.
+var PagingThreshold = 100000
+
+// pageRows is the number of rows stored per page; pageCacheSize is how many
+// decoded pages a store keeps in memory (the visible window plus whatever
+// was recently scrolled past).
+const (
+	pageRows      = 1024
+	pageCacheSize = 64
+)
+
+// PagedStore holds the cells of a huge panel in a temporary file. Rows are
+// grouped into pages and each page is stored column by column, so only an
+// index of page offsets and a small LRU cache of decoded pages stay in
+// memory. A PagedStore is read-only once loading finished; edits are kept in
+// the panel's Cells map on top of it.
+type PagedStore struct {
+	file    *os.File
+	offsets []int64 // start of page i; the last entry is the end of file
+	rows    int
+	cols    int
+
+	// load-time state
+	w       *bufio.Writer
+	pending [][]string
+
+	cache map[int][][]string // page -> columns -> values
+	lru   []int              // most recently used last
+
+	cleanup runtime.Cleanup
+}
+
+// tempFile is released by a cleanup once the store is unreachable, for
+// stores that were never closed, such as the result of a load that was
+// superseded before it was applied. Cleanups do not run at exit, so the
+// workspace closes the stores of replaced and removed panels itself, and
+// all of them on shutdown (Workspace.ReleaseStore, CloseStores).
+type tempFile struct {
+	f *os.File
+}
+
+func newPagedStore() (*PagedStore, error) {
+	f, err := os.CreateTemp("", "cellcanvas-page-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &PagedStore{file: f, w: bufio.NewWriterSize(f, 1<<20), offsets: []int64{0}, cache: make(map[int][][]string)}
+	s.cleanup = runtime.AddCleanup(s, func(t tempFile) {
+		t.f.Close()
+		os.Remove(t.f.Name())
+	}, tempFile{f})
+	return s, nil
+}
+
+// Close deletes the store's temporary file. The store reads as empty
+// afterwards; closing it again does nothing.
+func (s *PagedStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.cleanup.Stop()
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	s.file, s.cache, s.lru = nil, nil, nil
+	return err
+}
+
+// Rows returns the number of stored rows.
+func (s *PagedStore) Rows() int { return s.rows }
+
+// Cols returns the widest stored row.
+func (s *PagedStore) Cols() int { return s.cols }
+
+func (s *PagedStore) appendRow(rec []string) error {
+	s.pending = append(s.pending, rec)
+	s.rows++
+	s.cols = max(s.cols, len(rec))
+	if len(s.pending) == pageRows {
+		return s.flushPage()
+	}
+	return nil
+}
+
+// flushPage encodes pending rows as one page: the column count, then for
+// every column each row's value as a length-prefixed string.
+func (s *PagedStore) flushPage() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	cols := 0
+	for _, rec := range s.pending {
+		cols = max(cols, len(rec))
+	}
+	var buf []byte
+	buf = binary.AppendUvarint(buf, uint64(cols))
+	for c := 0; c < cols; c++ {
+		for _, rec := range s.pending {
+			v := ""
+			if c < len(rec) {
+				v = rec[c]
+			}
+			buf = binary.AppendUvarint(buf, uint64(len(v)))
+			buf = append(buf, v...)
+		}
+	}
+	if _, err := s.w.Write(buf); err != nil {
+		return err
+	}
+	s.offsets = append(s.offsets, s.offsets[len(s.offsets)-1]+int64(len(buf)))
+	s.pending = s.pending[:0]
+	return nil
+}
+
+func (s *PagedStore) finish() error {
+	if err := s.flushPage(); err != nil {
+		return err
+	}
+	s.pending = nil
+	err := s.w.Flush()
+	s.w = nil
+	return err
+}
+
+// Get returns the stored value at col, row, or "" outside the data.
+func (s *PagedStore) Get(col, row int) string {
+	if s.file == nil || row < 0 || row >= s.rows || col < 0 || col >= s.cols {
+		return ""
+	}
+	pg, err := s.page(row / pageRows)
+	if err != nil || col >= len(pg) {
+		return ""
+	}
+	return pg[col][row%pageRows]
+}
+
+func (s *PagedStore) page(i int) ([][]string, error) {
+	if pg, ok := s.cache[i]; ok {
+		if j := slices.Index(s.lru, i); j >= 0 {
+			s.lru = append(slices.Delete(s.lru, j, j+1), i)
+		}
+		return pg, nil
+	}
+	start, end := s.offsets[i], s.offsets[i+1]
+	buf := make([]byte, end-start)
+	if _, err := s.file.ReadAt(buf, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	n := min(pageRows, s.rows-i*pageRows)
+	pg, err := decodePage(buf, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.lru) >= pageCacheSize {
+		delete(s.cache, s.lru[0])
+		s.lru = s.lru[1:]
+	}
+	s.cache[i] = pg
+	s.lru = append(s.lru, i)
+	return pg, nil
+}
+
+func decodePage(buf []byte, rows int) ([][]string, error) {
+	cols, k := binary.Uvarint(buf)
+	if k <= 0 {
+		return nil, fmt.Errorf("corrupt page")
+	}
+	buf = buf[k:]
+	// one string backs every value of the page to keep allocations low
+	data := string(buf)
+	pos := 0
+	pg := make([][]string, cols)
+	for c := range pg {
+		pg[c] = make([]string, rows)
+		for r := 0; r < rows; r++ {
+			l, k := binary.Uvarint(buf[pos:])
+			if k <= 0 || pos+k+int(l) > len(buf) {
+				return nil, fmt.Errorf("corrupt page")
+			}
+			pos += k
+			pg[c][r] = data[pos : pos+int(l)]
+			pos += int(l)
+		}
+	}
+	return pg, nil
+}
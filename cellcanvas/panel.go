@@ -0,0 +1,247 @@
+package cellcanvas
+
+// Default cell dimensions in pixels for newly created panels.
+const (
+	DefaultCellW = 80
+	DefaultCellH = 24
+)
+
+// Panel is a rectangular grid of cells placed on the canvas at world
+// coordinates X, Y.
+type Panel struct {
+	X, Y     int
+	Cols     int
+	Rows     int
+	CellW    int
+	CellH    int
+	Cells    map[string]string // sparse map of cells keyed A1-style
+	Filename string
+	Loaded   bool
+	Name     string
+	// Source is set for live panels whose rows are fed by the frontend.
+	Source *Source
+	// Paged holds the data of panels too large to keep in memory (see
+	// PagingThreshold). Cells then only records edits made on top of it.
+	Paged *PagedStore
+	// ColWidths overrides the width of individual columns (see ColWidth)
+	ColWidths []int
+	// Wrap word-wraps cell text to the column width
+	Wrap bool
+	// Accent is an optional "#rrggbb" color for the header and border
+	Accent string
+	// Icon is an optional header icon: a built-in icon name or a short
+	// text badge
+	Icon string
+	// FontSize sets the size of cell text; 0 keeps the default debug font.
+	// Font optionally names a TTF/OTF file for it.
+	FontSize int
+	Font     string
+	// FixedCellW and FixedCellH, when > 0, set the default cell size in
+	// place of the size derived from the panel font
+	FixedCellW, FixedCellH int
+	// Gridlines is the gridline style ("solid", "dotted", "horizontal",
+	// "none"); empty follows the app-wide default
+	Gridlines string
+	// Zebra shades every other row
+	Zebra bool
+	// Borders holds explicit cell border styles keyed A1-style (see
+	// SetBorder)
+	Borders map[string]string
+	// PrintArea limits printing and PDF export to a range like "A1:D40";
+	// empty prints the whole panel
+	PrintArea string
+	// ExportTitle, ExportCaption and Watermark are printed on every PDF
+	// page of the panel: the title above the cells, the caption below
+	// them and the watermark across the page behind them. They are not
+	// shown on the canvas.
+	ExportTitle   string
+	ExportCaption string
+	Watermark     string
+	// View marks a linked view sharing data with the other panels saved
+	// to the same file (see SyncViews)
+	View bool
+	// TrackSources records the file and time every appended row came from
+	// in Sources
+	TrackSources bool
+	Sources      []RowSource
+	// SampleRows > 0 marks a preview holding only the first SampleRows
+	// records of its file. Its file is never overwritten by SaveState.
+	SampleRows int
+	// Outliers > 0 highlights numeric cells more than that many standard
+	// deviations from their column mean
+	Outliers float64
+	// Computed lists the columns filled from an expression over other
+	// columns (see SetComputed)
+	Computed []ComputedColumn
+	// Query makes the panel a derived panel showing the result of a query
+	// over another panel; its cells are refilled when that panel changes
+	Query *Query
+	// SQL makes the panel a SQL panel showing the result of a query over
+	// a SQLite table or another panel; its cells are refilled on Refresh
+	SQL *SQLQuery
+	// Selection is the panel's last selected range, restored when the
+	// panel becomes active again
+	Selection Selection
+	// Checksum is the SHA-256 of the panel's CSV as SaveState last wrote
+	// it, for noticing edits made to the file outside the app; empty when
+	// unknown
+	Checksum string
+	// DateColumns lists the columns holding dates, normalized to ISO
+	// dates on every load (see NormalizeDates)
+	DateColumns []DateColumn
+	// ColumnFormats sets how the numbers of some columns are shown
+	// (rounded, scientific, SI suffixes) without changing the cells
+	ColumnFormats []ColumnFormat
+	// Units tags columns with the unit of their numbers (ms, MB, USD)
+	Units []ColumnUnit
+	// NumberLocale names the locale its numbers are written in (see
+	// NumberLocales); empty follows DefaultNumberLocale
+	NumberLocale string
+	// ViewRows and ViewCols, when > 0, set how many rows and columns the
+	// panel shows at a time, scrolled to ScrollRow and ScrollCol (see
+	// VisibleRows)
+	ViewRows, ViewCols   int
+	ScrollRow, ScrollCol int
+	// Renderers draws some columns as star ratings or tag chips
+	Renderers []ColumnRenderer
+	// Images shows cells naming an image file or URL as a thumbnail of it
+	// (see ImageSource)
+	Images bool
+	// Delimiter separates the fields of the panel's CSV file, as sniffed
+	// when it was loaded or chosen since; empty for commas (see
+	// SniffDelimiter)
+	Delimiter string
+	// Scratch marks the session's scratch panel, which SaveState and
+	// SaveEncrypted leave out
+	Scratch bool
+	// Problems lists the CSV lines that did not load cleanly on the last
+	// load of the panel's file; it is not saved
+	Problems []CSVProblem
+	// rowHeights holds the heights of rows taller than CellH; see
+	// SetRowHeights
+	rowHeights []int
+	rowTops    []int
+}
+
+// Selection is a selected range of a panel: the cursor cell and the anchor
+// the range extends from (equal for a single cell).
+type Selection struct {
+	Col       int `yaml:"col"`
+	Row       int `yaml:"row"`
+	AnchorCol int `yaml:"anchor_col"`
+	AnchorRow int `yaml:"anchor_row"`
+}
+
+func NewPanel(x, y, cols, rows int) Panel {
+	// NewPanel creates a panel with default empty content. We no longer
+	// pre-fill sample values so newly created panels are blank.
+	return Panel{X: x, Y: y, Cols: cols, Rows: rows, CellW: DefaultCellW, CellH: DefaultCellH, Cells: make(map[string]string), Filename: "", Loaded: true}
+}
+
+// NewBlankPanel creates a panel with provided dimensions but no cell content
+// (empty map). Use this for freshly created blank panels via the UI.
+func NewBlankPanel(x, y, cols, rows int) Panel {
+	return Panel{X: x, Y: y, Cols: cols, Rows: rows, CellW: DefaultCellW, CellH: DefaultCellH, Cells: make(map[string]string), Filename: "", Loaded: true}
+}
+
+// GetCell returns the value shown at the given col,row (zero-based): the
+// stored text, or for a formula ("=SUM(A1:A3)") its computed result.
+// Returns empty string when not present.
+func (p *Panel) GetCell(col, row int) string {
+	v := p.RawCell(col, row)
+	if IsFormula(v) {
+		return p.formula(col, row, v)
+	}
+	return v
+}
+
+// RawCell returns the text stored at the given col,row, formulas as they
+// were typed. Editing, saving and undo work on this rather than GetCell.
+func (p *Panel) RawCell(col, row int) string {
+	if p == nil {
+		return ""
+	}
+	if p.Cells == nil {
+		return ""
+	}
+	// string(key) in a map index does not allocate
+	var buf [24]byte
+	key := AppendCellRef(buf[:0], col, row)
+	if v, ok := p.Cells[string(key)]; ok {
+		return v
+	}
+	if p.Paged != nil {
+		return p.Paged.Get(col, row)
+	}
+	return ""
+}
+
+// SetCell writes a value at the given col,row; a formula is stored as
+// typed and recomputed whenever it is read. Empty values remove the entry
+// to keep the structure sparse, except on paged panels where the empty
+// value must stay to mask the stored one.
+func (p *Panel) SetCell(col, row int, val string) {
+	if p == nil {
+		return
+	}
+	if p.Cells == nil {
+		p.Cells = make(map[string]string)
+	}
+	key := CellRef(col, row)
+	if val == "" && p.Paged == nil {
+		delete(p.Cells, key)
+	} else {
+		p.Cells[key] = val
+	}
+}
+
+// ReplaceContent swaps in the grid size and cells of src, and the delimiter
+// its file was read with, keeping p's placement and metadata (name,
+// source, ...). Loaders parse into a scratch panel and then call this so
+// nothing but the data is overwritten.
+func (p *Panel) ReplaceContent(src *Panel) {
+	p.Cols = src.Cols
+	p.Rows = src.Rows
+	p.Cells = src.Cells
+	p.Paged = src.Paged
+	p.Problems = src.Problems
+	if src.Delimiter != "" {
+		p.Delimiter = src.Delimiter
+	}
+}
+
+// AppendRow writes vals into a new row below the last row, growing the
+// panel's width if vals is wider.
+func (p *Panel) AppendRow(vals []string) {
+	row := p.Rows
+	p.Rows++
+	if len(vals) > p.Cols {
+		p.Cols = len(vals)
+	}
+	for col, v := range vals {
+		p.SetCell(col, row, v)
+	}
+}
+
+// DropRows deletes n rows starting at row start, shifting later rows up.
+func (p *Panel) DropRows(start, n int) {
+	if n <= 0 || start < 0 || start >= p.Rows {
+		return
+	}
+	n = min(n, p.Rows-start)
+	cells := make(map[string]string, len(p.Cells))
+	for key, val := range p.Cells {
+		col, row, err := ParseCellRef(key)
+		if err != nil {
+			continue
+		}
+		switch {
+		case row < start:
+			cells[key] = val
+		case row >= start+n:
+			cells[CellRef(col, row-n)] = val
+		}
+	}
+	p.Cells = cells
+	p.Rows -= n
+}
@@ -0,0 +1,65 @@
+package cellcanvas
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// ParseClipboard splits copied text into records. Text that is a Markdown
+// table is read as one; text with tabs is taken as tab-separated, the
+// format spreadsheets copy ranges in; other text is parsed as CSV, falling
+// back to one value per line.
+func ParseClipboard(text string) [][]string {
+	text = strings.TrimRight(text, "\r\n")
+	if text == "" {
+		return nil
+	}
+	if recs, start := markdownTable(strings.TrimLeft(text, "\r\n")); start == 0 {
+		return recs
+	}
+	if !strings.Contains(text, "\t") {
+		r := csv.NewReader(strings.NewReader(text))
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		if recs, err := r.ReadAll(); err == nil {
+			return recs
+		}
+	}
+	lines := strings.Split(text, "\n")
+	recs := make([][]string, len(lines))
+	for i, line := range lines {
+		recs[i] = strings.Split(strings.TrimSuffix(line, "\r"), "\t")
+	}
+	return recs
+}
+
+// RecordsWidth returns the field count of the widest record.
+func RecordsWidth(recs [][]string) int {
+	w := 0
+	for _, rec := range recs {
+		w = max(w, len(rec))
+	}
+	return w
+}
+
+// NewPanelFromRecords creates a panel at x, y holding recs, sized to fit
+// them as a CSV of the same records would load.
+func NewPanelFromRecords(x, y int, recs [][]string) (Panel, error) {
+	p := NewBlankPanel(x, y, 1, 1)
+	if err := setPanelRecords(&p, recs, PagingThreshold); err != nil {
+		return Panel{}, err
+	}
+	return p, nil
+}
+
+// PasteRows writes recs into p with recs[0][0] at col, row, growing the
+// panel to fit. Cells beyond the end of a short record are left alone.
+func (p *Panel) PasteRows(col, row int, recs [][]string) {
+	p.Rows = max(p.Rows, row+len(recs))
+	p.Cols = max(p.Cols, col+RecordsWidth(recs))
+	for i, rec := range recs {
+		for j, v := range rec {
+			p.SetCell(col+j, row+i, v)
+		}
+	}
+}
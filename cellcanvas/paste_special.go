@@ -0,0 +1,113 @@
+package cellcanvas
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PasteOp is how Paste Special combines a pasted value with the cell it
+// lands on.
+type PasteOp int
+
+const (
+	// PasteReplace writes the pasted value over the cell
+	PasteReplace PasteOp = iota
+	// PasteAdd adds the pasted number to the cell's
+	PasteAdd
+	// PasteMultiply multiplies the cell's number by the pasted one
+	PasteMultiply
+)
+
+// PasteOptions are the choices of Paste Special. The zero value is a plain
+// paste.
+type PasteOptions struct {
+	// ValuesOnly pastes the value of pasted formulas instead of the formula
+	ValuesOnly bool
+	// Transpose swaps the rows and columns of the pasted range
+	Transpose bool
+	// SkipBlanks leaves cells alone where the pasted value is empty
+	SkipBlanks bool
+	Op         PasteOp
+}
+
+// Transpose returns recs with rows and columns swapped; cells missing from
+// short records come out empty.
+func Transpose(recs [][]string) [][]string {
+	out := make([][]string, RecordsWidth(recs))
+	for c := range out {
+		out[c] = make([]string, len(recs))
+		for r, rec := range recs {
+			if c < len(rec) {
+				out[c][r] = rec[c]
+			}
+		}
+	}
+	return out
+}
+
+// PasteSpecial returns what pasting recs at col, row with opts writes into
+// p: recs with formulas replaced by their values, blanks replaced by the
+// cells they would overwrite, or values added to or multiplied into the
+// cells, as opts asks. Formulas are computed as if they stood where they
+// land, over the cells as they are before the paste. Transpose is applied
+// by the caller, before the paste is sized.
+func (p *Panel) PasteSpecial(col, row int, recs [][]string, opts PasteOptions) [][]string {
+	if !opts.ValuesOnly && !opts.SkipBlanks && opts.Op == PasteReplace {
+		return recs
+	}
+	num := p.Numbers()
+	out := make([][]string, len(recs))
+	for i, rec := range recs {
+		out[i] = make([]string, len(rec))
+		for j, v := range rec {
+			c, r := col+j, row+i
+			if IsFormula(v) && (opts.ValuesOnly || opts.Op != PasteReplace) {
+				v = p.formula(c, r, v)
+			}
+			switch {
+			case strings.TrimSpace(v) == "" && (opts.SkipBlanks || opts.Op != PasteReplace):
+				v = p.RawCell(c, r)
+			case opts.Op != PasteReplace:
+				v = p.combine(c, r, v, opts.Op, num)
+			}
+			out[i][j] = v
+		}
+	}
+	return out
+}
+
+// combine adds or multiplies the number v into the cell at col,row. An
+// empty cell counts as 0; a formula cell becomes a formula over the old one,
+// so it keeps following its references. Cells or values that are not
+// numbers are left as they are.
+func (p *Panel) combine(col, row int, v string, op PasteOp, num NumberLocale) string {
+	old := p.RawCell(col, row)
+	x, err := num.Parse(v)
+	if err != nil {
+		return old
+	}
+	sign := "+"
+	if op == PasteMultiply {
+		sign = "*"
+	}
+	if IsFormula(old) {
+		// numbers in formulas are written plain
+		return "=(" + old[1:] + ")" + sign + strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	y := 0.0
+	if strings.TrimSpace(old) != "" {
+		if y, err = num.Parse(old); err != nil {
+			return old
+		}
+	}
+	if op == PasteMultiply {
+		y *= x
+	} else {
+		y += x
+	}
+	if math.IsInf(y, 0) || math.IsNaN(y) {
+		return old
+	}
+	return num.Format(y, 12)
+}
@@ -0,0 +1,199 @@
+package cellcanvas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Text sizes in points: cell text and captions, panel titles and
+// watermarks.
+const (
+	pdfFontSize      = 8
+	pdfTitleSize     = 12
+	pdfWatermarkSize = 56
+)
+
+// WritePDF writes the print range of every panel to a PDF of size pages,
+// each panel starting on a new page and its pages ordered down, then
+// across (see PageBreaks). Cells are drawn with their gridlines, explicit
+// borders and text in Helvetica; characters outside Latin-1 print as "?".
+// A panel's ExportTitle and ExportCaption go in the top and bottom margin
+// of each of its pages, its Watermark diagonally across them.
+func WritePDF(path string, panels []Panel, size PageSize) error {
+	return WriteFileAtomic(path, 0644, func(f io.Writer) error {
+		return writePDF(f, panels, size)
+	})
+}
+
+// pdfWriter tracks byte offsets of the objects written so far for the
+// cross-reference table.
+type pdfWriter struct {
+	w       *bufio.Writer
+	n       int
+	offsets []int
+}
+
+func (pw *pdfWriter) printf(format string, args ...any) {
+	n, _ := fmt.Fprintf(pw.w, format, args...)
+	pw.n += n
+}
+
+// object starts object id; ids must be written in order.
+func (pw *pdfWriter) object(id int) {
+	for len(pw.offsets) < id {
+		pw.offsets = append(pw.offsets, 0)
+	}
+	pw.offsets[id-1] = pw.n
+	pw.printf("%d 0 obj\n", id)
+}
+
+// pdfPage is one page of one panel: the cell range it shows.
+type pdfPage struct {
+	p                      *Panel
+	col0, row0, col1, row1 int
+}
+
+func writePDF(f io.Writer, panels []Panel, size PageSize) error {
+	var pages []pdfPage
+	for i := range panels {
+		p := &panels[i]
+		_, _, colEnd, rowEnd := p.PrintRange()
+		cols, rows := p.PageBreaks(size)
+		cols, rows = append(cols, colEnd), append(rows, rowEnd)
+		for c := 0; c+1 < len(cols); c++ {
+			for r := 0; r+1 < len(rows); r++ {
+				pages = append(pages, pdfPage{p, cols[c], rows[r], cols[c+1], rows[r+1]})
+			}
+		}
+	}
+	// objects: 1 catalog, 2 page tree, 3 and 4 fonts, then a page and its
+	// content stream for each page
+	pw := &pdfWriter{w: bufio.NewWriter(f)}
+	pw.printf("%%PDF-1.4\n")
+	pw.object(1)
+	pw.printf("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pw.object(2)
+	pw.printf("<< /Type /Pages /Count %d /Kids [", len(pages))
+	for i := range pages {
+		pw.printf(" %d 0 R", 5+2*i)
+	}
+	pw.printf(" ] >>\nendobj\n")
+	pw.object(3)
+	pw.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+	pw.object(4)
+	pw.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+	for i, pg := range pages {
+		content := pdfPageContent(pg, size)
+		pw.object(5 + 2*i)
+		pw.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n", size.W, size.H, 6+2*i)
+		pw.object(6 + 2*i)
+		pw.printf("<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	}
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, off := range pw.offsets {
+		pw.printf("%010d 00000 n \n", off)
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+	return pw.w.Flush()
+}
+
+// pdfPageContent returns the content stream drawing pg. PDF's origin is
+// the bottom-left corner, so y is flipped.
+func pdfPageContent(pg pdfPage, size PageSize) string {
+	p := pg.p
+	var b strings.Builder
+	x0 := PageMargin - float64(p.ColX(pg.col0))*pxToPt
+	y0 := size.H - PageMargin + float64(p.RowY(pg.row0))*pxToPt
+	pdfLabels(&b, p, size)
+	b.WriteString("0.5 w 0.7 G\n")
+	for row := pg.row0; row < pg.row1; row++ {
+		for col := pg.col0; col < pg.col1; col++ {
+			x := x0 + float64(p.ColX(col))*pxToPt
+			w := float64(p.ColWidth(col)) * pxToPt
+			h := float64(p.RowHeight(row)) * pxToPt
+			y := y0 - float64(p.RowY(row))*pxToPt - h
+			fmt.Fprintf(&b, "%.2f %.2f %.2f %.2f re S\n", x, y, w, h)
+			if border := p.Border(col, row); border != "" {
+				pdfBorder(&b, border, x, y, w, h)
+			}
+			v := p.DisplayCell(col, row)
+			if v == "" {
+				continue
+			}
+			// clip the text to its cell
+			fmt.Fprintf(&b, "q %.2f %.2f %.2f %.2f re W n BT /F1 %d Tf %.2f %.2f Td (%s) Tj ET Q\n",
+				x, y, w, h, pdfFontSize, x+2, y+h-pdfFontSize-2, pdfEscape(v))
+		}
+	}
+	return b.String()
+}
+
+// pdfLabels draws p's watermark, title and caption. The watermark is drawn
+// first, so the cells print over it.
+func pdfLabels(b *strings.Builder, p *Panel, size PageSize) {
+	if p.Watermark != "" {
+		// Helvetica averages about half an em per character, close enough
+		// to center the text on the page diagonal
+		w := 0.5 * pdfWatermarkSize * float64(len([]rune(p.Watermark)))
+		cos, sin := size.W/math.Hypot(size.W, size.H), size.H/math.Hypot(size.W, size.H)
+		x := size.W/2 - cos*w/2 + sin*pdfWatermarkSize/3
+		y := size.H/2 - sin*w/2 - cos*pdfWatermarkSize/3
+		fmt.Fprintf(b, "q 0.88 g BT /F2 %d Tf %.4f %.4f %.4f %.4f %.2f %.2f Tm (%s) Tj ET Q\n",
+			pdfWatermarkSize, cos, sin, -sin, cos, x, y, pdfEscape(p.Watermark))
+	}
+	if p.ExportTitle != "" {
+		fmt.Fprintf(b, "q 0 g BT /F2 %d Tf %.2f %.2f Td (%s) Tj ET Q\n",
+			pdfTitleSize, float64(PageMargin), size.H-PageMargin+(PageMargin-pdfTitleSize)/2, pdfEscape(p.ExportTitle))
+	}
+	if p.ExportCaption != "" {
+		fmt.Fprintf(b, "q 0.3 g BT /F1 %d Tf %.2f %.2f Td (%s) Tj ET Q\n",
+			pdfFontSize, float64(PageMargin), float64(PageMargin-pdfFontSize)/2, pdfEscape(p.ExportCaption))
+	}
+}
+
+// pdfBorder strokes an explicit cell border (see SetBorder) in black.
+func pdfBorder(b *strings.Builder, style string, x, y, w, h float64) {
+	line := func(width, x0, y0, x1, y1 float64) {
+		fmt.Fprintf(b, "q 0 G %.2f w %.2f %.2f m %.2f %.2f l S Q\n", width, x0, y0, x1, y1)
+	}
+	switch style {
+	case BorderBox:
+		fmt.Fprintf(b, "q 0 G 0.75 w %.2f %.2f %.2f %.2f re S Q\n", x, y, w, h)
+	case BorderBoxThick:
+		fmt.Fprintf(b, "q 0 G 1.5 w %.2f %.2f %.2f %.2f re S Q\n", x, y, w, h)
+	case BorderTop:
+		line(0.75, x, y+h, x+w, y+h)
+	case BorderBottom:
+		line(0.75, x, y, x+w, y)
+	case BorderBottomThick:
+		line(1.5, x, y, x+w, y)
+	case BorderBottomDouble:
+		line(0.5, x, y, x+w, y)
+		line(0.5, x, y+2, x+w, y+2)
+	}
+}
+
+// pdfEscape returns s as the body of a PDF string literal in
+// WinAnsiEncoding: the first line only, Latin-1 kept, anything else "?".
+func pdfEscape(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 0x20 && r < 0x7f || r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package cellcanvas
+
+import "strings"
+
+// PageSize is a paper size in PDF points (1/72 inch).
+type PageSize struct {
+	W, H float64
+}
+
+// Paper sizes offered for printing and PDF export.
+var (
+	PageA4     = PageSize{W: 595, H: 842}
+	PageLetter = PageSize{W: 612, H: 792}
+)
+
+// Page layout. Cells are printed at 72 points per 96 pixels, so a panel
+// prints at the size it has on a typical screen.
+const (
+	PageMargin = 36 // points on every side
+	pxToPt     = 0.75
+)
+
+// Landscape returns s turned sideways.
+func (s PageSize) Landscape() PageSize {
+	return PageSize{W: s.H, H: s.W}
+}
+
+// PrintRange returns the cells printed from p as the half-open range
+// [col0, col1) x [row0, row1): the print area clamped to the panel, or the
+// whole panel when none is set.
+func (p *Panel) PrintRange() (col0, row0, col1, row1 int) {
+	col0, row0, col1, row1 = 0, 0, p.Cols, p.Rows
+	from, to, ok := strings.Cut(p.PrintArea, ":")
+	if !ok {
+		return
+	}
+	c0, r0, err0 := ParseCellRef(from)
+	c1, r1, err1 := ParseCellRef(to)
+	if err0 != nil || err1 != nil {
+		return
+	}
+	c0, c1 = max(0, min(c0, c1)), min(p.Cols, max(c0, c1)+1)
+	r0, r1 = max(0, min(r0, r1)), min(p.Rows, max(r0, r1)+1)
+	if c0 >= c1 || r0 >= r1 {
+		return
+	}
+	return c0, r0, c1, r1
+}
+
+// SetPrintArea limits printing to the inclusive cell range col0, row0 to
+// col1, row1. A range covering the whole panel clears the print area.
+func (p *Panel) SetPrintArea(col0, row0, col1, row1 int) {
+	if col0 <= 0 && row0 <= 0 && col1 >= p.Cols-1 && row1 >= p.Rows-1 {
+		p.PrintArea = ""
+		return
+	}
+	p.PrintArea = CellRef(col0, row0) + ":" + CellRef(col1, row1)
+}
+
+// PageBreaks splits p's print range into pages of size s. It returns the
+// first column of each page across and the first row of each page down;
+// a column or row larger than a page gets a page of its own.
+func (p *Panel) PageBreaks(s PageSize) (cols, rows []int) {
+	col0, row0, col1, row1 := p.PrintRange()
+	availW := (s.W - 2*PageMargin) / pxToPt
+	availH := (s.H - 2*PageMargin) / pxToPt
+	cols = breaks(col0, col1, availW, p.ColWidth)
+	rows = breaks(row0, row1, availH, p.RowHeight)
+	return cols, rows
+}
+
+// breaks returns the start of each run of [from, to) whose sizes fit in
+// avail.
+func breaks(from, to int, avail float64, size func(int) int) []int {
+	starts := []int{from}
+	used := 0.0
+	for i := from; i < to; i++ {
+		sz := float64(size(i))
+		if used+sz > avail && used > 0 {
+			starts = append(starts, i)
+			used = 0
+		}
+		used += sz
+	}
+	return starts
+}
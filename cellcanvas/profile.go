@@ -0,0 +1,208 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxProfileDistinct caps the distinct values counted per column, keeping
+// memory bounded on huge panels; the report then shows ">N".
+const maxProfileDistinct = 100000
+
+// profileTopValues is how many of the most frequent values are reported.
+const profileTopValues = 3
+
+// columnProfile accumulates the statistics of one column.
+type columnProfile struct {
+	header               string
+	values, empty        int
+	ints, numbers, bools int
+	dates                int
+	sum, min, max        float64
+	minText, maxText     string
+	distinct             map[string]int
+	distinctFull         bool
+}
+
+// Profiler summarizes every column of a panel: inferred type, how many
+// values are filled, empty and distinct, minimum, maximum, mean and the most
+// frequent values. Rows are scanned in steps so a caller can spread a huge
+// panel over several frames.
+type Profiler struct {
+	cols []columnProfile
+	// header is set when row 0 holds column names
+	header     bool
+	next, rows int
+}
+
+// NewProfiler prepares a profile of p. Row 0 is taken as a header row when
+// the panel has more rows and none of row 0's filled cells is a number.
+func NewProfiler(p *Panel) *Profiler {
+	pr := &Profiler{rows: p.UsedRows(), cols: make([]columnProfile, p.UsedCols())}
+	pr.header = pr.rows > 1 && looksLikeHeader(p, len(pr.cols))
+	for c := range pr.cols {
+		pr.cols[c].header = ColToLetters(c)
+		if pr.header {
+			if h := strings.TrimSpace(p.GetCell(c, 0)); h != "" {
+				pr.cols[c].header = h
+			}
+		}
+		pr.cols[c].distinct = make(map[string]int)
+	}
+	if pr.header {
+		pr.next = 1
+	}
+	return pr
+}
+
+func looksLikeHeader(p *Panel, cols int) bool {
+	filled := 0
+	for c := 0; c < cols; c++ {
+		v := strings.TrimSpace(p.GetCell(c, 0))
+		if v == "" {
+			continue
+		}
+		if _, err := p.Numbers().Parse(v); err == nil {
+			return false
+		}
+		filled++
+	}
+	return filled > 0
+}
+
+// Step profiles up to n more rows of p and reports whether all rows are
+// done.
+func (pr *Profiler) Step(p *Panel, n int) bool {
+	end := min(pr.rows, pr.next+n)
+	for r := pr.next; r < end; r++ {
+		for c := range pr.cols {
+			pr.cols[c].add(p.GetCell(c, r), p.Numbers())
+		}
+	}
+	pr.next = end
+	return pr.next >= pr.rows
+}
+
+// Progress returns how many rows were profiled out of how many.
+func (pr *Profiler) Progress() (done, total int) {
+	return pr.next, pr.rows
+}
+
+func (cp *columnProfile) add(v string, num NumberLocale) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		cp.empty++
+		return
+	}
+	cp.values++
+	if cp.values == 1 || v < cp.minText {
+		cp.minText = v
+	}
+	if cp.values == 1 || v > cp.maxText {
+		cp.maxText = v
+	}
+	if _, ok := cp.distinct[v]; ok || len(cp.distinct) < maxProfileDistinct {
+		cp.distinct[v]++
+	} else {
+		cp.distinctFull = true
+	}
+	if f, err := num.Parse(v); err == nil {
+		if cp.numbers == 0 || f < cp.min {
+			cp.min = f
+		}
+		if cp.numbers == 0 || f > cp.max {
+			cp.max = f
+		}
+		cp.numbers++
+		cp.sum += f
+		if c, _ := num.Canonical(v); !strings.Contains(c, ".") && !strings.ContainsAny(c, "eE") {
+			cp.ints++
+		}
+		return
+	}
+	if _, err := strconv.ParseBool(v); err == nil {
+		cp.bools++
+		return
+	}
+	for _, layout := range DateLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			cp.dates++
+			return
+		}
+	}
+}
+
+// kind names the column's inferred type.
+func (cp *columnProfile) kind() string {
+	switch {
+	case cp.values == 0:
+		return "empty"
+	case cp.ints == cp.values:
+		return "integer"
+	case cp.numbers == cp.values:
+		return "number"
+	case cp.bools == cp.values:
+		return "boolean"
+	case cp.dates == cp.values:
+		return "date"
+	case cp.numbers+cp.bools+cp.dates > 0:
+		return "text (mixed)"
+	}
+	return "text"
+}
+
+// topValues formats the most frequent values as "value (count)".
+func (cp *columnProfile) topValues() string {
+	type vc struct {
+		v string
+		n int
+	}
+	list := make([]vc, 0, len(cp.distinct))
+	for v, n := range cp.distinct {
+		list = append(list, vc{v, n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].n != list[j].n {
+			return list[i].n > list[j].n
+		}
+		return list[i].v < list[j].v
+	})
+	var parts []string
+	for _, e := range list[:min(len(list), profileTopValues)] {
+		parts = append(parts, fmt.Sprintf("%s (%d)", e.v, e.n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Panel returns the profile as a new panel at x, y with one row per column
+// of the profiled panel under a header row.
+func (pr *Profiler) Panel(x, y int) Panel {
+	headers := []string{"Column", "Type", "Values", "Empty", "Distinct", "Min", "Max", "Mean", "Top values"}
+	out := NewBlankPanel(x, y, len(headers), len(pr.cols)+1)
+	for c, h := range headers {
+		out.SetCell(c, 0, h)
+	}
+	for i := range pr.cols {
+		cp := &pr.cols[i]
+		distinct := strconv.Itoa(len(cp.distinct))
+		if cp.distinctFull {
+			distinct = ">" + distinct
+		}
+		row := []string{cp.header, cp.kind(), strconv.Itoa(cp.values), strconv.Itoa(cp.empty), distinct, "", "", "", cp.topValues()}
+		if k := cp.kind(); k == "integer" || k == "number" {
+			num := out.Numbers()
+			row[5] = num.Format(cp.min, -1)
+			row[6] = num.Format(cp.max, -1)
+			row[7] = num.Format(cp.sum/float64(cp.numbers), 6)
+		} else if cp.values > 0 {
+			row[5], row[6] = cp.minText, cp.maxText
+		}
+		for c, v := range row {
+			out.SetCell(c, i+1, v)
+		}
+	}
+	return out
+}
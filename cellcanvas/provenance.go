@@ -0,0 +1,75 @@
+package cellcanvas
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// RowSource records where Count rows starting at First came from: the
+// file they were imported or appended from and when. Panels keep them in
+// Sources, sorted by First and non-overlapping, when TrackSources is set.
+type RowSource struct {
+	First int       `yaml:"first"`
+	Count int       `yaml:"count"`
+	File  string    `yaml:"file"`
+	Time  time.Time `yaml:"time"`
+}
+
+// AddRowSource records that count rows from first came from file at t,
+// replacing what was recorded for those rows. It does nothing unless the
+// panel tracks sources.
+func (p *Panel) AddRowSource(first, count int, file string, t time.Time) {
+	if !p.TrackSources || count <= 0 {
+		return
+	}
+	end := first + count
+	var kept []RowSource
+	for _, s := range p.Sources {
+		sEnd := s.First + s.Count
+		if sEnd <= first || s.First >= end {
+			kept = append(kept, s)
+			continue
+		}
+		// keep the parts outside the new range
+		if s.First < first {
+			kept = append(kept, RowSource{First: s.First, Count: first - s.First, File: s.File, Time: s.Time})
+		}
+		if sEnd > end {
+			kept = append(kept, RowSource{First: end, Count: sEnd - end, File: s.File, Time: s.Time})
+		}
+	}
+	kept = append(kept, RowSource{First: first, Count: count, File: file, Time: t})
+	sort.Slice(kept, func(i, j int) bool { return kept[i].First < kept[j].First })
+	p.Sources = kept
+}
+
+// RowSourceAt returns the recorded source of row.
+func (p *Panel) RowSourceAt(row int) (RowSource, bool) {
+	i := sort.Search(len(p.Sources), func(i int) bool { return p.Sources[i].First+p.Sources[i].Count > row })
+	if i < len(p.Sources) && p.Sources[i].First <= row {
+		return p.Sources[i], true
+	}
+	return RowSource{}, false
+}
+
+// SavePanelCSVWithSources writes p with its row sources (see
+// WritePanelCSVWithSources) to path, replacing it atomically.
+func SavePanelCSVWithSources(path string, p *Panel) error {
+	return WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return WritePanelCSVWithSources(w, p)
+	})
+}
+
+// WritePanelCSVWithSources writes p like WritePanelCSV with two more
+// columns per row: the source file and import time (RFC 3339) of the rows
+// that have one.
+func WritePanelCSVWithSources(out io.Writer, p *Panel) error {
+	return writePanelCSV(out, p, func(row int) []string {
+		src, ok := p.RowSourceAt(row)
+		if !ok {
+			return []string{"", ""}
+		}
+		return []string{src.File, src.Time.Format(time.RFC3339)}
+	})
+}
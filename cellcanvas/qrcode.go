@@ -0,0 +1,453 @@
+package cellcanvas
+
+import (
+	"errors"
+	"strings"
+)
+
+// QR codes: the text of a cell encoded in byte mode at error correction
+// level M, in the smallest of versions 1 to qrMaxVersion (21 to 57 modules
+// a side) that holds it. A QRCODE column renderer draws each cell of its
+// column this way, and a =QRCODE(value) formula draws its own cell.
+
+// RenderQR draws cell values as QR codes
+const RenderQR = "qrcode"
+
+// qrMaxVersion is the largest QR version encoded, holding 213 bytes.
+const qrMaxVersion = 10
+
+// qrBlocks lists, per version at level M, the error correction codewords
+// of each block and the data codewords of the blocks of its two groups.
+var qrBlocks = [qrMaxVersion + 1]struct {
+	ec             int
+	n1, d1, n2, d2 int
+}{
+	{},
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+}
+
+// qrCodeFunc is the formula function drawn as a QR code.
+const qrCodeFunc = "QRCODE("
+
+// IsQRCodeFormula reports whether v is a =QRCODE(value) formula.
+func IsQRCodeFormula(v string) bool {
+	_, ok := qrCodeArg(strings.TrimSpace(strings.TrimPrefix(v, "=")))
+	return ok && IsFormula(v)
+}
+
+// qrCodeArg returns the argument of a QRCODE(value) formula body.
+func qrCodeArg(body string) (string, bool) {
+	if len(body) <= len(qrCodeFunc) || !strings.EqualFold(body[:len(qrCodeFunc)], qrCodeFunc) || !strings.HasSuffix(body, ")") {
+		return "", false
+	}
+	return strings.TrimSpace(body[len(qrCodeFunc) : len(body)-1]), true
+}
+
+// QRCode encodes text as a QR code and returns its modules row by row,
+// dark ones true, without the quiet zone around them.
+func QRCode(text string) ([][]bool, error) {
+	ver := 0
+	for v := 1; v <= qrMaxVersion; v++ {
+		b := qrBlocks[v]
+		// mode, count and data bits, with a 16-bit count from version 10
+		count := 8
+		if v >= 10 {
+			count = 16
+		}
+		if 4+count+8*len(text) <= 8*(b.n1*b.d1+b.n2*b.d2) {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, errors.New("too long for a QR code")
+	}
+	q := newQRSymbol(ver)
+	q.drawFunctionPatterns()
+	q.drawCodewords(qrCodewords(ver, []byte(text)))
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // masks undo themselves
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+	return q.dark, nil
+}
+
+// qrCodewords returns the data of text in version ver, padded, split into
+// blocks with their error correction and interleaved.
+func qrCodewords(ver int, text []byte) []byte {
+	b := qrBlocks[ver]
+	capacity := b.n1*b.d1 + b.n2*b.d2
+	var bits qrBits
+	bits.add(0b0100, 4)
+	if ver >= 10 {
+		bits.add(len(text), 16)
+	} else {
+		bits.add(len(text), 8)
+	}
+	for _, c := range text {
+		bits.add(int(c), 8)
+	}
+	bits.add(0, min(4, capacity*8-bits.n))
+	bits.add(0, (8-bits.n%8)%8)
+	for pad := 0xec; len(bits.b) < capacity; pad ^= 0xec ^ 0x11 {
+		bits.add(pad, 8)
+	}
+
+	var data, ecc [][]byte
+	gen := qrGenerator(b.ec)
+	rest := bits.b
+	for i := range b.n1 + b.n2 {
+		n := b.d1
+		if i >= b.n1 {
+			n = b.d2
+		}
+		data = append(data, rest[:n])
+		ecc = append(ecc, qrRemainder(rest[:n], gen))
+		rest = rest[n:]
+	}
+	var out []byte
+	for i := range max(b.d1, b.d2) {
+		for _, d := range data {
+			if i < len(d) {
+				out = append(out, d[i])
+			}
+		}
+	}
+	for i := range b.ec {
+		for _, e := range ecc {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// qrBits collects bits most significant first into bytes.
+type qrBits struct {
+	b []byte
+	n int
+}
+
+func (qb *qrBits) add(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if qb.n%8 == 0 {
+			qb.b = append(qb.b, 0)
+		}
+		if v>>i&1 == 1 {
+			qb.b[len(qb.b)-1] |= 0x80 >> (qb.n % 8)
+		}
+		qb.n++
+	}
+}
+
+// GF(256) with the QR polynomial x^8+x^4+x^3+x^2+1.
+var qrExp, qrLog = func() (exp [256]byte, log [256]byte) {
+	x := 1
+	for i := range 255 {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		if x <<= 1; x >= 0x100 {
+			x ^= 0x11d
+		}
+	}
+	exp[255] = exp[0]
+	return
+}()
+
+func qrMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return qrExp[(int(qrLog[a])+int(qrLog[b]))%255]
+}
+
+// qrGenerator returns the Reed-Solomon generator polynomial of degree n,
+// highest coefficient first.
+func qrGenerator(n int) []byte {
+	g := []byte{1}
+	for i := range n {
+		// multiply by (x + a^i)
+		next := make([]byte, len(g)+1)
+		for k := range next {
+			if k < len(g) {
+				next[k] = g[k]
+			}
+			if k > 0 {
+				next[k] ^= qrMul(g[k-1], qrExp[i])
+			}
+		}
+		g = next
+	}
+	return g
+}
+
+// qrRemainder returns the error correction codewords of data.
+func qrRemainder(data, gen []byte) []byte {
+	buf := make([]byte, len(data)+len(gen)-1)
+	copy(buf, data)
+	for i := range data {
+		if c := buf[i]; c != 0 {
+			for j, g := range gen {
+				buf[i+j] ^= qrMul(g, c)
+			}
+		}
+	}
+	return buf[len(data):]
+}
+
+// qrSymbol is a QR code being laid out; fixed marks the modules of the
+// function patterns, which data and masks leave alone.
+type qrSymbol struct {
+	ver   int
+	size  int
+	dark  [][]bool
+	fixed [][]bool
+}
+
+func newQRSymbol(ver int) *qrSymbol {
+	q := &qrSymbol{ver: ver, size: 17 + 4*ver}
+	q.dark = make([][]bool, q.size)
+	q.fixed = make([][]bool, q.size)
+	for y := range q.size {
+		q.dark[y] = make([]bool, q.size)
+		q.fixed[y] = make([]bool, q.size)
+	}
+	return q
+}
+
+func (q *qrSymbol) set(x, y int, dark bool) {
+	q.dark[y][x] = dark
+	q.fixed[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and
+// version information, and reserves the format information.
+func (q *qrSymbol) drawFunctionPatterns() {
+	n := q.size
+	for i := range n {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {n - 4, 3}, {3, n - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || x >= n || y < 0 || y >= n {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				q.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+	pos := q.alignmentPositions()
+	last := len(pos) - 1
+	for i, ax := range pos {
+		for j, ay := range pos {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // under a finder
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	q.drawFormatBits(0)
+	if q.ver >= 7 {
+		rem := q.ver
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := q.ver<<12 | rem
+		for i := range 18 {
+			dark := bits>>i&1 == 1
+			a, b := n-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+// alignmentPositions returns the centre coordinates of the alignment
+// patterns along each axis.
+func (q *qrSymbol) alignmentPositions() []int {
+	if q.ver == 1 {
+		return nil
+	}
+	count := q.ver/7 + 2
+	step := (q.ver*8 + count*3 + 5) / (count*4 - 4) * 2
+	pos := make([]int, count)
+	pos[0] = 6
+	for i := count - 1; i >= 1; i-- {
+		pos[i] = q.size - 7 - (count-1-i)*step
+	}
+	return pos
+}
+
+// drawFormatBits draws both copies of the format information for level M
+// and mask, and the dark module beside them.
+func (q *qrSymbol) drawFormatBits(mask int) {
+	data := 0b00<<3 | mask // level M
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	n := q.size
+	for i := range 6 {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		q.set(n-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, n-15+i, bit(i))
+	}
+	q.set(8, n-8, true)
+}
+
+// drawCodewords fills the modules left free by the function patterns with
+// data, two columns at a time in a zigzag from the bottom right.
+func (q *qrSymbol) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := range q.size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert // going up
+				}
+				if !q.fixed[y][x] && i < len(data)*8 {
+					q.dark[y][x] = data[i>>3]>>(7-i&7)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules mask selects.
+func (q *qrSymbol) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !q.fixed[y][x] {
+				q.dark[y][x] = !q.dark[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to read: long runs, 2x2 blocks,
+// finder-like patterns and an unbalanced share of dark modules.
+func (q *qrSymbol) penalty() int {
+	n := q.size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return q.dark[x][y]
+		}
+		return q.dark[y][x]
+	}
+	score := 0
+	for _, t := range []bool{false, true} {
+		for y := range n {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, t) == at(x-1, y, t) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= n; x++ {
+				if !at(x, y, t) || at(x+1, y, t) || !at(x+2, y, t) || !at(x+3, y, t) || !at(x+4, y, t) || at(x+5, y, t) || !at(x+6, y, t) {
+					continue
+				}
+				light := func(from, to int) bool {
+					for i := from; i < to; i++ {
+						if i >= 0 && i < n && at(i, y, t) {
+							return false
+						}
+					}
+					return true
+				}
+				if light(x-4, x) || light(x+7, x+11) {
+					score += 40
+				}
+			}
+		}
+	}
+	dark := 0
+	for y := range n {
+		for x := range n {
+			if q.dark[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				d := q.dark[y][x]
+				if q.dark[y][x+1] == d && q.dark[y+1][x] == d && q.dark[y+1][x+1] == d {
+					score += 3
+				}
+			}
+		}
+	}
+	// 10 for each 5% the dark share is away from half
+	score += abs(dark*20-n*n*10) / (n * n) * 10
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,817 @@
+package cellcanvas
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query defines a derived panel: its cells are the result of Text, a
+// SELECT-like query, run over panel From (an index into Workspace.Panels,
+// -1 once that panel was removed).
+//
+//	SELECT region, sum(sales) AS total WHERE year >= 2020 GROUP BY region ORDER BY total DESC LIMIT 10
+//
+// Columns are named by their header in row 0 of the source, as in computed
+// columns: bare words or [names with spaces]. The clauses are:
+//
+//   - SELECT * or a list of columns and aggregates count(*), count(col),
+//     sum(col), avg(col), min(col) and max(col), each optionally AS name
+//   - WHERE comparisons (= != < <= > >= and CONTAINS) of columns, numbers
+//     and 'text', combined with AND, OR, NOT and parentheses
+//   - GROUP BY one column; every other selected item must be an aggregate
+//   - ORDER BY a selected column, ASC (default) or DESC
+//   - LIMIT n rows
+//
+// Values that both parse as numbers compare as numbers and text as text; a
+// number and text are never equal or ordered.
+// The result has the selected names as its header row.
+type Query struct {
+	From int    `yaml:"from"`
+	Text string `yaml:"text"`
+	// Presets are named queries the panel can switch between
+	Presets []QueryPreset `yaml:"presets,omitempty"`
+}
+
+// query item aggregates
+const (
+	aggNone  = ""
+	aggCount = "count"
+	aggSum   = "sum"
+	aggAvg   = "avg"
+	aggMin   = "min"
+	aggMax   = "max"
+)
+
+// queryItem is a selected column or aggregate.
+type queryItem struct {
+	agg   string
+	name  string // source column; empty for count(*)
+	label string
+	col   int
+}
+
+// parsedQuery is a query before its names are resolved against a source.
+type parsedQuery struct {
+	star    bool
+	items   []queryItem
+	where   queryCond
+	groupBy string
+	orderBy string
+	desc    bool
+	limit   int // 0 for no limit
+}
+
+// queryCond is a WHERE condition.
+type queryCond interface {
+	match(row func(name string) string) bool
+}
+
+type (
+	andCond struct{ l, r queryCond }
+	orCond  struct{ l, r queryCond }
+	notCond struct{ x queryCond }
+	cmpCond struct {
+		op   string
+		l, r queryOperand
+	}
+	// queryOperand is a column name or a literal
+	queryOperand struct {
+		name string
+		lit  string
+		col  bool
+	}
+)
+
+func (c andCond) match(row func(string) string) bool { return c.l.match(row) && c.r.match(row) }
+func (c orCond) match(row func(string) string) bool  { return c.l.match(row) || c.r.match(row) }
+func (c notCond) match(row func(string) string) bool { return !c.x.match(row) }
+
+func (o queryOperand) value(row func(string) string) string {
+	if o.col {
+		return row(o.name)
+	}
+	return o.lit
+}
+
+func (c cmpCond) match(row func(string) string) bool {
+	l, r := strings.TrimSpace(c.l.value(row)), strings.TrimSpace(c.r.value(row))
+	if c.op == "contains" {
+		return strings.Contains(strings.ToLower(l), strings.ToLower(r))
+	}
+	if isNumber(l) != isNumber(r) {
+		// a number never equals or orders against text
+		return c.op == "!="
+	}
+	cmp := compareValues(l, r)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// compareValues orders a and b numerically when both are numbers and as
+// text otherwise.
+func compareValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// condNames calls f with every column name c refers to.
+func condNames(c queryCond, f func(string)) {
+	switch c := c.(type) {
+	case andCond:
+		condNames(c.l, f)
+		condNames(c.r, f)
+	case orCond:
+		condNames(c.l, f)
+		condNames(c.r, f)
+	case notCond:
+		condNames(c.x, f)
+	case cmpCond:
+		for _, o := range []queryOperand{c.l, c.r} {
+			if o.col {
+				f(o.name)
+			}
+		}
+	}
+}
+
+// ParseQuery checks the syntax of a query.
+func ParseQuery(text string) error {
+	_, err := parseQuery(text)
+	return err
+}
+
+func parseQuery(text string) (*parsedQuery, error) {
+	toks, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	ps := &queryParser{toks: toks}
+	q, err := ps.query()
+	if err != nil {
+		return nil, err
+	}
+	if t := ps.peek(); t.kind != tokEnd {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+	}
+	return q, nil
+}
+
+// query tokens
+const (
+	tokEnd = iota
+	tokWord
+	tokName // [bracketed name]
+	tokNumber
+	tokString
+	tokSymbol
+)
+
+type queryToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+func lexQuery(s string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] for [ at %d", i+1)
+			}
+			toks = append(toks, queryToken{tokName, strings.TrimSpace(s[i+1 : i+end]), i})
+			i += end + 1
+		case c == '\'':
+			// '' inside a string is a quote
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						b.WriteByte('\'')
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("missing ' for ' at %d", i+1)
+			}
+			toks = append(toks, queryToken{tokString, b.String(), i})
+			i = j + 1
+		case c == '-' && i+1 < len(s) && (s[i+1] == '.' || s[i+1] >= '0' && s[i+1] <= '9'), c == '.' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			if _, err := strconv.ParseFloat(s[i:j], 64); err != nil {
+				return nil, fmt.Errorf("bad number %q", s[i:j])
+			}
+			toks = append(toks, queryToken{tokNumber, s[i:j], i})
+			i = j
+		case strings.ContainsRune("*,()", rune(c)):
+			toks = append(toks, queryToken{tokSymbol, string(c), i})
+			i++
+		case strings.ContainsRune("=!<>", rune(c)):
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || c == '<' && s[j] == '>') {
+				j++
+			}
+			op := s[i:j]
+			switch op {
+			case "!":
+				return nil, fmt.Errorf("unexpected ! at %d", i+1)
+			case "<>":
+				op = "!="
+			case "==":
+				op = "="
+			}
+			toks = append(toks, queryToken{tokSymbol, op, i})
+			i = j
+		default:
+			j := i
+			for _, r := range s[i:] {
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+					break
+				}
+				j += len(string(r))
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q at %d", []rune(s[i:])[0], i+1)
+			}
+			toks = append(toks, queryToken{tokWord, s[i:j], i})
+			i = j
+		}
+	}
+	return append(toks, queryToken{kind: tokEnd, text: "end of query", pos: len(s)}), nil
+}
+
+// queryKeywords cannot be used as bare column names; bracket them instead.
+var queryKeywords = map[string]bool{
+	"select": true, "where": true, "group": true, "order": true, "by": true, "limit": true,
+	"and": true, "or": true, "not": true, "as": true, "asc": true, "desc": true, "contains": true,
+}
+
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+func (ps *queryParser) peek() queryToken { return ps.toks[ps.pos] }
+
+func (ps *queryParser) next() queryToken {
+	t := ps.toks[ps.pos]
+	if t.kind != tokEnd {
+		ps.pos++
+	}
+	return t
+}
+
+// keyword consumes the keyword kw when it comes next.
+func (ps *queryParser) keyword(kw string) bool {
+	if t := ps.peek(); t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		ps.pos++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the symbol sym when it comes next.
+func (ps *queryParser) symbol(sym string) bool {
+	if t := ps.peek(); t.kind == tokSymbol && t.text == sym {
+		ps.pos++
+		return true
+	}
+	return false
+}
+
+func (ps *queryParser) expected(what string) error {
+	t := ps.peek()
+	return fmt.Errorf("expected %s at %d, found %q", what, t.pos+1, t.text)
+}
+
+// name consumes a column name: a bracketed name or a bare non-keyword.
+func (ps *queryParser) name() (string, bool) {
+	switch t := ps.peek(); {
+	case t.kind == tokName, t.kind == tokWord && !queryKeywords[strings.ToLower(t.text)]:
+		ps.pos++
+		return t.text, true
+	}
+	return "", false
+}
+
+func (ps *queryParser) query() (*parsedQuery, error) {
+	if !ps.keyword("select") {
+		return nil, ps.expected("SELECT")
+	}
+	q := &parsedQuery{}
+	if ps.symbol("*") {
+		q.star = true
+	} else {
+		for {
+			it, err := ps.item()
+			if err != nil {
+				return nil, err
+			}
+			q.items = append(q.items, it)
+			if !ps.symbol(",") {
+				break
+			}
+		}
+	}
+	if ps.keyword("where") {
+		c, err := ps.or()
+		if err != nil {
+			return nil, err
+		}
+		q.where = c
+	}
+	if ps.keyword("group") {
+		if !ps.keyword("by") {
+			return nil, ps.expected("BY")
+		}
+		n, ok := ps.name()
+		if !ok {
+			return nil, ps.expected("a column to group by")
+		}
+		q.groupBy = n
+	}
+	if ps.keyword("order") {
+		if !ps.keyword("by") {
+			return nil, ps.expected("BY")
+		}
+		n, ok := ps.name()
+		if !ok {
+			return nil, ps.expected("a column to order by")
+		}
+		q.orderBy = n
+		if ps.keyword("desc") {
+			q.desc = true
+		} else {
+			ps.keyword("asc")
+		}
+	}
+	if ps.keyword("limit") {
+		t := ps.next()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tokNumber || err != nil || n <= 0 {
+			return nil, fmt.Errorf("LIMIT needs a positive whole number at %d", t.pos+1)
+		}
+		q.limit = n
+	}
+	return q, nil
+}
+
+func (ps *queryParser) item() (queryItem, error) {
+	var it queryItem
+	t := ps.peek()
+	switch agg := strings.ToLower(t.text); {
+	case t.kind == tokWord && (agg == aggCount || agg == aggSum || agg == aggAvg || agg == aggMin || agg == aggMax) &&
+		ps.toks[ps.pos+1].kind == tokSymbol && ps.toks[ps.pos+1].text == "(":
+		ps.pos += 2
+		it.agg = agg
+		if agg == aggCount && ps.symbol("*") {
+			it.label = "count(*)"
+		} else {
+			n, ok := ps.name()
+			if !ok {
+				return it, ps.expected("a column")
+			}
+			it.name = n
+			it.label = agg + "(" + n + ")"
+		}
+		if !ps.symbol(")") {
+			return it, ps.expected(")")
+		}
+	default:
+		n, ok := ps.name()
+		if !ok {
+			return it, ps.expected("a column or aggregate")
+		}
+		it.name, it.label = n, n
+	}
+	if ps.keyword("as") {
+		n, ok := ps.name()
+		if !ok {
+			return it, ps.expected("a name after AS")
+		}
+		it.label = n
+	}
+	return it, nil
+}
+
+func (ps *queryParser) or() (queryCond, error) {
+	l, err := ps.and()
+	for err == nil && ps.keyword("or") {
+		var r queryCond
+		if r, err = ps.and(); err == nil {
+			l = orCond{l, r}
+		}
+	}
+	return l, err
+}
+
+func (ps *queryParser) and() (queryCond, error) {
+	l, err := ps.not()
+	for err == nil && ps.keyword("and") {
+		var r queryCond
+		if r, err = ps.not(); err == nil {
+			l = andCond{l, r}
+		}
+	}
+	return l, err
+}
+
+func (ps *queryParser) not() (queryCond, error) {
+	if ps.keyword("not") {
+		x, err := ps.not()
+		return notCond{x}, err
+	}
+	if ps.symbol("(") {
+		c, err := ps.or()
+		if err != nil {
+			return nil, err
+		}
+		if !ps.symbol(")") {
+			return nil, ps.expected(")")
+		}
+		return c, nil
+	}
+	l, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	var op string
+	switch t := ps.peek(); {
+	case t.kind == tokSymbol && strings.ContainsAny(t.text, "=<>"):
+		op = t.text
+		ps.pos++
+	case ps.keyword("contains"):
+		op = "contains"
+	default:
+		return nil, ps.expected("a comparison")
+	}
+	r, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	return cmpCond{op: op, l: l, r: r}, nil
+}
+
+func (ps *queryParser) operand() (queryOperand, error) {
+	if n, ok := ps.name(); ok {
+		return queryOperand{name: n, col: true}, nil
+	}
+	switch t := ps.peek(); t.kind {
+	case tokNumber, tokString:
+		ps.pos++
+		return queryOperand{lit: t.text}, nil
+	}
+	return queryOperand{}, ps.expected("a column, number or 'text'")
+}
+
+// QueryRun evaluates a query over a source panel a chunk of rows at a time.
+type QueryRun struct {
+	q    *parsedQuery
+	src  *Panel
+	cols map[string]int // lower-cased header -> source column
+	row  int
+	out  [][]string
+	// groups accumulates aggregates, keyed by the group value ("" without
+	// GROUP BY); order keeps groups in order of first appearance
+	groups map[string][]aggState
+	order  []string
+	// num is the source's number locale; numbers are compared by value
+	num NumberLocale
+}
+
+// aggState accumulates one aggregate item.
+type aggState struct {
+	n        int // values counted
+	sum      float64
+	min, max float64
+	nums     int // numeric values seen
+}
+
+// StartQuery compiles text against the header row of src.
+func StartQuery(text string, src *Panel) (*QueryRun, error) {
+	q, err := parseQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	r := &QueryRun{q: q, src: src, cols: make(map[string]int), num: src.Numbers(), row: 1, groups: make(map[string][]aggState)}
+	for col := src.Cols - 1; col >= 0; col-- {
+		// the first of several equal headers wins
+		r.cols[strings.ToLower(strings.TrimSpace(src.GetCell(col, 0)))] = col
+	}
+	resolve := func(name string) (int, error) {
+		if col, ok := r.cols[strings.ToLower(name)]; ok {
+			return col, nil
+		}
+		return -1, fmt.Errorf("no column is called %q", name)
+	}
+	if q.star {
+		if q.groupBy != "" {
+			return nil, errors.New("SELECT * cannot be grouped; list the columns to show")
+		}
+		for col := 0; col < src.Cols; col++ {
+			h := src.GetCell(col, 0)
+			q.items = append(q.items, queryItem{name: h, label: h, col: col})
+		}
+	}
+	aggs := 0
+	for i := range q.items {
+		it := &q.items[i]
+		if it.agg != aggNone {
+			aggs++
+		}
+		if it.name == "" {
+			continue
+		}
+		if q.star {
+			continue
+		}
+		col, err := resolve(it.name)
+		if err != nil {
+			return nil, err
+		}
+		it.col = col
+	}
+	if q.groupBy != "" {
+		col, err := resolve(q.groupBy)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range q.items {
+			if it.agg == aggNone && it.col != col {
+				return nil, fmt.Errorf("%q must be grouped or aggregated", it.name)
+			}
+		}
+	} else if aggs > 0 && aggs < len(q.items) {
+		return nil, errors.New("mix of columns and aggregates needs GROUP BY")
+	}
+	var cerr error
+	if q.where != nil {
+		condNames(q.where, func(name string) {
+			if _, err := resolve(name); err != nil && cerr == nil {
+				cerr = err
+			}
+		})
+	}
+	if cerr != nil {
+		return nil, cerr
+	}
+	if q.orderBy != "" && r.orderCol() < 0 {
+		return nil, fmt.Errorf("ORDER BY %q is not a selected column", q.orderBy)
+	}
+	return r, nil
+}
+
+// grouped reports whether the query aggregates.
+func (r *QueryRun) grouped() bool {
+	if r.q.groupBy != "" {
+		return true
+	}
+	for _, it := range r.q.items {
+		if it.agg != aggNone {
+			return true
+		}
+	}
+	return false
+}
+
+// orderCol returns the output column ORDER BY sorts on, or -1.
+func (r *QueryRun) orderCol() int {
+	for i, it := range r.q.items {
+		if strings.EqualFold(it.label, r.q.orderBy) {
+			return i
+		}
+	}
+	for i, it := range r.q.items {
+		if it.agg == aggNone && strings.EqualFold(it.name, r.q.orderBy) {
+			return i
+		}
+	}
+	return -1
+}
+
+// canonical returns v trimmed, numbers in the source's locale rewritten
+// the way WHERE literals and strconv write them.
+func (r *QueryRun) canonical(v string) string {
+	if c, ok := r.num.Canonical(v); ok {
+		return c
+	}
+	return strings.TrimSpace(v)
+}
+
+// Matching returns the source rows, after the header, that pass the
+// query's WHERE clause, reading the whole source at once. Without WHERE
+// every row matches.
+func (r *QueryRun) Matching() []int {
+	src := r.src
+	var rows []int
+	for row := 1; row < src.Rows; row++ {
+		if r.q.where != nil && !r.q.where.match(func(name string) string {
+			return r.canonical(src.GetCell(r.cols[strings.ToLower(name)], row))
+		}) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Step evaluates up to n more source rows and reports whether the whole
+// source has been read.
+func (r *QueryRun) Step(n int) bool {
+	src := r.src
+	get := func(row int) func(string) string {
+		return func(name string) string {
+			return r.canonical(src.GetCell(r.cols[strings.ToLower(name)], row))
+		}
+	}
+	grouped := r.grouped()
+	groupCol := -1
+	if r.q.groupBy != "" {
+		groupCol = r.cols[strings.ToLower(r.q.groupBy)]
+	}
+	end := min(r.row+n, src.Rows)
+	for ; r.row < end; r.row++ {
+		if r.q.where != nil && !r.q.where.match(get(r.row)) {
+			continue
+		}
+		if !grouped {
+			rec := make([]string, len(r.q.items))
+			for i, it := range r.q.items {
+				if it.col >= 0 {
+					rec[i] = src.GetCell(it.col, r.row)
+				}
+			}
+			r.out = append(r.out, rec)
+			if r.q.limit > 0 && r.q.orderBy == "" && len(r.out) >= r.q.limit {
+				r.row = src.Rows
+				return true
+			}
+			continue
+		}
+		key := ""
+		if groupCol >= 0 {
+			key = src.GetCell(groupCol, r.row)
+		}
+		st, ok := r.groups[key]
+		if !ok {
+			st = make([]aggState, len(r.q.items))
+			r.groups[key] = st
+			r.order = append(r.order, key)
+		}
+		for i, it := range r.q.items {
+			if it.agg != aggNone {
+				st[i].add(it, src, r.row)
+			}
+		}
+	}
+	return r.row >= src.Rows
+}
+
+func (a *aggState) add(it queryItem, src *Panel, row int) {
+	if it.name == "" {
+		a.n++
+		return
+	}
+	v := strings.TrimSpace(src.GetCell(it.col, row))
+	if v == "" {
+		return
+	}
+	a.n++
+	f, err := src.Numbers().Parse(v)
+	if err != nil {
+		return
+	}
+	if a.nums == 0 || f < a.min {
+		a.min = f
+	}
+	if a.nums == 0 || f > a.max {
+		a.max = f
+	}
+	a.nums++
+	a.sum += f
+}
+
+func (a *aggState) value(agg string, num NumberLocale) string {
+	format := func(f float64) string { return num.Format(f, 12) }
+	switch {
+	case agg == aggCount:
+		return strconv.Itoa(a.n)
+	case a.nums == 0:
+		return ""
+	case agg == aggSum:
+		return format(a.sum)
+	case agg == aggAvg:
+		return format(a.sum / float64(a.nums))
+	case agg == aggMin:
+		return format(a.min)
+	}
+	return format(a.max)
+}
+
+// Result returns the query result read so far, header row first.
+func (r *QueryRun) Result() [][]string {
+	header := make([]string, len(r.q.items))
+	for i, it := range r.q.items {
+		header[i] = it.label
+	}
+	rows := r.out
+	if r.grouped() {
+		rows = nil
+		if len(r.order) == 0 && r.q.groupBy == "" {
+			// aggregates over no rows still give one row
+			r.order = []string{""}
+			r.groups[""] = make([]aggState, len(r.q.items))
+		}
+		for _, key := range r.order {
+			st := r.groups[key]
+			rec := make([]string, len(r.q.items))
+			for i, it := range r.q.items {
+				if it.agg == aggNone {
+					rec[i] = key
+				} else {
+					rec[i] = st[i].value(it.agg, r.num)
+				}
+			}
+			rows = append(rows, rec)
+		}
+	}
+	if c := r.orderCol(); c >= 0 {
+		rows = append([][]string(nil), rows...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			cmp := compareValues(r.canonical(rows[i][c]), r.canonical(rows[j][c]))
+			if r.q.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+	if r.q.limit > 0 && len(rows) > r.q.limit {
+		rows = rows[:r.q.limit]
+	}
+	return append([][]string{header}, rows...)
+}
+
+// SetQueryResult replaces the cells of p with recs.
+func (p *Panel) SetQueryResult(recs [][]string) {
+	tmp := NewBlankPanel(0, 0, 1, 1)
+	tmp.PasteRows(0, 0, recs)
+	p.ReplaceContent(&tmp)
+}
+
+// dropQueryPanel renumbers the query sources after panel i is removed.
+// Queries over panel i itself keep their last result with From -1.
+func (w *Workspace) dropQueryPanel(i int) {
+	for j := range w.Panels {
+		q := w.Panels[j].Query
+		switch {
+		case q == nil:
+		case q.From == i:
+			q.From = -1
+		case q.From > i:
+			q.From--
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cellcanvas
+
+// QueryPreset is a named query of a derived panel, like "Open bugs" or "Top
+// 20 by revenue", that the panel can switch back to. It is saved with the
+// panel's query in the state file.
+type QueryPreset struct {
+	Name string `yaml:"name"`
+	Text string `yaml:"text"`
+}
+
+// FindPreset returns the index of the preset called name, or -1.
+func (q *Query) FindPreset(name string) int {
+	for i := range q.Presets {
+		if q.Presets[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SavePreset records the current query as name, replacing a preset of the
+// same name.
+func (q *Query) SavePreset(name string) {
+	if i := q.FindPreset(name); i >= 0 {
+		q.Presets[i].Text = q.Text
+		return
+	}
+	q.Presets = append(q.Presets, QueryPreset{Name: name, Text: q.Text})
+}
+
+// DeletePreset removes the preset called name if there is one.
+func (q *Query) DeletePreset(name string) {
+	if i := q.FindPreset(name); i >= 0 {
+		q.Presets = append(q.Presets[:i], q.Presets[i+1:]...)
+	}
+}
+
+// ActivePreset returns the name of the first preset whose query is the
+// current one, or "".
+func (q *Query) ActivePreset() string {
+	for _, ps := range q.Presets {
+		if ps.Text == q.Text {
+			return ps.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package cellcanvas
+
+import "errors"
+
+// ErrPagedTruncate is returned by Truncate for panels whose data is paged
+// from disk.
+var ErrPagedTruncate = errors.New("panel data is paged from disk and cannot be truncated")
+
+// Resize changes the size p shows. Values outside the new size are kept:
+// they reappear when the panel is enlarged again and are still saved (see
+// DataExtent). Truncate drops them.
+func (p *Panel) Resize(cols, rows int) {
+	p.Cols, p.Rows = max(cols, 1), max(rows, 1)
+}
+
+// DataExtent returns the columns and rows needed to hold every value of p:
+// its size, grown to cover values left outside it by Resize.
+func (p *Panel) DataExtent() (cols, rows int) {
+	cols, rows = p.Cols, p.Rows
+	if p.Paged != nil {
+		cols, rows = max(cols, p.Paged.Cols()), max(rows, p.Paged.Rows())
+	}
+	for key, v := range p.Cells {
+		if v == "" {
+			continue
+		}
+		col, row, err := ParseCellRef(key)
+		if err != nil {
+			continue
+		}
+		cols, rows = max(cols, col+1), max(rows, row+1)
+	}
+	return cols, rows
+}
+
+// HiddenCells counts the values outside p's size, not counting paged data.
+func (p *Panel) HiddenCells() int {
+	n := 0
+	for key, v := range p.Cells {
+		if col, row, err := ParseCellRef(key); err == nil && v != "" && (col >= p.Cols || row >= p.Rows) {
+			n++
+		}
+	}
+	return n
+}
+
+// Truncate deletes the values outside p's size and returns how many were
+// dropped.
+func (p *Panel) Truncate() (int, error) {
+	if p.Paged != nil && (p.Paged.Cols() > p.Cols || p.Paged.Rows() > p.Rows) {
+		return 0, ErrPagedTruncate
+	}
+	n := 0
+	for key, v := range p.Cells {
+		col, row, err := ParseCellRef(key)
+		if err != nil || col < p.Cols && row < p.Rows {
+			continue
+		}
+		if v != "" {
+			n++
+		}
+		delete(p.Cells, key)
+	}
+	return n, nil
+}
@@ -0,0 +1,55 @@
+package cellcanvas
+
+import (
+	"io"
+	"os"
+)
+
+// LoadPanelCSVHead replaces the grid size and cells of p with the first n
+// records of the CSV file at path, without reading the rest. more reports
+// whether the file has further records. Bad records are handled as by
+// ReadPanelCSV. Failures are returned as a *FileError.
+func LoadPanelCSVHead(path string, p *Panel, n int) (more bool, err error) {
+	more, err = loadPanelCSVHead(path, p, n)
+	return more, fileError(path, err)
+}
+
+func loadPanelCSVHead(path string, p *Panel, n int) (more bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if err := sniffPanelDelimiter(f, p); err != nil {
+		return false, err
+	}
+	r := newCSVRecovery(f, RaggedRows, p.comma())
+	defer func() { p.Problems = r.report() }()
+	records := make([][]string, 0, min(n, 4096))
+	for len(records) < n {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return false, setPanelRecords(p, records, PagingThreshold)
+		}
+		if err != nil {
+			return false, err
+		}
+		records = append(records, rec)
+	}
+	seen, seenMore := len(r.problems), r.more
+	_, err = r.Read()
+	more = err != io.EOF
+	// problems past the sample are not the panel's
+	r.problems, r.more = r.problems[:seen], seenMore
+	return more, setPanelRecords(p, records, PagingThreshold)
+}
+
+// LoadPanelFile loads the CSV file at path into p: its first sampleRows
+// records when sampleRows > 0, else all of it. sampled reports whether the
+// file holds more records than were loaded.
+func LoadPanelFile(path string, p *Panel, sampleRows int) (sampled bool, err error) {
+	if sampleRows <= 0 {
+		return false, LoadPanelCSV(path, p)
+	}
+	return LoadPanelCSVHead(path, p, sampleRows)
+}
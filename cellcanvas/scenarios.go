@@ -0,0 +1,70 @@
+package cellcanvas
+
+// Scenario is a named set of input-cell values ("Best case", "Worst
+// case") that can be written back into the panels to compare results.
+type Scenario struct {
+	Name   string          `yaml:"name"`
+	Values []ScenarioValue `yaml:"values"`
+}
+
+// ScenarioValue is one input cell of a Scenario. Panel indexes
+// Workspace.Panels.
+type ScenarioValue struct {
+	Panel int    `yaml:"panel"`
+	Cell  string `yaml:"cell"`
+	Value string `yaml:"value"`
+}
+
+// FindScenario returns the index of the scenario called name, or -1.
+func (w *Workspace) FindScenario(name string) int {
+	for i := range w.Scenarios {
+		if w.Scenarios[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SaveScenario records the current values of the inclusive range
+// col0,row0..col1,row1 of panel as name, replacing a scenario of the same
+// name. Empty cells are recorded too, so switching clears them.
+func (w *Workspace) SaveScenario(name string, panel, col0, row0, col1, row1 int) {
+	s := Scenario{Name: name}
+	p := &w.Panels[panel]
+	for row := row0; row <= row1; row++ {
+		for col := col0; col <= col1; col++ {
+			s.Values = append(s.Values, ScenarioValue{Panel: panel, Cell: CellRef(col, row), Value: p.RawCell(col, row)})
+		}
+	}
+	if i := w.FindScenario(name); i >= 0 {
+		w.Scenarios[i] = s
+		return
+	}
+	w.Scenarios = append(w.Scenarios, s)
+}
+
+// DeleteScenario removes the scenario called name if there is one.
+func (w *Workspace) DeleteScenario(name string) {
+	if i := w.FindScenario(name); i >= 0 {
+		w.Scenarios = append(w.Scenarios[:i], w.Scenarios[i+1:]...)
+	}
+}
+
+// dropScenarioPanel forgets the inputs on panel i and renumbers those on
+// later panels after it is removed.
+func (w *Workspace) dropScenarioPanel(i int) {
+	for si := range w.Scenarios {
+		s := &w.Scenarios[si]
+		kept := s.Values[:0]
+		for _, v := range s.Values {
+			switch {
+			case v.Panel == i:
+				continue
+			case v.Panel > i:
+				v.Panel--
+			}
+			kept = append(kept, v)
+		}
+		s.Values = kept
+	}
+}
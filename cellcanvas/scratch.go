@@ -0,0 +1,64 @@
+package cellcanvas
+
+// withoutScratch returns w as it is saved: without its scratch panels. When
+// there are any, the result is a copy in which the panels after them moved
+// up, as after RemovePanelAt, and keep maps each of its panels to the index
+// of the same panel in w. Otherwise it is w itself and keep is nil. The copy
+// shares its paged stores with w, so none is closed.
+func (w *Workspace) withoutScratch() (saved *Workspace, keep []int) {
+	n := 0
+	for i := range w.Panels {
+		if w.Panels[i].Scratch {
+			n++
+		}
+	}
+	if n == 0 {
+		return w, nil
+	}
+	// RemovePanelAt rewrites these lists in place, so they are copied
+	c := *w
+	c.Panels = append([]Panel(nil), w.Panels...)
+	c.Watches = append([]Watch(nil), w.Watches...)
+	c.Layouts = append([]Layout(nil), w.Layouts...)
+	for i := range c.Layouts {
+		c.Layouts[i].Panels = append([]LayoutPos(nil), c.Layouts[i].Panels...)
+	}
+	c.Scenarios = append([]Scenario(nil), w.Scenarios...)
+	for i := range c.Scenarios {
+		c.Scenarios[i].Values = append([]ScenarioValue(nil), c.Scenarios[i].Values...)
+	}
+	for i := range c.Panels {
+		if q := c.Panels[i].Query; q != nil {
+			qc := *q
+			qc.Presets = append([]QueryPreset(nil), q.Presets...)
+			c.Panels[i].Query = &qc
+		}
+	}
+	for i := len(c.Panels) - 1; i >= 0; i-- {
+		if !c.Panels[i].Scratch {
+			continue
+		}
+		c.removePanelAt(i)
+		switch {
+		case c.ActivePanel == i:
+			c.ActivePanel = 0
+		case c.ActivePanel > i:
+			c.ActivePanel--
+		}
+	}
+	for i := range w.Panels {
+		if !w.Panels[i].Scratch {
+			keep = append(keep, i)
+		}
+	}
+	return &c, keep
+}
+
+// keepSaved copies the file names and checksums saving recorded in the
+// panels of saved, as returned by withoutScratch, back to w.
+func (w *Workspace) keepSaved(saved *Workspace, keep []int) {
+	for i, j := range keep {
+		w.Panels[j].Filename = saved.Panels[i].Filename
+		w.Panels[j].Checksum = saved.Panels[i].Checksum
+	}
+}
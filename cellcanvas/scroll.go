@@ -0,0 +1,104 @@
+package cellcanvas
+
+// Scrolling. A panel shows a window of its rows and columns starting at
+// ScrollRow and ScrollCol; the rest is reached by scrolling, so a large CSV
+// takes a fixed amount of room on the canvas instead of growing with its
+// data.
+
+// ScrollRowsOver and ScrollColsOver are the sizes past which a panel
+// without a window of its own (ViewRows, ViewCols) scrolls. 0 never
+// scrolls.
+var (
+	ScrollRowsOver = 200
+	ScrollColsOver = 40
+)
+
+// Window sizes given to panels that scroll because of their size.
+const (
+	DefaultViewRows = 30
+	DefaultViewCols = 12
+)
+
+// WindowRows returns how many rows p shows at a time: ViewRows when set,
+// DefaultViewRows once p has more than ScrollRowsOver rows, else all.
+func (p *Panel) WindowRows() int {
+	return window(p.Rows, p.ViewRows, ScrollRowsOver, DefaultViewRows)
+}
+
+// WindowCols returns how many columns p shows at a time, like WindowRows.
+func (p *Panel) WindowCols() int {
+	return window(p.Cols, p.ViewCols, ScrollColsOver, DefaultViewCols)
+}
+
+func window(n, view, over, def int) int {
+	switch {
+	case view > 0:
+		return min(view, n)
+	case over > 0 && n > over:
+		return def
+	}
+	return n
+}
+
+// VisibleRows returns the half-open range of rows p shows.
+func (p *Panel) VisibleRows() (first, last int) {
+	n := p.WindowRows()
+	first = max(min(p.ScrollRow, p.Rows-n), 0)
+	return first, first + n
+}
+
+// VisibleCols returns the half-open range of columns p shows.
+func (p *Panel) VisibleCols() (first, last int) {
+	n := p.WindowCols()
+	first = max(min(p.ScrollCol, p.Cols-n), 0)
+	return first, first + n
+}
+
+// Scrolls reports whether p hides some of its rows or columns.
+func (p *Panel) Scrolls() bool {
+	return p.WindowRows() < p.Rows || p.WindowCols() < p.Cols
+}
+
+// CellVisible reports whether cell col,row is in p's window.
+func (p *Panel) CellVisible(col, row int) bool {
+	r0, r1 := p.VisibleRows()
+	c0, c1 := p.VisibleCols()
+	return row >= r0 && row < r1 && col >= c0 && col < c1
+}
+
+// ScrollBy scrolls p by rows and cols, keeping the window inside the panel.
+// It reports whether the window moved.
+func (p *Panel) ScrollBy(rows, cols int) bool {
+	r0, _ := p.VisibleRows()
+	c0, _ := p.VisibleCols()
+	return p.scrollTo(r0+rows, c0+cols)
+}
+
+// ScrollTo scrolls p as little as needed to show cell col,row. It reports
+// whether the window moved.
+func (p *Panel) ScrollTo(col, row int) bool {
+	r0, r1 := p.VisibleRows()
+	c0, c1 := p.VisibleCols()
+	switch {
+	case row < r0:
+		r0 = row
+	case row >= r1:
+		r0 += row - r1 + 1
+	}
+	switch {
+	case col < c0:
+		c0 = col
+	case col >= c1:
+		c0 += col - c1 + 1
+	}
+	return p.scrollTo(r0, c0)
+}
+
+func (p *Panel) scrollTo(row, col int) bool {
+	row = max(min(row, p.Rows-p.WindowRows()), 0)
+	col = max(min(col, p.Cols-p.WindowCols()), 0)
+	r0, _ := p.VisibleRows()
+	c0, _ := p.VisibleCols()
+	p.ScrollRow, p.ScrollCol = row, col
+	return row != r0 || col != c0
+}
@@ -0,0 +1,100 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Workspace search. CombinedRecords stacks the data rows of many panels
+// into one table, matching columns by header, so that a workspace of CSVs
+// can be searched, or queried (see StartQuery), like one dataset.
+
+// The columns CombinedRecords puts before the data of every row: the panel
+// it comes from, that panel's file and the row's number there.
+const (
+	CombinedPanelCol  = "panel"
+	CombinedSourceCol = "source"
+	CombinedRowCol    = "row"
+	// CombinedCols is the number of those columns
+	CombinedCols = 3
+)
+
+// CombinedRecords returns the data rows of the panels include accepts,
+// header row first. Each row starts with the panel's name (or "Panel N"),
+// the base name of its file and its row number there, counted from 1 as
+// the row labels are; its values follow in the column of the same header,
+// columns first seen in a later panel going after the others. A panel
+// without a header row gives all its rows, its columns headed A, B, ...
+// Panels whose own first columns are the annotations, like an earlier
+// search result, are left out so results are not searched again.
+func (w *Workspace) CombinedRecords(include func(i int) bool) [][]string {
+	header := []string{CombinedPanelCol, CombinedSourceCol, CombinedRowCol}
+	cols := make(map[string]int)
+	var rows [][]string
+	for i := range w.Panels {
+		p := &w.Panels[i]
+		if !include(i) || p.combined() {
+			continue
+		}
+		first, colMap := 0, make([]int, p.Cols)
+		if p.HasHeader() {
+			first = 1
+		}
+		for c := range colMap {
+			h := ColToLetters(c)
+			if first == 1 {
+				h = strings.TrimSpace(p.GetCell(c, 0))
+			}
+			if h == "" {
+				colMap[c] = -1
+				continue
+			}
+			to, ok := cols[headerKey(h)]
+			if !ok {
+				to = len(header)
+				cols[headerKey(h)] = to
+				header = append(header, h)
+			}
+			colMap[c] = to
+		}
+		label := p.Name
+		if label == "" {
+			label = fmt.Sprintf("Panel %d", i+1)
+		}
+		file := ""
+		if p.Filename != "" {
+			file = filepath.Base(p.Filename)
+		}
+		for r := first; r < p.UsedRows(); r++ {
+			rec := make([]string, len(header))
+			copy(rec, []string{label, file, strconv.Itoa(r + 1)})
+			for c, to := range colMap {
+				if to >= 0 {
+					rec[to] = p.GetCell(c, r)
+				}
+			}
+			rows = append(rows, rec)
+		}
+	}
+	return append([][]string{header}, rows...)
+}
+
+// combined reports whether p starts with the columns CombinedRecords adds.
+func (p *Panel) combined() bool {
+	return p.Cols >= CombinedCols && headerKey(p.RawCell(0, 0)) == CombinedPanelCol &&
+		headerKey(p.RawCell(1, 0)) == CombinedSourceCol && headerKey(p.RawCell(2, 0)) == CombinedRowCol
+}
+
+// RecordContains reports whether a value of rec from field from on
+// contains text, compared case-insensitively.
+func RecordContains(rec []string, from int, text string) bool {
+	text = strings.ToLower(text)
+	for _, v := range rec[min(from, len(rec)):] {
+		if strings.Contains(strings.ToLower(v), text) {
+			return true
+		}
+	}
+	return false
+}
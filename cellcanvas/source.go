@@ -0,0 +1,34 @@
+package cellcanvas
+
+// Source kinds understood by the frontend.
+const (
+	SourceMQTT      = "mqtt"
+	SourceWebSocket = "websocket"
+	// SourceClipboard panels collect every text snippet copied to the OS
+	// clipboard as a (time, text) row.
+	SourceClipboard = "clipboard"
+	// SourceCommand panels re-run a shell command every Interval seconds and
+	// show its output as a grid, like watch(1).
+	SourceCommand = "command"
+)
+
+// Source describes where a live panel gets its rows from. Panels without a
+// Source are ordinary static grids. The frontend owns the connection; this
+// package only stores and persists the configuration.
+type Source struct {
+	Kind string `yaml:"kind"`
+	// URL is the broker (mqtt://host:1883) or stream (ws://host/path) address.
+	URL string `yaml:"url,omitempty"`
+	// Topic is the MQTT topic filter to subscribe to.
+	Topic string `yaml:"topic,omitempty"`
+	// MaxRows bounds the number of data rows kept; older rows are dropped
+	// first. Zero means unbounded.
+	MaxRows int `yaml:"max_rows,omitempty"`
+	// Command is the shell command line run by command panels.
+	Command string `yaml:"command,omitempty"`
+	// Interval is the number of seconds between command runs.
+	Interval int `yaml:"interval,omitempty"`
+	// Header records that row 1 holds column keys (set by JSON object feeds)
+	// and must survive row trimming.
+	Header bool `yaml:"header,omitempty"`
+}
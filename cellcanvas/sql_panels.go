@@ -0,0 +1,100 @@
+package cellcanvas
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SQLQuery defines a SQL panel: its cells are the result of Text, a query
+// in the language of Query with a FROM clause naming the table it runs
+// over.
+//
+//	SELECT customer, sum(total) AS spent FROM orders WHERE status = 'paid' GROUP BY customer
+//
+// With Database set the table is read from that SQLite file; without it
+// the table is another panel of the workspace, named by its name or as
+// panelN. Unlike a derived panel, a SQL panel only reruns when it is
+// refreshed.
+type SQLQuery struct {
+	Database string `yaml:"database,omitempty"`
+	Text     string `yaml:"text"`
+}
+
+// SplitFrom removes the FROM clause from a SQL panel's query, returning
+// the rest, a query over one table, and the table FROM names. The table is
+// a bare word, or 'quoted' or [bracketed] when it has spaces.
+func SplitFrom(text string) (query, table string, err error) {
+	toks, err := lexQuery(text)
+	if err != nil {
+		return "", "", err
+	}
+	for i, t := range toks {
+		if t.kind != tokWord || !strings.EqualFold(t.text, "from") {
+			continue
+		}
+		name := toks[i+1]
+		switch name.kind {
+		case tokWord, tokName, tokString:
+		default:
+			return "", "", fmt.Errorf("FROM needs a table at %d", name.pos+1)
+		}
+		query = strings.TrimSpace(text[:t.pos] + " " + text[toks[i+2].pos:])
+		if err := ParseQuery(query); err != nil {
+			return "", "", err
+		}
+		return query, name.text, nil
+	}
+	return "", "", errors.New("the query has no FROM clause naming its table")
+}
+
+// SQLTable returns the table named by the FROM clause of panel pi's SQL
+// query, and the query without it: the SQLite table read afresh when the
+// panel has a database, else the panel it names.
+func (w *Workspace) SQLTable(pi int) (*Panel, string, error) {
+	q := w.Panels[pi].SQL
+	text, table, err := SplitFrom(q.Text)
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Database != "" {
+		p, err := ReadSQLiteTable(q.Database, table)
+		if err != nil {
+			return nil, "", err
+		}
+		return &p, text, nil
+	}
+	if j := w.FindPanel(table); j >= 0 && j != pi {
+		return &w.Panels[j], text, nil
+	}
+	return nil, "", fmt.Errorf("no panel is named %s", table)
+}
+
+// FindPanel returns the index of the panel named name, ignoring case, or
+// of panel N for "panelN"; -1 when there is none.
+func (w *Workspace) FindPanel(name string) int {
+	for i := range w.Panels {
+		if strings.EqualFold(w.Panels[i].Name, name) {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(name), "panel")); err == nil && n >= 1 && n <= len(w.Panels) {
+		return n - 1
+	}
+	return -1
+}
+
+// Title names a SQL panel's source for its title: the table, and the
+// database file it is read from.
+func (q *SQLQuery) Title() string {
+	_, table, err := SplitFrom(q.Text)
+	if err != nil {
+		table = "?"
+	}
+	if q.Database != "" {
+		return table + " in " + filepath.Base(q.Database)
+	}
+	return table
+}
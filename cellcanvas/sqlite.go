@@ -0,0 +1,554 @@
+package cellcanvas
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A read-only reader for SQLite database files, enough to list the tables
+// and load one as a panel without a SQLite library: it walks the table's
+// b-tree pages and decodes its records. Only the main database file is
+// read, so changes still in a -wal journal are not seen. Tables declared
+// WITHOUT ROWID and virtual tables are not supported.
+
+const (
+	sqliteMagic = "SQLite format 3\x00"
+	// sqliteMaxDepth bounds the b-tree depth, so a corrupt file cannot
+	// recurse forever
+	sqliteMaxDepth = 40
+)
+
+var errSQLiteCorrupt = errors.New("the database file is corrupt")
+
+// sqliteDB is an open database file.
+type sqliteDB struct {
+	f        *os.File
+	pageSize int
+	// usable is the page size less the bytes reserved at the end of pages
+	usable int
+	pages  int
+	// enc is the text encoding: 1 UTF-8, 2 UTF-16le, 3 UTF-16be
+	enc byte
+}
+
+// sqliteTable is a table listed in the schema.
+type sqliteTable struct {
+	name string
+	root int
+	sql  string
+}
+
+func openSQLite(path string) (*sqliteDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var h [100]byte
+	if _, err := io.ReadFull(f, h[:]); err != nil || string(h[:16]) != sqliteMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a SQLite database", filepath.Base(path))
+	}
+	db := &sqliteDB{f: f, pageSize: int(binary.BigEndian.Uint16(h[16:])), enc: h[59]}
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	db.usable = db.pageSize - int(h[20])
+	if st, err := f.Stat(); err == nil && db.pageSize >= 512 {
+		db.pages = int(st.Size() / int64(db.pageSize))
+	}
+	if db.pageSize < 512 || db.usable < 480 || db.pages < 1 {
+		f.Close()
+		return nil, errSQLiteCorrupt
+	}
+	return db, nil
+}
+
+func (db *sqliteDB) Close() error {
+	return db.f.Close()
+}
+
+// page reads page n, counted from 1.
+func (db *sqliteDB) page(n int) ([]byte, error) {
+	if n < 1 || n > db.pages {
+		return nil, errSQLiteCorrupt
+	}
+	buf := make([]byte, db.pageSize)
+	if _, err := db.f.ReadAt(buf, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// walk calls fn with the rowid and record of every row of the table whose
+// b-tree starts at page root, in rowid order.
+func (db *sqliteDB) walk(root, depth int, fn func(rowid int64, rec []byte) error) error {
+	if depth > sqliteMaxDepth {
+		return errSQLiteCorrupt
+	}
+	p, err := db.page(root)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if root == 1 {
+		hdr = 100
+	}
+	if len(p) < hdr+12 {
+		return errSQLiteCorrupt
+	}
+	kind, ncells := p[hdr], int(binary.BigEndian.Uint16(p[hdr+3:]))
+	switch kind {
+	case 13: // table leaf
+		for i := 0; i < ncells; i++ {
+			ptr := hdr + 8 + 2*i
+			if ptr+2 > len(p) {
+				return errSQLiteCorrupt
+			}
+			rowid, rec, err := db.leafCell(p, int(binary.BigEndian.Uint16(p[ptr:])))
+			if err != nil {
+				return err
+			}
+			if err := fn(rowid, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case 5: // table interior
+		for i := 0; i < ncells; i++ {
+			ptr := hdr + 12 + 2*i
+			if ptr+2 > len(p) {
+				return errSQLiteCorrupt
+			}
+			off := int(binary.BigEndian.Uint16(p[ptr:]))
+			if off+4 > len(p) {
+				return errSQLiteCorrupt
+			}
+			if err := db.walk(int(binary.BigEndian.Uint32(p[off:])), depth+1, fn); err != nil {
+				return err
+			}
+		}
+		return db.walk(int(binary.BigEndian.Uint32(p[hdr+8:])), depth+1, fn)
+	case 2, 10:
+		return errors.New("tables WITHOUT ROWID are not supported")
+	}
+	return errSQLiteCorrupt
+}
+
+// leafCell returns the rowid and record of the table leaf cell at off of
+// page p, reading its overflow pages.
+func (db *sqliteDB) leafCell(p []byte, off int) (int64, []byte, error) {
+	if off >= len(p) {
+		return 0, nil, errSQLiteCorrupt
+	}
+	size, n := sqliteVarint(p[off:])
+	off += n
+	if off >= len(p) {
+		return 0, nil, errSQLiteCorrupt
+	}
+	rowid, n := sqliteVarint(p[off:])
+	off += n
+	total := int(size)
+	if size < 0 || size > math.MaxInt32 {
+		return 0, nil, errSQLiteCorrupt
+	}
+	// how much of the record is on this page, as the file format defines
+	u := db.usable
+	local := total
+	if x := u - 35; total > x {
+		m := (u-12)*32/255 - 23
+		local = m + (total-m)%(u-4)
+		if local > x {
+			local = m
+		}
+	}
+	if off+local > len(p) {
+		return 0, nil, errSQLiteCorrupt
+	}
+	rec := append([]byte(nil), p[off:off+local]...)
+	if local < total {
+		if off+local+4 > len(p) {
+			return 0, nil, errSQLiteCorrupt
+		}
+		next := int(binary.BigEndian.Uint32(p[off+local:]))
+		for seen := 0; len(rec) < total; seen++ {
+			if next == 0 || seen > db.pages {
+				return 0, nil, errSQLiteCorrupt
+			}
+			op, err := db.page(next)
+			if err != nil {
+				return 0, nil, err
+			}
+			next = int(binary.BigEndian.Uint32(op))
+			rec = append(rec, op[4:min(u, 4+total-len(rec))]...)
+		}
+	}
+	return int64(rowid), rec, nil
+}
+
+// sqliteVarint decodes the big-endian varint at the start of b and returns
+// it and its length.
+func sqliteVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return int64(v), i + 1
+		}
+	}
+	return int64(v), len(b)
+}
+
+// record decodes a record into its values as cell text; null reports the
+// NULL values.
+func (db *sqliteDB) record(rec []byte) (vals []string, null []bool, err error) {
+	hsize, n := sqliteVarint(rec)
+	if hsize < int64(n) || hsize > int64(len(rec)) {
+		return nil, nil, errSQLiteCorrupt
+	}
+	body := int(hsize)
+	for pos := n; pos < int(hsize); {
+		t, k := sqliteVarint(rec[pos:int(hsize)])
+		pos += k
+		size := sqliteSerialSize(t)
+		if size < 0 || body+size > len(rec) {
+			return nil, nil, errSQLiteCorrupt
+		}
+		v := rec[body : body+size]
+		body += size
+		switch {
+		case t == 0:
+			vals, null = append(vals, ""), append(null, true)
+			continue
+		case t >= 1 && t <= 6:
+			// big-endian two's complement of 1 to 8 bytes
+			x := int64(int8(v[0]))
+			for _, c := range v[1:] {
+				x = x<<8 | int64(c)
+			}
+			vals = append(vals, strconv.FormatInt(x, 10))
+		case t == 7:
+			vals = append(vals, strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(v)), 'g', -1, 64))
+		case t == 8 || t == 9:
+			vals = append(vals, strconv.Itoa(int(t-8)))
+		case t >= 12 && t%2 == 0:
+			vals = append(vals, fmt.Sprintf("(blob, %d bytes)", size))
+		case t >= 13:
+			vals = append(vals, db.text(v))
+		default:
+			return nil, nil, errSQLiteCorrupt
+		}
+		null = append(null, false)
+	}
+	return vals, null, nil
+}
+
+// sqliteSerialSize returns the number of bytes of a value of serial type
+// t, or -1 for reserved types.
+func sqliteSerialSize(t int64) int {
+	switch {
+	case t >= 0 && t <= 4:
+		return []int{0, 1, 2, 3, 4}[t]
+	case t == 5:
+		return 6
+	case t == 6 || t == 7:
+		return 8
+	case t == 8 || t == 9:
+		return 0
+	case t >= 12 && t < math.MaxInt32:
+		return int(t-12) / 2
+	}
+	return -1
+}
+
+// text decodes a text value in the database's encoding.
+func (db *sqliteDB) text(b []byte) string {
+	if db.enc != 2 && db.enc != 3 {
+		return string(b)
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		if db.enc == 2 {
+			u[i] = binary.LittleEndian.Uint16(b[2*i:])
+		} else {
+			u[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// tables lists the tables of the schema, leaving out SQLite's own and
+// virtual tables.
+func (db *sqliteDB) tables() ([]sqliteTable, error) {
+	var ts []sqliteTable
+	err := db.walk(1, 0, func(_ int64, rec []byte) error {
+		vals, _, err := db.record(rec)
+		if err != nil {
+			return err
+		}
+		if len(vals) < 5 || vals[0] != "table" || strings.HasPrefix(vals[1], "sqlite_") {
+			return nil
+		}
+		root, err := strconv.Atoi(vals[3])
+		if err != nil || root == 0 {
+			return nil
+		}
+		ts = append(ts, sqliteTable{name: vals[1], root: root, sql: vals[4]})
+		return nil
+	})
+	return ts, err
+}
+
+// SQLiteTables lists the names of the tables in the SQLite database at
+// path, in schema order.
+func SQLiteTables(path string) ([]string, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	ts, err := db.tables()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = t.name
+	}
+	return names, nil
+}
+
+// ReadSQLiteTable loads table of the SQLite database at path as a panel
+// named after the table, with its column names as the header row and one
+// row per table row in rowid order. NULLs load as empty cells and blobs as
+// their size. Its position is left at zero for the caller to arrange.
+func ReadSQLiteTable(path, table string) (Panel, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return Panel{}, err
+	}
+	defer db.Close()
+	fail := func(err error) (Panel, error) {
+		return Panel{}, fmt.Errorf("%s: %s: %w", filepath.Base(path), table, err)
+	}
+	ts, err := db.tables()
+	if err != nil {
+		return fail(err)
+	}
+	var t *sqliteTable
+	for i := range ts {
+		if strings.EqualFold(ts[i].name, table) {
+			t = &ts[i]
+		}
+	}
+	if t == nil {
+		return fail(errors.New("no such table"))
+	}
+	cols, defaults, rowidCol, withoutRowid := sqliteColumns(t.sql)
+	if withoutRowid {
+		return fail(errors.New("tables WITHOUT ROWID are not supported"))
+	}
+	recs := [][]string{cols}
+	err = db.walk(t.root, 0, func(rowid int64, rec []byte) error {
+		vals, null, err := db.record(rec)
+		if err != nil {
+			return err
+		}
+		// rows written before an ALTER TABLE ... ADD COLUMN lack the new
+		// columns, which read as their DEFAULT
+		for i := len(vals); i < len(cols); i++ {
+			vals, null = append(vals, defaults[i]), append(null, defaults[i] == "")
+		}
+		// an INTEGER PRIMARY KEY column is stored as the rowid
+		if rowidCol >= 0 {
+			for len(vals) <= rowidCol {
+				vals, null = append(vals, ""), append(null, true)
+			}
+			if null[rowidCol] {
+				vals[rowidCol] = strconv.FormatInt(rowid, 10)
+			}
+		}
+		recs = append(recs, vals)
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	p, err := NewPanelFromRecords(0, 0, recs)
+	if err != nil {
+		return Panel{}, err
+	}
+	p.Name = table
+	return p, nil
+}
+
+// sqliteColumns returns the column names a CREATE TABLE statement
+// declares with the cell text of their DEFAULT values, the index of its
+// INTEGER PRIMARY KEY column (-1 without one) and whether the table is
+// WITHOUT ROWID.
+func sqliteColumns(sql string) (cols, defaults []string, rowidCol int, withoutRowid bool) {
+	rowidCol = -1
+	start := strings.IndexByte(sql, '(')
+	if start < 0 {
+		return nil, nil, -1, false
+	}
+	var defs []string
+	depth, quote, from, end := 0, byte(0), start+1, len(sql)
+scan:
+	for i := start; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth == 0 {
+				defs, end = append(defs, sql[from:i]), i
+				break scan
+			}
+		case c == ',' && depth == 1:
+			defs, from = append(defs, sql[from:i]), i+1
+		}
+	}
+	withoutRowid = strings.Contains(strings.ToUpper(sql[end:]), "WITHOUT ROWID")
+	for _, d := range defs {
+		name, rest := sqliteName(strings.TrimSpace(d))
+		switch strings.ToUpper(name) {
+		case "", "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		up := strings.ToUpper(rest)
+		if f := strings.Fields(up); len(f) > 0 && f[0] == "INTEGER" && strings.Contains(up, "PRIMARY KEY") {
+			rowidCol = len(cols)
+		}
+		cols = append(cols, name)
+		defaults = append(defaults, sqliteDefault(rest))
+	}
+	return cols, defaults, rowidCol, withoutRowid
+}
+
+// sqliteDefault returns the cell text of the DEFAULT clause in the rest of
+// a column definition: a number, string or blob literal, NULL, TRUE or
+// FALSE, optionally in parentheses. Anything else, such as CURRENT_TIME or
+// an expression, and a column without a default read as "".
+func sqliteDefault(rest string) string {
+	quote := byte(0)
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case (i == 0 || !sqliteWordByte(rest[i-1])) && len(rest) >= i+7 && strings.EqualFold(rest[i:i+7], "DEFAULT") && (len(rest) == i+7 || !sqliteWordByte(rest[i+7])):
+			return sqliteLiteral(strings.TrimSpace(rest[i+7:]))
+		}
+	}
+	return ""
+}
+
+// sqliteLiteral returns the cell text of the literal s starts with.
+func sqliteLiteral(s string) string {
+	if s == "" {
+		return ""
+	}
+	switch c := s[0]; {
+	case c == '(':
+		if end := strings.LastIndexByte(s, ')'); end > 0 {
+			return sqliteLiteral(strings.TrimSpace(s[1:end]))
+		}
+		return ""
+	case c == '\'' || c == '"':
+		// a doubled quote stands for one
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] == c {
+				if i+1 < len(s) && s[i+1] == c {
+					i++
+				} else {
+					break
+				}
+			}
+			b.WriteByte(s[i])
+		}
+		return b.String()
+	case (c == 'x' || c == 'X') && len(s) > 1 && s[1] == '\'':
+		if end := strings.IndexByte(s[2:], '\''); end >= 0 {
+			return fmt.Sprintf("(blob, %d bytes)", end/2)
+		}
+		return ""
+	}
+	word := s
+	if i := strings.IndexFunc(s, func(r rune) bool { return r < 0x80 && !sqliteWordByte(byte(r)) && r != '.' && r != '+' && r != '-' }); i >= 0 {
+		word = s[:i]
+	}
+	switch strings.ToUpper(word) {
+	case "TRUE":
+		return "1"
+	case "FALSE":
+		return "0"
+	}
+	num := strings.TrimPrefix(word, "+")
+	neg := strings.HasPrefix(num, "-")
+	if hex := strings.TrimPrefix(num, "-"); len(hex) > 2 && (hex[:2] == "0x" || hex[:2] == "0X") {
+		if n, err := strconv.ParseUint(hex[2:], 16, 64); err == nil {
+			if neg {
+				return strconv.FormatInt(-int64(n), 10)
+			}
+			return strconv.FormatInt(int64(n), 10)
+		}
+		return ""
+	}
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil && !strings.ContainsAny(num, "_pPiInN") {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	// NULL, CURRENT_TIME and the like
+	return ""
+}
+
+// sqliteWordByte reports whether c can be part of a keyword or name.
+func sqliteWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// sqliteName splits the name, unquoted, off the start of a column
+// definition.
+func sqliteName(d string) (name, rest string) {
+	if d == "" {
+		return "", ""
+	}
+	if end := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[d[0]]; end != 0 {
+		if i := strings.IndexByte(d[1:], end); i >= 0 {
+			return d[1 : i+1], d[i+2:]
+		}
+		return d[1:], ""
+	}
+	if i := strings.IndexAny(d, " \t\r\n"); i >= 0 {
+		return d[:i], d[i:]
+	}
+	return d, ""
+}
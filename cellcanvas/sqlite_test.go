@@ -0,0 +1,135 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata are written by testdata/sqlite.py with 512 byte
+// pages.
+
+func readSQLite(t *testing.T, file, table string) [][]string {
+	t.Helper()
+	p, err := ReadSQLiteTable(filepath.Join("testdata", file), table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([][]string, p.Rows)
+	for r := range rows {
+		rows[r] = make([]string, p.Cols)
+		for c := range rows[r] {
+			rows[r][c] = p.GetCell(c, r)
+		}
+	}
+	return rows
+}
+
+func TestSQLiteTables(t *testing.T) {
+	got, err := SQLiteTables(filepath.Join("testdata", "tables.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"big", "notes", "altered", "keyed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadSQLiteTableMultiLevel(t *testing.T) {
+	// the root and its children are interior pages
+	rows := readSQLite(t, "tables.db", "big")
+	if len(rows) != 3001 {
+		t.Fatalf("got %d rows, want 3001", len(rows))
+	}
+	if want := []string{"id", "name", "score"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("header %q, want %q", rows[0], want)
+	}
+	for i, row := range rows[1:] {
+		// id is the INTEGER PRIMARY KEY, stored as the rowid
+		want := []string{strconv.Itoa(i * 2), fmt.Sprintf("row %d", i), strconv.FormatFloat(float64(i)/4, 'g', -1, 64)}
+		if !reflect.DeepEqual(row, want) {
+			t.Fatalf("row %d: got %q, want %q", i+1, row, want)
+		}
+	}
+}
+
+func TestReadSQLiteTableOverflow(t *testing.T) {
+	rows := readSQLite(t, "tables.db", "notes")
+	want := [][]string{{"body", "data"}}
+	for _, n := range []int{10, 600, 5000} {
+		want = append(want, []string{strings.Repeat("x", n), fmt.Sprintf("(blob, %d bytes)", n)})
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %d rows differing from the %d written", len(rows), len(want))
+	}
+}
+
+func TestReadSQLiteTableAddedColumns(t *testing.T) {
+	rows := readSQLite(t, "tables.db", "altered")
+	want := [][]string{
+		{"a", "b", "d", "e", "f", "g"},
+		{"1", "one", "7", "it's", "-2.5", ""},
+		{"2", "", "7", "it's", "-2.5", ""},
+		{"3", "three", "8", "set", "1.5", "g"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+}
+
+func TestReadSQLiteTableUTF16(t *testing.T) {
+	want := [][]string{{"w"}, {"héllo"}, {"日本語"}, {strings.Repeat("ü", 1000)}}
+	for _, file := range []string{"utf16le.db", "utf16be.db"} {
+		if rows := readSQLite(t, file, "words"); !reflect.DeepEqual(rows, want) {
+			t.Errorf("%s: got %q, want %q", file, rows, want)
+		}
+	}
+}
+
+func TestReadSQLiteTableErrors(t *testing.T) {
+	tests := []struct {
+		table, want string
+	}{
+		{"keyed", "WITHOUT ROWID"},
+		{"missing", "no such table"},
+	}
+	for _, tt := range tests {
+		_, err := ReadSQLiteTable(filepath.Join("testdata", "tables.db"), tt.table)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want one mentioning %q", tt.table, err, tt.want)
+		}
+	}
+	if _, err := ReadSQLiteTable(filepath.Join("testdata", "sqlite.py"), "big"); err == nil {
+		t.Error("reading a file that is not a database succeeded")
+	}
+}
+
+func TestSQLiteColumns(t *testing.T) {
+	tests := []struct {
+		sql          string
+		cols         []string
+		defaults     []string
+		rowidCol     int
+		withoutRowid bool
+	}{
+		{"CREATE TABLE t (a, b)", []string{"a", "b"}, []string{"", ""}, -1, false},
+		{"CREATE TABLE t (x TEXT, id INTEGER PRIMARY KEY)", []string{"x", "id"}, []string{"", ""}, 1, false},
+		{"CREATE TABLE t (k PRIMARY KEY, v) WITHOUT ROWID", []string{"k", "v"}, []string{"", ""}, -1, true},
+		{
+			`CREATE TABLE t ("my col" TEXT DEFAULT 'a,b', n INT DEFAULT -0x10, r DEFAULT +1e3, "default" DEFAULT(TRUE), z DEFAULT CURRENT_TIME, h DEFAULT x'0a0b', q TEXT DEFAULT 'it''s')`,
+			[]string{"my col", "n", "r", "default", "z", "h", "q"},
+			[]string{"a,b", "-16", "1000", "1", "", "(blob, 2 bytes)", "it's"},
+			-1, false,
+		},
+	}
+	for _, tt := range tests {
+		cols, defaults, rowidCol, withoutRowid := sqliteColumns(tt.sql)
+		if !reflect.DeepEqual(cols, tt.cols) || !reflect.DeepEqual(defaults, tt.defaults) || rowidCol != tt.rowidCol || withoutRowid != tt.withoutRowid {
+			t.Errorf("%s:\ngot  %q %q %d %v\nwant %q %q %d %v", tt.sql, cols, defaults, rowidCol, withoutRowid, tt.cols, tt.defaults, tt.rowidCol, tt.withoutRowid)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cellcanvas
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateVersion is the state.yml format written by this build. Bump it when
+// a change to StateFile would be misread by an older build, or when an
+// older file needs rewriting to be read correctly, and add the step to
+// stateMigrations.
+const StateVersion = 2
+
+// stateMigrations[v] rewrites a decoded state file of version v into
+// version v+1. Files written before versions were recorded are version 0.
+var stateMigrations = []func(doc map[string]any) error{
+	// 0 -> 1: only adds the version field
+	func(map[string]any) error { return nil },
+	// 1 -> 2: panels gain number_locale, date_columns, units and
+	// delimiter, which change how their CSV is read; older files lack
+	// them and read as before
+	func(map[string]any) error { return nil },
+}
+
+// decodeStateFile parses the YAML state b read from path, migrating files
+// of older versions first. Files of a newer version than StateVersion are
+// refused: fields this build does not know would be dropped on the next
+// save.
+func decodeStateFile(path string, b []byte) (*StateFile, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &FileError{Code: CodeParse, Path: path, Err: err}
+	}
+	v := 0
+	switch n := doc["version"].(type) {
+	case nil:
+	case int:
+		v = n
+	default:
+		return nil, &FileError{Code: CodeParse, Path: path, Err: fmt.Errorf("version %v is not a number", n)}
+	}
+	if v > StateVersion {
+		return nil, &FileError{Code: CodeNewerVersion, Path: path, Err: fmt.Errorf("state format version %d is newer than this build reads (%d)", v, StateVersion)}
+	}
+	if v < StateVersion {
+		for ; v < StateVersion; v++ {
+			if err := stateMigrations[v](doc); err != nil {
+				return nil, &FileError{Code: CodeParse, Path: path, Err: fmt.Errorf("upgrading from version %d: %w", v, err)}
+			}
+		}
+		doc["version"] = v
+		var err error
+		if b, err = yaml.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+	var sf StateFile
+	if err := yaml.Unmarshal(b, &sf); err != nil {
+		return nil, &FileError{Code: CodeParse, Path: path, Err: err}
+	}
+	return &sf, nil
+}
@@ -0,0 +1,48 @@
+# Regenerates the SQLite fixtures sqlite_test.go reads:
+#
+#	python3 sqlite.py
+#
+# Small pages give the 3000 row table interior pages two levels deep and
+# make the long values spill onto overflow pages.
+import os
+import sqlite3
+
+here = os.path.dirname(os.path.abspath(__file__))
+
+
+def create(name, encoding):
+    path = os.path.join(here, name)
+    if os.path.exists(path):
+        os.remove(path)
+    db = sqlite3.connect(path)
+    db.execute("PRAGMA page_size = 512")
+    db.execute(f"PRAGMA encoding = '{encoding}'")
+    return db
+
+
+db = create("tables.db", "UTF-8")
+db.execute("CREATE TABLE big (id INTEGER PRIMARY KEY, name TEXT, score REAL)")
+db.executemany("INSERT INTO big VALUES (?, ?, ?)",
+               [(i * 2, f"row {i}", i / 4) for i in range(3000)])
+db.execute("CREATE TABLE notes (body TEXT, data BLOB)")
+db.executemany("INSERT INTO notes VALUES (?, ?)",
+               [("x" * n, bytes(n)) for n in (10, 600, 5000)])
+db.execute("CREATE TABLE altered (a, b)")
+db.execute("INSERT INTO altered VALUES (1, 'one'), (2, NULL)")
+db.execute("ALTER TABLE altered ADD COLUMN d DEFAULT 7")
+db.execute("ALTER TABLE altered ADD COLUMN e TEXT DEFAULT 'it''s'")
+db.execute("ALTER TABLE altered ADD COLUMN f REAL DEFAULT (-2.5)")
+db.execute("ALTER TABLE altered ADD COLUMN g DEFAULT NULL")
+db.execute("INSERT INTO altered VALUES (3, 'three', 8, 'set', 1.5, 'g')")
+db.execute("CREATE TABLE keyed (k TEXT PRIMARY KEY, v) WITHOUT ROWID")
+db.execute("INSERT INTO keyed VALUES ('a', 1)")
+db.commit()
+db.close()
+
+for name, encoding in (("utf16le.db", "UTF-16le"), ("utf16be.db", "UTF-16be")):
+    db = create(name, encoding)
+    db.execute("CREATE TABLE words (w TEXT)")
+    db.executemany("INSERT INTO words VALUES (?)",
+                   [("héllo",), ("日本語",), ("ü" * 1000,)])
+    db.commit()
+    db.close()
@@ -0,0 +1,78 @@
+package cellcanvas
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// formulaRange matches A1-style references and A1:B2 ranges inside a
+// formula.
+var formulaRange = regexp.MustCompile(`\b([A-Za-z]{1,3}[0-9]+)(?::([A-Za-z]{1,3}[0-9]+))?\b`)
+
+// CellRange is an inclusive block of cells; a single cell has Col0 == Col1
+// and Row0 == Row1.
+type CellRange struct {
+	Col0, Row0, Col1, Row1 int
+}
+
+// Contains reports whether col,row lies inside r.
+func (r CellRange) Contains(col, row int) bool {
+	return col >= r.Col0 && col <= r.Col1 && row >= r.Row0 && row <= r.Row1
+}
+
+// FormulaRefs returns the cells and ranges referred to by v, a formula
+// ("=..." cell). Other values refer to nothing.
+func FormulaRefs(v string) []CellRange {
+	if !strings.HasPrefix(v, "=") {
+		return nil
+	}
+	var refs []CellRange
+	for _, m := range formulaRange.FindAllStringSubmatch(formulaRefText(v), -1) {
+		c0, r0, err := ParseCellRef(m[1])
+		if err != nil {
+			continue
+		}
+		c1, r1 := c0, r0
+		if m[2] != "" {
+			if c1, r1, err = ParseCellRef(m[2]); err != nil {
+				continue
+			}
+		}
+		refs = append(refs, CellRange{Col0: min(c0, c1), Row0: min(r0, r1), Col1: max(c0, c1), Row1: max(r0, r1)})
+	}
+	return refs
+}
+
+// Precedents returns the cells and ranges the formula at col,row refers to.
+func (p *Panel) Precedents(col, row int) []CellRange {
+	return FormulaRefs(p.RawCell(col, row))
+}
+
+// Dependents returns the formula cells that refer to col,row, directly or
+// through a range, in row order. Like the other formula checks it looks at
+// the edited cells only, not at the data of paged panels.
+func (p *Panel) Dependents(col, row int) []CellRange {
+	var deps []CellRange
+	for ref, v := range p.Cells {
+		if !strings.HasPrefix(v, "=") {
+			continue
+		}
+		for _, r := range FormulaRefs(v) {
+			if !r.Contains(col, row) {
+				continue
+			}
+			if c, rw, err := ParseCellRef(ref); err == nil {
+				deps = append(deps, CellRange{Col0: c, Row0: rw, Col1: c, Row1: rw})
+			}
+			break
+		}
+	}
+	sort.Slice(deps, func(a, b int) bool {
+		if deps[a].Row0 != deps[b].Row0 {
+			return deps[a].Row0 < deps[b].Row0
+		}
+		return deps[a].Col0 < deps[b].Col0
+	})
+	return deps
+}
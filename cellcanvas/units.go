@@ -0,0 +1,66 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnUnit tags column Col with Unit, like "ms", "MB" or "USD". The unit
+// is shown after the column's numbers (see DisplayCell) and is not stored in
+// the cells, which keep plain numbers.
+type ColumnUnit struct {
+	Col  int    `yaml:"col"`
+	Unit string `yaml:"unit"`
+}
+
+// UnitAt returns col's unit, or "".
+func (p *Panel) UnitAt(col int) string {
+	for _, u := range p.Units {
+		if u.Col == col {
+			return u.Unit
+		}
+	}
+	return ""
+}
+
+// SetUnit sets or, with an empty unit, removes col's unit. It returns the
+// number of cells below the header that are not numbers of the unit.
+func (p *Panel) SetUnit(col int, unit string) (bad int) {
+	unit = strings.TrimSpace(unit)
+	for i, u := range p.Units {
+		if u.Col == col {
+			p.Units = append(p.Units[:i], p.Units[i+1:]...)
+			break
+		}
+	}
+	if unit == "" {
+		return 0
+	}
+	p.Units = append(p.Units, ColumnUnit{Col: col, Unit: unit})
+	for row := 1; row < p.Rows; row++ {
+		v := p.RawCell(col, row)
+		if _, err := p.UnitValue(col, row, v); err != nil {
+			bad++
+		}
+	}
+	return bad
+}
+
+// UnitValue checks v, about to be written at col,row, against col's unit.
+// A number, optionally followed by the unit ("250 ms"), is returned as the
+// bare number; anything else but an empty cell or a formula is an error.
+// Without a unit, and in the header row, v is returned as it is.
+func (p *Panel) UnitValue(col, row int, v string) (string, error) {
+	unit := p.UnitAt(col)
+	if unit == "" || row == 0 || strings.TrimSpace(v) == "" || IsFormula(v) {
+		return v, nil
+	}
+	s := strings.TrimSpace(v)
+	if n := len(s) - len(unit); n > 0 && strings.EqualFold(s[n:], unit) {
+		s = strings.TrimSpace(s[:n])
+	}
+	if _, err := p.Numbers().Parse(s); err != nil {
+		return "", fmt.Errorf("column %s holds numbers in %s, not %q", ColToLetters(col), unit, v)
+	}
+	return s, nil
+}
@@ -0,0 +1,81 @@
+package cellcanvas
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Linked views: a panel with View set shows the same data as the other
+// panels saved to its file. They share one cell map, so an edit in any of
+// them shows in all, and SaveState writes the file once. Without View,
+// panels that happen to name the same file are independent and SaveState
+// moves the later ones to new files instead of letting them clobber it.
+
+// linked reports whether a and b are views of one file.
+func linked(a, b *Panel) bool {
+	return a != b && (a.View || b.View) && a.Filename != "" && filepath.Base(a.Filename) == filepath.Base(b.Filename)
+}
+
+// Linked reports whether panels i and j are views of one file.
+func (w *Workspace) Linked(i, j int) bool {
+	if i < 0 || j < 0 || i >= len(w.Panels) || j >= len(w.Panels) {
+		return false
+	}
+	return linked(&w.Panels[i], &w.Panels[j])
+}
+
+// SyncViews makes every view linked to panel i share its data and size.
+// Call it after panel i's content was replaced, resized or edited.
+func (w *Workspace) SyncViews(i int) {
+	if i < 0 || i >= len(w.Panels) {
+		return
+	}
+	src := &w.Panels[i]
+	for j := range w.Panels {
+		v := &w.Panels[j]
+		if !linked(src, v) {
+			continue
+		}
+		store := v.Paged
+		v.ReplaceContent(src)
+		w.ReleaseStore(store)
+		v.Filename = src.Filename
+		v.Loaded = src.Loaded
+		v.TrackSources, v.Sources = src.TrackSources, src.Sources
+	}
+}
+
+// AddView appends a linked view of panel i at x, y and returns its index.
+// A panel without a file is given one so the link survives a save.
+func (w *Workspace) AddView(i, x, y int) int {
+	if i < 0 || i >= len(w.Panels) {
+		return -1
+	}
+	src := &w.Panels[i]
+	if src.Filename == "" {
+		src.Filename = w.UnusedFilename()
+	}
+	v := NewBlankPanel(x, y, src.Cols, src.Rows)
+	v.CellW, v.CellH = src.CellW, src.CellH
+	v.FixedCellW, v.FixedCellH = src.FixedCellW, src.FixedCellH
+	v.ColWidths = append([]int(nil), src.ColWidths...)
+	v.Filename = src.Filename
+	v.View = true
+	v.ReplaceContent(src)
+	v.Loaded = src.Loaded
+	return w.AddPanel(v)
+}
+
+// UnusedFilename returns a generated panel_N.csv name no panel uses.
+func (w *Workspace) UnusedFilename() string {
+	used := make(map[string]bool)
+	for i := range w.Panels {
+		used[filepath.Base(w.Panels[i].Filename)] = true
+	}
+	for n := len(w.Panels) + 1; ; n++ {
+		name := fmt.Sprintf("panel_%d.csv", n)
+		if !used[name] {
+			return name
+		}
+	}
+}
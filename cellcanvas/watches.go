@@ -0,0 +1,63 @@
+package cellcanvas
+
+// Watch pins a cell so its value can be followed while its panel is off
+// screen. Panel indexes Workspace.Panels.
+type Watch struct {
+	Panel int    `yaml:"panel"`
+	Cell  string `yaml:"cell"`
+}
+
+// FindWatch returns the index of the watch on col,row of panel, or -1.
+func (w *Workspace) FindWatch(panel, col, row int) int {
+	ref := CellRef(col, row)
+	for i, wt := range w.Watches {
+		if wt.Panel == panel && wt.Cell == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddWatch pins col,row of panel. It reports false when the cell is
+// already watched.
+func (w *Workspace) AddWatch(panel, col, row int) bool {
+	if w.FindWatch(panel, col, row) >= 0 {
+		return false
+	}
+	w.Watches = append(w.Watches, Watch{Panel: panel, Cell: CellRef(col, row)})
+	return true
+}
+
+// RemoveWatch unpins watch i.
+func (w *Workspace) RemoveWatch(i int) {
+	if i >= 0 && i < len(w.Watches) {
+		w.Watches = append(w.Watches[:i], w.Watches[i+1:]...)
+	}
+}
+
+// WatchValue returns the current value of watch i and whether its panel
+// still exists.
+func (w *Workspace) WatchValue(i int) (string, bool) {
+	wt := w.Watches[i]
+	col, row, err := ParseCellRef(wt.Cell)
+	if err != nil || wt.Panel < 0 || wt.Panel >= len(w.Panels) {
+		return "", false
+	}
+	return w.Panels[wt.Panel].GetCell(col, row), true
+}
+
+// dropWatchPanel forgets the watches on panel i and renumbers those on
+// later panels after it is removed.
+func (w *Workspace) dropWatchPanel(i int) {
+	kept := w.Watches[:0]
+	for _, wt := range w.Watches {
+		switch {
+		case wt.Panel == i:
+			continue
+		case wt.Panel > i:
+			wt.Panel--
+		}
+		kept = append(kept, wt)
+	}
+	w.Watches = kept
+}
@@ -0,0 +1,444 @@
+package cellcanvas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StatePanel is a compact pointer to panel data stored in YAML state.
+type StatePanel struct {
+	X        int     `yaml:"x"`
+	Y        int     `yaml:"y"`
+	Filename string  `yaml:"file"`
+	Name     string  `yaml:"name,omitempty"`
+	Source   *Source `yaml:"source,omitempty"`
+	// ColWidths holds custom column widths in pixels; 0 keeps the default
+	ColWidths []int  `yaml:"col_widths,omitempty,flow"`
+	Wrap      bool   `yaml:"wrap,omitempty"`
+	Accent    string `yaml:"accent,omitempty"`
+	Icon      string `yaml:"icon,omitempty"`
+	FontSize  int    `yaml:"font_size,omitempty"`
+	Font      string `yaml:"font,omitempty"`
+	// CellW and CellH are the panel's fixed cell size; 0 follows the font
+	CellW     int    `yaml:"cell_w,omitempty"`
+	CellH     int    `yaml:"cell_h,omitempty"`
+	Gridlines string `yaml:"gridlines,omitempty"`
+	Zebra     bool   `yaml:"zebra,omitempty"`
+	// Borders maps cell refs to border styles
+	Borders   map[string]string `yaml:"borders,omitempty"`
+	PrintArea string            `yaml:"print_area,omitempty"`
+	View      bool              `yaml:"view,omitempty"`
+	// TrackSources and Sources hold row provenance
+	TrackSources bool        `yaml:"track_sources,omitempty"`
+	Sources      []RowSource `yaml:"sources,omitempty"`
+	SampleRows   int         `yaml:"sample_rows,omitempty"`
+	Outliers     float64     `yaml:"outliers,omitempty"`
+	// Computed holds the computed column definitions
+	Computed []ComputedColumn `yaml:"computed,omitempty"`
+	// Query is the definition of a derived panel
+	Query *Query `yaml:"query,omitempty"`
+	// SQL is the definition of a SQL panel
+	SQL *SQLQuery `yaml:"sql,omitempty"`
+	// Selection is the range last selected in the panel
+	Selection Selection `yaml:"selection,omitempty,flow"`
+	// ExportTitle, ExportCaption and Watermark label the panel's PDF pages
+	ExportTitle   string `yaml:"export_title,omitempty"`
+	ExportCaption string `yaml:"export_caption,omitempty"`
+	Watermark     string `yaml:"watermark,omitempty"`
+	// Checksum is the SHA-256 of the CSV file as it was saved
+	Checksum string `yaml:"checksum,omitempty"`
+	// NumberLocale is the panel's number locale, when not the default
+	NumberLocale string `yaml:"number_locale,omitempty"`
+	// DateColumns lists the columns holding dates and their formats
+	DateColumns []DateColumn `yaml:"date_columns,omitempty"`
+	// ColumnFormats lists how numbers are shown in some columns
+	ColumnFormats []ColumnFormat `yaml:"column_formats,omitempty"`
+	// Units lists the units of some columns
+	Units []ColumnUnit `yaml:"units,omitempty"`
+	// ViewRows, ViewCols, ScrollRow and ScrollCol are the panel's window
+	// and how far it is scrolled
+	ViewRows  int `yaml:"view_rows,omitempty"`
+	ViewCols  int `yaml:"view_cols,omitempty"`
+	ScrollRow int `yaml:"scroll_row,omitempty"`
+	ScrollCol int `yaml:"scroll_col,omitempty"`
+	// Renderers lists the columns drawn other than as text
+	Renderers []ColumnRenderer `yaml:"renderers,omitempty"`
+	// Images shows image cells as thumbnails
+	Images bool `yaml:"images,omitempty"`
+	// Delimiter separates the fields of the panel's CSV; empty for commas
+	Delimiter string `yaml:"delimiter,omitempty"`
+}
+
+// NewStatePanel describes p in a state file, with its data stored in
+// filename.
+func NewStatePanel(p *Panel, filename string) StatePanel {
+	return StatePanel{X: p.X, Y: p.Y, Filename: filename, Name: p.Name, Source: p.Source, ColWidths: p.ColWidths, Wrap: p.Wrap, Accent: p.Accent, Icon: p.Icon, FontSize: p.FontSize, Font: p.Font, CellW: p.FixedCellW, CellH: p.FixedCellH, Gridlines: p.Gridlines, Zebra: p.Zebra, Borders: p.Borders, PrintArea: p.PrintArea, View: p.View, TrackSources: p.TrackSources, Sources: p.Sources, SampleRows: p.SampleRows, Outliers: p.Outliers, Computed: p.Computed, Query: p.Query, SQL: p.SQL, Selection: p.Selection, ExportTitle: p.ExportTitle, ExportCaption: p.ExportCaption, Watermark: p.Watermark, Checksum: p.Checksum, NumberLocale: p.NumberLocale, DateColumns: p.DateColumns, ColumnFormats: p.ColumnFormats, Units: p.Units, ViewRows: p.ViewRows, ViewCols: p.ViewCols, ScrollRow: p.ScrollRow, ScrollCol: p.ScrollCol, Renderers: p.Renderers, Images: p.Images, Delimiter: p.Delimiter}
+}
+
+// apply copies the panel settings recorded in sp (everything but the data
+// file) onto p.
+func (sp *StatePanel) apply(p *Panel) {
+	p.X = sp.X
+	p.Y = sp.Y
+	p.Name = sp.Name
+	p.Source = sp.Source
+	p.ColWidths = sp.ColWidths
+	p.Wrap = sp.Wrap
+	p.Accent = sp.Accent
+	p.Icon = sp.Icon
+	p.FontSize = sp.FontSize
+	p.Font = sp.Font
+	p.FixedCellW, p.FixedCellH = sp.CellW, sp.CellH
+	if sp.CellW > 0 {
+		p.CellW = sp.CellW
+	}
+	if sp.CellH > 0 {
+		p.CellH = sp.CellH
+	}
+	p.Gridlines = sp.Gridlines
+	p.Zebra = sp.Zebra
+	p.Borders = sp.Borders
+	p.PrintArea = sp.PrintArea
+	p.ExportTitle = sp.ExportTitle
+	p.ExportCaption = sp.ExportCaption
+	p.Watermark = sp.Watermark
+	p.Checksum = sp.Checksum
+	p.NumberLocale = sp.NumberLocale
+	p.DateColumns = sp.DateColumns
+	p.ColumnFormats = sp.ColumnFormats
+	p.Units = sp.Units
+	p.ViewRows, p.ViewCols = sp.ViewRows, sp.ViewCols
+	p.ScrollRow, p.ScrollCol = sp.ScrollRow, sp.ScrollCol
+	p.Renderers = sp.Renderers
+	p.Images = sp.Images
+	p.Delimiter = sp.Delimiter
+	p.View = sp.View
+	p.TrackSources = sp.TrackSources
+	p.Sources = sp.Sources
+	p.SampleRows = sp.SampleRows
+	p.Outliers = sp.Outliers
+	p.Computed = sp.Computed
+	p.Query = sp.Query
+	p.SQL = sp.SQL
+	p.Selection = sp.Selection
+}
+
+// StateFile is the YAML document stored in state.yml.
+type StateFile struct {
+	// Version is the format version; see StateVersion
+	Version int          `yaml:"version"`
+	CamX    float64      `yaml:"cam_x"`
+	CamY    float64      `yaml:"cam_y"`
+	Panels  []StatePanel `yaml:"panels"`
+	// Zoom is the camera zoom factor; 0 is the same as 1
+	Zoom float64 `yaml:"zoom,omitempty"`
+	// Layouts are the named panel arrangements saved in the workspace
+	Layouts []Layout `yaml:"layouts,omitempty"`
+	// Watches are the cells pinned to the watch window
+	Watches []Watch `yaml:"watches,omitempty"`
+	// Scenarios are the named sets of input values
+	Scenarios []Scenario `yaml:"scenarios,omitempty"`
+	// ActivePanel is the index of the panel that was active
+	ActivePanel int `yaml:"active_panel,omitempty"`
+}
+
+// ReadStateFile parses the YAML state file at path.
+func ReadStateFile(path string) (*StateFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStateFile(path, b)
+}
+
+// WriteStateFile encodes sf as YAML to path, replacing it atomically.
+func WriteStateFile(path string, sf *StateFile) error {
+	return WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return encodeStateFile(w, sf)
+	})
+}
+
+// encodeStateFile writes sf as YAML, stamped with StateVersion.
+func encodeStateFile(w io.Writer, sf *StateFile) error {
+	sf.Version = StateVersion
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(sf); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Workspace is the set of panels on a canvas together with the camera
+// position that was saved with them.
+type Workspace struct {
+	Panels     []Panel
+	CamX, CamY float64
+	// Zoom scales the canvas on screen: 2 draws it twice as large, 0.5 at
+	// half size; 0 is the same as 1
+	Zoom float64
+	// Layouts holds named arrangements of the panels (see SaveLayout)
+	Layouts []Layout
+	// Watches holds the cells pinned to the watch window (see AddWatch)
+	Watches []Watch
+	// Scenarios holds named sets of input values (see SaveScenario)
+	Scenarios []Scenario
+	// ActivePanel is the index of the active panel, kept up to date by the
+	// UI so it is saved with the workspace
+	ActivePanel int
+}
+
+// Open reads a state file and synchronously loads every panel CSV it
+// references.
+func Open(statePath string) (*Workspace, error) {
+	w := &Workspace{}
+	if err := w.LoadState(statePath, nil); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// AddPanel appends p and returns its index.
+func (w *Workspace) AddPanel(p Panel) int {
+	w.Panels = append(w.Panels, p)
+	return len(w.Panels) - 1
+}
+
+// RemovePanelAt removes the panel at index i from the workspace if valid.
+// It does not delete any files on disk; it simply drops the panel from
+// the slice so SaveState will no longer reference it.
+func (w *Workspace) RemovePanelAt(i int) {
+	if i < 0 || i >= len(w.Panels) {
+		return
+	}
+	store := w.Panels[i].Paged
+	w.removePanelAt(i)
+	w.ReleaseStore(store)
+}
+
+// removePanelAt is RemovePanelAt leaving the panel's paged store open, for
+// copies of the workspace that share their stores with it.
+func (w *Workspace) removePanelAt(i int) {
+	w.Panels = append(w.Panels[:i], w.Panels[i+1:]...)
+	w.dropLayoutPanel(i)
+	w.dropWatchPanel(i)
+	w.dropScenarioPanel(i)
+	w.dropQueryPanel(i)
+}
+
+// ReleaseStore closes the paged store a panel of w stopped using, once its
+// content was replaced or it was removed, unless another panel (a linked
+// view) still shows it. A nil store is ignored.
+func (w *Workspace) ReleaseStore(s *PagedStore) {
+	if s == nil {
+		return
+	}
+	for i := range w.Panels {
+		if w.Panels[i].Paged == s {
+			return
+		}
+	}
+	s.Close()
+}
+
+// CloseStores closes the paged stores of all panels, deleting their
+// temporary files. Call it on shutdown and before w is replaced.
+func (w *Workspace) CloseStores() {
+	for i := range w.Panels {
+		if s := w.Panels[i].Paged; s != nil {
+			s.Close()
+		}
+	}
+}
+
+// LoadingPanel returns the index of the first panel, scratch panels aside,
+// that is a placeholder for a file still loading or that failed to load,
+// or -1 when every panel holds its data.
+func (w *Workspace) LoadingPanel() int {
+	for i := range w.Panels {
+		if !w.Panels[i].Loaded && !w.Panels[i].Scratch {
+			return i
+		}
+	}
+	return -1
+}
+
+// SaveState writes a small YAML file describing camera and panel pointers.
+// Each panel is saved as a separate CSV file next to the YAML file when the
+// panel has no Filename yet (or when force is true). Filenames in the YAML are
+// relative to the YAML file.
+//
+// The save is all-or-nothing: every file is written to a temporary first and
+// only renamed into place once all of them were written, state.yml last, so
+// a crash or error mid-save leaves the previous workspace intact. Scratch
+// panels are not saved, and the CSVs of panels that are not Loaded are
+// left as they are.
+func (w *Workspace) SaveState(statePath string) error {
+	if saved, keep := w.withoutScratch(); saved != w {
+		defer w.keepSaved(saved, keep)
+		return saved.SaveState(statePath)
+	}
+	dir := filepath.Dir(statePath)
+
+	var txn fileTxn
+	sf := StateFile{CamX: w.CamX, CamY: w.CamY, Zoom: w.Zoom, Layouts: w.Layouts, Watches: w.Watches, Scenarios: w.Scenarios, ActivePanel: w.ActivePanel}
+	// written maps each CSV written so far to the panel that wrote it
+	written := make(map[string]int)
+	for i := range w.Panels {
+		p := &w.Panels[i]
+		// if no filename assigned, create one
+		if p.Filename == "" {
+			// Use 1-based numbering for generated panel filenames to be more user-friendly
+			p.Filename = fmt.Sprintf("panel_%d.csv", i+1)
+		}
+		// write panel CSV next to state file
+		csvPath := p.Filename
+		if !filepath.IsAbs(csvPath) {
+			csvPath = filepath.Join(dir, csvPath)
+		}
+		if j, ok := written[filepath.Clean(csvPath)]; ok {
+			if linked(p, &w.Panels[j]) {
+				// a view: the data was written with the first panel
+				p.Checksum = w.Panels[j].Checksum
+				sf.Panels = append(sf.Panels, NewStatePanel(p, p.Filename))
+				continue
+			}
+			// an unrelated panel naming the same file would clobber it
+			p.Filename = w.UnusedFilename()
+			csvPath = filepath.Join(dir, p.Filename)
+		}
+		written[filepath.Clean(csvPath)] = i
+		if p.SampleRows > 0 || !p.Loaded {
+			// a preview, or a placeholder still loading or that failed to
+			// load: the file holds more than the panel
+			sf.Panels = append(sf.Panels, NewStatePanel(p, p.Filename))
+			continue
+		}
+		h := sha256.New()
+		if err := txn.write(csvPath, 0644, func(out io.Writer) error { return WritePanelCSV(io.MultiWriter(out, h), p) }); err != nil {
+			return err
+		}
+		p.Checksum = hex.EncodeToString(h.Sum(nil))
+
+		sf.Panels = append(sf.Panels, NewStatePanel(p, p.Filename))
+	}
+
+	if err := txn.write(statePath, 0644, func(out io.Writer) error { return encodeStateFile(out, &sf) }); err != nil {
+		return err
+	}
+	return txn.commit()
+}
+
+// LoadState reads YAML state and loads per-panel CSVs referenced by it.
+// Missing CSVs are ignored (panel will keep its current contents).
+//
+// When schedule is non-nil, panels that reference a CSV are left as blank,
+// not-Loaded placeholders and schedule is called with the panel index and
+// resolved CSV path so the caller can load it in the background (the
+// panel's settings, such as SampleRows, are already in place). With a nil
+// schedule the CSVs are loaded synchronously.
+func (w *Workspace) LoadState(statePath string, schedule func(idx int, csvPath string)) error {
+	sf, err := ReadStateFile(statePath)
+	if err != nil {
+		return err
+	}
+
+	// ensure we have enough panels
+	if len(sf.Panels) > len(w.Panels) {
+		// append new blank panels to match count
+		for i := len(w.Panels); i < len(sf.Panels); i++ {
+			// New panels created due to state having more panels than current
+			// should start empty with a small default size (5x5).
+			w.Panels = append(w.Panels, NewBlankPanel(20+i*32, 20+i*32, 5, 5))
+		}
+	}
+
+	// load each panel referenced. We do not block while loading CSVs; instead
+	// place blank panels at the configured positions and schedule background
+	// CSV loads so the UI can appear immediately.
+	baseDir := filepath.Dir(statePath)
+	for i, sp := range sf.Panels {
+		if i >= len(w.Panels) {
+			break
+		}
+		p := &w.Panels[i]
+		sp.apply(p)
+		// Make sure the panel is empty/blank until CSV load completes.
+		p.Cells = make(map[string]string)
+		if store := p.Paged; store != nil {
+			p.Paged = nil
+			w.ReleaseStore(store)
+		}
+		p.Rows = 5
+		p.Cols = 5
+		// If the panel references a CSV file, mark it not loaded and
+		// schedule loading; if there's no file, the panel is considered
+		// ready/active.
+		p.Loaded = (sp.Filename == "")
+		if sp.View && w.hasEarlierLink(i) {
+			// shares the data of an earlier panel once that one is loaded
+			p.Filename = sp.Filename
+			continue
+		}
+		if sp.Filename != "" {
+			csvPath := sp.Filename
+			if !filepath.IsAbs(csvPath) {
+				csvPath = filepath.Join(baseDir, csvPath)
+			}
+			p.Filename = sp.Filename
+			// schedule background load; safe even if the loader fails
+			if schedule != nil {
+				schedule(i, csvPath)
+			} else {
+				// synchronous load
+				tmp := NewBlankPanel(0, 0, 1, 1)
+				tmp.Delimiter = p.Delimiter
+				if sampled, err := LoadPanelFile(csvPath, &tmp, p.SampleRows); err == nil && !sampled {
+					p.SampleRows = 0
+				}
+				store := p.Paged
+				p.ReplaceContent(&tmp)
+				w.ReleaseStore(store)
+				p.NormalizeDates()
+				p.Filename = filepath.Base(csvPath)
+				p.Loaded = (tmp.Rows > 0 && tmp.Cols > 0) || len(tmp.Cells) > 0
+			}
+		}
+	}
+	w.CamX = sf.CamX
+	w.CamY = sf.CamY
+	w.Zoom = sf.Zoom
+	w.Layouts = sf.Layouts
+	w.Watches = sf.Watches
+	w.Scenarios = sf.Scenarios
+	w.ActivePanel = sf.ActivePanel
+	if schedule == nil {
+		w.syncAllViews()
+	}
+	return nil
+}
+
+// hasEarlierLink reports whether a panel before i is linked to it.
+func (w *Workspace) hasEarlierLink(i int) bool {
+	for j := 0; j < i; j++ {
+		if linked(&w.Panels[j], &w.Panels[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncAllViews shares the data of every panel with its views.
+func (w *Workspace) syncAllViews() {
+	for i := range w.Panels {
+		if !w.Panels[i].View {
+			w.SyncViews(i)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cellcanvas
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xlsx style indexes into the cellXfs table written by xlsxStyles.
+const (
+	xlsxStyleDefault = 0
+	xlsxStyleHeader  = 1 // bold, used for the key header row of feed panels
+	xlsxStyleWrap    = 2 // multi-line text
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment wrapText="1" vertical="top"/></xf></cellXfs>
+</styleSheet>
+`
+
+// WriteXLSX writes panels to an Office Open XML workbook, one sheet per
+// panel named after SheetName. Column widths follow the panel's columns,
+// numeric cells are stored as numbers, multi-line cells (and every cell of a
+// wrapping panel) wrap, and the header
+// row of a keyed feed panel is bold.
+func WriteXLSX(path string, panels []Panel) error {
+	return WriteFileAtomic(path, 0644, func(f io.Writer) error {
+		return writeXLSXZip(f, panels)
+	})
+}
+
+func writeXLSXZip(f io.Writer, panels []Panel) error {
+	zw := zip.NewWriter(f)
+
+	names := uniqueSheetNames(panels)
+	var types, sheets, rels strings.Builder
+	for i := range panels {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(names[i]), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(panels)+1)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` + types.String() + `</Types>
+`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>
+`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>
+`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>
+`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.body); err != nil {
+			return err
+		}
+	}
+	for i := range panels {
+		w, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := writeXLSXSheet(w, &panels[i]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeXLSXSheet(w io.Writer, p *Panel) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if p.Cols > 0 {
+		// Excel widths are in characters of the default font, about 7px each
+		cellW := p.CellW
+		if cellW <= 0 {
+			cellW = DefaultCellW
+		}
+		b.WriteString("<cols>")
+		// one <col> per run of equally wide columns
+		for c := 0; c < p.Cols; {
+			w := p.ColWidth(c)
+			if w <= 0 {
+				w = cellW
+			}
+			end := c + 1
+			for end < p.Cols && p.ColWidth(end) == p.ColWidth(c) {
+				end++
+			}
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.2f" customWidth="1"/>`, c+1, end, float64(w)/7)
+			c = end
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	header := p.Source != nil && p.Source.Header
+	for r := 0; r < p.Rows; r++ {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c := 0; c < p.Cols; c++ {
+			v := p.GetCell(c, r)
+			if v == "" {
+				continue
+			}
+			ref := CellRef(c, r)
+			style := xlsxStyleDefault
+			switch {
+			case header && r == 0:
+				style = xlsxStyleHeader
+			case p.Wrap || strings.Contains(v, "\n"):
+				style = xlsxStyleWrap
+			}
+			if num, ok := exportNumber(p, v); ok {
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, num)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(v))
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData></worksheet>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
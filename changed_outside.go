@@ -0,0 +1,65 @@
+package main
+
+import "fmt"
+
+// ChangedOutside asks, one file at a time, whether to accept panel CSVs
+// that were edited outside CellCanvas since the workspace was last saved
+// (see FileChangedOutside). There is no diff view yet, so the choice is
+// between taking the file as it is now and closing its panel.
+type ChangedOutside struct {
+	queue []FileChangedOutside
+}
+
+func NewChangedOutside() *ChangedOutside {
+	return &ChangedOutside{}
+}
+
+// Observe queues changed files and keeps the queue's panel indexes valid.
+func (co *ChangedOutside) Observe(g *Game, e Event) {
+	switch ev := e.(type) {
+	case FileChangedOutside:
+		co.queue = append(co.queue, ev)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("%s changed outside CellCanvas since it was saved", ev.File)})
+	case PanelRemoved:
+		kept := co.queue[:0]
+		for _, q := range co.queue {
+			switch {
+			case q.Panel == ev.Panel:
+				continue
+			case q.Panel > ev.Panel:
+				q.Panel--
+			}
+			kept = append(kept, q)
+		}
+		co.queue = kept
+	case WorkspaceLoaded:
+		co.queue = nil
+	}
+}
+
+// changedOutsideChoices are the answers to a file changed outside the app.
+var changedOutsideChoices = []string{
+	"Accept the file as it is now",
+	"Close the panel, leaving the file as it is",
+}
+
+// Update asks about the next queued file once nothing else has the input.
+func (co *ChangedOutside) Update(g *Game) {
+	if len(co.queue) == 0 || g.focus() != FocusCanvas {
+		return
+	}
+	q := co.queue[0]
+	co.queue = co.queue[1:]
+	if q.Panel >= len(g.canvas.Panels) {
+		return
+	}
+	g.power.redraw = true
+	title := fmt.Sprintf("%s (Panel %d) was changed outside CellCanvas since it was saved", q.File, q.Panel+1)
+	g.picker.Open(title, changedOutsideChoices, func(g *Game, i int) {
+		if i != 1 || q.Panel >= len(g.canvas.Panels) || g.canvas.Panels[q.Panel].Filename != q.File {
+			return
+		}
+		removePanel(g, q.Panel)
+		g.events.Publish(LogMessage{Text: "closed " + q.File + "; the file was not changed"})
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// clipboardPollInterval is how often the watcher samples the OS clipboard.
+const clipboardPollInterval = 500 * time.Millisecond
+
+// runClipboardFeed polls the clipboard and sends a (time, text) row for
+// every new text snippet. Whatever is on the clipboard when the watcher
+// starts is not collected.
+func runClipboardFeed(ctx context.Context, send func(feedMsg)) error {
+	if clipboard.Unsupported {
+		return fmt.Errorf("clipboard access is not supported on this system")
+	}
+	last, _ := clipboard.ReadAll()
+	t := time.NewTicker(clipboardPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		text, err := clipboard.ReadAll()
+		if err != nil {
+			return err
+		}
+		if text == "" || text == last {
+			continue
+		}
+		last = text
+		send(feedMsg{row: []string{time.Now().Format("2006-01-02 15:04:05"), text}})
+	}
+}
+
+// toggleClipboardCollector makes panel i the clipboard collector, or turns
+// collection off if it already is. Only one panel collects at a time.
+func toggleClipboardCollector(g *Game, i int) {
+	if i < 0 || i >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "No panel to collect into"})
+		return
+	}
+	p := &g.canvas.Panels[i]
+	if p.Source != nil && p.Source.Kind == cellcanvas.SourceClipboard {
+		p.Source = nil
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: clipboard collection off", i+1)})
+		return
+	}
+	if p.Source != nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d already has a live source", i+1)})
+		return
+	}
+	for j := range g.canvas.Panels {
+		if src := g.canvas.Panels[j].Source; src != nil && src.Kind == cellcanvas.SourceClipboard {
+			g.canvas.Panels[j].Source = nil
+		}
+	}
+	p.Source = &cellcanvas.Source{Kind: cellcanvas.SourceClipboard}
+	if len(p.Cells) == 0 {
+		p.Rows = 0
+		p.Cols = 2
+		p.AppendRow([]string{"time", "text"})
+		p.Source.Header = true
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: collecting clipboard", i+1)})
+}
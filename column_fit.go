@@ -0,0 +1,37 @@
+package main
+
+// Auto-fit sizing.
+const (
+	// autoFitSampleRows bounds how many rows are measured; larger columns
+	// are sampled evenly from top to bottom
+	autoFitSampleRows = 2000
+	autoFitMinW       = 24
+	autoFitMaxW       = 480
+	// colEdgeSlop is how close (in pixels) a click must land to a column
+	// boundary to count as a click on it
+	colEdgeSlop = 3
+)
+
+// autoFitColumn sets column col of panel pi to the width of its widest
+// value, padded like drawn cell text.
+func autoFitColumn(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	if !p.Loaded || col < 0 || col >= p.Cols {
+		return
+	}
+	step := max(1, p.Rows/autoFitSampleRows)
+	face := panelFonts.face(p)
+	w := 0
+	for row := 0; row < p.Rows; row += step {
+		w = max(w, textWidth(face, p.DisplayCell(col, row)))
+	}
+	w = min(max(w+2*PanelInnerPadding, autoFitMinW), autoFitMaxW)
+	if w == p.ColWidth(col) {
+		return
+	}
+	p.SetColWidth(col, w)
+	g.events.Publish(ColumnResized{Panel: pi, Col: col, Width: w})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// promptUnit asks for the unit of column col of panel pi. The unit is shown
+// after the column's numbers and in its distribution; the cells keep plain
+// numbers, and edits that are not numbers are rejected.
+func promptUnit(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	letters := cellcanvas.ColToLetters(col)
+	cur := g.canvas.Panels[pi].UnitAt(col)
+	g.prompt.Open(fmt.Sprintf("Unit of Panel %d column %s", pi+1, letters), "ms, MB, USD; empty for none", cur, func(g *Game, unit string) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		bad := p.SetUnit(col, unit)
+		g.power.redraw = true
+		switch u := p.UnitAt(col); {
+		case u == "":
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s has no unit", letters)})
+		case bad > 0:
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s is in %s; %d cells are not numbers", letters, u, bad)})
+		default:
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s is in %s", letters, u)})
+		}
+	})
+}
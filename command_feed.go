@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// defaultCommandInterval is the refresh period offered for new command panels.
+const defaultCommandInterval = 5
+
+// runCommandFeed runs cfg.Command every cfg.Interval seconds and sends its
+// stdout as a grid that replaces the panel content. Each run is bounded by the
+// interval so a hung command cannot pile up.
+func runCommandFeed(ctx context.Context, cfg cellcanvas.Source, send func(feedMsg)) error {
+	if strings.TrimSpace(cfg.Command) == "" {
+		return fmt.Errorf("no command configured")
+	}
+	interval := time.Duration(max(cfg.Interval, 1)) * time.Second
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, interval)
+		out, err := shellCommand(runCtx, cfg.Command).Output()
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			send(feedMsg{status: fmt.Sprintf("%s: %v", cfg.Command, err)})
+		}
+		if out != nil {
+			send(feedMsg{grid: parseCommandOutput(string(out))})
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// shellCommand runs line through the platform shell so pipes and quoting work
+// as they do in a terminal.
+func shellCommand(ctx context.Context, line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", line)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+// parseCommandOutput splits command output into rows of columns. Output that
+// looks like CSV (any comma) is parsed as CSV, tab-separated output is split
+// on tabs, and anything else on runs of whitespace (ps, df, ls -l style).
+func parseCommandOutput(out string) [][]string {
+	out = strings.TrimRight(out, "\r\n")
+	if out == "" {
+		return [][]string{}
+	}
+	if strings.Contains(out, ",") {
+		r := csv.NewReader(strings.NewReader(out))
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		if recs, err := r.ReadAll(); err == nil {
+			return recs
+		}
+	}
+	var grid [][]string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.Contains(line, "\t") {
+			grid = append(grid, strings.Split(line, "\t"))
+		} else {
+			grid = append(grid, strings.Fields(line))
+		}
+	}
+	return grid
+}
+
+// promptNewCommandPanel asks for a command and refresh interval and adds a
+// command panel at world coordinates x, y.
+func promptNewCommandPanel(g *Game, x, y int) {
+	g.prompt.Open("Command to run", "output is parsed as CSV, tabs or whitespace columns", "", func(g *Game, line string) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return
+		}
+		g.prompt.Open("Refresh every N seconds", "", strconv.Itoa(defaultCommandInterval), func(g *Game, v string) {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || n < 1 {
+				n = defaultCommandInterval
+			}
+			p := cellcanvas.NewBlankPanel(x, y, 5, 5)
+			p.Source = &cellcanvas.Source{Kind: cellcanvas.SourceCommand, Command: line, Interval: n}
+			g.feeds.Approve(p.Source)
+			i := g.canvas.AddPanel(p)
+			g.events.Publish(PanelAdded{Panel: i})
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: every %ds: %s", i+1, n, line)})
+		})
+	})
+}
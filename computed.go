@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// computeChunk is how many rows one scheduler step recomputes.
+const computeChunk = 4096
+
+// ComputedColumns keeps the computed columns of every panel filled. An
+// edit recomputes its row right away; loads, appends and header changes
+// refill the whole panel on the frame scheduler.
+type ComputedColumns struct {
+	gen int
+	// jobs holds the generation of the running refill of each panel
+	jobs map[int]int
+}
+
+func NewComputedColumns() *ComputedColumns {
+	return &ComputedColumns{jobs: make(map[int]int)}
+}
+
+// Observe recomputes what an event changed.
+func (cc *ComputedColumns) Observe(g *Game, e Event) {
+	switch ev := e.(type) {
+	case CellChanged:
+		p := cc.panel(g, ev.Panel)
+		switch {
+		case p == nil:
+		case ev.Row == 0:
+			// a header was renamed: names may resolve differently
+			cc.Start(g, ev.Panel)
+		case p.ComputedAt(ev.Col) < 0:
+			p.ComputeRows(ev.Row, ev.Row+1)
+		}
+	case PanelLoaded:
+		if cc.panel(g, ev.Panel) != nil {
+			cc.Start(g, ev.Panel)
+		}
+	case RowsAppended:
+		if cc.panel(g, ev.Panel) != nil {
+			cc.Start(g, ev.Panel)
+		}
+	case PanelRemoved, WorkspaceLoaded:
+		cc.gen++
+	}
+}
+
+// panel returns panel pi when it has computed columns.
+func (cc *ComputedColumns) panel(g *Game, pi int) *cellcanvas.Panel {
+	if pi < 0 || pi >= len(g.canvas.Panels) || len(g.canvas.Panels[pi].Computed) == 0 {
+		return nil
+	}
+	return &g.canvas.Panels[pi]
+}
+
+// Start refills the computed columns of panel pi, replacing a refill
+// already running for it.
+func (cc *ComputedColumns) Start(g *Game, pi int) {
+	cc.jobs[pi]++
+	job, gen := cc.jobs[pi], cc.gen
+	row := 1
+	g.canvas.scheduler.Add(func() bool {
+		if gen != cc.gen || job != cc.jobs[pi] || pi >= len(g.canvas.Panels) {
+			return true
+		}
+		p := &g.canvas.Panels[pi]
+		end := min(row+computeChunk, p.Rows)
+		p.ComputeRows(row, end)
+		row = end
+		g.power.redraw = true
+		return row >= p.Rows
+	})
+}
+
+// promptComputed asks for the expression of column col of panel pi.
+func promptComputed(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	cur := ""
+	if i := p.ComputedAt(col); i >= 0 {
+		cur = p.Computed[i].Expr
+	}
+	title := "Computed column " + cellcanvas.ColToLetters(col)
+	g.prompt.Open(title, "expression over column headers, e.g. price * qty or [unit price] * 1.2; empty removes it", cur, func(g *Game, expr string) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		if err := p.SetComputed(col, expr); err != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("computed column: %v", err)})
+			return
+		}
+		if p.ComputedAt(col) < 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d column %s is no longer computed", pi+1, cellcanvas.ColToLetters(col))})
+			return
+		}
+		p.Cols = max(p.Cols, col+1)
+		if old := p.RawCell(col, 0); old == "" {
+			// name the column after its expression
+			p.SetCell(col, 0, expr)
+			g.events.Publish(CellChanged{Panel: pi, Col: col, Row: 0, Old: old, New: expr})
+		}
+		g.computed.Start(g, pi)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d column %s = %s", pi+1, cellcanvas.ColToLetters(col), expr)})
+	})
+}
+
+// computedBlocksEdit reports (and explains) that a cell cannot be edited
+// because its column is computed. The header stays editable.
+func computedBlocksEdit(g *Game, pi, col, row int) bool {
+	p := &g.canvas.Panels[pi]
+	if row == 0 || p.ComputedAt(col) < 0 {
+		return false
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s is computed; use Computed Column... to change it", cellcanvas.ColToLetters(col))})
+	return true
+}
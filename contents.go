@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// Contents overlay geometry and the characters shown per name.
+const (
+	contentsW       = 380
+	maxContentsName = 28
+)
+
+// Contents is a table of contents of the workspace, docked to the top left
+// of the screen: every panel with its name, file and size, the active one
+// highlighted. It is built from the panels each frame, so it follows
+// panels as they are added, renamed, resized and removed. Clicking an
+// entry jumps to its panel, the mouse wheel scrolls a long list and
+// clicking the title closes it.
+type Contents struct {
+	visible bool
+	// first is the index of the first panel listed
+	first int
+	// box geometry from the last Draw, used for hit testing
+	x, y, h, rows int
+}
+
+func NewContents() *Contents {
+	return &Contents{}
+}
+
+// Toggle shows or hides the contents.
+func (ct *Contents) Toggle(g *Game) {
+	ct.visible = !ct.visible
+	g.power.redraw = true
+}
+
+// HandleInput handles clicks and the wheel over the contents and reports
+// whether it took them.
+func (ct *Contents) HandleInput(g *Game) bool {
+	if !ct.visible || ct.h == 0 {
+		return false
+	}
+	mx, my := g.frame.CursorPosition()
+	if mx < ct.x || mx >= ct.x+contentsW || my < ct.y || my >= ct.y+ct.h {
+		return false
+	}
+	if _, dy := g.frame.Wheel(); dy != 0 && !g.frame.CtrlPressed() {
+		ct.first = max(0, min(ct.first-int(dy), len(g.canvas.Panels)-ct.rows))
+		g.power.redraw = true
+		return true
+	}
+	if !g.frame.MouseJustPressed(ebiten.MouseButtonLeft) {
+		return false
+	}
+	i := (my-ct.y)/watchRowH - 1
+	switch {
+	case i < 0:
+		ct.visible = false
+	case ct.first+i < len(g.canvas.Panels):
+		jumpToPanel(g, ct.first+i)
+	}
+	g.power.redraw = true
+	return true
+}
+
+// Draw renders the contents; nothing is drawn while hidden.
+func (ct *Contents) Draw(screen *ebiten.Image, g *Game, face font.Face) {
+	if !ct.visible {
+		ct.h = 0
+		return
+	}
+	panels := g.canvas.Panels
+	sh := screen.Bounds().Dy()
+	ct.rows = max(1, min(len(panels), (sh-watchTop-watchBottom)/watchRowH-1))
+	ct.first = max(0, min(ct.first, len(panels)-ct.rows))
+	ct.x, ct.y = watchMargin, watchTop
+	ct.h = (ct.rows + 1) * watchRowH
+	ebitenutil.DrawRect(screen, float64(ct.x), float64(ct.y), contentsW, float64(ct.h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(ct.x), float64(ct.y), contentsW, watchRowH, ColorOverlayBg)
+	ebitenutil.DrawRect(screen, float64(ct.x), float64(ct.y+watchRowH-1), contentsW, 1, ColorMenuBorder)
+	title := fmt.Sprintf("Contents (%d panels)", len(panels))
+	if len(panels) > ct.rows {
+		title = fmt.Sprintf("Contents (%d-%d of %d panels)", ct.first+1, ct.first+ct.rows, len(panels))
+	}
+	drawTextAt(screen, face, title, ct.x+PanelInnerPadding, ct.y+1, ColorText)
+	drawTextAt(screen, face, "x", ct.x+contentsW-PanelInnerPadding-textWidth(face, "x"), ct.y+1, ColorTextDim)
+	if len(panels) == 0 {
+		drawTextAt(screen, face, "no panels", ct.x+PanelInnerPadding, ct.y+watchRowH+1, ColorTextDim)
+		return
+	}
+
+	mx, my := g.frame.CursorPosition()
+	for i := ct.first; i < ct.first+ct.rows && i < len(panels); i++ {
+		p := &panels[i]
+		y := ct.y + (i-ct.first+1)*watchRowH
+		switch {
+		case i == g.input.activePanel:
+			ebitenutil.DrawRect(screen, float64(ct.x), float64(y), contentsW, watchRowH, ColorMenuHighlight)
+		case mx >= ct.x && mx < ct.x+contentsW && my >= y && my < y+watchRowH:
+			ebitenutil.DrawRect(screen, float64(ct.x), float64(y), contentsW, watchRowH, ColorMenuBorder)
+		}
+		name := truncateText(panelLabel(p, i), maxContentsName)
+		if p.Filename != "" {
+			name += "  " + truncateText(filepath.Base(p.Filename), maxContentsName)
+		}
+		size := fmt.Sprintf("%d x %d", p.Rows, p.Cols)
+		if !p.Loaded {
+			size = "loading"
+		}
+		drawTextAt(screen, face, fmt.Sprintf("%d  %s", i+1, name), ct.x+PanelInnerPadding, y+1, ColorText)
+		drawTextAt(screen, face, size, ct.x+contentsW-PanelInnerPadding-textWidth(face, size), y+1, ColorTextDim)
+	}
+}
+
+// jumpToPanel makes panel pi active and centers the camera on it, or on
+// its top-left part when it is larger than the screen.
+func jumpToPanel(g *Game, pi int) {
+	p := &g.canvas.Panels[pi]
+	w, h := g.viewSize()
+	g.canvas.CamX = float64(w/2 - p.X - min(p.Width(), w/2)/2)
+	g.canvas.CamY = float64(h/2 - p.Y - min(p.Height(), h/2)/2)
+	g.input.switchToPanel(g, pi)
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"golang.org/x/image/font"
 )
 
@@ -17,14 +16,98 @@ const (
 	MenuActionSavePanelToFile
 	MenuActionExportPanelToCSV
 	MenuActionDeletePanel
+	MenuActionToggleStats
+	MenuActionNewFeedPanel
+	MenuActionToggleClipboardCollector
+	MenuActionNewCommandPanel
+	MenuActionImportODS
+	MenuActionExportODS
+	MenuActionExportXLSX
+	MenuActionCellHistory
+	MenuActionExportAudit
+	MenuActionEncryptWorkspace
+	MenuActionAutoFitColumn
+	MenuActionToggleWrap
+	MenuActionPanelColor
+	MenuActionPanelIcon
+	MenuActionPanelFont
+	MenuActionGridlines
+	MenuActionToggleZebra
+	MenuActionCellBorder
+	MenuActionPagePreview
+	MenuActionSetPrintArea
+	MenuActionExportPDF
+	MenuActionSaveLayout
+	MenuActionSwitchLayout
+	MenuActionDeleteLayout
+	MenuActionCheckWorkspace
+	MenuActionNewView
+	MenuActionAppendCSV
+	MenuActionTrackSources
+	MenuActionRowDetails
+	MenuActionExportWithSources
+	MenuActionPreviewCSV
+	MenuActionLoadFullFile
+	MenuActionProfilePanel
+	MenuActionShowDistribution
+	MenuActionHighlightOutliers
+	MenuActionListOutliers
+	MenuActionWatchCell
+	MenuActionGoalSeek
+	MenuActionSaveScenario
+	MenuActionSwitchScenario
+	MenuActionDeleteScenario
+	MenuActionFillSampleData
+	MenuActionComputedColumn
+	MenuActionCellSize
+	MenuActionTruncatePanel
+	MenuActionToggleRulers
+	MenuActionNewDerivedPanel
+	MenuActionEditQuery
+	MenuActionImportFolder
+	MenuActionSendToScratch
+	MenuActionKeepScratch
+	MenuActionExportLabels
+	MenuActionDiskChanges
+	MenuActionExportSelection
+	MenuActionExportFiltered
+	MenuActionNumberLocale
+	MenuActionDateColumn
+	MenuActionColumnFormat
+	MenuActionColumnUnit
+	MenuActionPasteSpecial
+	MenuActionPasteAsPanel
+	MenuActionQueryPresets
+	MenuActionSearchAll
+	MenuActionExportPanelXLSX
+	MenuActionToggleImages
+	MenuActionViewImage
+	MenuActionImportJSON
+	MenuActionExportJSON
+	MenuActionImportSQLite
+	MenuActionCellRenderer
+	MenuActionNewSQLPanel
+	MenuActionEditSQL
+	MenuActionRefreshSQL
+	MenuActionImportMarkdown
+	MenuActionCopyMarkdown
+	MenuActionCopySelectionMarkdown
+	MenuActionDelimiter
+	MenuActionContents
 )
 
+// menuItem pairs a context menu label with the action it triggers.
+type menuItem struct {
+	label  string
+	action MenuAction
+}
+
 // ContextMenu encapsulates the state and behavior of a right-click context menu
 // It provides methods to show/hide, update based on input, and draw itself.
 type ContextMenu struct {
 	visible  bool
 	x, y     int
-	items    []string
+	items    []menuItem
 	selected int
 	// target panel index for operations that should act on a specific panel
 	targetPanel int
@@ -32,8 +115,92 @@ type ContextMenu struct {
 
 func NewContextMenu() *ContextMenu {
 	return &ContextMenu{
-		visible:     false,
-		items:       []string{"New Blank Panel", "Load Panel from File ...", "Save Panel To...", "Export to CSV...", "Delete Panel"},
+		visible: false,
+		items: []menuItem{
+			{"New Blank Panel", MenuActionNewBlankPanel},
+			{"New Live Feed Panel...", MenuActionNewFeedPanel},
+			{"New Command Panel...", MenuActionNewCommandPanel},
+			{"New Linked View", MenuActionNewView},
+			{"New Derived Panel...", MenuActionNewDerivedPanel},
+			{"Paste as New Panel", MenuActionPasteAsPanel},
+			{"Edit Query...", MenuActionEditQuery},
+			{"Query Presets...", MenuActionQueryPresets},
+			{"New SQL Panel...", MenuActionNewSQLPanel},
+			{"Edit SQL...", MenuActionEditSQL},
+			{"Refresh", MenuActionRefreshSQL},
+			{"Search All Panels...", MenuActionSearchAll},
+			{"Contents", MenuActionContents},
+			{"Load Panel from File ...", MenuActionLoadPanelFromFile},
+			{"Import Folder...", MenuActionImportFolder},
+			{"Preview CSV (first N rows)...", MenuActionPreviewCSV},
+			{"Load Full File", MenuActionLoadFullFile},
+			{"Append CSV to Panel...", MenuActionAppendCSV},
+			{"CSV Delimiter...", MenuActionDelimiter},
+			{"Track Row Sources", MenuActionTrackSources},
+			{"Row Details...", MenuActionRowDetails},
+			{"Profile Panel", MenuActionProfilePanel},
+			{"Show Distribution", MenuActionShowDistribution},
+			{"Highlight Outliers...", MenuActionHighlightOutliers},
+			{"Outliers...", MenuActionListOutliers},
+			{"Export CSV with Row Sources...", MenuActionExportWithSources},
+			{"Save Panel To...", MenuActionSavePanelToFile},
+			{"Export to CSV...", MenuActionExportPanelToCSV},
+			{"Export Selection to CSV...", MenuActionExportSelection},
+			{"Export Filtered Rows to CSV...", MenuActionExportFiltered},
+			{"Import ODS...", MenuActionImportODS},
+			{"Export Workspace to ODS...", MenuActionExportODS},
+			{"Export Workspace to XLSX...", MenuActionExportXLSX},
+			{"Export Panel to XLSX...", MenuActionExportPanelXLSX},
+			{"Import JSON...", MenuActionImportJSON},
+			{"Import SQLite Table...", MenuActionImportSQLite},
+			{"Export Panel to JSON...", MenuActionExportJSON},
+			{"Import Markdown Table...", MenuActionImportMarkdown},
+			{"Copy Panel as Markdown", MenuActionCopyMarkdown},
+			{"Copy Selection as Markdown", MenuActionCopySelectionMarkdown},
+			{"Collect Clipboard Here", MenuActionToggleClipboardCollector},
+			{"Paste Special...", MenuActionPasteSpecial},
+			{"Send Values to Scratch", MenuActionSendToScratch},
+			{"Keep Scratch Panel", MenuActionKeepScratch},
+			{"History...", MenuActionCellHistory},
+			{"Fill with Sample Data...", MenuActionFillSampleData},
+			{"Computed Column...", MenuActionComputedColumn},
+			{"Date Column...", MenuActionDateColumn},
+			{"Watch Cell", MenuActionWatchCell},
+			{"Goal Seek...", MenuActionGoalSeek},
+			{"Auto-fit Column Width", MenuActionAutoFitColumn},
+			{"Wrap Text", MenuActionToggleWrap},
+			{"Show Images", MenuActionToggleImages},
+			{"View Image", MenuActionViewImage},
+			{"Cell Renderer...", MenuActionCellRenderer},
+			{"Panel Color...", MenuActionPanelColor},
+			{"Panel Icon...", MenuActionPanelIcon},
+			{"Panel Font...", MenuActionPanelFont},
+			{"Cell Size...", MenuActionCellSize},
+			{"Number Format...", MenuActionNumberLocale},
+			{"Number Display...", MenuActionColumnFormat},
+			{"Column Unit...", MenuActionColumnUnit},
+			{"Gridlines...", MenuActionGridlines},
+			{"Zebra Stripes", MenuActionToggleZebra},
+			{"Cell Border...", MenuActionCellBorder},
+			{"Page Break Preview", MenuActionPagePreview},
+			{"Rulers", MenuActionToggleRulers},
+			{"Set Print Area", MenuActionSetPrintArea},
+			{"Export Title and Watermark...", MenuActionExportLabels},
+			{"Export Workspace to PDF...", MenuActionExportPDF},
+			{"Save Layout As...", MenuActionSaveLayout},
+			{"Switch Layout...", MenuActionSwitchLayout},
+			{"Delete Layout...", MenuActionDeleteLayout},
+			{"Save Scenario...", MenuActionSaveScenario},
+			{"Switch Scenario...", MenuActionSwitchScenario},
+			{"Delete Scenario...", MenuActionDeleteScenario},
+			{"Truncate to Size", MenuActionTruncatePanel},
+			{"Delete Panel", MenuActionDeletePanel},
+			{"Usage Statistics", MenuActionToggleStats},
+			{"Export Audit Trail...", MenuActionExportAudit},
+			{"Encrypt Workspace...", MenuActionEncryptWorkspace},
+			{"Check Workspace...", MenuActionCheckWorkspace},
+			{"Disk Changes...", MenuActionDiskChanges},
+		},
 		selected:    -1,
 		targetPanel: -1,
 	}
@@ -59,7 +226,7 @@ func (cm *ContextMenu) Update(g *Game) MenuAction {
 		return MenuActionNone
 	}
 
-	mx, my := ebiten.CursorPosition()
+	mx, my := g.frame.CursorPosition()
 	itemH := 28
 	w := 240
 	x := cm.x
@@ -77,37 +244,21 @@ func (cm *ContextMenu) Update(g *Game) MenuAction {
 	}
 
 	// left click selects or closes
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		if cm.selected >= 0 {
-			switch cm.selected {
-			case 0:
-				// New Blank Panel at world coords
-				cm.visible = false
-				return MenuActionNewBlankPanel
-			case 1:
-				cm.visible = false
-				return MenuActionLoadPanelFromFile
-			case 2:
-				cm.visible = false
-				return MenuActionSavePanelToFile
-			case 3:
-				cm.visible = false
-				return MenuActionExportPanelToCSV
-			case 4:
-				cm.visible = false
-				return MenuActionDeletePanel
-			}
+	if g.frame.MouseJustPressed(ebiten.MouseButtonLeft) {
+		if cm.selected >= 0 && cm.selected < len(cm.items) {
+			cm.visible = false
+			return cm.items[cm.selected].action
 		} else {
 			cm.visible = false
 		}
 	}
 
 	// close menu on Escape
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) {
 		cm.visible = false
 	}
 	// if right-click again, close
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+	if g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
 		cm.visible = false
 	}
 
@@ -140,10 +291,10 @@ func (cm *ContextMenu) Draw(screen *ebiten.Image, face font.Face) {
 		if cm.selected == i {
 			ebitenutil.DrawRect(screen, float64(x), float64(iy), float64(w), float64(itemH), ColorMenuHighlight)
 			// draw text in white
-			drawTextAt(screen, face, it, x+PanelInnerPadding+2, iy+PanelInnerPadding, ColorText)
+			drawTextAt(screen, face, it.label, x+PanelInnerPadding+2, iy+PanelInnerPadding, ColorText)
 		} else {
 			// normal background (transparent) and text
-			drawTextAt(screen, face, it, x+PanelInnerPadding+2, iy+PanelInnerPadding, ColorText)
+			drawTextAt(screen, face, it.label, x+PanelInnerPadding+2, iy+PanelInnerPadding, ColorText)
 		}
 	}
 }
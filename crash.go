@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// crashDir holds crash reports and the emergency workspace snapshot. It is
+// created next to the working directory's state.yml.
+const crashDir = ".cellcanvas"
+
+// recoveryDir is where the emergency snapshot of the last crashed session is
+// written; a state.yml inside it triggers the restore offer on next launch.
+var recoveryDir = filepath.Join(crashDir, "recovery")
+
+// handleCrash writes a crash report and an emergency snapshot of all panel
+// data, and returns the error that should end the game loop.
+func (g *Game) handleCrash(r any) error {
+	stack := debug.Stack()
+	ts := time.Now().Format("20060102-150405")
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		log.Printf("crash: %v", err)
+	}
+	reportPath := filepath.Join(crashDir, "crash-"+ts+".txt")
+	report := fmt.Sprintf("CellCanvas crash at %s (tick %d)\n\npanic: %v\n\n%s", time.Now().Format(time.RFC3339), g.tick, r, stack)
+	if err := os.WriteFile(reportPath, []byte(report), 0644); err != nil {
+		log.Printf("crash: writing report: %v", err)
+	}
+	if err := g.canvas.writeRecoverySnapshot(recoveryDir); err != nil {
+		log.Printf("crash: writing recovery snapshot: %v", err)
+	} else {
+		log.Printf("crash: workspace snapshot written to %s", recoveryDir)
+	}
+	return fmt.Errorf("panic: %v (report: %s)", r, reportPath)
+}
+
+// writeRecoverySnapshot dumps the in-memory panels into dir as a state.yml
+// plus one CSV per loaded panel. CSV copies keep the original base filename
+// so that saving after a restore writes back to the original files. Panels
+// still loading are referenced by their original absolute path instead, since
+// their in-memory contents are only placeholders.
+func (c *Canvas) writeRecoverySnapshot(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if c.password != "" {
+		// never fall back to plain text for an encrypted workspace
+		return c.Workspace.SaveEncrypted(encryptedPath(filepath.Join(dir, "state.yml")), c.password)
+	}
+	sf := cellcanvas.StateFile{CamX: c.CamX, CamY: c.CamY, Zoom: c.Zoom, Layouts: c.Layouts, Watches: c.Watches, Scenarios: c.Scenarios, ActivePanel: c.ActivePanel}
+	for i := range c.Panels {
+		p := &c.Panels[i]
+		name := p.Filename
+		if name == "" {
+			name = fmt.Sprintf("panel_%d.csv", i+1)
+		}
+		file := filepath.Base(name)
+		if p.Loaded && p.SampleRows == 0 {
+			if err := cellcanvas.SavePanelCSV(filepath.Join(dir, file), p); err != nil {
+				return err
+			}
+		} else if abs, err := filepath.Abs(name); err == nil {
+			file = abs
+		}
+		sf.Panels = append(sf.Panels, cellcanvas.NewStatePanel(p, file))
+	}
+	return cellcanvas.WriteStateFile(filepath.Join(dir, "state.yml"), &sf)
+}
+
+// offerCrashRecovery asks whether to restore the snapshot left by a crashed
+// session. It returns the state file to load. Either way the snapshot is moved
+// aside so the question is only asked once.
+func offerCrashRecovery(defaultState string) string {
+	snap := filepath.Join(recoveryDir, "state.yml")
+	if _, err := os.Stat(snap); err != nil && !isEncrypted(snap) {
+		return defaultState
+	}
+	restore := dialog.Message("CellCanvas did not shut down cleanly last time.\nRestore the recovered workspace?").Title("Restore Workspace").YesNo()
+	archived := recoveryDir + "-" + time.Now().Format("20060102-150405")
+	if err := os.Rename(recoveryDir, archived); err != nil {
+		log.Printf("crash recovery: %v", err)
+		if restore {
+			return snap
+		}
+		return defaultState
+	}
+	if restore {
+		return filepath.Join(archived, "state.yml")
+	}
+	log.Printf("crash recovery declined; snapshot kept in %s", archived)
+	return defaultState
+}
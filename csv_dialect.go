@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// csvFileTypes are the extensions offered by the dialogs that load a
+// panel's file; the delimiter is sniffed whatever the extension.
+var csvFileTypes = []string{"csv", "tsv", "psv", "txt"}
+
+// pickDelimiter asks which delimiter panel pi's CSV is saved with. For a
+// panel with a file it then asks whether to keep the cells as they are or
+// to read the file again with the new delimiter, for a file that was
+// sniffed wrong.
+func pickDelimiter(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := make([]string, len(cellcanvas.Delimiters))
+	for i, d := range cellcanvas.Delimiters {
+		items[i] = d.Name
+		if d.Value == g.canvas.Panels[pi].Delimiter || d.Value == cellcanvas.DelimiterComma && g.canvas.Panels[pi].Delimiter == "" {
+			items[i] += " *"
+		}
+	}
+	g.picker.Open(fmt.Sprintf("Delimiter of Panel %d", pi+1), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		d := cellcanvas.Delimiters[i]
+		p := &g.canvas.Panels[pi]
+		p.Delimiter = d.Value
+		if p.Filename == "" || !p.Loaded || p.Paged != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is saved %s-separated", pi+1, d.Name)})
+			return
+		}
+		name := filepath.Base(p.Filename)
+		g.picker.Open("Re-read "+name+"?", []string{"Keep the cells, save " + d.Name + "-separated", "Read " + name + " again " + d.Name + "-separated"}, func(g *Game, j int) {
+			if j == 0 || pi >= len(g.canvas.Panels) {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is saved %s-separated", pi+1, d.Name)})
+				return
+			}
+			p := &g.canvas.Panels[pi]
+			path := p.Filename
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(defaultStatePath), path)
+			}
+			if g.canvas.saveManager == nil {
+				tmp := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+				tmp.Delimiter = d.Value
+				if _, err := cellcanvas.LoadPanelFile(path, &tmp, p.SampleRows); err != nil {
+					reportError(g, "Load "+name, err)
+					return
+				}
+				store := p.Paged
+				p.ReplaceContent(&tmp)
+				g.canvas.ReleaseStore(store)
+				g.events.Publish(PanelLoaded{Panel: pi})
+				return
+			}
+			p.Loaded = false
+			g.canvas.saveManager.ScheduleChecked(pi, path, p.SampleRows, "", d.Value)
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("reading %s %s-separated", name, d.Name)})
+		})
+	})
+}
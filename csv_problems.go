@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// observeCSVProblems opens a problems panel next to a panel whose CSV file
+// loaded with skipped or ragged lines. It runs on the scheduler so the
+// panel list does not change while the event is still being delivered.
+func observeCSVProblems(g *Game, e Event) {
+	var pi int
+	switch ev := e.(type) {
+	case PanelAdded:
+		pi = ev.Panel
+	case PanelLoaded:
+		pi = ev.Panel
+	default:
+		return
+	}
+	if pi < 0 || pi >= len(g.canvas.Panels) || len(g.canvas.Panels[pi].Problems) == 0 {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	problems, file := p.Problems, p.Filename
+	p.Problems = nil
+	g.canvas.scheduler.Add(func() bool {
+		if pi < len(g.canvas.Panels) {
+			showCSVProblems(g, pi, file, problems)
+		}
+		return true
+	})
+}
+
+// showCSVProblems adds a panel listing problems found loading file to the
+// right of panel pi.
+func showCSVProblems(g *Game, pi int, file string, problems []cellcanvas.CSVProblem) {
+	if len(problems) == 0 {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	out := cellcanvas.ProblemsPanel(problems, p.X+p.Width()+profileGap, p.Y)
+	out.Name = "Problems in " + filepath.Base(file)
+	i := g.canvas.AddPanel(out)
+	g.events.Publish(PanelAdded{Panel: i})
+	for col := 0; col < out.Cols; col++ {
+		autoFitColumn(g, i, col)
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("%s did not load cleanly; see %s", filepath.Base(file), out.Name)})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// pickDateColumn asks how column col of panel pi writes its dates: detected
+// from its cells, one of the common formats or a format typed in. The
+// column is then normalized to ISO dates, now and on every later load.
+func pickDateColumn(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	items := []string{"Detect the format"}
+	for _, l := range cellcanvas.DateLayouts {
+		items = append(items, cellcanvas.DateExample(l))
+	}
+	items = append(items, "Other format...")
+	if p.DateColumnAt(col) >= 0 {
+		items = append(items, "Not a date column")
+	}
+	title := fmt.Sprintf("Dates in Panel %d column %s look like", pi+1, cellcanvas.ColToLetters(col))
+	g.picker.Open(title, items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		n := len(cellcanvas.DateLayouts)
+		switch {
+		case i == 0:
+			layout, alts, ok := p.DetectDateLayout(col)
+			if !ok {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s does not hold dates in any known format; pick Other format...", cellcanvas.ColToLetters(col))})
+				return
+			}
+			setDateColumn(g, pi, col, layout)
+			if len(alts) > 0 {
+				ex := make([]string, len(alts))
+				for j, a := range alts {
+					ex[j] = cellcanvas.DateExample(a)
+				}
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("read dates like %s; they could also be like %s, pick that from Date Column... if so", cellcanvas.DateExample(layout), strings.Join(ex, " or "))})
+			}
+		case i <= n:
+			setDateColumn(g, pi, col, cellcanvas.DateLayouts[i-1])
+		case i == n+1:
+			g.prompt.Open("Date format", "with YYYY, YY, MM, MMM, DD, hh, mm, ss, e.g. DD.MM.YYYY or MMM DD YYYY", "DD.MM.YYYY", func(g *Game, spec string) {
+				if pi >= len(g.canvas.Panels) || strings.TrimSpace(spec) == "" {
+					return
+				}
+				setDateColumn(g, pi, col, cellcanvas.DateSpecLayout(spec))
+			})
+		default:
+			g.canvas.Panels[pi].SetDateColumn(col, "")
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d column %s is no longer a date column", pi+1, cellcanvas.ColToLetters(col))})
+		}
+	})
+}
+
+// setDateColumn makes col a date column written with layout and normalizes
+// it, each rewritten cell an edit that can be undone.
+func setDateColumn(g *Game, pi, col int, layout string) {
+	p := &g.canvas.Panels[pi]
+	old := make([]string, p.Rows)
+	for row := range old {
+		old[row] = p.RawCell(col, row)
+	}
+	normalized, failed := p.SetDateColumn(col, layout)
+	for row, v := range old {
+		if nv := p.RawCell(col, row); nv != v {
+			g.events.Publish(CellChanged{Panel: pi, Col: col, Row: row, Old: v, New: nv})
+		}
+	}
+	g.power.redraw = true
+	msg := fmt.Sprintf("Panel %d column %s: %d date(s) like %s rewritten as ISO dates", pi+1, cellcanvas.ColToLetters(col), normalized, cellcanvas.DateExample(layout))
+	if failed > 0 {
+		msg += fmt.Sprintf(", %d cell(s) did not match and were left alone", failed)
+	}
+	g.events.Publish(LogMessage{Text: msg})
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// queryChunk is how many source rows one scheduler step runs a query over.
+const queryChunk = 4096
+
+// DerivedPanels keeps derived panels (panels with a Query) up to date: a
+// change to a source panel reruns the queries over it on the frame
+// scheduler. A finished run replaces the derived panel's cells and is
+// published as PanelLoaded, so queries over derived panels follow too.
+type DerivedPanels struct {
+	gen int
+	// jobs holds the generation of the running query of each panel
+	jobs map[int]int
+	// waitSQL holds the SQL panels whose table is a panel still loading;
+	// they start once a panel loads
+	waitSQL map[int]bool
+}
+
+func NewDerivedPanels() *DerivedPanels {
+	return &DerivedPanels{jobs: make(map[int]int), waitSQL: make(map[int]bool)}
+}
+
+// Observe reruns the queries over the panel an event changed.
+func (d *DerivedPanels) Observe(g *Game, e Event) {
+	src := -1
+	switch ev := e.(type) {
+	case CellChanged:
+		src = ev.Panel
+	case PanelLoaded:
+		src = ev.Panel
+		// StartSQL puts a panel back while its table is still loading
+		for _, pi := range slices.Collect(maps.Keys(d.waitSQL)) {
+			d.StartSQL(g, pi)
+		}
+	case PanelResized:
+		src = ev.Panel
+	case RowsAppended:
+		src = ev.Panel
+	case PanelRemoved, WorkspaceLoaded:
+		// indexes changed or the panels were replaced: rerun them all
+		d.gen++
+		clear(d.waitSQL)
+		for i := range g.canvas.Panels {
+			if q := g.canvas.Panels[i].Query; q != nil && q.From >= 0 {
+				d.Start(g, i)
+			}
+			if g.canvas.Panels[i].SQL != nil {
+				d.StartSQL(g, i)
+			}
+		}
+	}
+	if src < 0 {
+		return
+	}
+	for i := range g.canvas.Panels {
+		if q := g.canvas.Panels[i].Query; q != nil && q.From == src && i != src {
+			d.Start(g, i)
+		}
+	}
+}
+
+// Start reruns the query of panel pi, replacing a run already going for
+// it. A source panel still loading is not waited for on the scheduler,
+// which would hold up the loads queued behind the run: the source's
+// PanelLoaded starts the query again, and a source that fails to load
+// leaves it as it is.
+func (d *DerivedPanels) Start(g *Game, pi int) {
+	d.jobs[pi]++
+	if q := g.canvas.Panels[pi].Query; q == nil || q.From < 0 || q.From >= len(g.canvas.Panels) || !g.canvas.Panels[q.From].Loaded {
+		return
+	}
+	job, gen := d.jobs[pi], d.gen
+	var run *cellcanvas.QueryRun
+	g.canvas.scheduler.Add(func() bool {
+		if gen != d.gen || job != d.jobs[pi] || pi >= len(g.canvas.Panels) {
+			return true
+		}
+		p := &g.canvas.Panels[pi]
+		if p.Query == nil || p.Query.From < 0 || p.Query.From >= len(g.canvas.Panels) {
+			return true
+		}
+		if run == nil {
+			src := &g.canvas.Panels[p.Query.From]
+			if !src.Loaded {
+				// reloading; its PanelLoaded starts the query again
+				return true
+			}
+			var err error
+			if run, err = cellcanvas.StartQuery(p.Query.Text, src); err != nil {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d query: %v", pi+1, err)})
+				return true
+			}
+		}
+		if !run.Step(queryChunk) {
+			return false
+		}
+		p.SetQueryResult(run.Result())
+		g.events.Publish(PanelLoaded{Panel: pi})
+		return true
+	})
+}
+
+// newDerivedPanel asks for a query over panel pi and places its result as
+// a new panel to the right.
+func newDerivedPanel(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	hint := "e.g. SELECT region, sum(sales) AS total WHERE year >= 2020 GROUP BY region ORDER BY total DESC"
+	g.prompt.Open(fmt.Sprintf("Query over Panel %d", pi+1), hint, "SELECT *", func(g *Game, text string) {
+		if pi >= len(g.canvas.Panels) || !queryValid(g, pi, text) {
+			return
+		}
+		src := &g.canvas.Panels[pi]
+		p := cellcanvas.NewBlankPanel(src.X+src.Width()+viewGap, src.Y, 1, 1)
+		p.Query = &cellcanvas.Query{From: pi, Text: text}
+		p.Name = "Query of " + panelLabel(src, pi)
+		p.NumberLocale = src.NumberLocale
+		i := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: i})
+		g.derived.Start(g, i)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d shows a query over Panel %d", i+1, pi+1)})
+	})
+}
+
+// editQuery changes the query of derived panel pi. An empty query turns it
+// back into a plain panel that keeps its last result.
+func editQuery(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	q := g.canvas.Panels[pi].Query
+	if q == nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is not a derived panel; use New Derived Panel...", pi+1)})
+		return
+	}
+	title := fmt.Sprintf("Query of Panel %d over Panel %d", pi+1, q.From+1)
+	g.prompt.Open(title, "empty keeps the current result as a plain panel", q.Text, func(g *Game, text string) {
+		if pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].Query == nil {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		if text == "" {
+			p.Query = nil
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is no longer derived", pi+1)})
+			return
+		}
+		if p.Query.From < 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("the source of Panel %d was deleted", pi+1)})
+			return
+		}
+		if !queryValid(g, p.Query.From, text) {
+			return
+		}
+		p.Query.Text = text
+		g.derived.Start(g, pi)
+	})
+}
+
+// panelLabel names panel pi of p for messages: its name, or its number.
+func panelLabel(p *cellcanvas.Panel, pi int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("Panel %d", pi+1)
+}
+
+// queryValid reports (and explains) whether text is a query over panel
+// from. Names can only be checked once the panel has loaded.
+func queryValid(g *Game, from int, text string) bool {
+	src := &g.canvas.Panels[from]
+	var err error
+	if src.Loaded {
+		_, err = cellcanvas.StartQuery(text, src)
+	} else {
+		err = cellcanvas.ParseQuery(text)
+	}
+	if err != nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("query: %v", err)})
+		return false
+	}
+	return true
+}
+
+// queryBlocksEdit reports (and explains) that panel pi cannot be edited
+// because its cells come from a query.
+func queryBlocksEdit(g *Game, pi int) bool {
+	p := &g.canvas.Panels[pi]
+	switch {
+	case p.Query != nil:
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is derived from a query; use Edit Query... to change it", pi+1)})
+	case p.SQL != nil:
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d shows a SQL query; use Edit SQL... to change it", pi+1)})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// encryptedPath returns the archive that replaces statePath when the
+// workspace is encrypted.
+func encryptedPath(statePath string) string {
+	return statePath + cellcanvas.EncryptedExt
+}
+
+// isEncrypted reports whether statePath has an encrypted archive.
+func isEncrypted(statePath string) bool {
+	_, err := os.Stat(encryptedPath(statePath))
+	return err == nil
+}
+
+// SaveState saves the workspace, as an encrypted archive when a password is
+// set and as plain state.yml plus CSVs otherwise, and refuses while the
+// workspace is read-only or its files changed on disk. It shadows
+// Workspace.SaveState so every save path honours encryption and locking.
+func (c *Canvas) SaveState(statePath string) error {
+	if c.readOnly {
+		return errReadOnly
+	}
+	if len(c.changedOnDisk) > 0 {
+		return fmt.Errorf("%w: %s", errChangedOnDisk, strings.Join(c.changedOnDisk, ", "))
+	}
+	if c.password != "" {
+		return c.Workspace.SaveEncrypted(encryptedPath(statePath), c.password)
+	}
+	return c.Workspace.SaveState(statePath)
+}
+
+// openEncryptedWorkspace replaces the canvas contents with the archive for
+// statePath.
+func (c *Canvas) openEncryptedWorkspace(statePath, password string) error {
+	w, err := cellcanvas.OpenEncrypted(encryptedPath(statePath), password)
+	if err != nil {
+		return err
+	}
+	c.Workspace.CloseStores()
+	c.Workspace = *w
+	c.password = password
+	return nil
+}
+
+// openWorkspace loads statePath, asking for the password first when the
+// workspace is encrypted and the current one does not open it.
+// WorkspaceLoaded is published once the panels are in place.
+func openWorkspace(g *Game, statePath string) {
+	if !isEncrypted(statePath) {
+		if err := g.canvas.LoadState(statePath); err != nil {
+			reportError(g, "Open workspace", err)
+			return
+		}
+		log.Printf("Loaded from %s", statePath)
+		g.events.Publish(WorkspaceLoaded{Path: statePath})
+		return
+	}
+	if g.canvas.password != "" && g.canvas.openEncryptedWorkspace(statePath, g.canvas.password) == nil {
+		g.events.Publish(WorkspaceLoaded{Path: statePath})
+		return
+	}
+	askWorkspacePassword(g, statePath, "")
+}
+
+func askWorkspacePassword(g *Game, statePath, problem string) {
+	hint := filepath.Base(encryptedPath(statePath)) + " is encrypted"
+	if problem != "" {
+		hint = problem + " - try again"
+	}
+	g.prompt.OpenMasked("Workspace password", hint, func(g *Game, pw string) {
+		if err := g.canvas.openEncryptedWorkspace(statePath, pw); err != nil {
+			if errors.Is(err, cellcanvas.ErrWrongPassword) {
+				askWorkspacePassword(g, statePath, "Wrong password")
+				return
+			}
+			reportError(g, "Open encrypted workspace", err)
+			return
+		}
+		g.events.Publish(WorkspaceLoaded{Path: statePath})
+	})
+}
+
+// promptEncryptWorkspace sets (or, with an empty password, removes) the
+// workspace password and saves straight away. Once the encrypted archive is
+// written and verified, the plain state.yml and panel CSVs next to it are
+// deleted so no unencrypted copy stays on disk.
+func promptEncryptWorkspace(g *Game) {
+	g.prompt.OpenMasked("New workspace password", "leave empty to store the workspace unencrypted", func(g *Game, pw string) {
+		if pw == "" {
+			decryptWorkspace(g)
+			return
+		}
+		g.prompt.OpenMasked("Repeat password", "", func(g *Game, again string) {
+			if again != pw {
+				g.events.Publish(LogMessage{Text: "passwords do not match; nothing changed"})
+				return
+			}
+			encryptWorkspace(g, pw)
+		})
+	})
+}
+
+func encryptWorkspace(g *Game, pw string) {
+	statePath := defaultStatePath
+	if i := g.canvas.LoadingPanel(); i >= 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("panel %d is still loading %s; encrypt once it has loaded", i+1, filepath.Base(g.canvas.Panels[i].Filename))})
+		return
+	}
+	// the CSVs of the panels going into the archive, relative to the state
+	// file; only these are deleted afterwards
+	archived := make(map[string]bool)
+	for i := range g.canvas.Panels {
+		p := &g.canvas.Panels[i]
+		if !p.Scratch && p.Loaded && p.SampleRows == 0 && p.Filename != "" && !filepath.IsAbs(p.Filename) {
+			archived[filepath.Clean(p.Filename)] = true
+		}
+	}
+	prev := g.canvas.password
+	g.canvas.password = pw
+	if err := g.canvas.SaveState(statePath); err != nil {
+		g.canvas.password = prev
+		reportError(g, "Encrypt workspace", err)
+		return
+	}
+	if _, err := cellcanvas.OpenEncrypted(encryptedPath(statePath), pw); err != nil {
+		log.Printf("encrypt: verifying archive: %v", err)
+		g.events.Publish(LogMessage{Text: "encrypted archive did not verify; plain files kept"})
+		return
+	}
+	removePlainWorkspace(statePath, archived)
+	g.events.Publish(WorkspaceSaved{Path: statePath})
+	g.events.Publish(LogMessage{Text: "workspace encrypted: " + filepath.Base(encryptedPath(statePath))})
+	if g.audit != nil {
+		g.events.Publish(LogMessage{Text: filepath.Base(g.audit.Path()) + " keeps the values logged before; later edits are logged without them"})
+	}
+}
+
+func decryptWorkspace(g *Game) {
+	statePath := defaultStatePath
+	if g.canvas.password == "" {
+		g.events.Publish(LogMessage{Text: "workspace is not encrypted"})
+		return
+	}
+	prev := g.canvas.password
+	g.canvas.password = ""
+	if err := g.canvas.SaveState(statePath); err != nil {
+		g.canvas.password = prev
+		reportError(g, "Decrypt workspace", err)
+		return
+	}
+	if err := os.Remove(encryptedPath(statePath)); err != nil {
+		log.Printf("decrypt: %v", err)
+	}
+	g.events.Publish(WorkspaceSaved{Path: statePath})
+	g.events.Publish(LogMessage{Text: "workspace saved unencrypted"})
+}
+
+// removePlainWorkspace deletes the plain state file and the panel CSVs it
+// references next to it that are in archived, the files whose panels were
+// written into the archive from their loaded data. CSVs referenced by
+// absolute path are left alone: they were opened from elsewhere and are
+// not the workspace's own copies.
+func removePlainWorkspace(statePath string, archived map[string]bool) {
+	sf, err := cellcanvas.ReadStateFile(statePath)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(statePath)
+	for _, sp := range sf.Panels {
+		if sp.Filename != "" && !filepath.IsAbs(sp.Filename) && archived[filepath.Clean(sp.Filename)] {
+			if err := os.Remove(filepath.Join(dir, sp.Filename)); err != nil && !os.IsNotExist(err) {
+				log.Printf("encrypt: %v", err)
+			}
+		}
+	}
+	if err := os.Remove(statePath); err != nil {
+		log.Printf("encrypt: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// maxQueuedErrors caps the errors waiting behind the one shown.
+const maxQueuedErrors = 8
+
+// ErrorDialog is a modal box explaining a failed operation: what failed,
+// the file and CSV line involved and what to try. Errors reported while one
+// is shown queue behind it. Enter, Escape or a click dismisses it; like
+// Prompt it owns all input while open.
+type ErrorDialog struct {
+	queue []ErrorReported
+}
+
+func NewErrorDialog() *ErrorDialog {
+	return &ErrorDialog{}
+}
+
+// Observe queues reported errors.
+func (d *ErrorDialog) Observe(e Event) {
+	if ev, ok := e.(ErrorReported); ok && len(d.queue) < maxQueuedErrors {
+		d.queue = append(d.queue, ev)
+	}
+}
+
+// Active reports whether an error is shown.
+func (d *ErrorDialog) Active() bool {
+	return len(d.queue) > 0
+}
+
+// Update dismisses the shown error.
+func (d *ErrorDialog) Update(g *Game) {
+	f := g.frame
+	if f.KeyJustPressed(ebiten.KeyEnter) || f.KeyJustPressed(ebiten.KeyEscape) || f.MouseJustPressed(ebiten.MouseButtonLeft) {
+		d.queue = d.queue[1:]
+	}
+}
+
+// Draw renders the shown error centered on screen.
+func (d *ErrorDialog) Draw(screen *ebiten.Image, face font.Face) {
+	if !d.Active() {
+		return
+	}
+	ev := d.queue[0]
+	lines := errorDetails(ev.Err)
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	w := min(640, sw-32)
+	h := 48 + len(lines)*18 + 24
+	x, y := (sw-w)/2, sh/3-h/2
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), 3, ColorError)
+	ebitenutil.DrawRect(screen, float64(x), float64(y+h-2), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 2, float64(h), ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x+w-2), float64(y), 2, float64(h), ColorMenuBorder)
+
+	title := ev.Op + " failed"
+	if n := len(d.queue) - 1; n > 0 {
+		title += fmt.Sprintf("  (%d more)", n)
+	}
+	drawTextAt(screen, face, title, x+PanelInnerPadding*2, y+10, ColorError)
+	for i, line := range lines {
+		drawTextAt(screen, face, line, x+PanelInnerPadding*2, y+36+i*18, ColorText)
+	}
+	drawTextAt(screen, face, "Enter or click to close", x+PanelInnerPadding*2, y+h-22, ColorTextDim)
+}
+
+// errorDetails describes err in a few lines: the message, the file and line
+// when known, and a hint for the error's code.
+func errorDetails(err error) []string {
+	var fe *cellcanvas.FileError
+	if !errors.As(err, &fe) {
+		return []string{err.Error(), errorHint(cellcanvas.CodeOf(err))}
+	}
+	lines := []string{fe.Err.Error(), "File: " + fe.Path}
+	if fe.Line > 0 {
+		lines = append(lines, fmt.Sprintf("Line: %d", fe.Line))
+	}
+	return append(lines, errorHint(fe.Code))
+}
+
+func errorHint(c cellcanvas.Code) string {
+	switch c {
+	case cellcanvas.CodeNotFound:
+		return "The file does not exist. It may have been moved or renamed."
+	case cellcanvas.CodePermission:
+		return "Permission denied. Check that the file is not read-only or open elsewhere."
+	case cellcanvas.CodeParse:
+		return "The file is not valid CSV. Fix the line above and load it again."
+	case cellcanvas.CodeNewerVersion:
+		return "The workspace was saved by a newer CellCanvas. Update to open it; the file was left unchanged."
+	case cellcanvas.CodeWrongPassword:
+		return "Check the password and try again."
+	}
+	return "See the console for more details."
+}
+
+// reportError logs a failed operation and shows it in the error dialog.
+func reportError(g *Game, op string, err error) {
+	log.Printf("%s: %v", op, err)
+	g.events.Publish(ErrorReported{Op: op, Err: err})
+}
@@ -0,0 +1,150 @@
+package main
+
+// Event is anything published on the EventBus. Concrete event types are
+// plain structs; subscribers switch on the type they care about.
+type Event interface{}
+
+// CellChanged is published after a cell value is committed.
+type CellChanged struct {
+	Panel    int
+	Col, Row int
+	Old, New string
+	// By names the API user who made the change; empty for edits made
+	// here. Conflict names whoever was editing the cell at the same time,
+	// whose edit this change overwrote (see CellLocks).
+	By, Conflict string
+}
+
+// PanelAdded is published after a panel is appended to the canvas.
+type PanelAdded struct {
+	Panel int
+}
+
+// PanelRemoved is published after a panel is dropped from the canvas. Panel
+// is the index it had; later panels have shifted down by one.
+type PanelRemoved struct {
+	Panel int
+}
+
+// PanelMoved is published when a panel drag ends at a new position.
+type PanelMoved struct {
+	Panel        int
+	FromX, FromY int
+	X, Y         int
+}
+
+// PanelResized is published when a resize drag ends with a new grid size.
+type PanelResized struct {
+	Panel      int
+	Cols, Rows int
+}
+
+// PanelLoaded is published when a panel's data finished loading from its
+// file.
+type PanelLoaded struct {
+	Panel int
+}
+
+// BulkEdit is published after an operation that changed many cells of a
+// panel at once without a CellChanged for each: a large paste, Truncate to
+// Size, a file loaded into the panel or merged into it. Action names the
+// operation, Cell the range or first cell it wrote and Detail what was
+// written, for the audit log.
+type BulkEdit struct {
+	Panel                int
+	Action, Cell, Detail string
+}
+
+// FileChangedOutside is published after panel Panel loaded from File
+// when the file no longer matches the checksum it was saved with.
+type FileChangedOutside struct {
+	Panel int
+	File  string
+}
+
+// RowsAppended is published when Count rows starting at First were
+// appended to a panel from the file Source.
+type RowsAppended struct {
+	Panel        int
+	First, Count int
+	Source       string
+}
+
+// ColumnResized is published when a column width changes.
+type ColumnResized struct {
+	Panel, Col int
+	Width      int
+}
+
+// SelectionChanged is published whenever the active panel, cell or
+// selected range changes. Row/Col is the cursor cell; the range spans from
+// it to AnchorRow/AnchorCol.
+type SelectionChanged struct {
+	Panel                int
+	Row, Col             int
+	AnchorRow, AnchorCol int
+}
+
+// CellClicked is published for a left click on a cell, before the selection
+// moves to it, so subscribers can still act on the previous selection.
+type CellClicked struct {
+	Panel    int
+	Row, Col int
+}
+
+// PanelNameClicked is published for a click on a panel's header name button.
+type PanelNameClicked struct {
+	Panel int
+}
+
+// WorkspaceSaved is published after the workspace state file is written.
+type WorkspaceSaved struct {
+	Path string
+}
+
+// WorkspaceLoaded is published after a workspace state file is read. Panel
+// contents may still be loading in the background.
+type WorkspaceLoaded struct {
+	Path string
+}
+
+// LogMessage asks for a short status line to be shown to the user.
+type LogMessage struct {
+	Text string
+}
+
+// ErrorReported is published when an operation the user started failed.
+// Op names it ("Open", "Export CSV", ...); the error dialog shows it with
+// the error's details.
+type ErrorReported struct {
+	Op  string
+	Err error
+}
+
+// EventBus is a synchronous, single-threaded publish/subscribe hub that lets
+// Game, Canvas, UI, InputManager and feature subsystems talk without holding
+// references to each other. Publish must be called from the game loop;
+// handlers run inline in subscription order.
+type EventBus struct {
+	handlers []func(Event)
+}
+
+// NewEventBus creates an empty bus.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers h to receive every published event.
+func (b *EventBus) Subscribe(h func(Event)) {
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers e to all subscribers. A nil bus drops the event.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// subsetPath asks where to export part of a panel, adding .csv when the
+// name has no extension. It returns "" when cancelled.
+func subsetPath(title string) string {
+	path, err := dialog.File().Filter("CSV", "csv").Title(title).Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file save failed: %v", err)
+		}
+		return ""
+	}
+	if path != "" && filepath.Ext(path) == "" {
+		path += ".csv"
+	}
+	return path
+}
+
+// exportSelection saves the selected range of panel pi as CSV. Selecting
+// whole rows or columns exports just those. Unlike Export to CSV... it does
+// not change the file the panel saves to.
+func exportSelection(g *Game, pi int) {
+	im := g.input
+	if pi < 0 || pi >= len(g.canvas.Panels) || pi != im.activePanel {
+		g.events.Publish(LogMessage{Text: "Select the cells to export first"})
+		return
+	}
+	col0, row0, col1, row1 := im.selectionRange()
+	path := subsetPath("Export Selection As")
+	if path == "" {
+		return
+	}
+	r := cellcanvas.CellRange{Col0: col0, Row0: row0, Col1: col1, Row1: row1}
+	if err := cellcanvas.SaveRangeCSV(path, &g.canvas.Panels[pi], r); err != nil {
+		reportError(g, "Export", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("exported %s:%s to %s", cellcanvas.CellRef(col0, row0), cellcanvas.CellRef(col1, row1), filepath.Base(path))})
+}
+
+// exportFilteredRows saves the rows of a derived panel's source that pass
+// its query's WHERE clause, with every source column and the header row,
+// where the derived panel itself only has the selected columns.
+func exportFilteredRows(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].Query == nil {
+		g.events.Publish(LogMessage{Text: "Export Filtered Rows works on a derived panel; its WHERE clause is the filter"})
+		return
+	}
+	q := g.canvas.Panels[pi].Query
+	if q.From < 0 || q.From >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "the source of this derived panel was removed"})
+		return
+	}
+	src := &g.canvas.Panels[q.From]
+	run, err := cellcanvas.StartQuery(q.Text, src)
+	if err != nil {
+		reportError(g, "Export", err)
+		return
+	}
+	path := subsetPath("Export Filtered Rows As")
+	if path == "" {
+		return
+	}
+	rows := append([]int{0}, run.Matching()...)
+	if err := cellcanvas.SaveRowsCSV(path, src, rows); err != nil {
+		reportError(g, "Export", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("exported %d of %d row(s) of Panel %d to %s", len(rows)-1, max(src.Rows-1, 0), q.From+1, filepath.Base(path))})
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/gorilla/websocket"
+	"github.com/sqweek/dialog"
+)
+
+// feedRetryDelay is how long a feed waits before reconnecting after an error.
+const feedRetryDelay = 5 * time.Second
+
+// feedMsg carries data (or a status line) from a feed goroutine to the game
+// loop; src identifies the panel's Source the message belongs to.
+type feedMsg struct {
+	src    *cellcanvas.Source
+	lines  []string
+	row    []string   // a pre-split row, appended as is
+	grid   [][]string // replaces the whole panel content
+	status string
+}
+
+// FeedManager runs one connection per live panel (MQTT, WebSocket, the
+// clipboard watcher or a periodic command) and
+// appends the rows they receive on the game loop. Connections are keyed by
+// the panel's *Source, which stays stable while panels are moved around the
+// slice or reloaded.
+type FeedManager struct {
+	msgs    chan feedMsg
+	running map[*cellcanvas.Source]context.CancelFunc
+	// approved holds command sources the user allowed to run; commands read
+	// from a state file must be confirmed before they execute.
+	approved map[*cellcanvas.Source]bool
+}
+
+// NewFeedManager creates a manager with no running feeds.
+func NewFeedManager() *FeedManager {
+	return &FeedManager{
+		msgs:     make(chan feedMsg, 256),
+		running:  make(map[*cellcanvas.Source]context.CancelFunc),
+		approved: make(map[*cellcanvas.Source]bool),
+	}
+}
+
+// Approve marks a command source as allowed to run without asking.
+func (fm *FeedManager) Approve(src *cellcanvas.Source) {
+	fm.approved[src] = true
+}
+
+// allowed reports whether src may be started, asking the user once for
+// command sources that were not created in this session.
+func (fm *FeedManager) allowed(src *cellcanvas.Source) bool {
+	if src.Kind != cellcanvas.SourceCommand {
+		return true
+	}
+	ok, asked := fm.approved[src]
+	if !asked {
+		ok = dialog.Message("This workspace wants to run a command periodically:\n\n%s\n\nAllow it?", src.Command).Title("Run Command Panel").YesNo()
+		fm.approved[src] = ok
+	}
+	return ok
+}
+
+// isFeedSource reports whether the FeedManager is responsible for src.
+func isFeedSource(src *cellcanvas.Source) bool {
+	if src == nil {
+		return false
+	}
+	switch src.Kind {
+	case cellcanvas.SourceMQTT, cellcanvas.SourceWebSocket, cellcanvas.SourceClipboard, cellcanvas.SourceCommand:
+		return true
+	}
+	return false
+}
+
+// Update starts feeds for new live panels, stops feeds whose panel is gone,
+// and applies every received row. It reports whether any message arrived.
+func (fm *FeedManager) Update(c *Canvas, bus *EventBus) bool {
+	live := make(map[*cellcanvas.Source]int)
+	for i := range c.Panels {
+		if src := c.Panels[i].Source; isFeedSource(src) {
+			live[src] = i
+			if _, ok := fm.running[src]; !ok && fm.allowed(src) {
+				ctx, cancel := context.WithCancel(context.Background())
+				fm.running[src] = cancel
+				go fm.run(ctx, src, *src)
+			}
+		}
+	}
+	for src, cancel := range fm.running {
+		if _, ok := live[src]; !ok {
+			cancel()
+			delete(fm.running, src)
+		}
+	}
+	for src := range fm.approved {
+		if _, ok := live[src]; !ok {
+			delete(fm.approved, src)
+		}
+	}
+
+	received := false
+	for {
+		select {
+		case m := <-fm.msgs:
+			received = true
+			i, ok := live[m.src]
+			if !ok {
+				continue
+			}
+			if m.status != "" {
+				bus.Publish(LogMessage{Text: fmt.Sprintf("Panel %d feed: %s", i+1, m.status)})
+				continue
+			}
+			p := &c.Panels[i]
+			if !p.Loaded {
+				continue
+			}
+			for _, line := range m.lines {
+				appendFeedLine(p, m.src, line)
+			}
+			if m.row != nil {
+				appendDataRow(p, m.row)
+			}
+			if m.grid != nil {
+				p.Rows = 0
+				p.Cols = 0
+				p.Cells = make(map[string]string)
+				for _, rec := range m.grid {
+					p.AppendRow(rec)
+				}
+				if p.Rows == 0 {
+					p.Rows, p.Cols = 1, 1
+				}
+			}
+			trimFeedRows(p, m.src)
+			if p.Wrap {
+				fitRowHeights(p)
+			}
+		default:
+			return received
+		}
+	}
+}
+
+// run connects to the feed described by cfg and keeps reconnecting until ctx
+// is cancelled. cfg is a copy so the goroutine never reads panel state.
+func (fm *FeedManager) run(ctx context.Context, src *cellcanvas.Source, cfg cellcanvas.Source) {
+	send := func(m feedMsg) {
+		m.src = src
+		select {
+		case fm.msgs <- m:
+		case <-ctx.Done():
+		}
+	}
+	for {
+		var err error
+		switch cfg.Kind {
+		case cellcanvas.SourceWebSocket:
+			err = runWebSocketFeed(ctx, cfg, send)
+		case cellcanvas.SourceMQTT:
+			err = runMQTTFeed(ctx, cfg, send)
+		case cellcanvas.SourceClipboard:
+			err = runClipboardFeed(ctx, send)
+		case cellcanvas.SourceCommand:
+			err = runCommandFeed(ctx, cfg, send)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			send(feedMsg{status: err.Error()})
+		}
+		select {
+		case <-time.After(feedRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func runWebSocketFeed(ctx context.Context, cfg cellcanvas.Source, send func(feedMsg)) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	send(feedMsg{status: "connected to " + cfg.URL})
+	// unblock ReadMessage when the feed is stopped
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		send(feedMsg{lines: splitFeedPayload(data)})
+	}
+}
+
+func runMQTTFeed(ctx context.Context, cfg cellcanvas.Source, send func(feedMsg)) error {
+	if cfg.Topic == "" {
+		return fmt.Errorf("no MQTT topic configured")
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.URL).
+		SetClientID(fmt.Sprintf("cellcanvas-%d", time.Now().UnixNano())).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			c.Subscribe(cfg.Topic, 0, func(_ mqtt.Client, m mqtt.Message) {
+				send(feedMsg{lines: splitFeedPayload(m.Payload())})
+			})
+		})
+	client := mqtt.NewClient(opts)
+	tok := client.Connect()
+	tok.Wait()
+	if err := tok.Error(); err != nil {
+		return err
+	}
+	send(feedMsg{status: "subscribed to " + cfg.Topic})
+	<-ctx.Done()
+	client.Disconnect(250)
+	return nil
+}
+
+// splitFeedPayload splits a message into non-empty lines; a message may
+// carry one row or a batch of newline-separated rows.
+func splitFeedPayload(data []byte) []string {
+	var lines []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// appendFeedLine adds one received row to p. JSON objects are matched to
+// columns by a header row of keys (unknown keys add columns); JSON arrays and
+// anything else parsed as a CSV line are appended positionally.
+func appendFeedLine(p *cellcanvas.Panel, src *cellcanvas.Source, line string) {
+	switch line[0] {
+	case '{':
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err == nil {
+			appendFeedObject(p, src, obj)
+			return
+		}
+	case '[':
+		var arr []any
+		if err := json.Unmarshal([]byte(line), &arr); err == nil {
+			vals := make([]string, len(arr))
+			for i, v := range arr {
+				vals[i] = feedValueString(v)
+			}
+			appendDataRow(p, vals)
+			return
+		}
+	}
+	rec, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		log.Printf("feed: skipping unparsable line %q: %v", line, err)
+		return
+	}
+	appendDataRow(p, rec)
+}
+
+func appendFeedObject(p *cellcanvas.Panel, src *cellcanvas.Source, obj map[string]any) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	if !src.Header {
+		if len(p.Cells) > 0 {
+			// rows already arrived without a header; stay positional
+			vals := make([]string, len(keys))
+			for i, k := range keys {
+				vals[i] = feedValueString(obj[k])
+			}
+			p.AppendRow(vals)
+			return
+		}
+		// start the key header on a fresh panel
+		src.Header = true
+		p.Rows = 1
+		p.Cols = 0
+	}
+	// header lives in row 0; find or add a column for every key
+	header := make(map[string]int)
+	for col := 0; col < p.Cols; col++ {
+		if h := p.GetCell(col, 0); h != "" {
+			header[h] = col
+		}
+	}
+	vals := make([]string, p.Cols)
+	for _, k := range keys {
+		col, ok := header[k]
+		if !ok {
+			col = p.Cols
+			p.Cols++
+			p.SetCell(col, 0, k)
+			header[k] = col
+			vals = append(vals, "")
+		}
+		vals[col] = feedValueString(obj[k])
+	}
+	p.AppendRow(vals)
+}
+
+// appendDataRow appends vals, first clearing the placeholder grid of a panel
+// that has never received data.
+func appendDataRow(p *cellcanvas.Panel, vals []string) {
+	if len(p.Cells) == 0 {
+		p.Rows = 0
+	}
+	p.AppendRow(vals)
+}
+
+func feedValueString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+// trimFeedRows drops the oldest data rows so at most src.MaxRows remain,
+// keeping the key header row of object feeds.
+func trimFeedRows(p *cellcanvas.Panel, src *cellcanvas.Source) {
+	if src.MaxRows <= 0 {
+		return
+	}
+	first := 0
+	if src.Header {
+		first = 1
+	}
+	if extra := p.Rows - first - src.MaxRows; extra > 0 {
+		p.DropRows(first, extra)
+	}
+}
+
+// defaultFeedMaxRows is the ring buffer size offered for new feed panels.
+const defaultFeedMaxRows = 200
+
+// promptNewFeedPanel walks the user through configuring a live feed (URL,
+// MQTT topic, row limit) and adds the panel at world coordinates x, y.
+func promptNewFeedPanel(g *Game, x, y int) {
+	g.prompt.Open("Live feed URL", "ws://host/path or mqtt://broker:1883", "", func(g *Game, url string) {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			return
+		}
+		src := &cellcanvas.Source{Kind: cellcanvas.SourceWebSocket, URL: url, MaxRows: defaultFeedMaxRows}
+		askRows := func(g *Game) {
+			g.prompt.Open("Maximum rows to keep", "older rows are dropped first; 0 keeps everything", strconv.Itoa(src.MaxRows), func(g *Game, v string) {
+				if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n >= 0 {
+					src.MaxRows = n
+				}
+				p := cellcanvas.NewBlankPanel(x, y, 5, 5)
+				p.Source = src
+				i := g.canvas.AddPanel(p)
+				g.events.Publish(PanelAdded{Panel: i})
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: live feed %s", i+1, url)})
+			})
+		}
+		if !strings.HasPrefix(url, "ws://") && !strings.HasPrefix(url, "wss://") {
+			src.Kind = cellcanvas.SourceMQTT
+			g.prompt.Open("MQTT topic", "e.g. sensors/+/temperature", "", func(g *Game, topic string) {
+				src.Topic = strings.TrimSpace(topic)
+				askRows(g)
+			})
+			return
+		}
+		askRows(g)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// Sample data limits: rows filled below a single selected cell, and the
+// largest range filled at once.
+const (
+	defaultFillRows = 20
+	maxFillCells    = 10000
+)
+
+// fillSampleData asks what to generate and fills the selected range with
+// it. With a single cell selected the defaultFillRows cells from it down
+// are filled.
+func fillSampleData(g *Game) {
+	pi := g.input.activePanel
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	col0, row0, col1, row1 := g.input.selectionRange()
+	if col0 == col1 && row0 == row1 {
+		row1 = row0 + defaultFillRows - 1
+	}
+	if (col1-col0+1)*(row1-row0+1) > maxFillCells {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("select at most %d cells to fill", maxFillCells)})
+		return
+	}
+	fill := func(g *Game, gen cellcanvas.Generator) {
+		if pi < len(g.canvas.Panels) {
+			fillRange(g, pi, col0, row0, col1, row1, gen)
+		}
+	}
+	kinds := []string{"Names", "Dates...", "Numbers in a range...", "Pick from a list..."}
+	g.picker.Open("Fill with Sample Data", kinds, func(g *Game, i int) {
+		switch i {
+		case 0:
+			fill(g, cellcanvas.NameGenerator())
+		case 1:
+			today := time.Now()
+			initial := today.AddDate(-1, 0, 0).Format("2006-01-02") + " " + today.Format("2006-01-02")
+			g.prompt.Open("Dates between", "first and last date, YYYY-MM-DD YYYY-MM-DD", initial, func(g *Game, v string) {
+				f := strings.Fields(v)
+				if len(f) != 2 {
+					return
+				}
+				from, err1 := time.Parse("2006-01-02", f[0])
+				to, err2 := time.Parse("2006-01-02", f[1])
+				if err1 != nil || err2 != nil {
+					g.events.Publish(LogMessage{Text: fmt.Sprintf("fill: %q are not two dates", v)})
+					return
+				}
+				fill(g, cellcanvas.DateGenerator(from, to))
+			})
+		case 2:
+			g.prompt.Open("Numbers between", "min and max, e.g. 1 100 or 0.00 9.99 for decimals", "1 100", func(g *Game, v string) {
+				f := strings.Fields(v)
+				if len(f) != 2 {
+					return
+				}
+				gen, err := cellcanvas.NumberGenerator(f[0], f[1])
+				if err != nil {
+					g.events.Publish(LogMessage{Text: "fill: " + err.Error()})
+					return
+				}
+				fill(g, gen)
+			})
+		case 3:
+			g.prompt.Open("Pick from", "comma-separated values", "", func(g *Game, v string) {
+				var items []string
+				for _, s := range strings.Split(v, ",") {
+					if s = strings.TrimSpace(s); s != "" {
+						items = append(items, s)
+					}
+				}
+				if len(items) == 0 {
+					return
+				}
+				fill(g, cellcanvas.ListGenerator(items))
+			})
+		}
+	})
+}
+
+// fillRange writes a generated value into every cell of the inclusive
+// range, growing the panel to fit. The generator is seeded from the frame
+// clock so replays fill the same values.
+func fillRange(g *Game, pi, col0, row0, col1, row1 int, gen cellcanvas.Generator) {
+	r := rand.New(rand.NewPCG(uint64(g.frame.TimeMs), uint64(g.tick)))
+	p := &g.canvas.Panels[pi]
+	cols, rows := p.Cols, p.Rows
+	p.Cols, p.Rows = max(p.Cols, col1+1), max(p.Rows, row1+1)
+	for row := row0; row <= row1; row++ {
+		for col := col0; col <= col1; col++ {
+			old, val := p.RawCell(col, row), gen(r)
+			p.SetCell(col, row, val)
+			if old != val {
+				g.events.Publish(CellChanged{Panel: pi, Col: col, Row: row, Old: old, New: val})
+			}
+		}
+	}
+	if p.Cols != cols || p.Rows != rows {
+		g.events.Publish(PanelResized{Panel: pi, Cols: p.Cols, Rows: p.Rows})
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("filled %s:%s of Panel %d", cellcanvas.CellRef(col0, row0), cellcanvas.CellRef(col1, row1), pi+1)})
+}
@@ -0,0 +1,75 @@
+package main
+
+// Focus names the input consumer that owns a frame. Consumers overlap on
+// keys and on screen (Escape closes the menu, dismisses a popover and
+// cancels an edit; an arrow key moves the selection and the edit caret), so
+// every frame exactly one of them gets the input: the highest applicable
+// one below, decided once before any handler runs. A consumer that opens
+// another (a menu item opening a prompt) therefore never hands it the input
+// that opened it.
+type Focus int
+
+const (
+	// FocusCanvas: selection, panel switching, shortcuts, panning and
+	// panel drags
+	FocusCanvas Focus = iota
+	// FocusEditing: a cell or panel name is being edited; the selection
+	// stays put
+	FocusEditing
+	// FocusDrag: a panel is being moved or resized or the canvas panned
+	FocusDrag
+	// FocusPopover: the distribution popover or the image viewer is open
+	FocusPopover
+	// FocusMenu: the context menu is open
+	FocusMenu
+	// FocusDialog: a modal dialog, prompt or picker is open
+	FocusDialog
+)
+
+// focus decides who owns this frame's input.
+func (g *Game) focus() Focus {
+	im := g.input
+	switch {
+	case g.errDialog.Active(), g.paste.Active(), g.prompt.Active(), g.picker.Active():
+		return FocusDialog
+	case g.contextMenu.visible:
+		return FocusMenu
+	case g.distribution.visible, g.images.visible:
+		return FocusPopover
+	case im.movingPanel != -1 || im.resizingPanel != -1 || im.dragging:
+		return FocusDrag
+	case im.editing || im.editingPanelName:
+		return FocusEditing
+	}
+	return FocusCanvas
+}
+
+// dispatchInput hands this frame's input to the consumer that owns it.
+func (g *Game) dispatchInput() {
+	switch g.focus() {
+	case FocusDialog:
+		// the topmost dialog owns all input
+		switch {
+		case g.errDialog.Active():
+			g.errDialog.Update(g)
+		case g.paste.Active():
+			g.paste.Update(g)
+		case g.prompt.Active():
+			g.prompt.Update(g)
+		default:
+			g.picker.Update(g)
+		}
+	case FocusMenu:
+		g.input.HandleContextMenuInput(g)
+	case FocusPopover:
+		g.distribution.Update(g)
+		g.images.HandleInput(g)
+	case FocusDrag:
+		g.input.HandlePanInput(g)
+		g.input.HandleCanvasInteraction(g)
+	case FocusEditing:
+		g.handleInput(true)
+	default:
+		g.handleInput(false)
+	}
+}
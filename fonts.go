@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/example/cellchain/cellcanvas"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// Cell text is drawn with Ebiten's debug font unless a panel sets a font
+// size. The debug font has fixed debugGlyphW x debugLineH glyphs; everything
+// measured for a panel goes through the helpers below so both cases agree.
+const (
+	debugGlyphW = 6
+	debugLineH  = 16
+	// defaultFontFile is used for panels that set a size but no font file
+	defaultFontFile = "res/Roboto-Regular.ttf"
+	minFontSize     = 6
+	maxFontSize     = 96
+)
+
+// textWidth returns the rendered width of s's longest line in face (nil
+// for the debug font).
+func textWidth(face font.Face, s string) int {
+	w := 0
+	for line := range strings.SplitSeq(s, "\n") {
+		if face == nil {
+			w = max(w, utf8.RuneCountInString(line)*debugGlyphW)
+		} else {
+			w = max(w, font.MeasureString(face, line).Ceil())
+		}
+	}
+	return w
+}
+
+// lineHeight returns the distance between lines of text in face.
+func lineHeight(face font.Face) int {
+	if face == nil {
+		return debugLineH
+	}
+	return face.Metrics().Height.Ceil()
+}
+
+// fontCache parses each font file once and keeps one face per file and
+// size.
+type fontCache struct {
+	fonts map[string]*opentype.Font
+	faces map[fontKey]font.Face
+}
+
+type fontKey struct {
+	file string
+	size int
+}
+
+// panelFonts holds the faces used for panel text.
+var panelFonts = &fontCache{fonts: make(map[string]*opentype.Font), faces: make(map[fontKey]font.Face)}
+
+// face returns the face for p's text, or nil for the default debug font.
+// A font file that cannot be loaded falls back to the bundled one.
+func (fc *fontCache) face(p *cellcanvas.Panel) font.Face {
+	if p.FontSize <= 0 {
+		return nil
+	}
+	file := p.Font
+	if file == "" {
+		file = defaultFontFile
+	}
+	key := fontKey{file, p.FontSize}
+	if f, ok := fc.faces[key]; ok {
+		return f
+	}
+	f, err := fc.load(file, p.FontSize)
+	if err != nil && file != defaultFontFile {
+		log.Printf("font %s: %v; using %s", file, err, defaultFontFile)
+		f, err = fc.load(defaultFontFile, p.FontSize)
+	}
+	if err != nil {
+		log.Printf("font: %v; using the debug font", err)
+	}
+	// cache failures too so a broken file is not re-read every frame
+	fc.faces[key] = f
+	return f
+}
+
+func (fc *fontCache) load(file string, size int) (font.Face, error) {
+	tt, ok := fc.fonts[file]
+	if !ok {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		tt, err = opentype.Parse(b)
+		if err != nil {
+			return nil, err
+		}
+		fc.fonts[file] = tt
+	}
+	return opentype.NewFace(tt, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+}
+
+// applyPanelFont sizes p's default cells to its font so text fits the
+// same way it does with the debug font. A fixed cell size set with Cell
+// Size... wins over the font.
+func applyPanelFont(p *cellcanvas.Panel) {
+	if face := panelFonts.face(p); face == nil {
+		p.CellW, p.CellH = cellcanvas.DefaultCellW, cellcanvas.DefaultCellH
+	} else {
+		lh := lineHeight(face)
+		p.CellH = lh + PanelInnerPadding + 2
+		p.CellW = cellcanvas.DefaultCellW * lh / debugLineH
+	}
+	if p.FixedCellW > 0 {
+		p.CellW = p.FixedCellW
+	}
+	if p.FixedCellH > 0 {
+		p.CellH = p.FixedCellH
+	}
+}
+
+// promptPanelFont asks for panel pi's font size and then, optionally, a
+// font file.
+func promptPanelFont(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	cur := strconv.Itoa(g.canvas.Panels[pi].FontSize)
+	hint := fmt.Sprintf("pixels, %d-%d; 0 for the default font", minFontSize, maxFontSize)
+	g.prompt.Open("Font size", hint, cur, func(g *Game, v string) {
+		size, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || pi >= len(g.canvas.Panels) {
+			return
+		}
+		if size != 0 {
+			size = min(max(size, minFontSize), maxFontSize)
+		}
+		p := &g.canvas.Panels[pi]
+		if size == 0 {
+			setPanelFont(g, pi, 0, "")
+			return
+		}
+		g.prompt.Open("Font file", "TTF/OTF path; empty for the bundled Roboto", p.Font, func(g *Game, file string) {
+			setPanelFont(g, pi, size, strings.TrimSpace(file))
+		})
+	})
+}
+
+func setPanelFont(g *Game, pi, size int, file string) {
+	if pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	p.FontSize, p.Font = size, file
+	applyPanelFont(p)
+	fitRowHeights(p)
+	g.events.Publish(PanelResized{Panel: pi, Cols: p.Cols, Rows: p.Rows})
+}
+
+// Limits of a fixed cell size, in pixels.
+const (
+	minCellSize = 8
+	maxCellSize = 1000
+)
+
+// promptCellSize asks for panel pi's fixed cell size as "width x height".
+// Empty goes back to the size derived from the panel font.
+func promptCellSize(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	cur := ""
+	if p.FixedCellW > 0 || p.FixedCellH > 0 {
+		cur = fmt.Sprintf("%dx%d", p.CellW, p.CellH)
+	}
+	hint := fmt.Sprintf("width x height in pixels, %d-%d; empty to follow the font", minCellSize, maxCellSize)
+	g.prompt.Open("Cell size", hint, cur, func(g *Game, v string) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		w, h := 0, 0
+		if v = strings.TrimSpace(v); v != "" {
+			ws, hs, ok := strings.Cut(strings.ToLower(v), "x")
+			var errW, errH error
+			w, errW = strconv.Atoi(strings.TrimSpace(ws))
+			h, errH = strconv.Atoi(strings.TrimSpace(hs))
+			if !ok || errW != nil || errH != nil {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("%q is not a cell size like 80x24", v)})
+				return
+			}
+			w = min(max(w, minCellSize), maxCellSize)
+			h = min(max(h, minCellSize), maxCellSize)
+		}
+		p := &g.canvas.Panels[pi]
+		p.FixedCellW, p.FixedCellH = w, h
+		applyPanelFont(p)
+		fitRowHeights(p)
+		g.events.Publish(PanelResized{Panel: pi, Cols: p.Cols, Rows: p.Rows})
+	})
+}
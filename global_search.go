@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// searchChunk is how many combined rows a workspace search reads per frame.
+const searchChunk = 4096
+
+// searchAllPanels asks for text to find, or a query, and runs it over the
+// data rows of every loaded panel stacked into one table (see
+// CombinedRecords). The matching rows, or the query's result, are placed
+// as a new panel at x, y. The result is not kept up to date.
+func searchAllPanels(g *Game, x, y int) {
+	hint := "text to find, or e.g. SELECT panel, count(*) AS n WHERE status = 'open' GROUP BY panel"
+	g.prompt.Open("Search All Panels", hint, "", func(g *Game, text string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+		// derived panels repeat their source's rows, and paged ones are too
+		// large to copy
+		recs := g.canvas.CombinedRecords(func(i int) bool {
+			p := &g.canvas.Panels[i]
+			return p.Loaded && p.Query == nil && p.Paged == nil
+		})
+		if len(recs) == 1 {
+			g.events.Publish(LogMessage{Text: "no loaded panel has rows to search"})
+			return
+		}
+		panels := make(map[string]bool)
+		for _, rec := range recs[1:] {
+			panels[rec[0]] = true
+		}
+
+		var run *cellcanvas.QueryRun
+		if strings.HasPrefix(strings.ToUpper(text), "SELECT ") {
+			src, err := cellcanvas.NewPanelFromRecords(0, 0, recs)
+			if err == nil {
+				run, err = cellcanvas.StartQuery(text, &src)
+			}
+			if err != nil {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("search: %v", err)})
+				return
+			}
+		}
+		out := [][]string{recs[0]}
+		row := 1
+		g.canvas.scheduler.Add(func() bool {
+			if run != nil {
+				if !run.Step(searchChunk) {
+					return false
+				}
+				out = run.Result()
+			} else {
+				for end := min(row+searchChunk, len(recs)); row < end; row++ {
+					if cellcanvas.RecordContains(recs[row], cellcanvas.CombinedCols, text) {
+						out = append(out, recs[row])
+					}
+				}
+				if row < len(recs) {
+					return false
+				}
+			}
+			i, err := g.canvas.AddPanelFromRecords(out, x, y)
+			if err != nil {
+				reportError(g, "Search all panels", err)
+				return true
+			}
+			g.canvas.Panels[i].Name = "Search: " + text
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: %d row(s) from searching %d row(s) of %d panel(s) for %q", i+1, len(out)-1, len(recs)-1, len(panels), text)})
+			return true
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// goalSeek asks for a target value and an input cell, then adjusts the
+// input until cell col,row of panel pi shows the target.
+func goalSeek(g *Game, pi, col, row int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	ref := cellcanvas.CellRef(col, row)
+	g.prompt.Open("Goal seek: set "+ref+" to", "target value", "", func(g *Game, v string) {
+		target, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("goal seek: %q is not a number", v)})
+			return
+		}
+		g.prompt.Open("Goal seek: by changing cell", "a cell of the same panel, e.g. B2", "", func(g *Game, v string) {
+			inCol, inRow, err := cellcanvas.ParseCellRef(strings.ToUpper(strings.TrimSpace(v)))
+			if err != nil || pi >= len(g.canvas.Panels) {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("goal seek: %q is not a cell", v)})
+				return
+			}
+			runGoalSeek(g, pi, col, row, inCol, inRow, target)
+		})
+	})
+}
+
+func runGoalSeek(g *Game, pi, col, row, inCol, inRow int, target float64) {
+	p := &g.canvas.Panels[pi]
+	ref, inRef := cellcanvas.CellRef(col, row), cellcanvas.CellRef(inCol, inRow)
+	if inCol == col && inRow == row {
+		g.events.Publish(LogMessage{Text: "goal seek: the input must be another cell"})
+		return
+	}
+	old := p.RawCell(inCol, inRow)
+	x, err := p.GoalSeek(col, row, inCol, inRow, target)
+	if errors.Is(err, cellcanvas.ErrNoDependence) {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("goal seek: %s does not change with %s", ref, inRef)})
+		return
+	}
+	if err != nil {
+		g.events.Publish(LogMessage{Text: "goal seek: " + err.Error()})
+		return
+	}
+	p.Cols, p.Rows = max(p.Cols, inCol+1), max(p.Rows, inRow+1)
+	if val := p.RawCell(inCol, inRow); val != old {
+		g.events.Publish(CellChanged{Panel: pi, Col: inCol, Row: inRow, Old: old, New: val})
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("goal seek: %s = %s gives %s = %s", inRef, formatStat(x, p.Numbers()), ref, p.GetCell(col, row))})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	_ "embed"
+	"image/color"
+	"log"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:embed res/grid.kage
+var gridShaderSrc []byte
+
+// gridShader draws a panel's cell backgrounds and grid lines as a single
+// quad. Its uniform slices are allocated once and updated in place so
+// drawing stays allocation-free.
+type gridShader struct {
+	shader   *ebiten.Shader
+	op       ebiten.DrawRectShaderOptions
+	cellSize []float32
+	origin   []float32
+	mode     []float32
+	zebra    []float32
+}
+
+// newGridShader compiles the grid shader; it returns nil (and the caller
+// falls back to drawing rects) if the GPU backend rejects it.
+func newGridShader() *gridShader {
+	sh, err := ebiten.NewShader(gridShaderSrc)
+	if err != nil {
+		log.Printf("grid shader unavailable, drawing per-cell rects: %v", err)
+		return nil
+	}
+	g := &gridShader{shader: sh, cellSize: make([]float32, 2), origin: make([]float32, 2), mode: make([]float32, 1), zebra: make([]float32, 1)}
+	g.op.Uniforms = map[string]any{
+		"CellSize":    g.cellSize,
+		"Origin":      g.origin,
+		"CellColor":   premultiplied(ColorCellBg),
+		"LineColor":   premultiplied(ColorPanelBg),
+		"StripeColor": premultiplied(ColorZebraRow),
+		"Mode":        g.mode,
+		"Zebra":       g.zebra,
+	}
+	return g
+}
+
+func premultiplied(c color.Color) []float32 {
+	r, g, b, a := c.RGBA()
+	return []float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+}
+
+// draw fills the on-screen part of the panel's content area with its grid
+// in the given style.
+// The quad is clipped to the screen and the origin reduced modulo two rows
+// (keeping stripe parity) so float32 precision holds for panels millions of
+// pixels tall.
+func (g *gridShader) draw(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, style string) {
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	x0, y0 := max(b.ContentX, 0), max(b.ContentY, 0)
+	x1, y1 := min(b.ContentX+b.ContentW, sw), min(b.ContentY+b.ContentH, sh)
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+	g.mode[0] = gridMode(style)
+	g.zebra[0] = 0
+	if p.Zebra {
+		g.zebra[0] = 1
+	}
+	g.cellSize[0], g.cellSize[1] = float32(p.CellW), float32(p.CellH)
+	g.origin[0] = float32(x0 - (x0-b.GridX)%p.CellW)
+	g.origin[1] = float32(y0 - (y0-b.GridY)%(2*p.CellH))
+	g.op.GeoM.Reset()
+	g.op.GeoM.Translate(float64(x0), float64(y0))
+	screen.DrawRectShader(x1-x0, y1-y0, g.shader, &g.op)
+}
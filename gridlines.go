@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Gridline styles, as stored in state.yml and settings.yml. A panel with no
+// style follows the global default from settings.
+const (
+	GridSolid      = "solid"
+	GridHorizontal = "horizontal"
+	GridDotted     = "dotted"
+	GridNone       = "none"
+)
+
+// gridStyles lists the styles in picker order with their labels.
+var gridStyles = []struct{ style, label string }{
+	{GridSolid, "Solid"},
+	{GridDotted, "Dotted"},
+	{GridHorizontal, "Horizontal only"},
+	{GridNone, "None"},
+}
+
+// gridMode maps a style to the grid shader's Mode uniform.
+func gridMode(style string) float32 {
+	switch style {
+	case GridHorizontal:
+		return 1
+	case GridDotted:
+		return 2
+	case GridNone:
+		return 3
+	}
+	return 0
+}
+
+// gridStyle returns the style p is drawn with.
+func (r *Renderer) gridStyle(p *cellcanvas.Panel) string {
+	if p.Gridlines != "" {
+		return p.Gridlines
+	}
+	return r.defaultGrid
+}
+
+// drawCellBackgrounds draws the backgrounds and gridlines of the visible
+// cells without the shader, one rect per cell (or per row, or one for the
+// whole range, depending on style).
+func (r *Renderer) drawCellBackgrounds(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, style string, firstRow, lastRow, firstCol, lastCol int) {
+	if firstRow >= lastRow || firstCol >= lastCol {
+		return
+	}
+	x0 := float64(b.GridX + p.ColX(firstCol))
+	x1 := float64(b.GridX + p.ColX(lastCol))
+	y0 := float64(b.GridY + p.RowY(firstRow))
+	y1 := float64(b.GridY + p.RowY(lastRow))
+	switch style {
+	case GridNone:
+		r.fillRect(screen, x0, y0, x1-x0, y1-y0, ColorCellBg)
+		r.drawStripes(screen, p, b, x0, x1, firstRow, lastRow)
+	case GridHorizontal:
+		for row := firstRow; row < lastRow; row++ {
+			y := float64(b.GridY + p.RowY(row))
+			r.fillRect(screen, x0, y, x1-x0, float64(p.RowHeight(row)-1), rowBg(p, row))
+		}
+	case GridDotted:
+		r.fillRect(screen, x0, y0, x1-x0, y1-y0, ColorCellBg)
+		r.drawStripes(screen, p, b, x0, x1, firstRow, lastRow)
+		// dots start at the screen edge so only visible ones are drawn
+		dx0, dx1 := math.Max(x0, 0), math.Min(x1, float64(screen.Bounds().Dx()))
+		dy0, dy1 := math.Max(y0, 0), math.Min(y1, float64(screen.Bounds().Dy()))
+		for row := firstRow; row < lastRow; row++ {
+			y := float64(b.GridY + p.RowY(row+1) - 1)
+			for x := math.Floor(dx0/4) * 4; x < dx1; x += 4 {
+				r.fillRect(screen, x, y, 2, 1, ColorPanelBg)
+			}
+		}
+		for col := firstCol; col < lastCol; col++ {
+			x := float64(b.GridX + p.ColX(col+1) - 1)
+			for y := math.Floor(dy0/4) * 4; y < dy1; y += 4 {
+				r.fillRect(screen, x, y, 1, 2, ColorPanelBg)
+			}
+		}
+	default:
+		for row := firstRow; row < lastRow; row++ {
+			y := float64(b.GridY + p.RowY(row))
+			x := x0
+			for col := firstCol; col < lastCol; col, x = col+1, x+float64(p.ColWidth(col)) {
+				r.fillRect(screen, x, y, float64(p.ColWidth(col)-1), float64(p.RowHeight(row)-1), rowBg(p, row))
+			}
+		}
+	}
+}
+
+// rowBg is the background color of row in p: odd rows of a zebra-striped
+// panel are shaded.
+func rowBg(p *cellcanvas.Panel, row int) color.Color {
+	if p.Zebra && row%2 == 1 {
+		return ColorZebraRow
+	}
+	return ColorCellBg
+}
+
+// drawStripes shades the odd rows between firstRow and lastRow of a
+// zebra-striped panel edge to edge, for styles without horizontal gaps.
+func (r *Renderer) drawStripes(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, x0, x1 float64, firstRow, lastRow int) {
+	if !p.Zebra {
+		return
+	}
+	for row := firstRow | 1; row < lastRow; row += 2 {
+		y := float64(b.GridY + p.RowY(row))
+		r.fillRect(screen, x0, y, x1-x0, float64(p.RowHeight(row)), ColorZebraRow)
+	}
+}
+
+// toggleZebra switches alternate row shading for panel pi.
+func toggleZebra(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	p.Zebra = !p.Zebra
+	state := "off"
+	if p.Zebra {
+		state = "on"
+	}
+	g.events.Publish(LogMessage{Text: "zebra stripes " + state})
+}
+
+// pickGridlines lets the user choose panel pi's gridline style.
+func pickGridlines(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := []string{fmt.Sprintf("Default (%s)", g.renderer.defaultGrid)}
+	for _, s := range gridStyles {
+		items = append(items, s.label)
+	}
+	g.picker.Open(fmt.Sprintf("Gridlines for Panel %d", pi+1), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		if i == 0 {
+			g.canvas.Panels[pi].Gridlines = ""
+			return
+		}
+		g.canvas.Panels[pi].Gridlines = gridStyles[i-1].style
+	})
+}
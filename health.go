@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// maxHealthItems caps the problems listed at once; fixing one lists the
+// next.
+const maxHealthItems = 20
+
+// checkWorkspace runs the workspace health check and lists the problems in
+// a picker; picking one applies its fix and checks again.
+func checkWorkspace(g *Game) {
+	var issues []cellcanvas.Issue
+	for _, is := range g.canvas.Check(defaultStatePath) {
+		// the audit trail lives next to state.yml but is not a panel
+		if is.Kind == cellcanvas.IssueOrphanFile && filepath.Base(is.File) == auditFile {
+			continue
+		}
+		issues = append(issues, is)
+	}
+	if len(issues) == 0 {
+		g.events.Publish(LogMessage{Text: "workspace check: no problems found"})
+		return
+	}
+	title := fmt.Sprintf("Workspace check: %d problem(s), pick one to fix", len(issues))
+	if len(issues) > maxHealthItems {
+		issues = issues[:maxHealthItems]
+	}
+	items := make([]string, len(issues))
+	for i, is := range issues {
+		items[i] = issueLabel(g, is)
+	}
+	g.picker.Open(title, items, func(g *Game, i int) {
+		if fixIssue(g, issues[i]) {
+			checkWorkspace(g)
+		}
+	})
+}
+
+// issueLabel describes is and its fix in one picker line.
+func issueLabel(g *Game, is cellcanvas.Issue) string {
+	where := fmt.Sprintf("Panel %d", is.Panel+1)
+	switch is.Kind {
+	case cellcanvas.IssueMissingFile:
+		fix := "write it from the panel"
+		if !g.canvas.Panels[is.Panel].Loaded {
+			fix = "drop the reference"
+		}
+		return fmt.Sprintf("%s: %s %s - fix: %s", where, is.File, is.Detail, fix)
+	case cellcanvas.IssueSharedFile, cellcanvas.IssueDuplicateName:
+		return fmt.Sprintf("%s: %s %s - fix: save to a new file", where, is.File, is.Detail)
+	case cellcanvas.IssueOrphanFile:
+		return fmt.Sprintf("%s %s - fix: open as a panel", filepath.Base(is.File), is.Detail)
+	case cellcanvas.IssueBadReference:
+		return fmt.Sprintf("%s: %s - fix: go to cell", where, is.Detail)
+	}
+	return where + ": " + is.Detail
+}
+
+// fixIssue applies the fix offered for is. It reports whether the check
+// should be run again.
+func fixIssue(g *Game, is cellcanvas.Issue) bool {
+	if is.Panel >= len(g.canvas.Panels) {
+		return false
+	}
+	switch is.Kind {
+	case cellcanvas.IssueMissingFile:
+		p := &g.canvas.Panels[is.Panel]
+		if !p.Loaded {
+			// the load failed; leave the panel blank and saved anew
+			p.Filename = ""
+			p.Loaded = true
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d no longer refers to %s", is.Panel+1, is.File)})
+			return true
+		}
+		path := p.Filename
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(defaultStatePath), path)
+		}
+		if err := cellcanvas.SavePanelCSV(path, p); err != nil {
+			reportError(g, "Write "+filepath.Base(path), err)
+			return false
+		}
+		g.events.Publish(LogMessage{Text: "wrote: " + filepath.Base(path)})
+	case cellcanvas.IssueSharedFile, cellcanvas.IssueDuplicateName:
+		p := &g.canvas.Panels[is.Panel]
+		p.Filename = g.canvas.UnusedFilename()
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d now saves to %s", is.Panel+1, p.Filename)})
+	case cellcanvas.IssueOrphanFile:
+		x, y := g.canvas.ScreenToWorld(g.power.layoutW/2, g.power.layoutH/2)
+		if err := g.canvas.AddPanelFromCSV(is.File, x, y); err != nil {
+			reportError(g, "Load "+filepath.Base(is.File), err)
+			return false
+		}
+	case cellcanvas.IssueBadReference:
+		goToCell(g, is.Panel, is.Col, is.Row)
+		return false
+	}
+	return true
+}
+
+// goToCell selects a cell and centers the camera on it.
+func goToCell(g *Game, panel, col, row int) {
+	p := &g.canvas.Panels[panel]
+	w, h := g.viewSize()
+	g.canvas.CamX = float64(w/2 - p.X - p.ColX(col))
+	g.canvas.CamY = float64(h/2 - p.Y - p.RowY(row))
+	g.input.setSelection(g, panel, row, col)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// Histogram popover geometry and scan chunk size.
+const (
+	histogramBins  = 20
+	histogramChunk = 4096
+	histogramW     = 260
+	histogramH     = 140
+)
+
+// Distribution is the "Show Distribution" popover: a small histogram of one
+// numeric column, computed on the frame scheduler and drawn next to where
+// the menu was opened. Any click or Esc closes it.
+type Distribution struct {
+	visible bool
+	panel   int
+	title   string
+	unit    string
+	num     cellcanvas.NumberLocale
+	x, y    int
+	hist    *cellcanvas.Histogram
+	done    bool
+	gen     int
+}
+
+func NewDistribution() *Distribution {
+	return &Distribution{}
+}
+
+// Observe closes the popover when its panel goes away or changes.
+func (d *Distribution) Observe(e Event) {
+	switch ev := e.(type) {
+	case PanelRemoved, WorkspaceLoaded:
+		d.close()
+	case CellChanged:
+		if d.visible && ev.Panel == d.panel && d.hist != nil && ev.Col == d.hist.Col {
+			d.close()
+		}
+	}
+}
+
+func (d *Distribution) close() {
+	d.visible = false
+	d.gen++
+}
+
+// Start opens the popover at screen position x, y for column col of panel
+// pi.
+func (d *Distribution) Start(g *Game, pi, col, x, y int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	if !p.Loaded {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is still loading", pi+1)})
+		return
+	}
+	d.close()
+	d.visible, d.done = true, false
+	d.panel, d.x, d.y = pi, x, y
+	d.title = cellcanvas.ColToLetters(col)
+	if h := strings.TrimSpace(p.GetCell(col, 0)); h != "" {
+		if _, err := p.Numbers().Parse(h); err != nil {
+			d.title += " " + h
+		}
+	}
+	d.num = p.Numbers()
+	if d.unit = p.UnitAt(col); d.unit != "" {
+		d.title += " (" + d.unit + ")"
+	}
+	d.hist = cellcanvas.NewHistogram(p, col, histogramBins)
+	gen := d.gen
+	g.canvas.scheduler.Add(func() bool {
+		if gen != d.gen || pi >= len(g.canvas.Panels) {
+			return true
+		}
+		if !d.hist.Step(&g.canvas.Panels[pi], histogramChunk) {
+			return false
+		}
+		d.done = true
+		g.power.redraw = true
+		if d.hist.Count == 0 {
+			d.close()
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s of Panel %d has no numbers", cellcanvas.ColToLetters(col), pi+1)})
+		}
+		return true
+	})
+}
+
+// Update closes the popover on any click or Esc. The click still reaches
+// the handlers behind it.
+func (d *Distribution) Update(g *Game) {
+	if !d.visible {
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) || g.frame.MouseJustPressed(ebiten.MouseButtonLeft) || g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
+		d.close()
+		g.power.redraw = true
+	}
+}
+
+// Draw renders the popover, or its progress while the scan is running.
+func (d *Distribution) Draw(screen *ebiten.Image, face font.Face) {
+	if !d.visible || d.hist == nil {
+		return
+	}
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	x := max(0, min(d.x, sw-histogramW))
+	y := max(0, min(d.y, sh-histogramH))
+	ebitenutil.DrawRect(screen, float64(x), float64(y), histogramW, histogramH, ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), histogramW, 1, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y+histogramH-1), histogramW, 1, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 1, histogramH, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x+histogramW-1), float64(y), 1, histogramH, ColorMenuBorder)
+
+	h := d.hist
+	pad := PanelInnerPadding
+	if !d.done {
+		drawTextAt(screen, face, d.title, x+pad, y+pad, ColorText)
+		drawTextAt(screen, face, fmt.Sprintf("computing... %d%%", int(h.Progress()*100)), x+pad, y+histogramH/2, ColorTextDim)
+		return
+	}
+	drawTextAt(screen, face, fmt.Sprintf("%s  (%d values)", d.title, h.Count), x+pad, y+pad, ColorText)
+
+	// bars, scaled to the fullest bin
+	top, bottom := y+pad+20, y+histogramH-pad-18
+	peak := 1
+	for _, n := range h.Bins {
+		peak = max(peak, n)
+	}
+	barW := float64(histogramW-2*pad) / float64(len(h.Bins))
+	for i, n := range h.Bins {
+		if n == 0 {
+			continue
+		}
+		bh := float64(bottom-top) * float64(n) / float64(peak)
+		bx := float64(x+pad) + float64(i)*barW
+		ebitenutil.DrawRect(screen, bx+1, float64(bottom)-bh, math.Max(barW-2, 1), bh, ColorMenuHighlight)
+	}
+	ebitenutil.DrawRect(screen, float64(x+pad), float64(bottom), float64(histogramW-2*pad), 1, ColorMenuBorder)
+
+	lo, hi := formatStat(h.Min, d.num), formatStat(h.Max, d.num)
+	if d.unit != "" {
+		lo, hi = lo+" "+d.unit, hi+" "+d.unit
+	}
+	drawTextAt(screen, face, lo, x+pad, bottom+2, ColorTextDim)
+	drawTextAt(screen, face, hi, x+histogramW-pad-font.MeasureString(face, hi).Round(), bottom+2, ColorTextDim)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"gopkg.in/yaml.v3"
+)
+
+// historyFile is written next to the state file when history persistence is
+// enabled (-keep-history).
+const historyFile = "history.yml"
+
+// maxCellHistory is how many previous values are kept per cell.
+const maxCellHistory = 20
+
+// HistoryEntry is one previous value of a cell and when it was replaced.
+// By and Conflict are copied from the CellChanged that replaced it.
+type HistoryEntry struct {
+	Time     time.Time `yaml:"time"`
+	Value    string    `yaml:"value"`
+	By       string    `yaml:"by,omitempty"`
+	Conflict string    `yaml:"conflict,omitempty"`
+}
+
+type historyKey struct {
+	panel, col, row int
+}
+
+// CellHistory records the previous values of every edited cell from
+// CellChanged events. Entries are keyed by panel index and follow panels
+// when an earlier one is deleted. The history of an encrypted workspace is
+// kept for the session only: history.yml would hold every old value in
+// plain text next to the archive.
+type CellHistory struct {
+	entries map[historyKey][]HistoryEntry
+	// persist saves and restores history alongside the state file
+	persist bool
+}
+
+// NewCellHistory creates an empty history.
+func NewCellHistory(persist bool) *CellHistory {
+	return &CellHistory{entries: make(map[historyKey][]HistoryEntry), persist: persist}
+}
+
+// Observe folds one event into the history.
+func (h *CellHistory) Observe(e Event, c *Canvas) {
+	switch ev := e.(type) {
+	case CellChanged:
+		k := historyKey{ev.Panel, ev.Col, ev.Row}
+		list := append(h.entries[k], HistoryEntry{Time: time.Now(), Value: ev.Old, By: ev.By, Conflict: ev.Conflict})
+		if len(list) > maxCellHistory {
+			list = list[len(list)-maxCellHistory:]
+		}
+		h.entries[k] = list
+	case PanelRemoved:
+		moved := make(map[historyKey][]HistoryEntry, len(h.entries))
+		for k, list := range h.entries {
+			switch {
+			case k.panel == ev.Panel:
+				continue
+			case k.panel > ev.Panel:
+				k.panel--
+			}
+			moved[k] = list
+		}
+		h.entries = moved
+	case WorkspaceSaved:
+		if !h.persist {
+			return
+		}
+		path := filepath.Join(filepath.Dir(ev.Path), historyFile)
+		if c.password != "" {
+			// drop the plain copy left from before the workspace was encrypted
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("history: %v", err)
+			}
+			return
+		}
+		if err := h.save(path); err != nil {
+			log.Printf("history: %v", err)
+		}
+	case WorkspaceLoaded:
+		if c.password != "" {
+			h.entries = make(map[historyKey][]HistoryEntry)
+			return
+		}
+		h.Load(ev.Path)
+	}
+}
+
+// Entries returns the previous values of a cell, oldest first.
+func (h *CellHistory) Entries(panel, col, row int) []HistoryEntry {
+	return h.entries[historyKey{panel, col, row}]
+}
+
+// Load replaces the history with the one saved next to statePath, or clears
+// it when persistence is off or there is none.
+func (h *CellHistory) Load(statePath string) {
+	h.entries = make(map[historyKey][]HistoryEntry)
+	if !h.persist {
+		return
+	}
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(statePath), historyFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("history: %v", err)
+		}
+		return
+	}
+	var recs []historyRecord
+	if err := yaml.Unmarshal(b, &recs); err != nil {
+		log.Printf("history: %v", err)
+		return
+	}
+	for _, r := range recs {
+		col, row, err := cellcanvas.ParseCellRef(r.Cell)
+		if err != nil || r.Panel < 1 {
+			continue
+		}
+		h.entries[historyKey{r.Panel - 1, col, row}] = r.Entries
+	}
+}
+
+// historyRecord is the on-disk form of one cell's history.
+type historyRecord struct {
+	Panel   int            `yaml:"panel"` // 1-based, as shown in panel headers
+	Cell    string         `yaml:"cell"`
+	Entries []HistoryEntry `yaml:"entries"`
+}
+
+func (h *CellHistory) save(path string) error {
+	recs := make([]historyRecord, 0, len(h.entries))
+	for k, list := range h.entries {
+		recs = append(recs, historyRecord{Panel: k.panel + 1, Cell: cellcanvas.CellRef(k.col, k.row), Entries: list})
+	}
+	b, err := yaml.Marshal(recs)
+	if err != nil {
+		return err
+	}
+	// a crash mid-save keeps the previous history
+	return cellcanvas.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// historyUser names the user who made a change, by as recorded in a
+// HistoryEntry.
+func historyUser(by string) string {
+	if by == "" {
+		return hostUser
+	}
+	return by
+}
+
+// showCellHistory opens a picker listing the previous values of a cell,
+// newest first; picking one restores it.
+func showCellHistory(g *Game, panel, col, row int) {
+	list := g.history.Entries(panel, col, row)
+	ref := cellcanvas.CellRef(col, row)
+	if len(list) == 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d %s: no history", panel+1, ref)})
+		return
+	}
+	items := make([]string, len(list))
+	for i := range list {
+		e := list[len(list)-1-i]
+		v := strings.ReplaceAll(e.Value, "\n", " ")
+		if v == "" {
+			v = "(empty)"
+		}
+		items[i] = e.Time.Format("Jan 2 15:04:05") + "   " + v
+		if e.Conflict != "" {
+			items[i] += fmt.Sprintf("   (overwritten by %s while %s was editing)", historyUser(e.By), e.Conflict)
+		} else if e.By != "" {
+			items[i] += "   (overwritten by " + e.By + ")"
+		}
+	}
+	g.picker.Open(fmt.Sprintf("History of Panel %d %s - click to restore", panel+1, ref), items, func(g *Game, i int) {
+		if panel >= len(g.canvas.Panels) {
+			return
+		}
+		list := g.history.Entries(panel, col, row)
+		if i >= len(list) {
+			return
+		}
+		val := list[len(list)-1-i].Value
+		p := &g.canvas.Panels[panel]
+		old := p.RawCell(col, row)
+		if old == val {
+			return
+		}
+		p.SetCell(col, row, val)
+		g.events.Publish(CellChanged{Panel: panel, Col: col, Row: row, Old: old, New: val})
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("restored Panel %d %s", panel+1, ref)})
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// importFolderModes are the ways Import Folder can lay out the files it
+// loads.
+var importFolderModes = []string{"Separate panels in a grid", "One panel with a source column"}
+
+// FolderImport loads the files matched by Import Folder one per frame on
+// the scheduler, so a folder of many CSVs does not stall the canvas.
+type FolderImport struct {
+	// gen is bumped when the workspace is replaced, stopping running
+	// imports
+	gen int
+}
+
+func NewFolderImport() *FolderImport {
+	return &FolderImport{}
+}
+
+// Observe stops imports into a workspace that was replaced.
+func (fi *FolderImport) Observe(e Event) {
+	if _, ok := e.(WorkspaceLoaded); ok {
+		fi.gen++
+	}
+}
+
+// importFolder asks for a folder, a file name pattern and a layout, then
+// loads every matching CSV with its top-left corner at world x, y.
+func importFolder(g *Game, x, y int) {
+	dir, err := dialog.Directory().Title("Import Folder").Browse()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("folder open failed: %v", err)
+		}
+		return
+	}
+	g.prompt.Open("Files to import", "glob like *.csv or day-*.csv, or /regexp/ matched against file names", "*.csv", func(g *Game, pattern string) {
+		paths, err := cellcanvas.MatchFiles(dir, pattern)
+		if err != nil {
+			reportError(g, "Import Folder", err)
+			return
+		}
+		if len(paths) == 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("no files in %s match %s", filepath.Base(dir), pattern)})
+			return
+		}
+		title := fmt.Sprintf("Import %d file(s) from %s", len(paths), filepath.Base(dir))
+		g.picker.Open(title, importFolderModes, func(g *Game, mode int) {
+			if mode == 0 {
+				importGrid(g, paths, x, y)
+			} else {
+				importStacked(g, paths, x, y)
+			}
+		})
+	})
+}
+
+// importGrid loads each of paths into its own panel, laid out in rows of
+// about the square root of their count.
+func importGrid(g *Game, paths []string, x, y int) {
+	perRow := int(math.Ceil(math.Sqrt(float64(len(paths)))))
+	gen := g.folderImport.gen
+	done, failed := 0, 0
+	px, py, rowH := x, y, 0
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("importing %d file(s)...", len(paths))})
+	g.canvas.scheduler.Add(func() bool {
+		if gen != g.folderImport.gen {
+			return true
+		}
+		path := paths[done]
+		done++
+		p := cellcanvas.NewBlankPanel(px, py, 1, 1)
+		if err := cellcanvas.LoadPanelCSV(path, &p); err != nil {
+			reportError(g, "Import Folder", err)
+			failed++
+		} else {
+			p.Filename = filepath.Base(path)
+			p.X, p.Y = px, py
+			p.Loaded = true
+			g.events.Publish(PanelAdded{Panel: g.canvas.AddPanel(p)})
+			px += p.Width() + PanelPaddingX*2 + viewGap
+			rowH = max(rowH, p.Height()+PanelPaddingY*2)
+			if (done-failed)%perRow == 0 {
+				px, py, rowH = x, py+rowH+PanelHeaderHeight+viewGap, 0
+			}
+		}
+		g.power.redraw = true
+		if done < len(paths) {
+			return false
+		}
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("imported %d of %d file(s)", done-failed, len(paths))})
+		return true
+	})
+}
+
+// importStacked appends the rows of paths, matched by header, into one new
+// panel with a source column naming the file each row came from. The panel
+// tracks row sources, so Row Details also shows the file.
+func importStacked(g *Game, paths []string, x, y int) {
+	type span struct {
+		first, n int
+		name     string
+	}
+	gen := g.folderImport.gen
+	done, unclean := 0, 0
+	var spans []span
+	dst := cellcanvas.NewBlankPanel(x, y, 1, 1)
+	dst.Rows = 0
+	dst.TrackSources = true
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("importing %d file(s) into one panel...", len(paths))})
+	g.canvas.scheduler.Add(func() bool {
+		if gen != g.folderImport.gen {
+			return true
+		}
+		path := paths[done]
+		done++
+		src := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+		if err := cellcanvas.LoadPanelCSV(path, &src); err != nil {
+			reportError(g, "Import Folder", err)
+		} else {
+			first, n := dst.AppendPanel(&src, true)
+			dst.AddRowSource(first, n, path, time.Now())
+			spans = append(spans, span{first, n, filepath.Base(path)})
+			if len(src.Problems) > 0 {
+				log.Printf("%s: %d problem(s) loading", path, len(src.Problems))
+				unclean++
+			}
+		}
+		if done < len(paths) {
+			return false
+		}
+		if len(spans) == 0 {
+			return true
+		}
+		// the source column goes after every column the files brought
+		col := dst.UsedCols()
+		dst.SetCell(col, 0, "source")
+		for _, s := range spans {
+			for r := s.first; r < s.first+s.n; r++ {
+				if r > 0 {
+					dst.SetCell(col, r, s.name)
+				}
+			}
+		}
+		dst.Cols = max(dst.Cols, col+1)
+		if unclean > 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("%d file(s) did not load cleanly; see the log", unclean)})
+		}
+		dst.Name = filepath.Base(filepath.Dir(paths[0]))
+		i := g.canvas.AddPanel(dst)
+		g.events.Publish(PanelAdded{Panel: i})
+		g.power.redraw = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("imported %d row(s) from %d file(s) into Panel %d", dst.UsedRows()-1, len(spans), i+1)})
+		return true
+	})
+}
@@ -2,11 +2,12 @@ package main
 
 import (
 	"log"
+	"math"
 	"path/filepath"
 
+	"github.com/example/cellchain/cellcanvas"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/sqweek/dialog"
 	"golang.org/x/image/font"
 )
@@ -27,10 +28,41 @@ type InputManager struct {
 	resizingPanel int
 	moveOffsetX   int
 	moveOffsetY   int
+	// panel position (move) or cols/rows (resize) when the drag started,
+	// used to report PanelMoved/PanelResized on release
+	dragStartX int
+	dragStartY int
+	// last click on a column boundary, for double-click auto-fit
+	lastEdgePanel int
+	lastEdgeCol   int
+	lastEdgeTime  int64
+	// resizeCols, resizeRows is the size the panel being resized gets
+	// when the mouse is released
+	resizeCols, resizeRows int
+	// movingCells is set while the selected range is dragged by its
+	// outline; moveGrabCol, moveGrabRow is the grabbed cell relative to the
+	// range's top-left, and moveTo* where the range would land
+	movingCells                       bool
+	moveGrabCol, moveGrabRow          int
+	moveToPanel, moveToCol, moveToRow int
+	// scrollPanel is the panel whose scrollbar is dragged, or -1;
+	// scrollCols is set for its horizontal bar
+	scrollPanel int
+	scrollCols  bool
+	// nudgeGrid is the grid Ctrl+Shift+arrows move panels along; 0 moves
+	// them by one pixel
+	nudgeGrid int
+	// keepInView stops panning from moving every panel off screen
+	keepInView bool
 
-	// selection (moved from Game)
-	activePanel    int
-	selRow, selCol int
+	// selection (moved from Game); the anchor is the fixed corner of a range
+	// selection and equals selRow/selCol when a single cell is selected
+	activePanel          int
+	selRow, selCol       int
+	anchorRow, anchorCol int
+	// restorePanel is the saved active panel of a workspace that is made
+	// active once its data has loaded; -1 when none is waiting
+	restorePanel int
 
 	// editing (moved from Game)
 	editing      bool
@@ -49,40 +81,44 @@ func NewInputManager() *InputManager {
 	return &InputManager{
 		movingPanel:      -1,
 		resizingPanel:    -1,
+		lastEdgePanel:    -1,
 		activePanel:      0,
 		selRow:           0,
 		selCol:           0,
 		editingPanelName: false,
 		editPanelIndex:   -1,
+		restorePanel:     -1,
+		scrollPanel:      -1,
 	}
 }
 
 func (im *InputManager) HandlePanInput(g *Game) {
 	// start/stop dragging with right mouse
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+	if g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
 		im.dragging = true
-		im.lastMouseX, im.lastMouseY = ebiten.CursorPosition()
+		im.lastMouseX, im.lastMouseY = g.frame.CursorPosition()
 		im.rightPressedX, im.rightPressedY = im.lastMouseX, im.lastMouseY
 	}
-	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight) {
+	if im.dragging && g.frame.MouseJustReleased(ebiten.MouseButtonRight) {
 		// determine if this was a click (small movement) or a drag
-		mx, my := ebiten.CursorPosition()
+		mx, my := g.frame.CursorPosition()
 		dx := mx - im.rightPressedX
 		dy := my - im.rightPressedY
 		// small threshold -> treat as click and open context menu
 		if abs(dx) < 6 && abs(dy) < 6 {
 			// toggle context menu at cursor
 			// Determine which panel (if any) was clicked so menu actions can act on it.
+			vx, vy := g.canvas.ToView(mx, my)
 			target := -1
-			for i := len(g.canvas.panels) - 1; i >= 0; i-- {
-				p := g.canvas.panels[i]
-				b := p.GetBounds(g.canvas.camX, g.canvas.camY)
+			for i := len(g.canvas.Panels) - 1; i >= 0; i-- {
+				p := g.canvas.Panels[i]
+				b := g.canvas.Bounds(&p)
 				baseX := b.ContentX
 				baseY := b.ContentY
 				w := b.ContentW
 				h := b.ContentH
 				headerY := baseY - PanelHeaderHeight
-				if mx >= baseX && mx <= baseX+w && my >= headerY && my <= headerY+h {
+				if vx >= baseX && vx <= baseX+w && vy >= headerY && vy <= headerY+h {
 					target = i
 					break
 				}
@@ -91,12 +127,16 @@ func (im *InputManager) HandlePanInput(g *Game) {
 		}
 		im.dragging = false
 	}
-	if im.dragging && ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) {
-		mx, my := ebiten.CursorPosition()
+	if im.dragging && g.frame.MousePressed(ebiten.MouseButtonRight) {
+		mx, my := g.frame.CursorPosition()
 		dx := mx - im.lastMouseX
 		dy := my - im.lastMouseY
-		g.canvas.camX += float64(dx)
-		g.canvas.camY += float64(dy)
+		// the camera is in view pixels; at a zoom the cursor moves further
+		g.canvas.CamX += float64(dx) / g.canvas.zoom()
+		g.canvas.CamY += float64(dy) / g.canvas.zoom()
+		if im.keepInView {
+			constrainCamera(g)
+		}
 		im.lastMouseX = mx
 		im.lastMouseY = my
 	}
@@ -110,9 +150,402 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 		// nothing to do
 	case MenuActionNewBlankPanel:
 		// compute world coords (screen - cam)
-		wx := int(float64(g.contextMenu.x) - g.canvas.camX)
-		wy := int(float64(g.contextMenu.y) - g.canvas.camY)
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
 		g.canvas.AddPanelAt(wx, wy)
+	case MenuActionToggleStats:
+		g.stats.Toggle()
+	case MenuActionNewFeedPanel:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		promptNewFeedPanel(g, wx, wy)
+	case MenuActionNewCommandPanel:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		promptNewCommandPanel(g, wx, wy)
+	case MenuActionPasteAsPanel:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		pasteAsNewPanel(g, wx, wy)
+	case MenuActionImportODS:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		importODS(g, wx, wy)
+	case MenuActionExportODS:
+		exportWorkspace(g, "OpenDocument Spreadsheet", "ods", cellcanvas.WriteODS)
+	case MenuActionExportXLSX:
+		exportWorkspace(g, "Excel Workbook", "xlsx", cellcanvas.WriteXLSX)
+	case MenuActionImportJSON:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		importJSON(g, wx, wy)
+	case MenuActionDelimiter:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickDelimiter(g, target)
+	case MenuActionContents:
+		g.contents.Toggle(g)
+	case MenuActionImportMarkdown:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		importMarkdown(g, wx, wy)
+	case MenuActionCopyMarkdown:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		copyMarkdownTable(g, target, false)
+	case MenuActionCopySelectionMarkdown:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		copyMarkdownTable(g, target, true)
+	case MenuActionImportSQLite:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		importSQLite(g, wx, wy)
+	case MenuActionExportJSON:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		exportPanelJSON(g, target)
+	case MenuActionExportPanelXLSX:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		exportPanel(g, target, "Excel Workbook", "xlsx", cellcanvas.WriteXLSX)
+	case MenuActionCellHistory:
+		// the cell under the menu, else the selected cell
+		panel, col, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col, row = im.activePanel, im.selCol, im.selRow
+		}
+		if panel < 0 || panel >= len(g.canvas.Panels) {
+			break
+		}
+		showCellHistory(g, panel, col, row)
+	case MenuActionEncryptWorkspace:
+		promptEncryptWorkspace(g)
+	case MenuActionToggleWrap:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		toggleWrap(g, target)
+	case MenuActionToggleImages:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		toggleImages(g, target)
+	case MenuActionCellRenderer:
+		// the column under the menu, else the selected one
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		pickColumnRenderer(g, panel, col)
+	case MenuActionViewImage:
+		// the cell under the menu, else the selected cell
+		panel, col, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col, row = im.activePanel, im.selCol, im.selRow
+		}
+		if !g.images.OpenCell(g, panel, col, row) {
+			g.events.Publish(LogMessage{Text: "the cell does not name an image file or URL"})
+		}
+	case MenuActionPanelColor:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickPanelColor(g, target)
+	case MenuActionPanelIcon:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickPanelIcon(g, target)
+	case MenuActionPanelFont:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		promptPanelFont(g, target)
+	case MenuActionCellSize:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		promptCellSize(g, target)
+	case MenuActionTruncatePanel:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		truncatePanel(g, target)
+	case MenuActionProfilePanel:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		g.profiles.Start(g, target)
+	case MenuActionHighlightOutliers:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		promptOutliers(g, target)
+	case MenuActionListOutliers:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		listOutliers(g, target)
+	case MenuActionWatchCell:
+		// the cell under the menu, else the selected cell
+		panel, col, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col, row = im.activePanel, im.selCol, im.selRow
+		}
+		toggleWatch(g, panel, col, row)
+	case MenuActionGoalSeek:
+		// the formula cell under the menu, else the selected cell
+		panel, col, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col, row = im.activePanel, im.selCol, im.selRow
+		}
+		goalSeek(g, panel, col, row)
+	case MenuActionShowDistribution:
+		// the column under the menu, else the selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		g.distribution.Start(g, panel, col, g.contextMenu.x, g.contextMenu.y)
+	case MenuActionPreviewCSV:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		previewCSV(g, wx, wy)
+	case MenuActionLoadFullFile:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		loadFullFile(g, target)
+	case MenuActionTrackSources:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		toggleRowSources(g, target)
+	case MenuActionRowDetails:
+		// the row under the menu, else the selected row
+		panel, _, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, row = im.activePanel, im.selRow
+		}
+		if panel < 0 || panel >= len(g.canvas.Panels) {
+			break
+		}
+		showRowDetails(g, panel, row)
+	case MenuActionExportWithSources:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		exportWithSources(g, target)
+	case MenuActionExportSelection:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		exportSelection(g, target)
+	case MenuActionExportFiltered:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		exportFilteredRows(g, target)
+	case MenuActionAppendCSV:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		appendCSVToPanel(g, target)
+	case MenuActionNewView:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		addLinkedView(g, target)
+	case MenuActionCheckWorkspace:
+		checkWorkspace(g)
+	case MenuActionSaveLayout:
+		promptSaveLayout(g)
+	case MenuActionSwitchLayout:
+		pickLayout(g)
+	case MenuActionDeleteLayout:
+		pickDeleteLayout(g)
+	case MenuActionComputedColumn:
+		// the column under the menu, else the selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		promptComputed(g, panel, col)
+	case MenuActionDateColumn:
+		// the column under the menu, else the selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		pickDateColumn(g, panel, col)
+	case MenuActionFillSampleData:
+		fillSampleData(g)
+	case MenuActionSaveScenario:
+		promptSaveScenario(g)
+	case MenuActionSwitchScenario:
+		pickScenario(g)
+	case MenuActionDeleteScenario:
+		pickDeleteScenario(g)
+	case MenuActionPagePreview:
+		togglePagePreview(g)
+	case MenuActionNewDerivedPanel:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		newDerivedPanel(g, target)
+	case MenuActionNewSQLPanel:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		newSQLPanel(g, wx, wy)
+	case MenuActionEditSQL:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		editSQL(g, target)
+	case MenuActionRefreshSQL:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		refreshSQL(g, target)
+	case MenuActionEditQuery:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		editQuery(g, target)
+	case MenuActionQueryPresets:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickPreset(g, target)
+	case MenuActionSearchAll:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		searchAllPanels(g, wx, wy)
+	case MenuActionImportFolder:
+		wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
+		importFolder(g, wx, wy)
+	case MenuActionPasteSpecial:
+		pickPasteSpecial(g, pasteSpecialPaste)
+	case MenuActionSendToScratch:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		sendToScratch(g, target)
+	case MenuActionKeepScratch:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		keepScratch(g, target)
+	case MenuActionDiskChanges:
+		g.syncWatch.Ask(g)
+	case MenuActionToggleRulers:
+		g.rulers.Toggle(g)
+	case MenuActionSetPrintArea:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		setPrintArea(g, target)
+	case MenuActionExportLabels:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		promptExportLabels(g, target)
+	case MenuActionExportPDF:
+		exportWorkspace(g, "PDF Document", "pdf", func(path string, panels []cellcanvas.Panel) error {
+			return cellcanvas.WritePDF(path, panels, g.settings.PageSize())
+		})
+	case MenuActionCellBorder:
+		// the selected range when the menu was opened on it, else the cell
+		// under the menu
+		panel, col, row, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		col0, row0, col1, row1 := im.selectionRange()
+		if !ok {
+			panel, col, row = im.activePanel, im.selCol, im.selRow
+		}
+		if panel != im.activePanel || col < col0 || col > col1 || row < row0 || row > row1 {
+			col0, row0, col1, row1 = col, row, col, row
+		}
+		pickCellBorder(g, panel, col0, row0, col1, row1)
+	case MenuActionToggleZebra:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		toggleZebra(g, target)
+	case MenuActionGridlines:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickGridlines(g, target)
+	case MenuActionNumberLocale:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		pickNumberLocale(g, target)
+	case MenuActionColumnFormat:
+		// the column under the menu, else the selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		pickColumnFormat(g, panel, col)
+	case MenuActionColumnUnit:
+		// the column under the menu, else the selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if !ok {
+			panel, col = im.activePanel, im.selCol
+		}
+		promptUnit(g, panel, col)
+	case MenuActionAutoFitColumn:
+		// the column under the menu, else every selected column
+		panel, col, _, ok := g.canvas.CellAt(g.contextMenu.x, g.contextMenu.y)
+		if ok {
+			autoFitColumn(g, panel, col)
+			break
+		}
+		if im.activePanel < 0 || im.activePanel >= len(g.canvas.Panels) {
+			break
+		}
+		col0, _, col1, _ := im.selectionRange()
+		for col := col0; col <= col1; col++ {
+			autoFitColumn(g, im.activePanel, col)
+		}
+	case MenuActionExportAudit:
+		exportAuditTrail(g)
+	case MenuActionToggleClipboardCollector:
+		target := g.contextMenu.targetPanel
+		if target < 0 {
+			target = im.activePanel
+		}
+		toggleClipboardCollector(g, target)
 	case MenuActionLoadPanelFromFile:
 		// Determine which panel to load into: context menu target or active panel
 		target := g.contextMenu.targetPanel
@@ -120,7 +553,7 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 			target = im.activePanel
 		}
 		// ask for a CSV file
-		path, err := dialog.File().Filter("CSV", "csv").Title("Load Panel CSV").Load()
+		path, err := dialog.File().Filter("CSV", csvFileTypes...).Title("Load Panel CSV").Load()
 		if err != nil {
 			if err != dialog.ErrCancelled {
 				log.Printf("file open failed: %v", err)
@@ -133,42 +566,14 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 		absPath, _ := filepath.Abs(path)
 		if target < 0 {
 			// create a new panel positioned at the context menu world coords
-			wx := int(float64(g.contextMenu.x) - g.canvas.camX)
-			wy := int(float64(g.contextMenu.y) - g.canvas.camY)
+			wx, wy := g.canvas.ScreenToWorld(g.contextMenu.x, g.contextMenu.y)
 			if err := g.canvas.AddPanelFromCSV(absPath, wx, wy); err != nil {
-				log.Printf("add panel from csv failed: %v", err)
-				if g.ui != nil {
-					g.ui.addClickLog("failed to add: " + filepath.Base(absPath))
-				}
+				reportError(g, "Add panel", err)
 			} else {
-				if g.ui != nil {
-					g.ui.addClickLog("added panel: " + filepath.Base(absPath))
-				}
+				g.events.Publish(LogMessage{Text: "added panel: " + filepath.Base(absPath)})
 			}
 		} else {
-			if g.canvas.saveManager != nil {
-				g.canvas.saveManager.ScheduleLoad(target, absPath)
-				if g.ui != nil {
-					g.ui.addClickLog("scheduled load: " + filepath.Base(absPath))
-				}
-			} else {
-				tmp := NewBlankPanel(0, 0, 1, 1)
-				if err := loadPanelCSV(absPath, &tmp); err != nil {
-					log.Printf("load failed: %v", err)
-					if g.ui != nil {
-						g.ui.addClickLog("failed to load: " + filepath.Base(absPath))
-					}
-				} else {
-					tmp.X = g.canvas.panels[target].X
-					tmp.Y = g.canvas.panels[target].Y
-					tmp.Filename = filepath.Base(absPath)
-					tmp.Loaded = true
-					g.canvas.panels[target] = tmp
-					if g.ui != nil {
-						g.ui.addClickLog("loaded: " + tmp.Filename)
-					}
-				}
-			}
+			loadIntoPanel(g, target, absPath)
 		}
 	case MenuActionSavePanelToFile, MenuActionExportPanelToCSV:
 		// Determine the panel to save: context menu target or active panel
@@ -176,10 +581,8 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 		if target < 0 {
 			target = im.activePanel
 		}
-		if target < 0 || target >= len(g.canvas.panels) {
-			if g.ui != nil {
-				g.ui.addClickLog("No panel to save")
-			}
+		if target < 0 || target >= len(g.canvas.Panels) {
+			g.events.Publish(LogMessage{Text: "No panel to save"})
 			break
 		}
 		path, err := dialog.File().Filter("CSV", "csv").Title("Save Panel As").Save()
@@ -193,17 +596,12 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 			break
 		}
 		absPath, _ := filepath.Abs(path)
-		if err := savePanelCSV(absPath, &g.canvas.panels[target]); err != nil {
-			log.Printf("save failed: %v", err)
-			if g.ui != nil {
-				g.ui.addClickLog("failed to save: " + filepath.Base(absPath))
-			}
+		if err := cellcanvas.SavePanelCSV(absPath, &g.canvas.Panels[target]); err != nil {
+			reportError(g, "Save "+filepath.Base(absPath), err)
 		} else {
 			// update the panel's filename (use relative path if in same directory)
-			g.canvas.panels[target].Filename = filepath.Base(absPath)
-			if g.ui != nil {
-				g.ui.addClickLog("saved: " + g.canvas.panels[target].Filename)
-			}
+			g.canvas.Panels[target].Filename = filepath.Base(absPath)
+			g.events.Publish(LogMessage{Text: "saved: " + g.canvas.Panels[target].Filename})
 		}
 	case MenuActionDeletePanel:
 		// Determine the panel to delete: context menu target or active panel
@@ -211,78 +609,165 @@ func (im *InputManager) HandleContextMenuInput(g *Game) {
 		if target < 0 {
 			target = im.activePanel
 		}
-		if target < 0 || target >= len(g.canvas.panels) {
-			if g.ui != nil {
-				g.ui.addClickLog("No panel to delete")
-			}
+		if target < 0 || target >= len(g.canvas.Panels) {
+			g.events.Publish(LogMessage{Text: "No panel to delete"})
 			break
 		}
-		name := g.canvas.panels[target].Filename
-		// Remove the panel without touching any CSVs on disk
-		g.canvas.RemovePanelAt(target)
-		// adjust active panel selection
-		if len(g.canvas.panels) == 0 {
-			im.activePanel = 0
-			im.selRow = 0
-			im.selCol = 0
-		} else if im.activePanel >= len(g.canvas.panels) {
-			im.activePanel = len(g.canvas.panels) - 1
-			im.selRow = 0
-			im.selCol = 0
-		}
-		if g.ui != nil {
-			if name == "" {
-				g.ui.addClickLog("deleted panel")
-			} else {
-				g.ui.addClickLog("deleted panel: " + name)
-			}
+		name := g.canvas.Panels[target].Filename
+		removePanel(g, target)
+		if name == "" {
+			g.events.Publish(LogMessage{Text: "deleted panel"})
+		} else {
+			g.events.Publish(LogMessage{Text: "deleted panel: " + name})
 		}
 	}
 }
 
+// removePanel takes panel pi off the canvas without touching any CSVs on
+// disk and moves the selection to a panel that still exists.
+func removePanel(g *Game, pi int) {
+	im := g.input
+	g.canvas.RemovePanelAt(pi)
+	if len(g.canvas.Panels) == 0 {
+		im.setSelection(g, 0, 0, 0)
+	} else if im.activePanel >= len(g.canvas.Panels) {
+		im.setSelection(g, len(g.canvas.Panels)-1, 0, 0)
+	}
+	g.events.Publish(PanelRemoved{Panel: pi})
+}
+
 func (im *InputManager) HandleSelectionNavigation(g *Game) {
 	// selection navigation (only when not editing)
 	if im.editing {
 		return
 	}
 	// guard: make sure active panel exists
-	if im.activePanel < 0 || im.activePanel >= len(g.canvas.panels) {
+	if im.activePanel < 0 || im.activePanel >= len(g.canvas.Panels) {
+		return
+	}
+	p := g.canvas.Panels[im.activePanel]
+	if g.frame.CtrlPressed() && g.frame.KeyJustPressed(ebiten.KeyA) {
+		im.selectRange(g, im.activePanel, 0, 0, p.Rows-1, p.Cols-1)
+		return
+	}
+	if g.frame.CtrlPressed() && g.frame.KeyJustPressed(ebiten.KeyT) {
+		toggleTrace(g)
+		return
+	}
+	if g.frame.CtrlPressed() && g.frame.KeyJustPressed(ebiten.KeyZ) {
+		undoMove(g)
+		return
+	}
+	if g.frame.CtrlPressed() && g.frame.KeyJustPressed(ebiten.KeyV) {
+		if g.frame.ShiftPressed() {
+			pickPasteSpecial(g, pasteSpecialPaste)
+		} else {
+			pasteClipboard(g)
+		}
+		return
+	}
+	if g.frame.CtrlPressed() && g.frame.KeyJustPressed(ebiten.KeyR) {
+		g.rulers.Toggle(g)
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyHome) {
+		// Home shows all panels, Shift+Home goes to the origin
+		goHome(g, !g.frame.ShiftPressed())
+		return
+	}
+	if g.frame.CtrlPressed() && (g.frame.ShiftPressed() || g.frame.AltPressed()) {
+		// Ctrl+Shift/Ctrl+Alt+arrows lay the panel out instead
+		nudgePanel(g, im.activePanel)
 		return
 	}
-	p := g.canvas.panels[im.activePanel]
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		if im.selRow > 0 {
-			im.selRow--
+	row, col := im.selRow, im.selCol
+	if g.frame.KeyJustPressed(ebiten.KeyArrowUp) {
+		if row > 0 {
+			row--
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-		if im.selRow < p.Rows-1 {
-			im.selRow++
+	if g.frame.KeyJustPressed(ebiten.KeyArrowDown) {
+		if row < p.Rows-1 {
+			row++
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
-		if im.selCol > 0 {
-			im.selCol--
+	if g.frame.KeyJustPressed(ebiten.KeyArrowLeft) {
+		if col > 0 {
+			col--
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
-		if im.selCol < p.Cols-1 {
-			im.selCol++
+	if g.frame.KeyJustPressed(ebiten.KeyArrowRight) {
+		if col < p.Cols-1 {
+			col++
 		}
 	}
+	if row == im.selRow && col == im.selCol {
+		return
+	}
+	if g.frame.ShiftPressed() {
+		// Shift+arrows grow the range from the anchor
+		im.selectRange(g, im.activePanel, im.anchorRow, im.anchorCol, row, col)
+		return
+	}
+	im.setSelection(g, im.activePanel, row, col)
+}
+
+// setSelection selects a single cell and publishes SelectionChanged when
+// the selection actually changed.
+func (im *InputManager) setSelection(g *Game, panel, row, col int) {
+	im.selectRange(g, panel, row, col, row, col)
 }
 
+// selectRange selects the cells between the anchor and the cursor cell
+// (inclusive); the cursor is the cell that is edited and moved by the arrow
+// keys. SelectionChanged is published when anything changed.
+func (im *InputManager) selectRange(g *Game, panel, anchorRow, anchorCol, row, col int) {
+	if panel == im.activePanel && row == im.selRow && col == im.selCol && anchorRow == im.anchorRow && anchorCol == im.anchorCol {
+		return
+	}
+	im.activePanel = panel
+	im.selRow = row
+	im.selCol = col
+	im.anchorRow = anchorRow
+	im.anchorCol = anchorCol
+	if panel >= 0 && panel < len(g.canvas.Panels) {
+		g.canvas.Panels[panel].Selection = cellcanvas.Selection{Col: col, Row: row, AnchorCol: anchorCol, AnchorRow: anchorRow}
+	}
+	g.events.Publish(SelectionChanged{Panel: panel, Row: row, Col: col, AnchorRow: anchorRow, AnchorCol: anchorCol})
+}
+
+// selectionRange returns the selected cells of the active panel as an
+// inclusive, normalized rectangle.
+func (im *InputManager) selectionRange() (col0, row0, col1, row1 int) {
+	return min(im.selCol, im.anchorCol), min(im.selRow, im.anchorRow), max(im.selCol, im.anchorCol), max(im.selRow, im.anchorRow)
+}
+
+// HandlePanelSwitching moves to the next panel on Tab and the previous one
+// on Shift+Tab; Ctrl+Tab lists the panels to pick from.
 func (im *InputManager) HandlePanelSwitching(g *Game) {
-	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
-		if len(g.canvas.panels) == 0 {
-			return
-		}
-		im.activePanel = (im.activePanel + 1) % len(g.canvas.panels)
-		im.selRow = 0
-		im.selCol = 0
+	n := len(g.canvas.Panels)
+	if !g.frame.KeyJustPressed(ebiten.KeyTab) || n == 0 {
+		return
+	}
+	switch {
+	case g.frame.CtrlPressed():
+		pickPanel(g)
+	case g.frame.ShiftPressed():
+		im.switchToPanel(g, (im.activePanel-1+n)%n)
+	default:
+		im.switchToPanel(g, (im.activePanel+1)%n)
 	}
 }
 
+// switchToPanel makes panel pi active with the selection it had last.
+func (im *InputManager) switchToPanel(g *Game, pi int) {
+	p := &g.canvas.Panels[pi]
+	s := p.Selection
+	clampRow := func(r int) int { return max(0, min(r, p.Rows-1)) }
+	clampCol := func(c int) int { return max(0, min(c, p.Cols-1)) }
+	im.selectRange(g, pi, clampRow(s.AnchorRow), clampCol(s.AnchorCol), clampRow(s.Row), clampCol(s.Col))
+}
+
 func (im *InputManager) GetLockedPanels() map[int]bool {
 	locked := make(map[int]bool)
 	if im.movingPanel != -1 {
@@ -305,41 +790,58 @@ func (im *InputManager) Draw(screen *ebiten.Image, face font.Face, g *Game) {
 	// from the renderer to here, but for now we'll create a minimal version
 	// that draws the selection overlay
 
-	if im.activePanel >= 0 && im.activePanel < len(g.canvas.panels) {
-		p := g.canvas.panels[im.activePanel]
-		b := p.GetBounds(g.canvas.camX, g.canvas.camY)
+	if im.activePanel >= 0 && im.activePanel < len(g.canvas.Panels) {
+		p := g.canvas.Panels[im.activePanel]
+		b := g.canvas.Bounds(&p)
 
 		// Draw selection overlay
-		baseX := float64(b.ContentX)
-		baseY := float64(b.ContentY)
-		sx := baseX + float64(im.selCol*p.CellW)
-		sy := baseY + float64(im.selRow*p.CellH)
-		cellW := float64(p.CellW - 1)
-		cellH := float64(p.CellH - 1)
-		borderWidth := 2.0
+		baseX := float64(b.GridX)
+		baseY := float64(b.GridY)
+		if col0, row0, col1, row1 := im.selectionRange(); col0 != col1 || row0 != row1 {
+			// shade the range, clipped to the screen since it can span
+			// millions of rows, and to the cells the panel shows
+			x0 := math.Max(baseX+float64(p.ColX(col0)), math.Max(float64(b.ContentX), 0))
+			y0 := math.Max(baseY+float64(p.RowY(row0)), math.Max(float64(b.ContentY), 0))
+			x1 := math.Min(baseX+float64(p.ColX(col1+1)), math.Min(float64(b.ContentX+b.ContentW), float64(screen.Bounds().Dx())))
+			y1 := math.Min(baseY+float64(p.RowY(row1+1)), math.Min(float64(b.ContentY+b.ContentH), float64(screen.Bounds().Dy())))
+			if x1 > x0 && y1 > y0 {
+				ebitenutil.DrawRect(screen, x0, y0, x1-x0, y1-y0, ColorSelectionRange)
+			}
+		}
+		// the cursor cell is outlined when the panel's window shows it
+		if p.CellVisible(im.selCol, im.selRow) {
+			sx := baseX + float64(p.ColX(im.selCol))
+			sy := baseY + float64(p.RowY(im.selRow))
+			cellW := float64(p.ColWidth(im.selCol) - 1)
+			cellH := float64(p.RowHeight(im.selRow) - 1)
+			borderWidth := 2.0
 
-		// Draw blue border instead of filled rectangle
-		// Top border
-		ebitenutil.DrawRect(screen, sx, sy, cellW, borderWidth, ColorSelection)
-		// Bottom border
-		ebitenutil.DrawRect(screen, sx, sy+cellH-borderWidth, cellW, borderWidth, ColorSelection)
-		// Left border
-		ebitenutil.DrawRect(screen, sx, sy, borderWidth, cellH, ColorSelection)
-		// Right border
-		ebitenutil.DrawRect(screen, sx+cellW-borderWidth, sy, borderWidth, cellH, ColorSelection)
+			// Draw blue border instead of filled rectangle
+			// Top border
+			ebitenutil.DrawRect(screen, sx, sy, cellW, borderWidth, ColorSelection)
+			// Bottom border
+			ebitenutil.DrawRect(screen, sx, sy+cellH-borderWidth, cellW, borderWidth, ColorSelection)
+			// Left border
+			ebitenutil.DrawRect(screen, sx, sy, borderWidth, cellH, ColorSelection)
+			// Right border
+			ebitenutil.DrawRect(screen, sx+cellW-borderWidth, sy, borderWidth, cellH, ColorSelection)
+		}
 	}
+	im.drawResizeGhost(screen, face, g)
+	im.drawMoveGhost(screen, face, g)
 }
 
 func (im *InputManager) HandleCanvasInteraction(g *Game) {
 	c := g.canvas
-	mx, my := ebiten.CursorPosition()
+	// panels are hit-tested in view pixels, before the zoom
+	mx, my := c.ToView(g.frame.CursorPosition())
 
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+	if g.frame.MouseJustPressed(ebiten.MouseButtonLeft) {
 		// check panels from top (last) to bottom (first)
 		picked := -1
-		for i := len(c.panels) - 1; i >= 0; i-- {
-			p := c.panels[i]
-			b := p.GetBounds(c.camX, c.camY)
+		for i := len(c.Panels) - 1; i >= 0; i-- {
+			p := c.Panels[i]
+			b := c.Bounds(&p)
 			baseX := b.ContentX
 			baseY := b.ContentY
 			w := b.ContentW
@@ -347,13 +849,16 @@ func (im *InputManager) HandleCanvasInteraction(g *Game) {
 			// header area (title bar)
 			headerY := baseY - PanelHeaderHeight
 			// detect header-centered name button clicks (centered horizontally)
-			btnX := baseX + w/2 - PanelNameButtonW/2
-			btnY := headerY + (PanelHeaderHeight-PanelNameButtonH)/2
-			if mx >= btnX && mx <= btnX+PanelNameButtonW && my >= btnY && my <= btnY+PanelNameButtonH {
+			// the query preset dropdown of derived panels
+			if x, y, w, h, ok := b.PresetButton(&p); ok && mx >= x && mx <= x+w && my >= y && my <= y+h {
+				pickPreset(g, i)
+				picked = i
+				break
+			}
+			btnX, btnY, btnW, btnH := b.NameButton()
+			if mx >= btnX && mx <= btnX+btnW && my >= btnY && my <= btnY+btnH {
 				// header name button clicked
-				if g.ui != nil {
-					g.ui.OnPanelNameClick(g, i)
-				}
+				g.events.Publish(PanelNameClicked{Panel: i})
 				picked = i
 				break
 			}
@@ -362,6 +867,7 @@ func (im *InputManager) HandleCanvasInteraction(g *Game) {
 				picked = i
 				// start moving
 				im.movingPanel = i
+				im.dragStartX, im.dragStartY = p.X, p.Y
 				im.moveOffsetX = mx - baseX
 				im.moveOffsetY = my - baseY
 				break
@@ -370,10 +876,21 @@ func (im *InputManager) HandleCanvasInteraction(g *Game) {
 			if mx >= baseX+w-ResizeHandleSize && mx <= baseX+w && my >= baseY+h-ResizeHandleSize && my <= baseY+h {
 				picked = i
 				im.resizingPanel = i
+				im.dragStartX, im.dragStartY = p.WindowCols(), p.WindowRows()
+				im.resizeCols, im.resizeRows = im.dragStartX, im.dragStartY
 				im.moveOffsetX = mx - (baseX + w)
 				im.moveOffsetY = my - (baseY + h)
 				break
 			}
+			if im.grabScrollbar(g, i, b, mx, my) {
+				picked = i
+				break
+			}
+			// the margin left of the cells holds the row handles
+			if im.grabRowHandle(g, i, b, mx, my) {
+				picked = i
+				break
+			}
 			// click inside panel -> select panel and a cell (only when loaded)
 			if mx >= baseX && mx <= baseX+w && my >= baseY && my <= baseY+h {
 				if !p.Loaded {
@@ -381,21 +898,41 @@ func (im *InputManager) HandleCanvasInteraction(g *Game) {
 					continue
 				}
 				picked = i
-				im.activePanel = i
+				// a press on the selection's outline drags its contents
+				if im.grabSelectionBorder(g, i, b, mx, my) {
+					break
+				}
 				// compute selected cell
-				cx := mx - baseX
-				cy := my - baseY
-				col := cx / p.CellW
-				row := cy / p.CellH
+				cx := mx - b.GridX
+				cy := my - b.GridY
+				col := p.ColAt(cx)
+				row := p.RowAt(cy)
+				// Ctrl+click on an image cell shows the image
+				if g.frame.CtrlPressed() && g.images.OpenCell(g, i, col, row) {
+					break
+				}
+				// double-click on a column boundary fits that column
+				// instead of editing the cell
+				if edge := p.ColEdgeNear(cx, colEdgeSlop); edge >= 0 {
+					if im.lastEdgePanel == i && im.lastEdgeCol == edge && g.frame.TimeMs-im.lastEdgeTime <= g.ui.dblClickMs {
+						im.lastEdgePanel = -1
+						autoFitColumn(g, i, edge)
+						break
+					}
+					im.lastEdgePanel, im.lastEdgeCol, im.lastEdgeTime = i, edge, g.frame.TimeMs
+				}
 				if row >= 0 && row < p.Rows && col >= 0 && col < p.Cols {
-					// notify UI about the click BEFORE updating selection
-					// so OnCellClick can commit edits to the OLD cell position
-					if g.ui != nil {
-						g.ui.OnCellClick(g, i, row, col)
+					// notify subscribers about the click BEFORE updating selection
+					// so the UI can commit edits to the OLD cell position
+					// (handlers run synchronously).
+					g.events.Publish(CellClicked{Panel: i, Row: row, Col: col})
+					// NOW update the selection to the new cell; Shift+click
+					// extends a range from the anchor in the same panel
+					if g.frame.ShiftPressed() && i == im.activePanel {
+						im.selectRange(g, i, im.anchorRow, im.anchorCol, row, col)
+					} else {
+						im.setSelection(g, i, row, col)
 					}
-					// NOW update the selection to the new cell
-					im.selRow = row
-					im.selCol = col
 				}
 				break
 			}
@@ -403,58 +940,43 @@ func (im *InputManager) HandleCanvasInteraction(g *Game) {
 		_ = picked
 	}
 
+	im.dragCells(g)
+	im.dragScrollbar(g, mx, my)
+
 	// dragging move
-	if im.movingPanel != -1 && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+	if im.movingPanel != -1 && g.frame.MousePressed(ebiten.MouseButtonLeft) {
 		i := im.movingPanel
 		// new panel origin is cursor minus offset, adjusted by camera
-		newX := mx - im.moveOffsetX - int(c.camX)
-		newY := my - im.moveOffsetY - int(c.camY)
-		c.panels[i].X = newX
-		c.panels[i].Y = newY
-	}
-	// dragging resize
-	if im.resizingPanel != -1 && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		i := im.resizingPanel
-		b := c.panels[i].GetBounds(c.camX, c.camY)
-		baseX := b.ContentX
-		baseY := b.ContentY
+		newX := mx - im.moveOffsetX - int(c.CamX)
+		newY := my - im.moveOffsetY - int(c.CamY)
+		c.Panels[i].X = newX
+		c.Panels[i].Y = newY
+	}
+	// dragging resize: only the preview follows the cursor, the panel is
+	// resized on release; Escape cancels
+	if im.resizingPanel != -1 && g.frame.KeyJustPressed(ebiten.KeyEscape) {
+		im.resizingPanel = -1
+		g.events.Publish(LogMessage{Text: "resize cancelled"})
+	}
+	if im.resizingPanel != -1 && g.frame.MousePressed(ebiten.MouseButtonLeft) {
+		p := &c.Panels[im.resizingPanel]
+		b := c.Bounds(p)
 		// compute width/height from base to cursor (minus offset)
-		w := (mx - baseX - im.moveOffsetX)
-		h := (my - baseY - im.moveOffsetY)
-		if w < 64 {
-			w = 64
-		}
-		if h < 32 {
-			h = 32
-		}
-		// determine cols/rows from new size
-		cols := w / c.panels[i].CellW
-		rows := h / c.panels[i].CellH
-		if cols < 1 {
-			cols = 1
-		}
-		if rows < 1 {
-			rows = 1
-		}
-		// resize cell grid preserving existing data where possible
-		old := c.panels[i]
-		newCells := make(map[string]string)
-		for key, val := range old.Cells {
-			col, row, err := ParseCellRef(key)
-			if err != nil {
-				continue
-			}
-			if row >= 0 && row < rows && col >= 0 && col < cols {
-				newCells[CellRef(col, row)] = val
-			}
-		}
-		c.panels[i].Cols = cols
-		c.panels[i].Rows = rows
-		c.panels[i].Cells = newCells
+		w := max(mx-b.ContentX-im.moveOffsetX, 64)
+		h := max(my-b.ContentY-im.moveOffsetY, 32)
+		firstRow, _ := p.VisibleRows()
+		firstCol, _ := p.VisibleCols()
+		im.resizeCols, im.resizeRows = snapCols(p, firstCol, w), snapRows(p, firstRow, h)
 	}
 
 	// release move/resize when mouse released
-	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+	if g.frame.MouseJustReleased(ebiten.MouseButtonLeft) {
+		if i := im.movingPanel; i >= 0 && i < len(c.Panels) && (c.Panels[i].X != im.dragStartX || c.Panels[i].Y != im.dragStartY) {
+			g.events.Publish(PanelMoved{Panel: i, FromX: im.dragStartX, FromY: im.dragStartY, X: c.Panels[i].X, Y: c.Panels[i].Y})
+		}
+		if i := im.resizingPanel; i >= 0 && i < len(c.Panels) && (im.resizeCols != im.dragStartX || im.resizeRows != im.dragStartY) {
+			resizePanel(g, i, im.resizeCols, im.resizeRows)
+		}
 		im.movingPanel = -1
 		im.resizingPanel = -1
 	}
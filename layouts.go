@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// layoutAnimTicks is how long panels take to glide to a layout's positions.
+const layoutAnimTicks = 24
+
+// LayoutAnimator moves the panels and camera to a saved layout over a few
+// frames. Animated panels are locked against overlap resolution until they
+// arrive.
+type LayoutAnimator struct {
+	// from and to are the start and target positions, indexed by panel;
+	// moving[i] is false for panels the layout has no position for
+	from, to       []cellcanvas.LayoutPos
+	moving         []bool
+	camFromX, camX float64
+	camFromY, camY float64
+	tick           int
+	running        bool
+}
+
+func NewLayoutAnimator() *LayoutAnimator {
+	return &LayoutAnimator{}
+}
+
+// Start begins moving c towards l.
+func (a *LayoutAnimator) Start(c *Canvas, l *cellcanvas.Layout) {
+	n := len(c.Panels)
+	a.from, a.to, a.moving = make([]cellcanvas.LayoutPos, n), make([]cellcanvas.LayoutPos, n), make([]bool, n)
+	for i := range c.Panels {
+		a.from[i] = cellcanvas.LayoutPos{X: c.Panels[i].X, Y: c.Panels[i].Y}
+		a.to[i] = a.from[i]
+		if i < len(l.Panels) {
+			a.to[i], a.moving[i] = l.Panels[i], true
+		}
+	}
+	a.camFromX, a.camFromY = c.CamX, c.CamY
+	a.camX, a.camY = l.CamX, l.CamY
+	a.tick = 0
+	a.running = true
+}
+
+// Stop abandons the animation where it is, e.g. when the panels change.
+func (a *LayoutAnimator) Stop() {
+	a.running = false
+}
+
+// Update advances the animation one frame. It reports whether it is still
+// running and publishes PanelMoved for every panel once they arrive.
+func (a *LayoutAnimator) Update(c *Canvas) bool {
+	if !a.running {
+		return false
+	}
+	a.tick++
+	t := float64(a.tick) / layoutAnimTicks
+	if t >= 1 {
+		t = 1
+		a.running = false
+	}
+	// ease out: fast start, gentle arrival
+	e := 1 - (1-t)*(1-t)*(1-t)
+	for i := range a.from {
+		if i >= len(c.Panels) || !a.moving[i] {
+			continue
+		}
+		c.Panels[i].X = a.from[i].X + int(float64(a.to[i].X-a.from[i].X)*e)
+		c.Panels[i].Y = a.from[i].Y + int(float64(a.to[i].Y-a.from[i].Y)*e)
+	}
+	c.CamX = a.camFromX + (a.camX-a.camFromX)*e
+	c.CamY = a.camFromY + (a.camY-a.camFromY)*e
+	if !a.running {
+		for i := range a.from {
+			if i < len(c.Panels) && a.moving[i] && a.from[i] != a.to[i] {
+				c.events.Publish(PanelMoved{Panel: i, FromX: a.from[i].X, FromY: a.from[i].Y, X: a.to[i].X, Y: a.to[i].Y})
+			}
+		}
+	}
+	return true
+}
+
+// Lock adds the panels being animated to locked.
+func (a *LayoutAnimator) Lock(locked map[int]bool) {
+	if !a.running {
+		return
+	}
+	for i, m := range a.moving {
+		if m {
+			locked[i] = true
+		}
+	}
+}
+
+// Observe stops the animation when panels are added, removed or reloaded,
+// since its indexes would no longer match.
+func (a *LayoutAnimator) Observe(e Event) {
+	switch e.(type) {
+	case PanelAdded, PanelRemoved, WorkspaceLoaded:
+		a.Stop()
+	}
+}
+
+// promptSaveLayout asks for a name and saves the current arrangement under
+// it.
+func promptSaveLayout(g *Game) {
+	g.prompt.Open("Save Layout", "name, e.g. analysis", "", func(g *Game, name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		g.canvas.SaveLayout(name)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("layout %q saved", name)})
+	})
+}
+
+// pickLayout lets the user switch to a saved layout.
+func pickLayout(g *Game) {
+	if len(g.canvas.Layouts) == 0 {
+		g.events.Publish(LogMessage{Text: "No saved layouts (use Save Layout As...)"})
+		return
+	}
+	names := make([]string, len(g.canvas.Layouts))
+	for i, l := range g.canvas.Layouts {
+		names[i] = l.Name
+	}
+	g.picker.Open("Switch Layout", names, func(g *Game, i int) {
+		if i >= len(g.canvas.Layouts) {
+			return
+		}
+		g.layouts.Start(g.canvas, &g.canvas.Layouts[i])
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("layout %q", g.canvas.Layouts[i].Name)})
+	})
+}
+
+// pickDeleteLayout lets the user remove a saved layout.
+func pickDeleteLayout(g *Game) {
+	if len(g.canvas.Layouts) == 0 {
+		g.events.Publish(LogMessage{Text: "No saved layouts"})
+		return
+	}
+	names := make([]string, len(g.canvas.Layouts))
+	for i, l := range g.canvas.Layouts {
+		names[i] = l.Name
+	}
+	g.picker.Open("Delete Layout", names, func(g *Game, i int) {
+		g.canvas.DeleteLayout(names[i])
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("layout %q deleted", names[i])})
+	})
+}
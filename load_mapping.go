@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// loadModes are the ways a file can be loaded into a panel that already
+// has a header.
+var loadModes = []string{"Match columns by header", "Choose the column for each file column...", "Replace the panel by position"}
+
+// loadIntoPanel loads the CSV at path into panel pi. When pi already has a
+// header whose columns the file does not share in the same order, it asks
+// how to line the file's columns up with the panel's first, instead of
+// replacing the panel column by column.
+func loadIntoPanel(g *Game, pi int, path string) {
+	p := &g.canvas.Panels[pi]
+	if !p.Loaded || !p.HasHeader() {
+		loadByPosition(g, pi, path, nil)
+		return
+	}
+	src := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+	if err := cellcanvas.LoadPanelCSV(path, &src); err != nil {
+		reportError(g, "Load "+filepath.Base(path), err)
+		return
+	}
+	colMap := cellcanvas.MatchColumns(p, &src)
+	if len(colMap) == p.Cols && cellcanvas.SameColumns(colMap) {
+		loadByPosition(g, pi, path, &src)
+		return
+	}
+	g.picker.Open("Load "+filepath.Base(path)+" into Panel "+fmt.Sprint(pi+1), loadModes, func(g *Game, mode int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		switch mode {
+		case 0:
+			// file columns the panel lacks go after its last used one
+			next := g.canvas.Panels[pi].UsedCols()
+			for c, dst := range colMap {
+				if dst < 0 {
+					colMap[c] = next
+					next++
+				}
+			}
+			loadMapped(g, pi, path, &src, colMap)
+		case 1:
+			chooseColumns(g, pi, path, &src, colMap, 0)
+		case 2:
+			loadByPosition(g, pi, path, &src)
+		}
+	})
+}
+
+// loadByPosition replaces the content of panel pi with the file at path,
+// column for column: with src when it was read already, otherwise in the
+// background.
+func loadByPosition(g *Game, pi int, path string, src *cellcanvas.Panel) {
+	if src == nil && g.canvas.saveManager != nil {
+		g.canvas.saveManager.ScheduleLoad(pi, path)
+		g.events.Publish(BulkEdit{Panel: pi, Action: "load file", Detail: filepath.Base(path)})
+		g.events.Publish(LogMessage{Text: "scheduled load: " + filepath.Base(path)})
+		return
+	}
+	if src == nil {
+		tmp := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+		if err := cellcanvas.LoadPanelCSV(path, &tmp); err != nil {
+			reportError(g, "Load "+filepath.Base(path), err)
+			return
+		}
+		src = &tmp
+	}
+	p := &g.canvas.Panels[pi]
+	store := p.Paged
+	p.ReplaceContent(src)
+	g.canvas.ReleaseStore(store)
+	p.NormalizeDates()
+	p.Filename = filepath.Base(path)
+	p.Loaded = true
+	g.events.Publish(PanelLoaded{Panel: pi})
+	g.events.Publish(BulkEdit{Panel: pi, Action: "load file", Detail: p.Filename})
+	g.events.Publish(LogMessage{Text: "loaded: " + p.Filename})
+}
+
+// chooseColumns asks, one file column at a time from column c on, which
+// panel column it goes to or whether to skip it, starting from the column
+// with the same header. Escape cancels the load.
+func chooseColumns(g *Game, pi int, path string, src *cellcanvas.Panel, colMap []int, c int) {
+	if c == len(colMap) {
+		loadMapped(g, pi, path, src, colMap)
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	items := make([]string, 0, p.Cols+1)
+	for col := 0; col < p.Cols; col++ {
+		items = append(items, fmt.Sprintf("%s: %s", cellcanvas.ColToLetters(col), p.RawCell(col, 0)))
+	}
+	items = append(items, "Skip this column")
+	title := fmt.Sprintf("File column %d of %d, %q, goes to", c+1, len(colMap), src.RawCell(c, 0))
+	g.picker.Open(title, items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		colMap[c] = -1
+		if i < len(items)-1 {
+			colMap[c] = i
+		}
+		chooseColumns(g, pi, path, src, colMap, c+1)
+	})
+	g.picker.selected = len(items) - 1
+	if colMap[c] >= 0 {
+		g.picker.selected = colMap[c]
+	}
+}
+
+// loadMapped replaces the rows of panel pi below its header with those of
+// src, placed by colMap.
+func loadMapped(g *Game, pi int, path string, src *cellcanvas.Panel, colMap []int) {
+	p := &g.canvas.Panels[pi]
+	store := p.Paged
+	p.ReplaceMapped(src, colMap)
+	g.canvas.ReleaseStore(store)
+	p.NormalizeDates()
+	p.Filename = filepath.Base(path)
+	p.Loaded = true
+	g.events.Publish(PanelLoaded{Panel: pi})
+	g.events.Publish(BulkEdit{Panel: pi, Action: "load file", Cell: "A2", Detail: p.Filename + " by column"})
+	showCSVProblems(g, pi, path, src.Problems)
+	skipped := 0
+	for _, dst := range colMap {
+		if dst < 0 {
+			skipped++
+		}
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("loaded %s into Panel %d: %d column(s) mapped, %d skipped", p.Filename, pi+1, len(colMap)-skipped, skipped)})
+}
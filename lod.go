@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Level of detail: once cells are drawn smaller than lodMinCellPx on screen
+// their text is unreadable and drawing it dominates the frame, so panels are
+// shown as blocks of roughly lodBlockPx pixels shaded by how many of their
+// cells hold a value.
+const (
+	lodMinCellPx = 4
+	lodBlockPx   = 8
+	// lodSamples bounds the cells inspected per block along each axis, so a
+	// block standing for thousands of rows stays cheap
+	lodSamples = 4
+)
+
+// useDensityView reports whether p's cells are too small on screen for text.
+// The on-screen cell size is taken from the panel bounds so any scaling the
+// camera applies is accounted for.
+func useDensityView(p *cellcanvas.Panel, b PanelBounds) bool {
+	cols, rows := p.WindowCols(), p.WindowRows()
+	if cols == 0 || rows == 0 {
+		return false
+	}
+	return float64(b.ContentW)/float64(cols) < lodMinCellPx || float64(b.ContentH)/float64(rows) < lodMinCellPx
+}
+
+// drawPanelDensity draws the on-screen part of p's window as density
+// blocks over the plain cell background.
+func (r *Renderer) drawPanelDensity(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
+	r.fillRect(screen, float64(b.ContentX), float64(b.ContentY), float64(b.ContentW), float64(b.ContentH), ColorCellBg)
+	r0, r1 := p.VisibleRows()
+	c0, c1 := p.VisibleCols()
+	if r1 <= r0 || c1 <= c0 {
+		return
+	}
+	cw := float64(b.ContentW) / float64(c1-c0)
+	ch := float64(b.ContentH) / float64(r1-r0)
+	// cells per block along each axis
+	bc := max(1, int(math.Ceil(lodBlockPx/cw)))
+	br := max(1, int(math.Ceil(lodBlockPx/ch)))
+	sw, sh := float64(screen.Bounds().Dx()), float64(screen.Bounds().Dy())
+	// ranges are counted from the window's first row and column
+	firstCol, lastCol := densityRange(float64(b.ContentX), cw, bc, c1-c0, sw)
+	firstRow, lastRow := densityRange(float64(b.ContentY), ch, br, r1-r0, sh)
+	for row := firstRow; row < lastRow; row += br {
+		rows := min(br, r1-r0-row)
+		for col := firstCol; col < lastCol; col += bc {
+			cols := min(bc, c1-c0-col)
+			d := blockDensity(p, c0+col, r0+row, cols, rows)
+			if d == 0 {
+				continue
+			}
+			x := float64(b.ContentX) + float64(col)*cw
+			y := float64(b.ContentY) + float64(row)*ch
+			r.tintRect(screen, x, y, float64(cols)*cw, float64(rows)*ch, ColorDensity, float32(0.25+0.75*d))
+		}
+	}
+}
+
+// densityRange returns the half-open range of cells, aligned to whole blocks
+// of step cells, that fall within a screen extent of size.
+func densityRange(base, cell float64, step, n int, size float64) (int, int) {
+	first := 0
+	if base < 0 {
+		first = int(-base/cell) / step * step
+	}
+	last := min(n, int((size-base)/cell)+1)
+	return first, max(first, last)
+}
+
+// blockDensity returns the fraction of sampled cells in the block that are
+// non-empty.
+func blockDensity(p *cellcanvas.Panel, col, row, cols, rows int) float64 {
+	sc := min(cols, lodSamples)
+	sr := min(rows, lodSamples)
+	filled := 0
+	for i := 0; i < sr; i++ {
+		r := row + i*rows/sr
+		for j := 0; j < sc; j++ {
+			if p.GetCell(col+j*cols/sc, r) != "" {
+				filled++
+			}
+		}
+	}
+	return float64(filled) / float64(sc*sr)
+}
@@ -1,16 +1,24 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
 
+	"github.com/example/cellchain/cellcanvas"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 const (
 	windowWidth  = 1280
 	windowHeight = 720
-	defaultCellW = 80
-	defaultCellH = 24
+
+	// defaultStatePath is the workspace state file loaded at startup and
+	// written by Ctrl+S.
+	defaultStatePath = "state.yml"
 )
 
 type Game struct {
@@ -19,19 +27,185 @@ type Game struct {
 
 	input       *InputManager
 	contextMenu *ContextMenu
+	events      *EventBus
+	stats       *UsageStats
+	prompt      *Prompt
+	renderer    *Renderer
+	settings    Settings
+	power       powerState
+	picker      *Picker
+	selStats    *SelectionStats
+	history     *CellHistory
+	audit       *AuditLog
+	// lock is the single-writer lock on the state file; nil when the
+	// workspace is read-only or could not be locked
+	lock          *cellcanvas.WorkspaceLock
+	lockRefreshed time.Time
+	feeds         *FeedManager
+	layouts       *LayoutAnimator
+	profiles      *Profiles
+	distribution  *Distribution
+	images        *CellImages
+	outliers      *OutlierMarks
+	trace         *FormulaTrace
+	watch         *WatchWindow
+	contents      *Contents
+	computed      *ComputedColumns
+	errDialog     *ErrorDialog
+	paste         *PasteDialog
+	rulers        *Rulers
+	derived       *DerivedPanels
+	folderImport  *FolderImport
+	scratch       *Scratch
+	moves         *CellMoves
+	locks         *CellLocks
+	syncWatch     *SyncWatch
+	changed       *ChangedOutside
+
+	// frame holds the input for the current tick, captured live or read
+	// from a replay file.
+	frame    *InputFrame
+	tick     int64
+	start    time.Time
+	recorder *InputRecorder
+	player   *InputPlayer
+	api      *APIServer
+
+	// crashErr is set when Draw recovers from a panic; the next Update
+	// returns it to stop the game loop.
+	crashErr error
 }
 
-func NewGame() *Game {
-	g := &Game{}
+// gameOptions carries command-line configuration into NewGame.
+type gameOptions struct {
+	recordPath string
+	replayPath string
+	serveAddr  string
+	// keepHistory persists cell edit history next to the state file
+	keepHistory bool
+	// audit appends data-changing operations to an audit file
+	audit bool
+}
+
+func NewGame(opts gameOptions) *Game {
+	g := &Game{start: time.Now(), frame: &InputFrame{}}
 	g.canvas = NewCanvas()
 	g.ui = NewUI()
+	g.settings = LoadSettings(settingsPath)
+	g.renderer = NewRenderer()
+	g.renderer.defaultGrid = g.settings.Gridlines
+	g.renderer.page = g.settings.PageSize()
+	cellcanvas.RaggedRows = g.settings.RaggedRows
+	cellcanvas.DefaultNumberLocale, _ = cellcanvas.LookupNumberLocale(g.settings.NumberLocale)
+	g.ui.dblClickMs = int64(g.settings.DoubleClickMs)
+	g.ui.editTrigger = g.settings.EditTrigger
+	g.ui.renameTrigger = g.settings.RenameTrigger
+	g.ui.typeToEdit = g.settings.TypeToEdit
+	g.ui.exits = g.settings.EditExits
 	g.input = NewInputManager()
+	g.input.nudgeGrid = g.settings.NudgeGrid
+	g.input.keepInView = g.settings.KeepPanelsInView
 	g.contextMenu = NewContextMenu()
+	g.events = NewEventBus()
+	g.canvas.events = g.events
+	g.stats = NewUsageStats()
+	g.prompt = NewPrompt()
+	g.picker = NewPicker()
+	g.selStats = NewSelectionStats()
+	g.history = NewCellHistory(opts.keepHistory)
+	g.feeds = NewFeedManager()
+	g.layouts = NewLayoutAnimator()
+	g.profiles = NewProfiles()
+	g.distribution = NewDistribution()
+	g.images = NewCellImages()
+	g.renderer.images = g.images
+	g.outliers = NewOutlierMarks()
+	g.renderer.outliers = g.outliers
+	g.trace = NewFormulaTrace()
+	g.watch = NewWatchWindow()
+	g.contents = NewContents()
+	g.computed = NewComputedColumns()
+	g.errDialog = NewErrorDialog()
+	g.rulers = NewRulers()
+	g.derived = NewDerivedPanels()
+	g.folderImport = NewFolderImport()
+	g.scratch = NewScratch()
+	g.moves = NewCellMoves()
+	g.locks = NewCellLocks()
+	g.syncWatch = NewSyncWatch()
+	g.changed = NewChangedOutside()
+	g.paste = NewPasteDialog()
+	g.events.Subscribe(func(e Event) { g.ui.OnEvent(g, e) })
+	g.events.Subscribe(func(e Event) { g.stats.Observe(e, g.canvas) })
+	g.events.Subscribe(func(e Event) { g.history.Observe(e, g.canvas) })
+	g.events.Subscribe(func(e Event) { g.selStats.Observe(g, e) })
+	g.events.Subscribe(func(e Event) { refitRows(g.canvas, e) })
+	g.events.Subscribe(g.layouts.Observe)
+	g.events.Subscribe(g.profiles.Observe)
+	g.events.Subscribe(g.distribution.Observe)
+	g.events.Subscribe(g.outliers.Observe)
+	g.events.Subscribe(g.trace.Observe)
+	g.events.Subscribe(func(e Event) { g.computed.Observe(g, e) })
+	g.events.Subscribe(func(e Event) { g.derived.Observe(g, e) })
+	g.events.Subscribe(g.errDialog.Observe)
+	g.events.Subscribe(g.paste.Observe)
+	g.events.Subscribe(g.moves.Observe)
+	g.events.Subscribe(g.folderImport.Observe)
+	g.events.Subscribe(g.locks.Observe)
+	g.events.Subscribe(func(e Event) { g.syncWatch.Observe(g, e) })
+	g.events.Subscribe(func(e Event) { g.changed.Observe(g, e) })
+	g.events.Subscribe(func(e Event) { observeCSVProblems(g, e) })
+	g.events.Subscribe(func(e Event) { observeActivePanel(g, e) })
+	g.events.Subscribe(func(e Event) { observeScroll(g, e) })
+	g.events.Subscribe(func(e Event) { syncViews(g.canvas, e) })
+	// anything published may change what is on screen
+	g.events.Subscribe(func(Event) { g.power.redraw = true })
+	if opts.audit {
+		a, err := OpenAuditLog(filepath.Dir(defaultStatePath))
+		if err != nil {
+			log.Printf("audit: %v", err)
+		} else {
+			g.audit = a
+		}
+	}
+	g.events.Subscribe(func(e Event) { g.audit.Observe(e, g.canvas) })
+	if opts.replayPath != "" {
+		pl, err := LoadInputPlayer(opts.replayPath)
+		if err != nil {
+			log.Printf("replay: %v", err)
+		} else {
+			g.player = pl
+			log.Printf("replaying input from %s", opts.replayPath)
+		}
+	} else if opts.recordPath != "" {
+		rec, err := NewInputRecorder(opts.recordPath)
+		if err != nil {
+			log.Printf("record: %v", err)
+		} else {
+			g.recorder = rec
+			log.Printf("recording input to %s", opts.recordPath)
+		}
+	}
+	// Recorded input only replays faithfully if panel loads land on the
+	// same tick every run, so load synchronously while recording/replaying.
+	if g.recorder != nil || g.player != nil {
+		g.canvas.saveManager.synchronous = true
+		g.canvas.scheduler.unbudgeted = true
+	}
 	// selection and editing state moved into InputManager
 	// Attempt to load initial layout from `state.yml` non-blocking.
 	// LoadState schedules any CSV loads in the background.
-	if err := g.canvas.LoadState("state.yml"); err != nil {
-		log.Printf("LoadState: %v", err)
+	statePath := defaultStatePath
+	if g.player == nil {
+		statePath = offerCrashRecovery(statePath)
+	}
+	if g.player == nil {
+		acquireWorkspaceLock(g, defaultStatePath)
+	}
+	openWorkspace(g, statePath)
+	if opts.serveAddr != "" {
+		g.api = NewAPIServer(opts.serveAddr)
+		g.api.Start()
 	}
 	return g
 }
@@ -43,55 +217,208 @@ func abs(a int) int {
 	return a
 }
 
-func (g *Game) Update() error {
-	// input handling
-	g.input.HandlePanInput(g)
-	g.input.HandleCanvasInteraction(g)
+// nextInputFrame advances the tick counter and returns this tick's input,
+// taken from the replay file while one is playing and from Ebiten otherwise.
+func (g *Game) nextInputFrame() *InputFrame {
+	g.tick++
+	if g.player != nil {
+		if !g.player.Done() {
+			return g.player.Frame(g.tick)
+		}
+		log.Printf("replay finished at tick %d", g.tick)
+		g.player = nil
+	}
+	f := captureInputFrame(g.tick, g.start)
+	if err := g.recorder.Record(f); err != nil {
+		log.Printf("record: %v", err)
+		g.recorder.Close()
+		g.recorder = nil
+	}
+	return f
+}
 
-	// delegate panel mouse interactions to canvas (it will update selection on Game)
-	g.canvas.Update(g, g.input.GetLockedPanels())
+func (g *Game) Update() (err error) {
+	if g.crashErr != nil {
+		return g.crashErr
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = g.handleCrash(r)
+		}
+	}()
+	g.frame = g.nextInputFrame()
 
-	g.input.HandleContextMenuInput(g)
+	// run requests queued by the HTTP API before input so their effects are
+	// visible this frame
+	active := g.api.Drain(g)
 
-	g.input.HandleSelectionNavigation(g)
+	g.dispatchInput()
+	g.selStats.Update(g)
+	g.outliers.Update(g)
+	g.trace.Update(g)
+	g.locks.Update(g)
+	g.syncWatch.Update(g)
+	g.changed.Update(g)
 
-	// let UI handle editing input, caret and commit/cancel
-	g.ui.Update(g)
+	// glide panels towards a layout being switched to
+	if g.layouts.Update(g.canvas) {
+		active = true
+	}
+
+	// apply background loads and resolve overlaps (mouse interaction is
+	// handled by InputManager above)
+	locked := g.input.GetLockedPanels()
+	g.layouts.Lock(locked)
+	if g.canvas.Update(locked) {
+		active = true
+	}
+
+	// show image cells that finished loading
+	if g.images.Update() {
+		active = true
+	}
+
+	// append rows received by live feed panels
+	if g.feeds.Update(g.canvas, g.events) {
+		active = true
+	}
 
-	g.input.HandlePanelSwitching(g)
+	g.refreshWorkspaceLock()
+	g.updatePower(active)
 
 	return nil
 }
 
+// handleInput runs the canvas input handlers in order. While editing, the
+// keys that move the selection or switch panels belong to the edit.
+func (g *Game) handleInput(editing bool) {
+	// the watch window, the contents and the status line float above the
+	// canvas
+	if g.watch.HandleInput(g) || g.contents.HandleInput(g) || g.selStats.HandleClick(g) {
+		return
+	}
+	handleZoomInput(g)
+	handleScrollInput(g)
+	g.input.HandlePanInput(g)
+	g.input.HandleCanvasInteraction(g)
+
+	if !editing {
+		g.input.HandleSelectionNavigation(g)
+	}
+
+	// let UI handle editing input, caret and commit/cancel
+	g.ui.Update(g)
+
+	if !editing {
+		g.input.HandlePanelSwitching(g)
+	}
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
+	defer func() {
+		if r := recover(); r != nil && g.crashErr == nil {
+			g.crashErr = g.handleCrash(r)
+		}
+	}()
+	// the screen keeps its content between frames; skip repainting while
+	// nothing changed
+	if !g.needsRedraw() {
+		return
+	}
 	// dark background
 	screen.Fill(ColorBackground)
 
-	// draw canvas (panels)
-	g.canvas.Draw(screen, g.input)
+	// draw canvas (panels); selection overlay and editing are drawn by
+	// InputManager below. While zoomed the canvas goes on a view image
+	// scaled onto the screen; far out, panels are drawn as an overview.
+	if z := g.canvas.zoom(); z < overviewZoom {
+		g.renderer.DrawOverview(screen, g.canvas, g.input.activePanel)
+	} else {
+		view := g.renderer.viewImage(screen, z)
+		g.renderer.hoverName = g.canvas.NameButtonAt(g.frame.CursorPosition())
+		g.renderer.DrawCanvas(view, g.canvas, g.input)
 
-	// draw input-related elements (selection, editing)
-	g.input.Draw(screen, g.ui.face, g)
+		// draw input-related elements (selection, editing)
+		g.input.Draw(view, g.ui.face, g)
+		g.trace.Draw(view, g.canvas)
+		g.locks.Draw(view, g.ui.face, g.canvas)
+		g.ui.drawCellEdit(view, g)
+		g.renderer.presentView(screen, view, z)
+	}
+	g.rulers.Draw(screen, g.ui.face, g)
 
 	// draw UI (HUD, editing overlays)
 	g.ui.Draw(screen, g)
+	g.selStats.Draw(screen, g.ui.face, g.tick)
+	g.distribution.Draw(screen, g.ui.face)
+	g.images.Draw(screen, g.ui.face)
+	g.watch.Draw(screen, g, g.ui.face)
+	g.contents.Draw(screen, g, g.ui.face)
 
 	// draw context menu
 	g.contextMenu.Draw(screen, g.ui.face)
+
+	// modal picker and prompt on top of everything
+	g.picker.Draw(screen, g.ui.face)
+	g.prompt.Draw(screen, g.ui.face)
+	g.paste.Draw(screen, g.ui.face, g)
+	g.errDialog.Draw(screen, g.ui.face)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	// Return the outside dimensions so the logical screen matches window size.
 	// This prevents black bars when the window is resized.
+	if outsideWidth != g.power.layoutW || outsideHeight != g.power.layoutH {
+		g.power.layoutW, g.power.layoutH = outsideWidth, outsideHeight
+		g.power.redraw = true
+	}
 	return outsideWidth, outsideHeight
 }
 
 func main() {
+	var opts gameOptions
+	flag.StringVar(&opts.recordPath, "record", "", "record all input to this replay file")
+	flag.StringVar(&opts.replayPath, "replay", "", "play back input from this replay file")
+	flag.StringVar(&opts.serveAddr, "serve", "", "serve the HTTP API on this address (e.g. 127.0.0.1:8077)")
+	flag.BoolVar(&opts.keepHistory, "keep-history", false, "save cell edit history next to the state file")
+	flag.BoolVar(&opts.audit, "audit", false, "append every data change to "+auditFile+" next to the state file")
+	flag.IntVar(&cellcanvas.PagingThreshold, "page-rows", cellcanvas.PagingThreshold, "page CSV panels with more rows than this to a temp file (0 = never)")
+	memProfile := flag.String("memprofile", "", "write an allocation profile to this file on exit (inspect with go tool pprof)")
+	flag.Parse()
+
 	ebiten.SetWindowSize(windowWidth, windowHeight)
 	ebiten.SetWindowTitle("CellCanvas - Spreadsheet Panels")
 	ebiten.SetWindowResizable(true)
-	g := NewGame()
-	if err := ebiten.RunGame(g); err != nil {
+	// idle frames are not repainted (see power.go)
+	ebiten.SetScreenClearedEveryFrame(false)
+	g := NewGame(opts)
+	err := ebiten.RunGame(g)
+	g.lock.Release()
+	// cleanups do not run at exit, so page files would be left behind
+	g.canvas.CloseStores()
+	if *memProfile != "" {
+		writeMemProfile(*memProfile)
+	}
+	if cerr := g.recorder.Close(); cerr != nil {
+		log.Printf("record: %v", cerr)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// writeMemProfile saves the allocation profile, e.g. to check that idle
+// frames stay allocation-free:
+//
+//	go tool pprof -sample_index=alloc_objects cellchain mem.prof
+func writeMemProfile(path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("memprofile: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+		log.Printf("memprofile: %v", err)
+	}
+}
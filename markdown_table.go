@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// copyMarkdownTable copies panel pi to the clipboard as a Markdown table:
+// the selected cells when selection is set and pi is the active panel,
+// else the whole used range.
+func copyMarkdownTable(g *Game, pi int, selection bool) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "No panel to copy"})
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	r := cellcanvas.CellRange{Col1: p.UsedCols() - 1, Row1: p.UsedRows() - 1}
+	if selection {
+		if pi != g.input.activePanel {
+			g.events.Publish(LogMessage{Text: "Select the cells to copy first"})
+			return
+		}
+		r.Col0, r.Row0, r.Col1, r.Row1 = g.input.selectionRange()
+	}
+	if r.Col1 < r.Col0 || r.Row1 < r.Row0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is empty", pi+1)})
+		return
+	}
+	if err := clipboard.WriteAll(cellcanvas.MarkdownTable(p, r)); err != nil {
+		reportError(g, "Copy as Markdown", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("copied %s:%s as a Markdown table", cellcanvas.CellRef(r.Col0, r.Row0), cellcanvas.CellRef(r.Col1, r.Row1))})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// columnFormatStyles are the display styles offered by pickColumnFormat,
+// after "As stored", with the decimals asked for first.
+var columnFormatStyles = []struct {
+	label    string
+	style    string
+	decimals int
+}{
+	{"Round to decimals...", cellcanvas.DisplayFixed, 2},
+	{"Scientific (1.23e+06)...", cellcanvas.DisplaySci, 3},
+	{"Scientific when very large or small...", cellcanvas.DisplayAuto, 2},
+	{"SI suffixes (1.23M)...", cellcanvas.DisplaySI, 2},
+}
+
+// pickColumnFormat asks how the numbers of column col of panel pi are shown,
+// then how many decimals. Only the display changes: cells keep their value.
+func pickColumnFormat(g *Game, pi, col int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := []string{"As stored"}
+	for _, s := range columnFormatStyles {
+		items = append(items, s.label)
+	}
+	letters := cellcanvas.ColToLetters(col)
+	g.picker.Open(fmt.Sprintf("Show numbers in Panel %d column %s", pi+1, letters), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		if i == 0 {
+			g.canvas.Panels[pi].SetColumnFormat(col, "", 0)
+			g.power.redraw = true
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s shows numbers as stored", letters)})
+			return
+		}
+		s := columnFormatStyles[i-1]
+		cur := s.decimals
+		if p := &g.canvas.Panels[pi]; p.ColumnFormatAt(col) >= 0 {
+			cur = p.ColumnFormats[p.ColumnFormatAt(col)].Decimals
+		}
+		g.prompt.Open("Decimals", "digits after the point", strconv.Itoa(cur), func(g *Game, text string) {
+			d, err := strconv.Atoi(strings.TrimSpace(text))
+			if err != nil || d < 0 || d > 15 {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("decimals must be a whole number from 0 to 15, not %q", text)})
+				return
+			}
+			if pi >= len(g.canvas.Panels) {
+				return
+			}
+			g.canvas.Panels[pi].SetColumnFormat(col, s.style, d)
+			g.power.redraw = true
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("column %s: %s, %d decimals", letters, strings.TrimSuffix(s.label, "..."), d)})
+		})
+	})
+}
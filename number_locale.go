@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// pickNumberLocale lets the user choose how the numbers of panel pi are
+// written, then refills what is computed from them: its computed columns
+// and the derived panels over it. Cells keep their text; only how it is
+// read as a number changes.
+func pickNumberLocale(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := []string{fmt.Sprintf("Default (%s)", cellcanvas.DefaultNumberLocale.Label)}
+	for _, l := range cellcanvas.NumberLocales {
+		items = append(items, fmt.Sprintf("%s (%s)", l.Label, l.Name))
+	}
+	g.picker.Open(fmt.Sprintf("Numbers in Panel %d", pi+1), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		p.NumberLocale = ""
+		if i > 0 {
+			p.NumberLocale = cellcanvas.NumberLocales[i-1].Name
+		}
+		if len(p.Computed) > 0 {
+			g.computed.Start(g, pi)
+		}
+		for j := range g.canvas.Panels {
+			if q := g.canvas.Panels[j].Query; q != nil && q.From == pi {
+				g.derived.Start(g, j)
+			}
+		}
+		g.power.redraw = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d numbers are written like %s", pi+1, p.Numbers().Label)})
+	})
+}
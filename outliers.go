@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// outlierChunk is how many rows one scheduler step scans for outliers.
+const outlierChunk = 2048
+
+// maxOutlierItems caps the outliers listed by "Outliers...".
+const maxOutlierItems = 50
+
+// outlierAlpha is the opacity of the warning tint over outlier cells.
+const outlierAlpha = 0.35
+
+// outlierSet holds the outliers found in one panel. A rescan keeps showing
+// the previous ones until it completes.
+type outlierSet struct {
+	k    float64
+	list []cellcanvas.Outlier
+}
+
+// OutlierMarks keeps the outliers of every panel with highlighting on up
+// to date, rescanning a panel on the frame scheduler after it changes.
+type OutlierMarks struct {
+	sets  map[int]*outlierSet
+	dirty map[int]bool
+}
+
+func NewOutlierMarks() *OutlierMarks {
+	return &OutlierMarks{sets: make(map[int]*outlierSet), dirty: make(map[int]bool)}
+}
+
+// Observe marks panels whose data changed for a rescan. Removing or
+// reloading panels shifts indexes, so everything is rescanned.
+func (m *OutlierMarks) Observe(e Event) {
+	switch ev := e.(type) {
+	case CellChanged:
+		m.dirty[ev.Panel] = true
+	case PanelResized:
+		m.dirty[ev.Panel] = true
+	case PanelLoaded:
+		m.dirty[ev.Panel] = true
+	case RowsAppended:
+		m.dirty[ev.Panel] = true
+	case PanelRemoved, WorkspaceLoaded:
+		m.sets = make(map[int]*outlierSet)
+		m.dirty = make(map[int]bool)
+	}
+}
+
+// Update starts scans for panels that turned highlighting on or changed,
+// and drops the results of panels that turned it off.
+func (m *OutlierMarks) Update(g *Game) {
+	for pi := range g.canvas.Panels {
+		p := &g.canvas.Panels[pi]
+		set := m.sets[pi]
+		switch {
+		case p.Outliers <= 0:
+			delete(m.sets, pi)
+		case !p.Loaded:
+		case set == nil || set.k != p.Outliers || m.dirty[pi]:
+			m.start(g, pi, set)
+		}
+		delete(m.dirty, pi)
+	}
+}
+
+func (m *OutlierMarks) start(g *Game, pi int, prev *outlierSet) {
+	p := &g.canvas.Panels[pi]
+	set := &outlierSet{k: p.Outliers}
+	if prev != nil {
+		set.list = prev.list
+	}
+	m.sets[pi] = set
+	scan := cellcanvas.NewOutlierScan(p, p.Outliers)
+	g.canvas.scheduler.Add(func() bool {
+		if m.sets[pi] != set {
+			// abandoned for a newer scan
+			return true
+		}
+		if !scan.Step(&g.canvas.Panels[pi], outlierChunk) {
+			return false
+		}
+		set.list = scan.Outliers
+		g.power.redraw = true
+		return true
+	})
+}
+
+// drawOutliers tints the visible outlier cells of panel pi.
+func (r *Renderer) drawOutliers(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, pi, firstRow, lastRow, firstCol, lastCol int) {
+	if r.outliers == nil || p.Outliers <= 0 {
+		return
+	}
+	set := r.outliers.sets[pi]
+	if set == nil {
+		return
+	}
+	for _, o := range set.list {
+		if o.Col < firstCol || o.Col >= lastCol || o.Row < firstRow || o.Row >= lastRow {
+			continue
+		}
+		x := float64(b.GridX + p.ColX(o.Col))
+		y := float64(b.GridY + p.RowY(o.Row))
+		r.tintRect(screen, x, y, float64(p.ColWidth(o.Col)), float64(p.RowHeight(o.Row)), ColorOutlier, outlierAlpha)
+	}
+}
+
+// promptOutliers asks for the threshold of panel pi; 0 turns highlighting
+// off.
+func promptOutliers(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	k := g.canvas.Panels[pi].Outliers
+	if k <= 0 {
+		k = g.settings.OutlierK
+	}
+	cur := strconv.FormatFloat(k, 'g', -1, 64)
+	g.prompt.Open("Highlight outliers beyond", "standard deviations from the column mean; 0 turns highlighting off", cur, func(g *Game, v string) {
+		k, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || k < 0 || pi >= len(g.canvas.Panels) {
+			return
+		}
+		g.canvas.Panels[pi].Outliers = k
+		if k == 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: outliers off", pi+1)})
+			return
+		}
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: outliers beyond %g sd", pi+1, k)})
+	})
+}
+
+// listOutliers shows the outliers of panel pi, in row order;
+// picking one selects it.
+func listOutliers(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	set := g.outliers.sets[pi]
+	if g.canvas.Panels[pi].Outliers <= 0 || set == nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: outlier highlighting is off", pi+1)})
+		return
+	}
+	if len(set.list) == 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: no outliers", pi+1)})
+		return
+	}
+	list := set.list
+	title := fmt.Sprintf("Panel %d: %d outliers beyond %g sd", pi+1, len(list), set.k)
+	if len(list) > maxOutlierItems {
+		list = list[:maxOutlierItems]
+		title += fmt.Sprintf(" (first %d)", maxOutlierItems)
+	}
+	items := make([]string, len(list))
+	for i, o := range list {
+		items[i] = fmt.Sprintf("%s  %s  (%+.1f sd)", cellcanvas.CellRef(o.Col, o.Row), formatStat(o.Value, g.canvas.Panels[pi].Numbers()), o.Z)
+	}
+	g.picker.Open(title, items, func(g *Game, i int) {
+		if pi < len(g.canvas.Panels) {
+			goToCell(g, pi, list[i].Col, list[i].Row)
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package main
 
+import "github.com/example/cellchain/cellcanvas"
+
 // Layout constants for panel drawing and hit testing
 // Layout constants are now in theme.go
 
@@ -10,18 +12,26 @@ type PanelBounds struct {
 	ContentW, ContentH int
 	TotalX, TotalY     int
 	TotalW, TotalH     int
+	// GridX, GridY is where the panel's first cell would be drawn: the
+	// content's corner, less the rows and columns scrolled past. Cells are
+	// at GridX+ColX(col), GridY+RowY(row).
+	GridX, GridY int
 }
 
-// GetBounds returns the on-screen bounding rectangles for content and total area of a panel.
-func (p *Panel) GetBounds(camX, camY float64) PanelBounds {
-	contentX := int(float64(p.X) + camX)
-	contentY := int(float64(p.Y) + camY)
-	contentW := p.Cols * p.CellW
-	contentH := p.Rows * p.CellH
+// Bounds returns the on-screen bounding rectangles for content and total
+// area of a panel under the canvas camera, in view pixels: at a zoom other
+// than 1 the view is scaled onto the screen (see zoom.go).
+func (c *Canvas) Bounds(p *cellcanvas.Panel) PanelBounds {
+	contentX := int(float64(p.X) + c.CamX)
+	contentY := int(float64(p.Y) + c.CamY)
+	contentW := p.Width()
+	contentH := p.Height()
 	totalX := contentX - PanelPaddingX
 	totalY := contentY - PanelHeaderHeight
 	totalW := contentW + PanelPaddingX*2
 	totalH := contentH + PanelHeaderHeight + PanelPaddingY*2
+	firstRow, _ := p.VisibleRows()
+	firstCol, _ := p.VisibleCols()
 	return PanelBounds{
 		ContentX: contentX,
 		ContentY: contentY,
@@ -31,10 +41,47 @@ func (p *Panel) GetBounds(camX, camY float64) PanelBounds {
 		TotalY:   totalY,
 		TotalW:   totalW,
 		TotalH:   totalH,
+		GridX:    contentX - p.ColX(firstCol),
+		GridY:    contentY - p.RowY(firstRow),
 	}
 }
 
-func (p *Panel) GetScreenRect(camX, camY float64) (x, y, w, h int) {
-	b := p.GetBounds(camX, camY)
-	return b.TotalX, b.TotalY, b.TotalW, b.TotalH
+// NameButton returns the screen rectangle of the name button centered in
+// the panel header.
+func (b PanelBounds) NameButton() (x, y, w, h int) {
+	x = b.ContentX + b.ContentW/2 - PanelNameButtonW/2
+	y = b.ContentY - PanelHeaderHeight + (PanelHeaderHeight-PanelNameButtonH)/2
+	return x, y, PanelNameButtonW, PanelNameButtonH
+}
+
+// NameButtonAt returns the panel whose header name button is under screen
+// position (mx, my), or -1.
+func (c *Canvas) NameButtonAt(mx, my int) int {
+	mx, my = c.ToView(mx, my)
+	for i := len(c.Panels) - 1; i >= 0; i-- {
+		x, y, w, h := c.Bounds(&c.Panels[i]).NameButton()
+		if mx >= x && mx <= x+w && my >= y && my <= y+h {
+			return i
+		}
+	}
+	return -1
+}
+
+// CellAt returns the panel index and cell under screen position (mx, my),
+// checking panels from top to bottom. ok is false when the point is not over
+// a cell of a loaded panel.
+func (c *Canvas) CellAt(mx, my int) (panel, col, row int, ok bool) {
+	mx, my = c.ToView(mx, my)
+	for i := len(c.Panels) - 1; i >= 0; i-- {
+		p := &c.Panels[i]
+		b := c.Bounds(p)
+		if mx < b.ContentX || mx >= b.ContentX+b.ContentW || my < b.ContentY || my >= b.ContentY+b.ContentH {
+			continue
+		}
+		if !p.Loaded {
+			return -1, 0, 0, false
+		}
+		return i, p.ColAt(mx - b.GridX), p.RowAt(my - b.GridY), true
+	}
+	return -1, 0, 0, false
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// parseHexColor parses "#rrggbb"; ok is false for anything else.
+func parseHexColor(s string) (color.RGBA, bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+}
+
+// pickPanelColor lets the user choose panel pi's accent from AccentPalette.
+func pickPanelColor(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := []string{"None"}
+	for _, a := range AccentPalette {
+		items = append(items, fmt.Sprintf("%s  %s", a.Name, a.Hex))
+	}
+	g.picker.Open(fmt.Sprintf("Color for Panel %d", pi+1), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		if i == 0 {
+			p.Accent = ""
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d color cleared", pi+1)})
+			return
+		}
+		a := AccentPalette[i-1]
+		p.Accent = a.Hex
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d color: %s", pi+1, strings.ToLower(a.Name))})
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Header icons are drawn as vector shapes: the bundled fonts have no emoji
+// glyphs, so anything that is not a built-in icon name is shown as a short
+// text badge instead.
+const (
+	iconSize = 12
+	iconGap  = 6
+	// maxBadgeRunes is how much of a custom text badge is shown
+	maxBadgeRunes = 3
+)
+
+// PanelIcons lists the built-in header icons in picker order.
+var PanelIcons = []string{"circle", "square", "diamond", "triangle", "star", "flag", "check", "cross"}
+
+// drawPanelIcon draws icon with its top-left corner at x, y.
+func (r *Renderer) drawPanelIcon(screen *ebiten.Image, icon string, x, y float64) {
+	fx, fy, s := float32(x), float32(y), float32(iconSize)
+	path := &r.icon
+	path.Reset()
+	switch icon {
+	case "circle":
+		path.Arc(fx+s/2, fy+s/2, s/2, 0, 2*math.Pi, vector.Clockwise)
+	case "square":
+		path.MoveTo(fx+1, fy+1)
+		path.LineTo(fx+s-1, fy+1)
+		path.LineTo(fx+s-1, fy+s-1)
+		path.LineTo(fx+1, fy+s-1)
+	case "diamond":
+		path.MoveTo(fx+s/2, fy)
+		path.LineTo(fx+s, fy+s/2)
+		path.LineTo(fx+s/2, fy+s)
+		path.LineTo(fx, fy+s/2)
+	case "triangle":
+		path.MoveTo(fx+s/2, fy)
+		path.LineTo(fx+s, fy+s)
+		path.LineTo(fx, fy+s)
+	case "star":
+		starPath(path, fx, fy, s)
+	case "flag":
+		path.MoveTo(fx+1, fy)
+		path.LineTo(fx+s, fy+s/4)
+		path.LineTo(fx+3, fy+s/2)
+		path.LineTo(fx+3, fy+s)
+		path.LineTo(fx+1, fy+s)
+	case "check":
+		vector.StrokeLine(screen, fx+1, fy+s/2, fx+s/3, fy+s-2, 2, ColorText, true)
+		vector.StrokeLine(screen, fx+s/3, fy+s-2, fx+s-1, fy+2, 2, ColorText, true)
+		return
+	case "cross":
+		vector.StrokeLine(screen, fx+2, fy+2, fx+s-2, fy+s-2, 2, ColorText, true)
+		vector.StrokeLine(screen, fx+s-2, fy+2, fx+2, fy+s-2, 2, ColorText, true)
+		return
+	default:
+		drawTextAt(screen, nil, badgeText(icon), int(x), int(y)-2, ColorText)
+		return
+	}
+	path.Close()
+	var op vector.DrawPathOptions
+	op.AntiAlias = true
+	op.ColorScale.ScaleWithColor(ColorText)
+	vector.FillPath(screen, path, nil, &op)
+}
+
+// starPath adds a five-pointed star of size s with its top-left corner at
+// x, y to path, not yet closed.
+func starPath(path *vector.Path, x, y, s float32) {
+	for i := range 10 {
+		rad := s / 2
+		if i%2 == 1 {
+			rad = s / 5
+		}
+		a := float64(i)*math.Pi/5 - math.Pi/2
+		px := x + s/2 + rad*float32(math.Cos(a))
+		py := y + s/2 + rad*float32(math.Sin(a))
+		if i == 0 {
+			path.MoveTo(px, py)
+		} else {
+			path.LineTo(px, py)
+		}
+	}
+}
+
+// badgeText shortens a custom icon to the part shown in the header.
+func badgeText(s string) string {
+	if rs := []rune(s); len(rs) > maxBadgeRunes {
+		return string(rs[:maxBadgeRunes])
+	}
+	return s
+}
+
+// pickPanelIcon lets the user choose panel pi's header icon.
+func pickPanelIcon(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	items := append([]string{"None"}, PanelIcons...)
+	items = append(items, "Text badge...")
+	g.picker.Open(fmt.Sprintf("Icon for Panel %d", pi+1), items, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) {
+			return
+		}
+		switch {
+		case i == 0:
+			g.canvas.Panels[pi].Icon = ""
+		case i <= len(PanelIcons):
+			g.canvas.Panels[pi].Icon = PanelIcons[i-1]
+		default:
+			g.prompt.Open("Badge text", fmt.Sprintf("up to %d characters, e.g. $ or DB", maxBadgeRunes), "", func(g *Game, v string) {
+				if pi < len(g.canvas.Panels) {
+					g.canvas.Panels[pi].Icon = badgeText(strings.TrimSpace(v))
+				}
+			})
+		}
+	})
+}
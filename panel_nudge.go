@@ -0,0 +1,53 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// nudgePanel lays out panel pi from the keyboard: Ctrl+Shift+arrows move
+// it by a pixel, or to the next line of the nudge grid when one is set, and
+// Ctrl+Alt+arrows add or remove a column (right, left) or row (down, up).
+func nudgePanel(g *Game, pi int) {
+	f := g.frame
+	dx, dy := 0, 0
+	switch {
+	case f.KeyJustPressed(ebiten.KeyArrowLeft):
+		dx = -1
+	case f.KeyJustPressed(ebiten.KeyArrowRight):
+		dx = 1
+	case f.KeyJustPressed(ebiten.KeyArrowUp):
+		dy = -1
+	case f.KeyJustPressed(ebiten.KeyArrowDown):
+		dy = 1
+	default:
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	if f.AltPressed() {
+		if cols, rows := max(p.Cols+dx, 1), max(p.Rows+dy, 1); cols != p.Cols || rows != p.Rows {
+			resizePanel(g, pi, cols, rows)
+		}
+		return
+	}
+	fromX, fromY := p.X, p.Y
+	p.X, p.Y = nudge(p.X, dx, g.input.nudgeGrid), nudge(p.Y, dy, g.input.nudgeGrid)
+	g.events.Publish(PanelMoved{Panel: pi, FromX: fromX, FromY: fromY, X: p.X, Y: p.Y})
+}
+
+// nudge moves v one step in direction d (-1, 0 or 1): one pixel, or to the
+// next multiple of grid in that direction when grid > 0.
+func nudge(v, d, grid int) int {
+	if d == 0 || grid <= 0 {
+		return v + d
+	}
+	// floor division, so negative coordinates snap the same way
+	q := v / grid
+	if v%grid != 0 && v < 0 {
+		q--
+	}
+	if d > 0 {
+		return (q + 1) * grid
+	}
+	if q*grid == v {
+		return (q - 1) * grid
+	}
+	return q * grid
+}
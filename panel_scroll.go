@@ -0,0 +1,149 @@
+package main
+
+import (
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// scrollWheelRows is how many rows one wheel notch scrolls a panel
+	scrollWheelRows = 3
+	// scrollThumbMin is the shortest a scrollbar thumb gets, in pixels
+	scrollThumbMin = 8
+)
+
+// handleScrollInput scrolls the panel under the cursor with the mouse
+// wheel: rows, or columns with Shift. Ctrl+wheel zooms instead.
+func handleScrollInput(g *Game) {
+	f := g.frame
+	dx, dy := f.Wheel()
+	if f.CtrlPressed() || (dx == 0 && dy == 0) {
+		return
+	}
+	if f.ShiftPressed() && dx == 0 {
+		dx, dy = dy, 0
+	}
+	mx, my := g.canvas.ToView(f.CursorPosition())
+	for i := len(g.canvas.Panels) - 1; i >= 0; i-- {
+		p := &g.canvas.Panels[i]
+		b := g.canvas.Bounds(p)
+		if mx < b.TotalX || mx >= b.TotalX+b.TotalW || my < b.TotalY || my >= b.TotalY+b.TotalH {
+			continue
+		}
+		if p.ScrollBy(-wheelSteps(dy)*scrollWheelRows, -wheelSteps(dx)) {
+			g.power.redraw = true
+		}
+		return
+	}
+}
+
+// wheelSteps rounds a wheel delta away from zero, so that the small deltas
+// of touchpads still scroll.
+func wheelSteps(d float64) int {
+	switch {
+	case d > 0:
+		return max(int(d), 1)
+	case d < 0:
+		return min(int(d), -1)
+	}
+	return 0
+}
+
+// observeScroll scrolls the active panel to keep the cursor cell in view
+// as the selection moves.
+func observeScroll(g *Game, e Event) {
+	if ev, ok := e.(SelectionChanged); ok && ev.Panel >= 0 && ev.Panel < len(g.canvas.Panels) {
+		g.canvas.Panels[ev.Panel].ScrollTo(ev.Col, ev.Row)
+	}
+}
+
+// scrollbars returns the tracks of p's vertical scrollbar, in the padding
+// right of its cells, and horizontal one, below them; w or h is 0 for a
+// direction p does not scroll in.
+func scrollbars(p *cellcanvas.Panel, b PanelBounds) (v, h [4]int) {
+	if p.WindowRows() < p.Rows {
+		v = [4]int{b.ContentX + b.ContentW, b.ContentY, PanelPaddingX, b.ContentH}
+	}
+	if p.WindowCols() < p.Cols {
+		h = [4]int{b.ContentX, b.ContentY + b.ContentH, b.ContentW, PanelPaddingY}
+	}
+	return v, h
+}
+
+// thumb returns the offset and length of the thumb of a track size pixels
+// long showing n of total items from first on.
+func thumb(size, first, n, total int) (int, int) {
+	l := max(size*n/total, scrollThumbMin)
+	return (size - l) * first / max(total-n, 1), l
+}
+
+// drawScrollbars draws the scrollbars of a panel that scrolls.
+func (r *Renderer) drawScrollbars(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
+	v, h := scrollbars(p, b)
+	if v[3] > 0 {
+		first, last := p.VisibleRows()
+		r.fillRect(screen, float64(v[0]), float64(v[1]), float64(v[2]), float64(v[3]), ColorScrollTrack)
+		off, l := thumb(v[3], first, last-first, p.Rows)
+		r.fillRect(screen, float64(v[0]), float64(v[1]+off), float64(v[2]), float64(l), ColorScrollThumb)
+	}
+	if h[2] > 0 {
+		first, last := p.VisibleCols()
+		r.fillRect(screen, float64(h[0]), float64(h[1]), float64(h[2]), float64(h[3]), ColorScrollTrack)
+		off, l := thumb(h[2], first, last-first, p.Cols)
+		r.fillRect(screen, float64(h[0]+off), float64(h[1]), float64(l), float64(h[3]), ColorScrollThumb)
+	}
+}
+
+// grabScrollbar starts dragging a scrollbar of panel pi when view position
+// mx, my is on one. It reports whether it did.
+func (im *InputManager) grabScrollbar(g *Game, pi int, b PanelBounds, mx, my int) bool {
+	p := &g.canvas.Panels[pi]
+	if !p.Loaded {
+		return false
+	}
+	v, h := scrollbars(p, b)
+	in := func(t [4]int) bool {
+		return t[2] > 0 && t[3] > 0 && mx >= t[0] && mx < t[0]+t[2] && my >= t[1] && my < t[1]+t[3]
+	}
+	switch {
+	case in(v):
+		im.scrollPanel, im.scrollCols = pi, false
+	case in(h):
+		im.scrollPanel, im.scrollCols = pi, true
+	default:
+		return false
+	}
+	return true
+}
+
+// dragScrollbar scrolls the panel whose scrollbar is held so that the
+// thumb's middle follows the cursor.
+func (im *InputManager) dragScrollbar(g *Game, mx, my int) {
+	pi := im.scrollPanel
+	if pi < 0 {
+		return
+	}
+	if pi >= len(g.canvas.Panels) || !g.frame.MousePressed(ebiten.MouseButtonLeft) {
+		im.scrollPanel = -1
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	b := g.canvas.Bounds(p)
+	// first item for a thumb centered on pos of a size pixel track
+	at := func(pos, size, n, total int) int {
+		_, l := thumb(size, 0, n, total)
+		return (pos - l/2) * max(total-n, 1) / max(size-l, 1)
+	}
+	r0, _ := p.VisibleRows()
+	c0, _ := p.VisibleCols()
+	if im.scrollCols {
+		c0 = at(mx-b.ContentX, b.ContentW, p.WindowCols(), p.Cols)
+	} else {
+		r0 = at(my-b.ContentY, b.ContentH, p.WindowRows(), p.Rows)
+	}
+	first, _ := p.VisibleRows()
+	firstCol, _ := p.VisibleCols()
+	if p.ScrollBy(r0-first, c0-firstCol) {
+		g.power.redraw = true
+	}
+}
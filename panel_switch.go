@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// pickPanel lists the panels by number, name and file, and switches to the
+// one picked, bringing it into view.
+func pickPanel(g *Game) {
+	items := make([]string, len(g.canvas.Panels))
+	for i := range g.canvas.Panels {
+		p := &g.canvas.Panels[i]
+		label := fmt.Sprintf("Panel %d", i+1)
+		if p.Name != "" {
+			label += "  " + p.Name
+		}
+		if p.Filename != "" {
+			label += "  (" + filepath.Base(p.Filename) + ")"
+		}
+		items[i] = label
+	}
+	g.picker.Open("Switch to panel", items, func(g *Game, i int) {
+		if i >= len(g.canvas.Panels) {
+			return
+		}
+		g.input.switchToPanel(g, i)
+		revealPanel(g, i)
+	})
+	g.picker.selected = max(0, min(g.input.activePanel, len(items)-1))
+}
+
+// revealPanel pans the camera to panel pi's top-left corner when the panel
+// is entirely off screen.
+func revealPanel(g *Game, pi int) {
+	b := g.canvas.Bounds(&g.canvas.Panels[pi])
+	w, h := g.viewSize()
+	if b.TotalX < w && b.TotalX+b.TotalW > 0 && b.TotalY < h && b.TotalY+b.TotalH > 0 {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	g.canvas.CamX = float64(w/4 - p.X)
+	g.canvas.CamY = float64(h/4 - p.Y)
+}
+
+// observeActivePanel keeps the workspace's ActivePanel in step with the
+// selection so it is saved, and makes the saved panel active again, with
+// its saved selection, when a workspace is opened. A panel still loading is
+// waited for, since its selection is clamped to the panel's size.
+func observeActivePanel(g *Game, e Event) {
+	im := g.input
+	switch ev := e.(type) {
+	case SelectionChanged:
+		g.canvas.ActivePanel = ev.Panel
+	case WorkspaceLoaded:
+		im.restorePanel = -1
+		pi := g.canvas.ActivePanel
+		switch {
+		case pi < 0 || pi >= len(g.canvas.Panels):
+		case g.canvas.Panels[pi].Loaded:
+			im.switchToPanel(g, pi)
+		default:
+			im.restorePanel = pi
+		}
+	case PanelLoaded:
+		if ev.Panel == im.restorePanel {
+			im.restorePanel = -1
+			im.switchToPanel(g, ev.Panel)
+		}
+	case PanelAdded, PanelRemoved:
+		im.restorePanel = -1
+	}
+}
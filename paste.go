@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+const (
+	// pasteConfirmCells is the size above which a paste is previewed and
+	// must be confirmed
+	pasteConfirmCells = 10000
+	// pasteChunk is how many rows one scheduler step pastes
+	pasteChunk = 4096
+	// pastePreviewRows is how many rows the preview shows
+	pastePreviewRows = 4
+)
+
+// pasteJob is a parsed clipboard waiting to be written at Col, Row of
+// Panel, with the Paste Special choices opts.
+type pasteJob struct {
+	Panel, Col, Row int
+	recs            [][]string
+	cols            int
+	opts            cellcanvas.PasteOptions
+}
+
+// PasteDialog previews a large paste: its size, target range and how much
+// the panel grows. Enter pastes it in chunks on the frame scheduler, Escape
+// cancels. Like Prompt it owns all input while open.
+type PasteDialog struct {
+	job *pasteJob
+	// reading is set while the clipboard is read and parsed in the
+	// background
+	reading bool
+	// gen is bumped when panels are removed, stopping running pastes
+	gen int
+	// special holds the last Paste Special choices
+	special cellcanvas.PasteOptions
+}
+
+func NewPasteDialog() *PasteDialog {
+	return &PasteDialog{}
+}
+
+// Observe drops a previewed paste whose panel went away.
+func (d *PasteDialog) Observe(e Event) {
+	switch e.(type) {
+	case PanelRemoved, WorkspaceLoaded:
+		d.job = nil
+		d.gen++
+	}
+}
+
+// Active reports whether a paste is waiting for confirmation.
+func (d *PasteDialog) Active() bool {
+	return d.job != nil
+}
+
+// Update confirms or cancels the previewed paste.
+func (d *PasteDialog) Update(g *Game) {
+	f := g.frame
+	switch {
+	case f.KeyJustPressed(ebiten.KeyEnter):
+		job := d.job
+		d.job = nil
+		runPaste(g, job)
+	case f.KeyJustPressed(ebiten.KeyEscape):
+		d.job = nil
+		g.events.Publish(LogMessage{Text: "paste cancelled"})
+	}
+}
+
+// Draw renders the preview centered on screen.
+func (d *PasteDialog) Draw(screen *ebiten.Image, face font.Face, g *Game) {
+	if d.job == nil || d.job.Panel >= len(g.canvas.Panels) {
+		return
+	}
+	job := d.job
+	p := &g.canvas.Panels[job.Panel]
+	rows, cols := max(p.Rows, job.Row+len(job.recs)), max(p.Cols, job.Col+job.cols)
+	lines := []string{
+		fmt.Sprintf("%d rows x %d columns into %s:%s of Panel %d", len(job.recs), job.cols,
+			cellcanvas.CellRef(job.Col, job.Row), cellcanvas.CellRef(job.Col+job.cols-1, job.Row+len(job.recs)-1), job.Panel+1),
+	}
+	for _, rec := range job.recs[:min(len(job.recs), pastePreviewRows)] {
+		lines = append(lines, "  "+truncateText(strings.Join(rec, " | "), 72))
+	}
+	if len(job.recs) > pastePreviewRows {
+		lines = append(lines, "  ...")
+	}
+	warn := ""
+	if rows != p.Rows || cols != p.Cols {
+		warn = fmt.Sprintf("Panel %d grows from %d x %d to %d x %d cells", job.Panel+1, p.Rows, p.Cols, rows, cols)
+	}
+
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	w := min(640, sw-32)
+	h := 48 + (len(lines)+1)*18 + 24
+	x, y := (sw-w)/2, sh/3-h/2
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y+h-2), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 2, float64(h), ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x+w-2), float64(y), 2, float64(h), ColorMenuBorder)
+
+	drawTextAt(screen, face, "Paste large clipboard?", x+PanelInnerPadding*2, y+10, ColorText)
+	for i, line := range lines {
+		drawTextAt(screen, face, line, x+PanelInnerPadding*2, y+36+i*18, ColorText)
+	}
+	if warn != "" {
+		drawTextAt(screen, face, warn, x+PanelInnerPadding*2, y+36+len(lines)*18, ColorError)
+	}
+	drawTextAt(screen, face, "Enter to paste, Esc to cancel", x+PanelInnerPadding*2, y+h-22, ColorTextDim)
+}
+
+// truncateText cuts s to n runes, marking the cut with "...".
+func truncateText(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+	return s
+}
+
+// pasteClipboard pastes the clipboard at the top-left cell of the
+// selection. The clipboard is read and parsed off the game loop; pastes of
+// more than pasteConfirmCells cells are previewed first.
+func pasteClipboard(g *Game) {
+	pasteClipboardWith(g, cellcanvas.PasteOptions{})
+}
+
+// pasteClipboardWith pastes the clipboard like pasteClipboard, with the
+// Paste Special choices opts.
+func pasteClipboardWith(g *Game, opts cellcanvas.PasteOptions) {
+	im := g.input
+	pi := im.activePanel
+	if pi < 0 || pi >= len(g.canvas.Panels) || !g.canvas.Panels[pi].Loaded || previewBlocksEdit(g, pi) || queryBlocksEdit(g, pi) {
+		return
+	}
+	col, row, _, _ := im.selectionRange()
+	readClipboard(g, func(g *Game, recs [][]string) {
+		if opts.Transpose {
+			recs = cellcanvas.Transpose(recs)
+		}
+		job := &pasteJob{Panel: pi, Col: col, Row: row, recs: recs, cols: cellcanvas.RecordsWidth(recs), opts: opts}
+		if len(job.recs)*job.cols > pasteConfirmCells {
+			g.paste.job = job
+		} else {
+			pasteSmall(g, job)
+		}
+	})
+}
+
+// pasteAsNewPanel adds a panel at world position x, y holding the
+// clipboard, sized to fit it.
+func pasteAsNewPanel(g *Game, x, y int) {
+	readClipboard(g, func(g *Game, recs [][]string) {
+		i, err := g.canvas.AddPanelFromRecords(recs, x, y)
+		if err != nil {
+			reportError(g, "Paste as new panel", err)
+			return
+		}
+		p := &g.canvas.Panels[i]
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("pasted %d x %d cells as Panel %d", p.Rows, p.Cols, i+1)})
+	})
+}
+
+// readClipboard reads and parses the clipboard off the game loop, then
+// calls done with its records on the loop. Nothing happens when the
+// clipboard is empty, fails to read or panels were removed meanwhile; only
+// one read runs at a time.
+func readClipboard(g *Game, done func(g *Game, recs [][]string)) {
+	if g.paste.reading {
+		return
+	}
+	type result struct {
+		recs [][]string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		text, err := clipboard.ReadAll()
+		ch <- result{cellcanvas.ParseClipboard(text), err}
+	}()
+	g.paste.reading = true
+	gen := g.paste.gen
+	g.canvas.scheduler.Add(func() bool {
+		var res result
+		select {
+		case res = <-ch:
+		default:
+			return false
+		}
+		g.paste.reading = false
+		switch {
+		case res.err != nil:
+			reportError(g, "Paste", res.err)
+		case len(res.recs) == 0:
+			g.events.Publish(LogMessage{Text: "clipboard is empty"})
+		case gen != g.paste.gen:
+		default:
+			done(g, res.recs)
+		}
+		return true
+	})
+}
+
+// pasteSmall writes job at once, publishing every changed cell. Values that
+// are not numbers are skipped in columns with a unit.
+func pasteSmall(g *Game, job *pasteJob) {
+	p := &g.canvas.Panels[job.Panel]
+	cols, rows := p.Cols, p.Rows
+	p.Cols, p.Rows = max(p.Cols, job.Col+job.cols), max(p.Rows, job.Row+len(job.recs))
+	skipped := 0
+	for i, rec := range p.PasteSpecial(job.Col, job.Row, job.recs, job.opts) {
+		for j, val := range rec {
+			col, row := job.Col+j, job.Row+i
+			val, err := p.UnitValue(col, row, val)
+			if err != nil {
+				skipped++
+				continue
+			}
+			old := p.RawCell(col, row)
+			p.SetCell(col, row, val)
+			if old != val {
+				g.events.Publish(CellChanged{Panel: job.Panel, Col: col, Row: row, Old: old, New: val})
+			}
+		}
+	}
+	if p.Cols != cols || p.Rows != rows {
+		g.events.Publish(PanelResized{Panel: job.Panel, Cols: p.Cols, Rows: p.Rows})
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("pasted %d x %d cells into Panel %d", len(job.recs), job.cols, job.Panel+1)})
+	if skipped > 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("skipped %d values that are not numbers in columns with a unit", skipped)})
+	}
+}
+
+// runPaste writes a confirmed large paste pasteChunk rows per frame.
+// Individual cells are not published (the cell history would hold every
+// one); PanelLoaded tells subscribers the panel's data was replaced. As in
+// pasteSmall, values that are not numbers are skipped in columns with a
+// unit.
+func runPaste(g *Game, job *pasteJob) {
+	done, gen, skipped := 0, g.paste.gen, 0
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("pasting %d rows into Panel %d...", len(job.recs), job.Panel+1)})
+	g.canvas.scheduler.Add(func() bool {
+		if gen != g.paste.gen {
+			return true
+		}
+		p := &g.canvas.Panels[job.Panel]
+		end := min(done+pasteChunk, len(job.recs))
+		recs := p.PasteSpecial(job.Col, job.Row+done, job.recs[done:end], job.opts)
+		for i, rec := range recs {
+			for j, val := range rec {
+				col, row := job.Col+j, job.Row+done+i
+				if v, err := p.UnitValue(col, row, val); err != nil {
+					rec[j] = p.RawCell(col, row)
+					skipped++
+				} else {
+					rec[j] = v
+				}
+			}
+		}
+		p.PasteRows(job.Col, job.Row+done, recs)
+		done = end
+		g.power.redraw = true
+		if done < len(job.recs) {
+			return false
+		}
+		g.events.Publish(PanelResized{Panel: job.Panel, Cols: p.Cols, Rows: p.Rows})
+		g.events.Publish(PanelLoaded{Panel: job.Panel})
+		last := cellcanvas.CellRef(job.Col+job.cols-1, job.Row+len(job.recs)-1)
+		g.events.Publish(BulkEdit{Panel: job.Panel, Action: "paste", Cell: cellcanvas.CellRef(job.Col, job.Row) + ":" + last, Detail: fmt.Sprintf("%d x %d cells", len(job.recs), job.cols)})
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("pasted %d x %d cells into Panel %d", len(job.recs), job.cols, job.Panel+1)})
+		if skipped > 0 {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("skipped %d values that are not numbers in columns with a unit", skipped)})
+		}
+		return true
+	})
+}
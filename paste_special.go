@@ -0,0 +1,49 @@
+package main
+
+import "github.com/example/cellchain/cellcanvas"
+
+// pasteSpecialPaste is the index of the Paste entry of pickPasteSpecial.
+const pasteSpecialPaste = 4
+
+// pasteOpLabels name the Paste Special operations, by cellcanvas.PasteOp.
+var pasteOpLabels = []string{"Replace the cells", "Add into the cells", "Multiply the cells"}
+
+// pickPasteSpecial opens the Paste Special choices for a paste into the
+// selection: values only, transpose, skip blanks and how to combine with
+// the cells pasted over. Picking a choice toggles it and reopens the list
+// with it highlighted; Paste pastes with them. The choices are kept for the
+// next Paste Special.
+func pickPasteSpecial(g *Game, highlight int) {
+	o := g.paste.special
+	check := func(on bool, label string) string {
+		if on {
+			return "[x] " + label
+		}
+		return "[ ] " + label
+	}
+	items := []string{
+		check(o.ValuesOnly, "Values only (formulas become their values)"),
+		check(o.Transpose, "Transpose (rows become columns)"),
+		check(o.SkipBlanks, "Skip blanks"),
+		"Operation: " + pasteOpLabels[o.Op],
+		"Paste",
+	}
+	g.picker.Open("Paste Special", items, func(g *Game, i int) {
+		o := &g.paste.special
+		switch i {
+		case 0:
+			o.ValuesOnly = !o.ValuesOnly
+		case 1:
+			o.Transpose = !o.Transpose
+		case 2:
+			o.SkipBlanks = !o.SkipBlanks
+		case 3:
+			o.Op = (o.Op + 1) % cellcanvas.PasteOp(len(pasteOpLabels))
+		default:
+			pasteClipboardWith(g, *o)
+			return
+		}
+		pickPasteSpecial(g, i)
+	})
+	g.picker.selected = highlight
+}
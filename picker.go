@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// pickerItemH is the row height of a Picker entry.
+const pickerItemH = 24
+
+// Picker is a modal list: clicking an entry (or Enter on the highlighted
+// one) calls onPick with its index, Escape or a click outside cancels. Like
+// Prompt it owns all input while open.
+type Picker struct {
+	visible  bool
+	title    string
+	items    []string
+	selected int
+	onPick   func(g *Game, i int)
+	// box geometry from the last Draw, used for hit testing
+	x, y, w int
+}
+
+// NewPicker creates a hidden picker.
+func NewPicker() *Picker {
+	return &Picker{}
+}
+
+// Open shows the picker with the given entries.
+func (pk *Picker) Open(title string, items []string, onPick func(g *Game, i int)) {
+	pk.visible = true
+	pk.title = title
+	pk.items = items
+	pk.selected = 0
+	pk.onPick = onPick
+	pk.w = 0
+}
+
+// Active reports whether the picker is open.
+func (pk *Picker) Active() bool {
+	return pk.visible
+}
+
+// Update handles hover, arrow keys, Enter, clicks and Escape.
+func (pk *Picker) Update(g *Game) {
+	if !pk.visible {
+		return
+	}
+	f := g.frame
+	if f.KeyJustPressed(ebiten.KeyEscape) || len(pk.items) == 0 {
+		pk.visible = false
+		return
+	}
+	mx, my := f.CursorPosition()
+	listY := pk.y + pickerItemH
+	over := pk.w > 0 && mx >= pk.x && mx < pk.x+pk.w && my >= listY && my < listY+len(pk.items)*pickerItemH
+	if over {
+		pk.selected = (my - listY) / pickerItemH
+	}
+	switch {
+	case f.KeyJustPressed(ebiten.KeyArrowUp) && pk.selected > 0:
+		pk.selected--
+	case f.KeyJustPressed(ebiten.KeyArrowDown) && pk.selected < len(pk.items)-1:
+		pk.selected++
+	}
+	pick := f.KeyJustPressed(ebiten.KeyEnter) || f.KeyJustPressed(ebiten.KeyNumpadEnter)
+	if f.MouseJustPressed(ebiten.MouseButtonLeft) {
+		if !over {
+			pk.visible = false
+			return
+		}
+		pick = true
+	}
+	if pick {
+		pk.visible = false
+		if pk.onPick != nil {
+			pk.onPick(g, pk.selected)
+		}
+	}
+}
+
+// Draw renders the picker as a centered box with a title row.
+func (pk *Picker) Draw(screen *ebiten.Image, face font.Face) {
+	if !pk.visible {
+		return
+	}
+	sw := screen.Bounds().Dx()
+	sh := screen.Bounds().Dy()
+	pk.w = min(520, sw-32)
+	h := pickerItemH*(len(pk.items)+1) + PanelPaddingY
+	pk.x = (sw - pk.w) / 2
+	pk.y = max(8, sh/3-h/2)
+	x, y, w := pk.x, pk.y, pk.w
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y+h-2), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 2, float64(h), ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x+w-2), float64(y), 2, float64(h), ColorMenuBorder)
+
+	drawTextAt(screen, face, pk.title, x+PanelInnerPadding*2, y+PanelInnerPadding, ColorTextDim)
+	for i, it := range pk.items {
+		iy := y + pickerItemH*(i+1)
+		if i == pk.selected {
+			ebitenutil.DrawRect(screen, float64(x+2), float64(iy), float64(w-4), pickerItemH, ColorMenuHighlight)
+		}
+		drawTextAt(screen, face, it, x+PanelInnerPadding*2, iy+PanelInnerPadding, ColorText)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Power saving: after idleTicksBeforeSleep quiet ticks the game loop drops
+// to idleTPS and Draw stops repainting (the screen is not cleared between
+// frames, so the last picture stays up). Any input or background activity
+// restores full speed on the next tick.
+const (
+	idleTicksBeforeSleep = 120 // two seconds at 60 TPS
+	idleTPS              = 10
+)
+
+// powerState tracks whether the app is idle.
+type powerState struct {
+	quietTicks int
+	sleeping   bool
+	// redraw is set whenever the next Draw must repaint
+	redraw  bool
+	lastMX  int
+	lastMY  int
+	layoutW int
+	layoutH int
+}
+
+// updatePower records whether this tick did anything visible and adjusts the
+// tick rate. active covers background work; input and running animations
+// are checked here.
+func (g *Game) updatePower(active bool) {
+	ps := &g.power
+	mx, my := g.frame.CursorPosition()
+	if !g.frame.idle() || mx != ps.lastMX || my != ps.lastMY {
+		// cursor movement matters for hover highlights
+		active = true
+	}
+	ps.lastMX, ps.lastMY = mx, my
+	// caret blinking, the live stats clock and replays are animations
+	if g.input.editing || g.input.editingPanelName || g.prompt.Active() || g.stats.visible || g.player != nil {
+		active = true
+	}
+	if active {
+		ps.quietTicks = 0
+		ps.redraw = true
+		if ps.sleeping {
+			ps.sleeping = false
+			ebiten.SetTPS(ebiten.DefaultTPS)
+		}
+		return
+	}
+	ps.quietTicks++
+	if !ps.sleeping && ps.quietTicks >= idleTicksBeforeSleep {
+		ps.sleeping = true
+		ebiten.SetTPS(idleTPS)
+	}
+}
+
+// needsRedraw reports (and clears) whether Draw has to repaint.
+func (g *Game) needsRedraw() bool {
+	r := g.power.redraw
+	g.power.redraw = false
+	return r
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// outsidePrintAlpha is how strongly cells outside the print area are dimmed
+// in page-break preview.
+const outsidePrintAlpha = 0.5
+
+// drawPageBreaks overlays p's print area and the page boundaries of the
+// PDF export: cells outside the print area are dimmed and a line is drawn
+// at the start of every page.
+func (r *Renderer) drawPageBreaks(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
+	col0, row0, col1, row1 := p.PrintRange()
+	ax0, ay0 := float64(b.GridX+p.ColX(col0)), float64(b.GridY+p.RowY(row0))
+	ax1, ay1 := float64(b.GridX+p.ColX(col1)), float64(b.GridY+p.RowY(row1))
+	cx0, cy0 := float64(b.ContentX), float64(b.ContentY)
+	cx1, cy1 := cx0+float64(b.ContentW), cy0+float64(b.ContentH)
+	// a scrolled panel shows part of the print area
+	ax0, ay0 = math.Min(math.Max(ax0, cx0), cx1), math.Min(math.Max(ay0, cy0), cy1)
+	ax1, ay1 = math.Min(math.Max(ax1, cx0), cx1), math.Min(math.Max(ay1, cy0), cy1)
+	// dim the four bands around the print area
+	r.tintRect(screen, cx0, cy0, cx1-cx0, ay0-cy0, ColorOutsidePrint, outsidePrintAlpha)
+	r.tintRect(screen, cx0, ay1, cx1-cx0, cy1-ay1, ColorOutsidePrint, outsidePrintAlpha)
+	r.tintRect(screen, cx0, ay0, ax0-cx0, ay1-ay0, ColorOutsidePrint, outsidePrintAlpha)
+	r.tintRect(screen, ax1, ay0, cx1-ax1, ay1-ay0, ColorOutsidePrint, outsidePrintAlpha)
+
+	cols, rows := p.PageBreaks(r.page)
+	for _, col := range append(cols, col1) {
+		x := float64(b.GridX + p.ColX(col))
+		if x < cx0 || x > cx1 {
+			continue
+		}
+		r.fillRect(screen, x-1, ay0, 2, ay1-ay0, ColorPageBreak)
+	}
+	for _, row := range append(rows, row1) {
+		y := float64(b.GridY + p.RowY(row))
+		if y < cy0 || y > cy1 {
+			continue
+		}
+		r.fillRect(screen, ax0, y-1, ax1-ax0, 2, ColorPageBreak)
+	}
+}
+
+// togglePagePreview switches the page-break preview on or off for all
+// panels.
+func togglePagePreview(g *Game) {
+	g.renderer.pagePreview = !g.renderer.pagePreview
+	state := "off"
+	if g.renderer.pagePreview {
+		state = "on"
+	}
+	g.events.Publish(LogMessage{Text: "page break preview " + state})
+}
+
+// setPrintArea makes the selected range the print area of panel pi. A
+// single selected cell (or the whole panel) clears it.
+func setPrintArea(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	col0, row0, col1, row1 := g.input.selectionRange()
+	if pi != g.input.activePanel || (col0 == col1 && row0 == row1) {
+		p.PrintArea = ""
+	} else {
+		p.SetPrintArea(col0, row0, col1, row1)
+	}
+	if p.PrintArea == "" {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d prints in full", pi+1)})
+		return
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d print area %s", pi+1, p.PrintArea)})
+}
+
+// promptExportLabels asks for the title, caption and watermark printed on
+// panel pi's PDF pages, one after the other. Empty answers remove them;
+// Escape at any step keeps all three as they were.
+func promptExportLabels(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	const hint = "printed on PDF export only; empty for none"
+	g.prompt.Open("Export title", hint, p.ExportTitle, func(g *Game, title string) {
+		g.prompt.Open("Export caption", hint, p.ExportCaption, func(g *Game, caption string) {
+			g.prompt.Open("Watermark", "e.g. DRAFT or CONFIDENTIAL; empty for none", p.Watermark, func(g *Game, mark string) {
+				if pi >= len(g.canvas.Panels) {
+					return
+				}
+				p := &g.canvas.Panels[pi]
+				p.ExportTitle = strings.TrimSpace(title)
+				p.ExportCaption = strings.TrimSpace(caption)
+				p.Watermark = strings.TrimSpace(mark)
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d export labels set", pi+1)})
+			})
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// profileChunk is how many rows one scheduler step profiles.
+const profileChunk = 2048
+
+// profileGap is the space left between a panel and its profile.
+const profileGap = 40
+
+// Profiles runs "Profile Panel" jobs on the frame scheduler. Removing or
+// reloading panels abandons running jobs, since their panel index may now
+// point elsewhere.
+type Profiles struct {
+	gen int
+}
+
+func NewProfiles() *Profiles {
+	return &Profiles{}
+}
+
+// Observe cancels running jobs when panels go away.
+func (pf *Profiles) Observe(e Event) {
+	switch e.(type) {
+	case PanelRemoved, WorkspaceLoaded:
+		pf.gen++
+	}
+}
+
+// Start profiles panel pi and adds the report as a new panel to its right.
+func (pf *Profiles) Start(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	if !g.canvas.Panels[pi].Loaded {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is still loading", pi+1)})
+		return
+	}
+	pr := cellcanvas.NewProfiler(&g.canvas.Panels[pi])
+	gen := pf.gen
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("profiling Panel %d...", pi+1)})
+	g.canvas.scheduler.Add(func() bool {
+		if gen != pf.gen || pi >= len(g.canvas.Panels) {
+			return true
+		}
+		p := &g.canvas.Panels[pi]
+		if !pr.Step(p, profileChunk) {
+			return false
+		}
+		out := pr.Panel(p.X+p.Width()+profileGap, p.Y)
+		out.Name = "Profile"
+		if p.Name != "" {
+			out.Name = "Profile of " + p.Name
+		}
+		i := g.canvas.AddPanel(out)
+		g.events.Publish(PanelAdded{Panel: i})
+		for col := 0; col < out.Cols; col++ {
+			autoFitColumn(g, i, col)
+		}
+		_, rows := pr.Progress()
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("profiled %d rows of Panel %d", rows, pi+1)})
+		return true
+	})
+}
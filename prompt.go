@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// textField is a single-line rune buffer with a cursor, shared by modal
+// inputs that need basic editing (typing, backspace, delete, arrows,
+// home/end).
+type textField struct {
+	text   []rune
+	cursor int
+}
+
+func (t *textField) set(s string) {
+	t.text = []rune(s)
+	t.cursor = len(t.text)
+}
+
+func (t *textField) String() string { return string(t.text) }
+
+// update applies this frame's editing input and reports whether the text or
+// cursor changed.
+func (t *textField) update(f *InputFrame) bool {
+	changed := false
+	for _, r := range f.InputChars() {
+		if r < ' ' {
+			continue
+		}
+		t.text = append(t.text[:t.cursor], append([]rune{r}, t.text[t.cursor:]...)...)
+		t.cursor++
+		changed = true
+	}
+	switch {
+	case f.KeyJustPressed(ebiten.KeyBackspace) && t.cursor > 0:
+		t.text = append(t.text[:t.cursor-1], t.text[t.cursor:]...)
+		t.cursor--
+	case f.KeyJustPressed(ebiten.KeyDelete) && t.cursor < len(t.text):
+		t.text = append(t.text[:t.cursor], t.text[t.cursor+1:]...)
+	case f.KeyJustPressed(ebiten.KeyArrowLeft) && t.cursor > 0:
+		t.cursor--
+	case f.KeyJustPressed(ebiten.KeyArrowRight) && t.cursor < len(t.text):
+		t.cursor++
+	case f.KeyJustPressed(ebiten.KeyHome):
+		t.cursor = 0
+	case f.KeyJustPressed(ebiten.KeyEnd):
+		t.cursor = len(t.text)
+	default:
+		return changed
+	}
+	return true
+}
+
+// Prompt is a modal single-line text input. While it is open it owns all
+// keyboard and mouse input; Enter submits, Escape cancels.
+type Prompt struct {
+	visible  bool
+	title    string
+	hint     string
+	field    textField
+	masked   bool
+	onSubmit func(g *Game, value string)
+}
+
+// NewPrompt creates a hidden prompt.
+func NewPrompt() *Prompt {
+	return &Prompt{}
+}
+
+// Open shows the prompt with an initial value. onSubmit runs on Enter with
+// the entered text.
+func (pr *Prompt) Open(title, hint, initial string, onSubmit func(g *Game, value string)) {
+	pr.visible = true
+	pr.title = title
+	pr.hint = hint
+	pr.masked = false
+	pr.field.set(initial)
+	pr.onSubmit = onSubmit
+}
+
+// OpenMasked is like Open but shows the text as asterisks, for passwords.
+func (pr *Prompt) OpenMasked(title, hint string, onSubmit func(g *Game, value string)) {
+	pr.Open(title, hint, "", onSubmit)
+	pr.masked = true
+}
+
+// Active reports whether the prompt is open.
+func (pr *Prompt) Active() bool {
+	return pr.visible
+}
+
+// Update handles editing and submit/cancel keys.
+func (pr *Prompt) Update(g *Game) {
+	if !pr.visible {
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) {
+		pr.visible = false
+		return
+	}
+	if g.frame.KeyJustPressed(ebiten.KeyEnter) || g.frame.KeyJustPressed(ebiten.KeyNumpadEnter) {
+		pr.visible = false
+		if pr.onSubmit != nil {
+			pr.onSubmit(g, pr.field.String())
+		}
+		return
+	}
+	pr.field.update(g.frame)
+}
+
+// Draw renders the prompt as a centered dialog box.
+func (pr *Prompt) Draw(screen *ebiten.Image, face font.Face) {
+	if !pr.visible {
+		return
+	}
+	sw := screen.Bounds().Dx()
+	sh := screen.Bounds().Dy()
+	w := min(640, sw-32)
+	h := 84
+	x := (sw - w) / 2
+	y := sh/3 - h/2
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y+h-2), float64(w), 2, ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 2, float64(h), ColorMenuBorder)
+	ebitenutil.DrawRect(screen, float64(x+w-2), float64(y), 2, float64(h), ColorMenuBorder)
+
+	drawTextAt(screen, face, pr.title, x+PanelInnerPadding*2, y+8, ColorText)
+	if pr.hint != "" {
+		drawTextAt(screen, face, pr.hint, x+PanelInnerPadding*2, y+62, ColorTextDim)
+	}
+
+	// input box
+	bx := x + PanelInnerPadding*2
+	by := y + 32
+	ebitenutil.DrawRect(screen, float64(bx), float64(by), float64(w-PanelInnerPadding*4), 22, ColorCellBg)
+	shown := pr.field.String()
+	if pr.masked {
+		shown = strings.Repeat("*", len(pr.field.text))
+	}
+	drawTextAt(screen, face, shown, bx+4, by+4, ColorText)
+	pre := string([]rune(shown)[:pr.field.cursor])
+	b, _ := font.BoundString(face, pre)
+	caretX := int((b.Max.X - b.Min.X) >> 6)
+	ebitenutil.DrawRect(screen, float64(bx+4+caretX), float64(by+3), 2, 16, ColorText)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// Row details limits: characters shown per value and columns listed.
+const (
+	maxDetailValue = 60
+	maxDetailCols  = 24
+)
+
+// toggleRowSources switches row provenance tracking for panel pi. Rows
+// already in the panel stay without a source.
+func toggleRowSources(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	p.TrackSources = !p.TrackSources
+	if !p.TrackSources {
+		p.Sources = nil
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: row sources off", pi+1)})
+		return
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: recording the source of appended rows", pi+1)})
+}
+
+// showRowDetails lists every value of a row next to its column header,
+// followed by the row's source when one was recorded.
+func showRowDetails(g *Game, panel, row int) {
+	p := &g.canvas.Panels[panel]
+	var items []string
+	for col := 0; col < min(p.Cols, maxDetailCols); col++ {
+		label := cellcanvas.ColToLetters(col)
+		if h := strings.TrimSpace(p.GetCell(col, 0)); h != "" && row > 0 {
+			label = h
+		}
+		v := strings.ReplaceAll(p.GetCell(col, row), "\n", " ")
+		if r := []rune(v); len(r) > maxDetailValue {
+			v = string(r[:maxDetailValue]) + "..."
+		}
+		items = append(items, label+": "+v)
+	}
+	if p.Cols > maxDetailCols {
+		items = append(items, fmt.Sprintf("(%d more columns)", p.Cols-maxDetailCols))
+	}
+	if src, ok := p.RowSourceAt(row); ok {
+		items = append(items, fmt.Sprintf("source: %s, %s", filepath.Base(src.File), src.Time.Format("Jan 2 2006 15:04:05")))
+	} else if p.TrackSources {
+		items = append(items, "source: not recorded")
+	}
+	g.picker.Open(fmt.Sprintf("Panel %d row %d", panel+1, row+1), items, nil)
+}
+
+// exportWithSources saves panel pi as CSV with each row's source file and
+// import time in two extra columns.
+func exportWithSources(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "No panel to export"})
+		return
+	}
+	path, err := dialog.File().Filter("CSV", "csv").Title("Export With Row Sources").Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file save failed: %v", err)
+		}
+		return
+	}
+	if filepath.Ext(path) == "" {
+		path += ".csv"
+	}
+	if err := cellcanvas.SavePanelCSVWithSources(path, &g.canvas.Panels[pi]); err != nil {
+		reportError(g, "Export", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: "exported: " + filepath.Base(path)})
+}
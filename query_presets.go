@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Query presets are named queries of a derived panel, switched from the
+// dropdown at the right of its header. The dropdown shows the preset the
+// panel's query matches, or presetNone.
+const (
+	presetNone  = "Presets"
+	presetArrow = " v"
+	// entries after the presets in the dropdown
+	presetSave   = "Save Query as Preset..."
+	presetDelete = "Delete Preset..."
+)
+
+// PresetButton returns the view rectangle of the preset dropdown at the
+// right of the header of p. ok is false when p is not a derived panel or
+// its header is too narrow to hold the dropdown beside the name button.
+func (b PanelBounds) PresetButton(p *cellcanvas.Panel) (x, y, w, h int, ok bool) {
+	if p.Query == nil {
+		return 0, 0, 0, 0, false
+	}
+	nx, ny, nw, nh := b.NameButton()
+	w = PanelNameButtonW
+	x = b.TotalX + b.TotalW - PanelInnerPadding - w
+	return x, ny, w, nh, x >= nx+nw+PanelInnerPadding
+}
+
+// drawPresetButton draws the preset dropdown of derived panel p, if its
+// header has room for it.
+func (r *Renderer) drawPresetButton(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
+	x, y, w, h, ok := b.PresetButton(p)
+	if !ok {
+		return
+	}
+	r.fillRect(screen, float64(x), float64(y), float64(w), float64(h), ColorPanelHeaderBtn)
+	name := p.Query.ActivePreset()
+	var clr color.Color = ColorText
+	if name == "" {
+		name, clr = presetNone, ColorPlaceholder
+	}
+	for rs := []rune(name); textWidth(nil, name+presetArrow) > w-4 && len(rs) > 0; name = string(rs) {
+		rs = rs[:len(rs)-1]
+	}
+	label := name + presetArrow
+	drawTextAt(screen, nil, label, x+w-2-textWidth(nil, label), y, clr)
+}
+
+// pickPreset opens the preset dropdown of derived panel pi: its presets,
+// then entries to save the current query as one or delete one.
+func pickPreset(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	q := g.canvas.Panels[pi].Query
+	if q == nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is not a derived panel; presets are saved queries of one", pi+1)})
+		return
+	}
+	active := q.ActivePreset()
+	items := make([]string, 0, len(q.Presets)+2)
+	for _, ps := range q.Presets {
+		if ps.Name == active {
+			items = append(items, "* "+ps.Name)
+		} else {
+			items = append(items, "  "+ps.Name)
+		}
+	}
+	n := len(items)
+	items = append(items, presetSave)
+	if n > 0 {
+		items = append(items, presetDelete)
+	}
+	g.picker.Open(fmt.Sprintf("Presets of Panel %d", pi+1), items, func(g *Game, i int) {
+		switch {
+		case i < n:
+			applyPreset(g, pi, strings.TrimSpace(items[i][2:]))
+		case items[i] == presetSave:
+			promptSavePreset(g, pi)
+		default:
+			pickDeletePreset(g, pi)
+		}
+	})
+}
+
+// applyPreset switches derived panel pi to its preset called name.
+func applyPreset(g *Game, pi int, name string) {
+	if pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].Query == nil {
+		return
+	}
+	q := g.canvas.Panels[pi].Query
+	i := q.FindPreset(name)
+	if i < 0 {
+		return
+	}
+	if q.From < 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("the source of Panel %d was deleted", pi+1)})
+		return
+	}
+	if !queryValid(g, q.From, q.Presets[i].Text) {
+		return
+	}
+	q.Text = q.Presets[i].Text
+	g.derived.Start(g, pi)
+	g.power.redraw = true
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: preset %q", pi+1, name)})
+}
+
+// promptSavePreset asks for a name and saves the current query of derived
+// panel pi as a preset under it.
+func promptSavePreset(g *Game, pi int) {
+	q := g.canvas.Panels[pi].Query
+	g.prompt.Open("Save Query as Preset", "name, e.g. Open bugs", q.ActivePreset(), func(g *Game, name string) {
+		name = strings.TrimSpace(name)
+		if name == "" || pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].Query == nil {
+			return
+		}
+		g.canvas.Panels[pi].Query.SavePreset(name)
+		g.power.redraw = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: preset %q saved", pi+1, name)})
+	})
+}
+
+// pickDeletePreset lets the user remove a preset of derived panel pi.
+func pickDeletePreset(g *Game, pi int) {
+	q := g.canvas.Panels[pi].Query
+	names := make([]string, len(q.Presets))
+	for i, ps := range q.Presets {
+		names[i] = ps.Name
+	}
+	g.picker.Open("Delete Preset", names, func(g *Game, i int) {
+		if pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].Query == nil {
+			return
+		}
+		g.canvas.Panels[pi].Query.DeletePreset(names[i])
+		g.power.redraw = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d: preset %q deleted", pi+1, names[i])})
+	})
+}
@@ -4,38 +4,131 @@ import (
 	"fmt"
 	"image/color"
 
+	"github.com/example/cellchain/cellcanvas"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/font"
 )
 
-// Renderer handles all drawing operations for the application.
-type Renderer struct{}
+// Renderer handles all drawing operations for the application. It is
+// created once and owned by Game so that its caches survive between frames;
+// steady-state drawing of an unchanged canvas allocates nothing.
+type Renderer struct {
+	// titles caches the "Panel N" header labels
+	titles []string
+	// pixel is a 1x1 white image scaled and tinted to draw solid rects
+	pixel *ebiten.Image
+	// grid draws cell backgrounds in one quad per panel; nil falls back to
+	// one rect per cell
+	grid *gridShader
+	// icon is reused for drawing header icons
+	icon vector.Path
+	// defaultGrid is the gridline style of panels without their own
+	defaultGrid string
+	// pagePreview overlays print areas and page breaks of size page
+	pagePreview bool
+	page        cellcanvas.PageSize
+	// outliers tints the cells flagged by outlier highlighting
+	outliers *OutlierMarks
+	// images holds the thumbnails of panels showing images
+	images *CellImages
+	// qrCodes caches the QR codes of cells drawn as one
+	qrCodes map[string]*ebiten.Image
+	// hoverName is the panel whose name button is under the cursor, or -1
+	hoverName int
+	// view is the offscreen image the canvas is drawn on while zoomed
+	view *ebiten.Image
+}
+
+// unnamedPanel is shown in the name button of panels without a name.
+const unnamedPanel = "(unnamed)"
 
 // NewRenderer creates a new Renderer instance.
 func NewRenderer() *Renderer {
-	return &Renderer{}
+	return &Renderer{grid: newGridShader(), defaultGrid: GridSolid, hoverName: -1}
 }
 
 // DrawCanvas renders the entire canvas including all panels.
 func (r *Renderer) DrawCanvas(screen *ebiten.Image, c *Canvas, im *InputManager) {
-	for pi := range c.panels {
-		r.drawPanel(screen, c, &c.panels[pi], pi, im)
+	for pi := range c.Panels {
+		r.drawPanel(screen, c, &c.Panels[pi], pi, im)
+	}
+	// sticky headers go on top of every panel
+	for pi := range c.Panels {
+		r.drawStickyHeader(screen, &c.Panels[pi], c.Bounds(&c.Panels[pi]), pi)
+	}
+}
+
+// drawStickyHeader pins a copy of the header to the top of the window while
+// a panel's own header is scrolled off but its cells are still visible. As
+// the panel's bottom edge comes up, the pinned header is pushed up with it.
+func (r *Renderer) drawStickyHeader(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, pi int) {
+	bottom := b.ContentY + b.ContentH
+	if b.TotalY >= 0 || bottom <= 0 {
+		return
+	}
+	y := min(0, bottom-PanelHeaderHeight)
+	x0 := max(b.TotalX, 0)
+	x1 := min(b.TotalX+b.TotalW, screen.Bounds().Dx())
+	if x1 <= x0 {
+		return
+	}
+	r.fillRect(screen, float64(x0), float64(y), float64(x1-x0), float64(PanelHeaderHeight), ColorPanelBg)
+	var line color.Color = ColorPanelBorder
+	if accent, ok := parseHexColor(p.Accent); ok {
+		r.tintRect(screen, float64(x0), float64(y), float64(x1-x0), float64(PanelHeaderHeight), accent, accentHeaderAlpha)
+		line = accent
+	}
+	r.fillRect(screen, float64(x0), float64(y+PanelHeaderHeight-PanelBorderWidth), float64(x1-x0), float64(PanelBorderWidth), line)
+	label := r.panelTitle(pi)
+	if p.Name != "" {
+		label = p.Name
+	}
+	// keep the label on screen while the panel's left edge is scrolled off
+	drawTextAt(screen, nil, label, x0+PanelInnerPadding, y+2, ColorText)
+}
+
+func (r *Renderer) panelTitle(pi int) string {
+	for len(r.titles) <= pi {
+		r.titles = append(r.titles, fmt.Sprintf("Panel %d", len(r.titles)+1))
+	}
+	return r.titles[pi]
+}
+
+// fillRect draws a solid rectangle. Unlike ebitenutil.DrawRect it reuses one
+// source image and needs no per-call allocation.
+func (r *Renderer) fillRect(screen *ebiten.Image, x, y, w, h float64, clr color.Color) {
+	r.tintRect(screen, x, y, w, h, clr, 1)
+}
+
+// tintRect draws a rectangle of clr at the given opacity.
+func (r *Renderer) tintRect(screen *ebiten.Image, x, y, w, h float64, clr color.Color, alpha float32) {
+	if r.pixel == nil {
+		r.pixel = ebiten.NewImage(1, 1)
+		r.pixel.Fill(color.White)
 	}
+	var op ebiten.DrawImageOptions
+	op.GeoM.Scale(w, h)
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleWithColor(clr)
+	op.ColorScale.ScaleAlpha(alpha)
+	screen.DrawImage(r.pixel, &op)
 }
 
-func (r *Renderer) drawPanel(screen *ebiten.Image, c *Canvas, p *Panel, pi int, im *InputManager) {
-	b := p.GetBounds(c.camX, c.camY)
+func (r *Renderer) drawPanel(screen *ebiten.Image, c *Canvas, p *cellcanvas.Panel, pi int, im *InputManager) {
+	b := c.Bounds(p)
 
 	r.drawPanelBackground(screen, b)
-	r.drawPanelHeader(screen, p, b, pi)
-	r.drawPanelBorder(screen, b)
+	r.drawPanelHeader(screen, p, b, pi, im)
+	r.drawPanelBorder(screen, p, b)
 
 	if !p.Loaded {
 		r.drawPanelLoading(screen, b)
 	} else {
 		r.drawPanelContent(screen, p, b, pi, im)
+		r.drawScrollbars(screen, p, b)
 	}
 
 	// Selection and editing are now handled by InputManager.Draw()
@@ -43,54 +136,108 @@ func (r *Renderer) drawPanel(screen *ebiten.Image, c *Canvas, p *Panel, pi int,
 }
 
 func (r *Renderer) drawPanelBackground(screen *ebiten.Image, b PanelBounds) {
-	ebitenutil.DrawRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(b.TotalH), ColorPanelBg)
+	r.fillRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(b.TotalH), ColorPanelBg)
 }
 
-func (r *Renderer) drawPanelHeader(screen *ebiten.Image, p *Panel, b PanelBounds, pi int) {
+func (r *Renderer) drawPanelHeader(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, pi int, im *InputManager) {
 	baseX := float64(b.ContentX)
 	baseY := float64(b.ContentY)
 
-	// panel title
-	drawTextAt(screen, nil, fmt.Sprintf("Panel %d", pi+1), int(baseX)+PanelInnerPadding, int(baseY-PanelHeaderHeight+2), ColorText)
+	if accent, ok := parseHexColor(p.Accent); ok {
+		r.tintRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(PanelHeaderHeight), accent, accentHeaderAlpha)
+	}
 
-	// draw a blank clickable name button centered in the header
-	btnX := baseX + float64(b.ContentW)/2 - float64(PanelNameButtonW)/2
-	btnY := float64(baseY) - float64(PanelHeaderHeight) + float64((PanelHeaderHeight-PanelNameButtonH)/2)
-	ebitenutil.DrawRect(screen, btnX, btnY, float64(PanelNameButtonW), float64(PanelNameButtonH), ColorPanelHeaderBtn)
+	// panel title, followed by the panel's icon
+	title := r.panelTitle(pi)
+	drawTextAt(screen, nil, title, int(baseX)+PanelInnerPadding, int(baseY-PanelHeaderHeight+2), ColorText)
+	if p.Icon != "" {
+		r.drawPanelIcon(screen, p.Icon, baseX+float64(PanelInnerPadding+textWidth(nil, title)+iconGap), baseY-PanelHeaderHeight+float64(PanelHeaderHeight-iconSize)/2)
+	}
 
-	// draw panel's alias/name inside the header button
-	nameToShow := p.Name
+	if p.SampleRows > 0 {
+		drawTextAt(screen, nil, previewBadge, b.TotalX+b.TotalW-PanelInnerPadding-textWidth(nil, previewBadge), int(baseY-PanelHeaderHeight+2), ColorPreviewBadge)
+	} else if p.Scratch {
+		drawTextAt(screen, nil, scratchBadge, b.TotalX+b.TotalW-PanelInnerPadding-textWidth(nil, scratchBadge), int(baseY-PanelHeaderHeight+2), ColorTextDim)
+	}
+	r.drawPresetButton(screen, p, b)
 
-	// Draw text if present
-	tx := btnX + float64(PanelNameButtonW)/2 // default center position
-	if nameToShow != "" {
-		drawTextAt(screen, nil, nameToShow, int(tx), int(btnY), ColorText)
+	// the clickable name button centered in the header, lit while hovered
+	btnX, btnY, btnW, btnH := b.NameButton()
+	btnColor := ColorPanelHeaderBtn
+	if r.hoverName == pi {
+		btnColor = ColorNameButtonHover
 	}
+	r.fillRect(screen, float64(btnX), float64(btnY), float64(btnW), float64(btnH), btnColor)
 
-	// Panel name editing is now handled by InputManager.Draw()
+	// the name, the name being typed (with a caret) or a placeholder
+	name := p.Name
+	var clr color.Color = ColorText
+	renaming := im.editingPanelName && im.editPanelIndex == pi
+	switch {
+	case renaming:
+		rs := []rune(im.editPanelBuffer)
+		cur := max(0, min(im.editPanelCursor, len(rs)))
+		name = string(rs[:cur]) + "|" + string(rs[cur:])
+	case name == "":
+		name, clr = unnamedPanel, ColorPlaceholder
+	}
+	// long names lose their end; the one being typed its start
+	for rs := []rune(name); textWidth(nil, name) > btnW-4 && len(rs) > 0; name = string(rs) {
+		if renaming {
+			rs = rs[1:]
+		} else {
+			rs = rs[:len(rs)-1]
+		}
+	}
+	drawTextAt(screen, nil, name, btnX+(btnW-textWidth(nil, name))/2, btnY, clr)
 }
 
-func (r *Renderer) drawPanelBorder(screen *ebiten.Image, b PanelBounds) {
-	ebitenutil.DrawRect(screen, float64(b.TotalX), float64(b.TotalY), float64(PanelBorderWidth), float64(b.TotalH), ColorPanelBorder)
-	ebitenutil.DrawRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(PanelBorderWidth), ColorPanelBorder)
-	ebitenutil.DrawRect(screen, float64(b.TotalX+b.TotalW-PanelBorderWidth), float64(b.TotalY), float64(PanelBorderWidth), float64(b.TotalH), ColorPanelBorder)
-	ebitenutil.DrawRect(screen, float64(b.TotalX), float64(b.TotalY+b.TotalH-PanelBorderWidth), float64(b.TotalW), float64(PanelBorderWidth), ColorPanelBorder)
+func (r *Renderer) drawPanelBorder(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
+	var clr color.Color = ColorPanelBorder
+	if accent, ok := parseHexColor(p.Accent); ok {
+		clr = accent
+	}
+	r.fillRect(screen, float64(b.TotalX), float64(b.TotalY), float64(PanelBorderWidth), float64(b.TotalH), clr)
+	r.fillRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(PanelBorderWidth), clr)
+	r.fillRect(screen, float64(b.TotalX+b.TotalW-PanelBorderWidth), float64(b.TotalY), float64(PanelBorderWidth), float64(b.TotalH), clr)
+	r.fillRect(screen, float64(b.TotalX), float64(b.TotalY+b.TotalH-PanelBorderWidth), float64(b.TotalW), float64(PanelBorderWidth), clr)
 }
 
 func (r *Renderer) drawPanelLoading(screen *ebiten.Image, b PanelBounds) {
-	ebitenutil.DrawRect(screen, float64(b.ContentX), float64(b.ContentY), float64(b.ContentW), float64(b.ContentH), ColorPanelLoading)
+	r.fillRect(screen, float64(b.ContentX), float64(b.ContentY), float64(b.ContentW), float64(b.ContentH), ColorPanelLoading)
 	drawTextAt(screen, nil, "Loading...", b.ContentX+PanelInnerPadding, b.ContentY+PanelInnerPadding, ColorText)
 }
 
-func (r *Renderer) drawPanelContent(screen *ebiten.Image, p *Panel, b PanelBounds, pi int, im *InputManager) {
-	baseX := float64(b.ContentX)
-	baseY := float64(b.ContentY)
-	for row := 0; row < p.Rows; row++ {
-		for col := 0; col < p.Cols; col++ {
-			x := baseX + float64(col*p.CellW)
-			y := baseY + float64(row*p.CellH)
-			// cell bg
-			ebitenutil.DrawRect(screen, x, y, float64(p.CellW-1), float64(p.CellH-1), ColorCellBg)
+func (r *Renderer) drawPanelContent(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, pi int, im *InputManager) {
+	baseX := float64(b.GridX)
+	baseY := float64(b.GridY)
+	if useDensityView(p, b) {
+		r.drawPanelDensity(screen, p, b)
+		return
+	}
+	// only visit rows in the panel's window and on screen; paged panels can
+	// have millions
+	r0, r1 := p.VisibleRows()
+	c0, c1 := p.VisibleCols()
+	firstRow := max(r0, p.RowAt(-b.GridY))
+	lastRow := max(firstRow, min(r1, p.RowAt(screen.Bounds().Dy()-b.GridY)+1))
+	firstCol := max(c0, p.ColAt(-b.GridX))
+	lastCol := max(firstCol, min(c1, p.ColAt(screen.Bounds().Dx()-b.GridX)+1))
+	firstX := baseX + float64(p.ColX(firstCol))
+	// cell backgrounds and gridlines; the shader assumes equal column widths
+	// and row heights
+	style := r.gridStyle(p)
+	if r.grid != nil && p.UniformCols() && p.UniformRows() {
+		r.grid.draw(screen, p, b, style)
+	} else {
+		r.drawCellBackgrounds(screen, p, b, style, firstRow, lastRow, firstCol, lastCol)
+	}
+	r.drawOutliers(screen, p, b, pi, firstRow, lastRow, firstCol, lastCol)
+	face := panelFonts.face(p)
+	for row := firstRow; row < lastRow; row++ {
+		x := firstX
+		for col := firstCol; col < lastCol; col, x = col+1, x+float64(p.ColWidth(col)) {
+			y := baseY + float64(p.RowY(row))
 
 			// If this cell is being edited, skip drawing its static content so we don't get double-draw
 			if im.editing && !im.editingPanelName && im.activePanel == pi && im.selRow == row && im.selCol == col {
@@ -98,45 +245,45 @@ func (r *Renderer) drawPanelContent(screen *ebiten.Image, p *Panel, b PanelBound
 			}
 
 			// cell text
-			key := CellRef(col, row)
-			txt := ""
-			if v, ok := p.Cells[key]; ok {
-				txt = v
+			txt := p.DisplayCell(col, row)
+			if txt == "" {
+				continue
+			}
+			// or, when it names an image, a thumbnail once it has loaded
+			if p.Images && r.images != nil {
+				if src, ok := p.ImageSource(p.GetCell(col, row)); ok {
+					if img := r.images.Thumb(src); img != nil {
+						r.drawCellImage(screen, img, x, y, p.ColWidth(col), p.RowHeight(row))
+						continue
+					}
+				}
+			}
+			// or as its column's renderer draws it, below the header
+			kind := p.RendererAt(col)
+			if cellcanvas.IsQRCodeFormula(p.RawCell(col, row)) {
+				kind = cellcanvas.RenderQR
+			}
+			if kind != "" && (row > 0 || !p.HasHeader()) {
+				if r.drawRenderedCell(screen, face, kind, p.GetCell(col, row), x, y, p.ColWidth(col), p.RowHeight(row)) {
+					continue
+				}
 			}
 			// Editing text is now handled by InputManager.Draw()
-			drawTextAt(screen, nil, txt, int(x)+PanelInnerPadding, int(y)+PanelInnerPadding, ColorText)
+			drawTextAt(screen, face, cellText(p, face, col, txt), int(x)+PanelInnerPadding, int(y)+PanelInnerPadding, ColorText)
 		}
 	}
-}
-
-func (r *Renderer) drawPanelSelection(screen *ebiten.Image, p *Panel, b PanelBounds, pi int, state CanvasDrawState) {
-	if pi == state.ActivePanel {
-		baseX := float64(b.ContentX)
-		baseY := float64(b.ContentY)
-		sx := baseX + float64(state.SelCol*p.CellW)
-		sy := baseY + float64(state.SelRow*p.CellH)
-		cellW := float64(p.CellW - 1)
-		cellH := float64(p.CellH - 1)
-		borderWidth := 2.0
-
-		// Draw blue border instead of filled rectangle
-		// Top border
-		ebitenutil.DrawRect(screen, sx, sy, cellW, borderWidth, ColorSelection)
-		// Bottom border
-		ebitenutil.DrawRect(screen, sx, sy+cellH-borderWidth, cellW, borderWidth, ColorSelection)
-		// Left border
-		ebitenutil.DrawRect(screen, sx, sy, borderWidth, cellH, ColorSelection)
-		// Right border
-		ebitenutil.DrawRect(screen, sx+cellW-borderWidth, sy, borderWidth, cellH, ColorSelection)
+	r.drawCellBorders(screen, p, b, firstRow, lastRow, firstCol, lastCol)
+	if r.pagePreview {
+		r.drawPageBreaks(screen, p, b)
 	}
 }
 
-func (r *Renderer) drawResizeHandle(screen *ebiten.Image, p *Panel, b PanelBounds) {
+func (r *Renderer) drawResizeHandle(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds) {
 	baseX := float64(b.ContentX)
 	baseY := float64(b.ContentY)
-	rx := baseX + float64(p.Cols*p.CellW) - ResizeHandleSize
-	ry := baseY + float64(p.Rows*p.CellH) - ResizeHandleSize
-	ebitenutil.DrawRect(screen, rx, ry, float64(ResizeHandleSize), float64(ResizeHandleSize), ColorResizeHandle)
+	rx := baseX + float64(p.Width()) - ResizeHandleSize
+	ry := baseY + float64(p.Height()) - ResizeHandleSize
+	r.fillRect(screen, rx, ry, float64(ResizeHandleSize), float64(ResizeHandleSize), ColorResizeHandle)
 }
 
 // drawTextAt draws text using the provided face. If face is nil, falls back to ebitenutil.DebugPrintAt.
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// replayTPS is the tick rate assumed when synthesizing timestamps for ticks
+// that have no recorded input.
+const replayTPS = 60
+
+// InputFrame is a snapshot of all input observed during a single Update tick.
+// Every input query in the app goes through the current frame rather than
+// calling Ebiten directly, so live input and input replayed from a recording
+// are indistinguishable to the rest of the program.
+type InputFrame struct {
+	Tick   int64 `json:"tick"`
+	TimeMs int64 `json:"ms"` // milliseconds since the session started
+	MouseX int   `json:"mx"`
+	MouseY int   `json:"my"`
+
+	Keys         []ebiten.Key         `json:"keys,omitempty"`
+	JustKeys     []ebiten.Key         `json:"just_keys,omitempty"`
+	Buttons      []ebiten.MouseButton `json:"buttons,omitempty"`
+	JustPressed  []ebiten.MouseButton `json:"just_pressed,omitempty"`
+	JustReleased []ebiten.MouseButton `json:"just_released,omitempty"`
+	Chars        string               `json:"chars,omitempty"`
+	WheelX       float64              `json:"wx,omitempty"`
+	WheelY       float64              `json:"wy,omitempty"`
+}
+
+var trackedMouseButtons = []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonRight, ebiten.MouseButtonMiddle}
+
+// captureInputFrame reads the live Ebiten input state into a new frame.
+func captureInputFrame(tick int64, start time.Time) *InputFrame {
+	f := &InputFrame{Tick: tick, TimeMs: time.Since(start).Milliseconds()}
+	f.MouseX, f.MouseY = ebiten.CursorPosition()
+	f.Keys = inpututil.AppendPressedKeys(nil)
+	f.JustKeys = inpututil.AppendJustPressedKeys(nil)
+	for _, b := range trackedMouseButtons {
+		if ebiten.IsMouseButtonPressed(b) {
+			f.Buttons = append(f.Buttons, b)
+		}
+		if inpututil.IsMouseButtonJustPressed(b) {
+			f.JustPressed = append(f.JustPressed, b)
+		}
+		if inpututil.IsMouseButtonJustReleased(b) {
+			f.JustReleased = append(f.JustReleased, b)
+		}
+	}
+	f.Chars = string(ebiten.AppendInputChars(nil))
+	f.WheelX, f.WheelY = ebiten.Wheel()
+	return f
+}
+
+// idle reports whether the frame carries no input besides the cursor position.
+func (f *InputFrame) idle() bool {
+	return len(f.Keys) == 0 && len(f.JustKeys) == 0 && len(f.Buttons) == 0 &&
+		len(f.JustPressed) == 0 && len(f.JustReleased) == 0 && f.Chars == "" &&
+		f.WheelX == 0 && f.WheelY == 0
+}
+
+func (f *InputFrame) CursorPosition() (int, int) { return f.MouseX, f.MouseY }
+
+func (f *InputFrame) KeyPressed(k ebiten.Key) bool { return slices.Contains(f.Keys, k) }
+
+func (f *InputFrame) KeyJustPressed(k ebiten.Key) bool { return slices.Contains(f.JustKeys, k) }
+
+func (f *InputFrame) MousePressed(b ebiten.MouseButton) bool { return slices.Contains(f.Buttons, b) }
+
+func (f *InputFrame) MouseJustPressed(b ebiten.MouseButton) bool {
+	return slices.Contains(f.JustPressed, b)
+}
+
+func (f *InputFrame) MouseJustReleased(b ebiten.MouseButton) bool {
+	return slices.Contains(f.JustReleased, b)
+}
+
+func (f *InputFrame) InputChars() []rune { return []rune(f.Chars) }
+
+func (f *InputFrame) Wheel() (float64, float64) { return f.WheelX, f.WheelY }
+
+// CtrlPressed reports whether either Control key is held.
+func (f *InputFrame) CtrlPressed() bool {
+	return f.KeyPressed(ebiten.KeyControlLeft) || f.KeyPressed(ebiten.KeyControlRight)
+}
+
+// ShiftPressed reports whether either Shift key is held.
+func (f *InputFrame) ShiftPressed() bool {
+	return f.KeyPressed(ebiten.KeyShiftLeft) || f.KeyPressed(ebiten.KeyShiftRight)
+}
+
+// AltPressed reports whether either Alt key is held.
+func (f *InputFrame) AltPressed() bool {
+	return f.KeyPressed(ebiten.KeyAltLeft) || f.KeyPressed(ebiten.KeyAltRight)
+}
+
+// InputRecorder appends input frames as JSON lines to a replay file. Frames
+// with no input and an unchanged cursor are skipped to keep files small; the
+// player fills those ticks back in.
+type InputRecorder struct {
+	f      *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	lastMX int
+	lastMY int
+}
+
+// NewInputRecorder creates (or truncates) the replay file at path.
+func NewInputRecorder(path string) (*InputRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &InputRecorder{f: f, w: w, enc: json.NewEncoder(w), lastMX: -1, lastMY: -1}, nil
+}
+
+// Record writes the frame if it carries any input or cursor movement.
+func (r *InputRecorder) Record(f *InputFrame) error {
+	if r == nil {
+		return nil
+	}
+	if f.idle() && f.MouseX == r.lastMX && f.MouseY == r.lastMY {
+		return nil
+	}
+	r.lastMX, r.lastMY = f.MouseX, f.MouseY
+	return r.enc.Encode(f)
+}
+
+// Close flushes buffered frames and closes the replay file.
+func (r *InputRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	if err := r.w.Flush(); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// InputPlayer feeds frames from a replay file back to the game tick by tick.
+type InputPlayer struct {
+	frames []*InputFrame
+	next   int
+	last   *InputFrame
+}
+
+// LoadInputPlayer reads a replay file written by InputRecorder.
+func LoadInputPlayer(path string) (*InputPlayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pl := &InputPlayer{last: &InputFrame{}}
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var fr InputFrame
+		if err := dec.Decode(&fr); err != nil {
+			return nil, fmt.Errorf("replay frame %d: %w", len(pl.frames)+1, err)
+		}
+		pl.frames = append(pl.frames, &fr)
+	}
+	return pl, nil
+}
+
+// Done reports whether every recorded frame has been played.
+func (pl *InputPlayer) Done() bool {
+	return pl.next >= len(pl.frames)
+}
+
+// Frame returns the input for the given tick. Ticks without a recorded frame
+// get an idle frame holding the last cursor position, with a timestamp
+// extrapolated from the previous frame.
+func (pl *InputPlayer) Frame(tick int64) *InputFrame {
+	if pl.next < len(pl.frames) && pl.frames[pl.next].Tick <= tick {
+		pl.last = pl.frames[pl.next]
+		pl.next++
+		return pl.last
+	}
+	return &InputFrame{
+		Tick:   tick,
+		TimeMs: pl.last.TimeMs + (tick-pl.last.Tick)*1000/replayTPS,
+		MouseX: pl.last.MouseX,
+		MouseY: pl.last.MouseY,
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// resizePanel makes panel pi show cols x rows cells. Only what is shown
+// changes; cells left outside are kept and the user is told so. In a
+// direction the panel scrolls in, its window gets the new size instead and
+// the data is left alone.
+func resizePanel(g *Game, pi, cols, rows int) {
+	p := &g.canvas.Panels[pi]
+	if p.WindowRows() < p.Rows {
+		p.ViewRows, rows = rows, p.Rows
+	}
+	if p.WindowCols() < p.Cols {
+		p.ViewCols, cols = cols, p.Cols
+	}
+	p.Resize(cols, rows)
+	g.events.Publish(PanelResized{Panel: pi, Cols: p.Cols, Rows: p.Rows})
+	if n := p.HiddenCells(); n > 0 {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("%d cells outside Panel %d are kept; Truncate to Size drops them", n, pi+1)})
+	}
+}
+
+// truncatePanel permanently drops the cells left outside panel pi by
+// shrinking it, so they are no longer saved.
+func truncatePanel(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	n, err := g.canvas.Panels[pi].Truncate()
+	switch {
+	case err != nil:
+		reportError(g, "Truncate panel", err)
+	case n == 0:
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d has no cells outside its size", pi+1)})
+	default:
+		// the cells were not edited one by one; tell subscribers the data
+		// was replaced
+		g.events.Publish(PanelLoaded{Panel: pi})
+		g.events.Publish(BulkEdit{Panel: pi, Action: "truncate", Detail: fmt.Sprintf("dropped %d cells", n)})
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("dropped %d cells outside Panel %d", n, pi+1)})
+	}
+}
+
+// snapCols returns how many whole columns of p from column first on fit in
+// w pixels, rounding to the nearest column edge; at least 1.
+func snapCols(p *cellcanvas.Panel, first, w int) int {
+	x := p.ColX(first) + w
+	c := p.ColAt(x)
+	if x-p.ColX(c) >= p.ColWidth(c)/2 {
+		c++
+	}
+	return max(c-first, 1)
+}
+
+// snapRows returns how many whole rows of p from row first on fit in h
+// pixels, rounding to the nearest row edge; at least 1.
+func snapRows(p *cellcanvas.Panel, first, h int) int {
+	y := p.RowY(first) + h
+	r := p.RowAt(y)
+	if y-p.RowY(r) >= p.RowHeight(r)/2 {
+		r++
+	}
+	return max(r-first, 1)
+}
+
+// drawResizeGhost outlines the size a resize drag in progress will give
+// the panel and labels it with the row and column count.
+func (im *InputManager) drawResizeGhost(screen *ebiten.Image, face font.Face, g *Game) {
+	if im.resizingPanel < 0 || im.resizingPanel >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[im.resizingPanel]
+	b := g.canvas.Bounds(p)
+	x, y := float64(b.ContentX), float64(b.ContentY)
+	firstRow, _ := p.VisibleRows()
+	firstCol, _ := p.VisibleCols()
+	w := float64(p.ColX(firstCol+im.resizeCols) - p.ColX(firstCol))
+	h := float64(p.RowY(firstRow+im.resizeRows) - p.RowY(firstRow))
+	ebitenutil.DrawRect(screen, x, y, w, 2, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x, y+h-2, w, 2, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x, y, 2, h, ColorResizeGhost)
+	ebitenutil.DrawRect(screen, x+w-2, y, 2, h, ColorResizeGhost)
+
+	label := fmt.Sprintf("%d rows x %d cols", im.resizeRows, im.resizeCols)
+	lx, ly := int(x+w)+6, int(y+h)+4
+	ebitenutil.DrawRect(screen, float64(lx), float64(ly), float64(textWidth(face, label)+PanelInnerPadding*2), float64(lineHeight(face)+PanelInnerPadding), ColorMenuBg)
+	drawTextAt(screen, face, label, lx+PanelInnerPadding, ly+PanelInnerPadding/2, ColorText)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// maxRowDropCells bounds the cells a row drop may rewrite, counting the rows
+// shifted below the insertion point, so a drop cannot stall the frame.
+const maxRowDropCells = 200000
+
+// draggingRows reports whether the drag-move in progress carries whole rows:
+// the selection spans every column of its panel. Rows are inserted where
+// they are dropped, in their own panel as in another, instead of written
+// over cells.
+func (im *InputManager) draggingRows(g *Game) bool {
+	if !im.movingCells || im.activePanel < 0 || im.activePanel >= len(g.canvas.Panels) {
+		return false
+	}
+	col0, _, col1, _ := im.selectionRange()
+	return col0 == 0 && col1 == g.canvas.Panels[im.activePanel].Cols-1
+}
+
+// rowDrop works out a drop of the selected rows into panel dst at row at:
+// the source rows, the destination column of each source column, and the
+// row they are inserted before. When both panels have a header, columns
+// are matched by header name, columns without a match become new ones and
+// the source's header row is not carried; within one panel the header row
+// stays where it is.
+type rowDrop struct {
+	dst        int
+	first, n   int
+	at         int
+	colMap     []int
+	newHeaders map[int]string
+}
+
+// planRowDrop returns the drop of the selected rows onto panel dst at
+// about row at, which is kept below dst's header and above its last row.
+func planRowDrop(g *Game, dst, at int) rowDrop {
+	im := g.input
+	_, row0, _, row1 := im.selectionRange()
+	sp, dp := &g.canvas.Panels[im.activePanel], &g.canvas.Panels[dst]
+	d := rowDrop{dst: dst, first: row0, n: row1 - row0 + 1}
+	same := dst == im.activePanel
+	byHeader := !same && sp.HasHeader() && dp.HasHeader()
+	if (byHeader || same && sp.HasHeader()) && d.first == 0 {
+		d.first, d.n = 1, d.n-1
+	}
+	top := 0
+	if dp.HasHeader() {
+		top = 1
+	}
+	d.at = min(max(at, top), max(dp.UsedRows(), top))
+	d.colMap = make([]int, sp.Cols)
+	for c := range d.colMap {
+		d.colMap[c] = c
+	}
+	if byHeader {
+		d.colMap = cellcanvas.MatchColumns(dp, sp)
+		// each dst column takes one src column, the first with its header;
+		// the other columns, with or without a header, go after its last
+		// used one, headed like in src, as in AppendPanel
+		taken := make(map[int]bool)
+		next := dp.UsedCols()
+		d.newHeaders = make(map[int]string)
+		for c, to := range d.colMap {
+			if to >= 0 && !taken[to] {
+				taken[to] = true
+				continue
+			}
+			d.colMap[c] = -1
+			if d.carries(sp, c) {
+				d.colMap[c] = next
+				d.newHeaders[next] = sp.RawCell(c, 0)
+				next++
+			}
+		}
+	}
+	return d
+}
+
+// carries reports whether column c of the source panel sp has a header or
+// a value in the dropped rows. A column with neither is left out.
+func (d rowDrop) carries(sp *cellcanvas.Panel, c int) bool {
+	if sp.RawCell(c, 0) != "" {
+		return true
+	}
+	for r := d.first; r < d.first+d.n; r++ {
+		if sp.RawCell(c, r) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// dropRows inserts the selected rows into panel dst before row at, moving
+// the rows below down, and removes them from their panel unless keep is
+// set. The inserted rows are selected; Ctrl+Z undoes the drop. Nothing is
+// dropped when a value is not a number for a column with a unit.
+func dropRows(g *Game, dst, at int, keep bool) {
+	src := g.input.activePanel
+	for _, pi := range []int{src, dst} {
+		p := &g.canvas.Panels[pi]
+		if !p.Loaded || previewBlocksEdit(g, pi) || queryBlocksEdit(g, pi) {
+			return
+		}
+		if p.Paged != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is paged; rows cannot be moved in or out of it", pi+1)})
+			return
+		}
+	}
+	d := planRowDrop(g, dst, at)
+	if d.n <= 0 {
+		g.events.Publish(LogMessage{Text: "only the header row is selected; nothing to drop"})
+		return
+	}
+	if dst == src && !keep {
+		reorderRows(g, d)
+		return
+	}
+	sp, dp := &g.canvas.Panels[src], &g.canvas.Panels[dst]
+	used := dp.UsedRows()
+	if cells := (max(used, d.at) - d.at + d.n) * dp.Cols; cells > maxRowDropCells {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("dropping here would rewrite %d cells, more than %d; drop nearer the end of Panel %d", cells, maxRowDropCells, dst+1)})
+		return
+	}
+
+	var w cellWrites
+	cols := dp.Cols
+	for _, to := range d.colMap {
+		cols = max(cols, to+1)
+	}
+	// shift the rows below the insertion point down
+	for r := used - 1; r >= d.at; r-- {
+		for c := 0; c < cols; c++ {
+			w.set(dst, c, r+d.n, dp.RawCell(c, r))
+		}
+	}
+	for r := 0; r < d.n; r++ {
+		for c := 0; c < cols; c++ {
+			w.set(dst, c, d.at+r, "")
+		}
+		for c, to := range d.colMap {
+			if to < 0 {
+				continue
+			}
+			v, err := unitWrite(g, src, c, dst, to, d.at+r, sp.RawCell(c, d.first+r))
+			if err != nil {
+				g.events.Publish(LogMessage{Text: err.Error() + "; nothing dropped"})
+				return
+			}
+			w.set(dst, to, d.at+r, v)
+		}
+	}
+	for c, h := range d.newHeaders {
+		w.set(dst, c, 0, h)
+	}
+	if !keep {
+		// close the gap the rows leave
+		for r := d.first; r < sp.UsedRows(); r++ {
+			for c := 0; c < sp.Cols; c++ {
+				w.set(src, c, r, sp.RawCell(c, r+d.n))
+			}
+		}
+	}
+
+	oldCols, oldRows := dp.Cols, dp.Rows
+	dp.Cols, dp.Rows = cols, max(dp.Rows, max(used, d.at)+d.n)
+	w.apply(g)
+	if dp.Cols != oldCols || dp.Rows != oldRows {
+		g.events.Publish(PanelResized{Panel: dst, Cols: dp.Cols, Rows: dp.Rows})
+	}
+	g.input.switchToPanel(g, dst)
+	g.input.selectRange(g, dst, d.at, 0, d.at+d.n-1, dp.Cols-1)
+	verb := "moved"
+	if keep {
+		verb = "copied"
+	}
+	how := "by position"
+	if d.newHeaders != nil {
+		how = "matching columns by header"
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("%s %d row(s) from Panel %d to row %d of Panel %d, %s (Ctrl+Z undoes)", verb, d.n, src+1, d.at+1, dst+1, how)})
+}
+
+// reorderRows moves the rows of d within their own panel to before row
+// d.at, moving the rows in between up or down to make room.
+func reorderRows(g *Game, d rowDrop) {
+	pi := d.dst
+	p := &g.canvas.Panels[pi]
+	if d.at >= d.first && d.at <= d.first+d.n {
+		// dropped where they are, or a plain click on a row handle
+		return
+	}
+	// the rows from lo to hi change places; order lists where each comes from
+	lo, hi := min(d.first, d.at), max(d.first+d.n, d.at)
+	if cells := (hi - lo) * p.Cols; cells > maxRowDropCells {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("moving the rows this far would rewrite %d cells, more than %d", cells, maxRowDropCells)})
+		return
+	}
+	order := make([]int, 0, hi-lo)
+	moved := make([]int, 0, d.n)
+	for r := d.first; r < d.first+d.n; r++ {
+		moved = append(moved, r)
+	}
+	to := d.at
+	if d.at < d.first {
+		order = append(order, moved...)
+		for r := d.at; r < d.first; r++ {
+			order = append(order, r)
+		}
+	} else {
+		for r := d.first + d.n; r < d.at; r++ {
+			order = append(order, r)
+		}
+		order = append(order, moved...)
+		to = d.at - d.n
+	}
+	var w cellWrites
+	for i, from := range order {
+		for c := 0; c < p.Cols; c++ {
+			w.set(pi, c, lo+i, p.RawCell(c, from))
+		}
+	}
+	w.apply(g)
+	g.input.selectRange(g, pi, to, 0, to+d.n-1, p.Cols-1)
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("moved %d row(s) to row %d (Ctrl+Z undoes)", d.n, to+1)})
+}
+
+// grabRowHandle starts dragging row handles: a press in the margin left of
+// panel pi's cells selects the row beside it, or with Shift extends the
+// selected rows to it, and starts a row drag. It reports whether it did.
+func (im *InputManager) grabRowHandle(g *Game, pi int, b PanelBounds, mx, my int) bool {
+	p := &g.canvas.Panels[pi]
+	if !p.Loaded || im.editing || mx < b.TotalX || mx >= b.ContentX || my < b.ContentY || my >= b.ContentY+b.ContentH {
+		return false
+	}
+	row := p.RowAt(my - b.GridY)
+	if row < 0 || row >= p.Rows || p.Cols == 0 {
+		return false
+	}
+	anchor := row
+	if g.frame.ShiftPressed() && pi == im.activePanel {
+		anchor = im.anchorRow
+	}
+	if pi != im.activePanel {
+		im.switchToPanel(g, pi)
+	}
+	im.selectRange(g, pi, anchor, 0, row, p.Cols-1)
+	_, row0, _, _ := im.selectionRange()
+	im.movingCells = true
+	im.moveGrabCol, im.moveGrabRow = 0, row-row0
+	im.moveToPanel, im.moveToCol, im.moveToRow = pi, 0, row0
+	return true
+}
+
+// rowDropAt returns the row boundary nearest to view position y over
+// panel pi, the row a row drag dropped there inserts before.
+func rowDropAt(g *Game, pi, y int) int {
+	p := &g.canvas.Panels[pi]
+	y -= g.canvas.Bounds(p).GridY
+	row := p.RowAt(y)
+	return p.RowAt(y + p.RowHeight(row)/2)
+}
+
+// drawRowDropGhost marks where a row drop in progress inserts the rows: a
+// line across the target panel above the row they go before.
+func (im *InputManager) drawRowDropGhost(screen *ebiten.Image, face font.Face, g *Game) {
+	d := planRowDrop(g, im.moveToPanel, im.moveToRow)
+	p := &g.canvas.Panels[d.dst]
+	b := g.canvas.Bounds(p)
+	x, y := float64(b.ContentX), float64(b.GridY+p.RowY(d.at))
+	ebitenutil.DrawRect(screen, x, y-1, float64(b.ContentW), 3, ColorResizeGhost)
+
+	verb := "move"
+	if g.frame.CtrlPressed() {
+		verb = "copy"
+	}
+	label := fmt.Sprintf("%s %d row(s) to Panel %d row %d", verb, d.n, d.dst+1, d.at+1)
+	if d.dst == im.activePanel {
+		label = fmt.Sprintf("%s %d row(s) to row %d", verb, d.n, d.at+1)
+	}
+	lx, ly := int(x)+6, int(y)+4
+	ebitenutil.DrawRect(screen, float64(lx), float64(ly), float64(textWidth(face, label)+PanelInnerPadding*2), float64(lineHeight(face)+PanelInnerPadding), ColorMenuBg)
+	drawTextAt(screen, face, label, lx+PanelInnerPadding, ly+PanelInnerPadding/2, ColorText)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// Ruler geometry, in pixels: the strips along the top and left edge, and
+// the world distance between minor and labelled ticks.
+const (
+	rulerTopH  = 18
+	rulerLeftW = 40
+	rulerMinor = 20
+	rulerMajor = 100
+)
+
+// Rulers overlays world coordinates: rulers along the top and left edge,
+// the cursor position and each panel's origin. It helps laying panels out
+// precisely and checking positions against state.yml.
+type Rulers struct {
+	visible bool
+}
+
+func NewRulers() *Rulers {
+	return &Rulers{}
+}
+
+// Toggle shows or hides the rulers.
+func (r *Rulers) Toggle(g *Game) {
+	r.visible = !r.visible
+	state := "off"
+	if r.visible {
+		state = "on"
+	}
+	g.events.Publish(LogMessage{Text: "rulers " + state})
+}
+
+func (r *Rulers) Draw(screen *ebiten.Image, face font.Face, g *Game) {
+	if !r.visible {
+		return
+	}
+	c := g.canvas
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	z := c.zoom()
+	// screen position of world coordinate v along an axis with camera cam
+	toScreen := func(v int, cam float64) float64 { return (float64(v) + cam) * z }
+
+	// panel origins, above each panel's header
+	for i := range c.Panels {
+		b := scaleBounds(c.Bounds(&c.Panels[i]), z)
+		if b.TotalX+b.TotalW < 0 || b.TotalX > sw || b.TotalY+b.TotalH < 0 || b.TotalY > sh {
+			continue
+		}
+		p := &c.Panels[i]
+		drawTextAt(screen, face, fmt.Sprintf("%d, %d", p.X, p.Y), b.TotalX, b.TotalY-lineHeight(face), ColorTextDim)
+	}
+
+	// zoomed out, ticks are spaced by powers of ten so they stay apart
+	minor, major := rulerMinor, rulerMajor
+	for float64(minor)*z < 6 {
+		minor, major = minor*10, major*10
+	}
+	x0, y0 := c.ScreenToWorld(rulerLeftW, rulerTopH)
+	ebitenutil.DrawRect(screen, 0, 0, float64(sw), rulerTopH, ColorOverlayBg)
+	ebitenutil.DrawRect(screen, 0, 0, rulerLeftW, float64(sh), ColorOverlayBg)
+	for x := floorTo(x0, minor); toScreen(x, c.CamX) < float64(sw); x += minor {
+		sx := math.Round(toScreen(x, c.CamX))
+		if x%major == 0 {
+			ebitenutil.DrawRect(screen, sx, 0, 1, rulerTopH, ColorMenuBorder)
+			drawTextAt(screen, face, fmt.Sprint(x), int(sx)+2, 1, ColorTextDim)
+		} else {
+			ebitenutil.DrawRect(screen, sx, rulerTopH-5, 1, 5, ColorMenuBorder)
+		}
+	}
+	for y := floorTo(y0, minor); toScreen(y, c.CamY) < float64(sh); y += minor {
+		sy := math.Round(toScreen(y, c.CamY))
+		if y%major == 0 {
+			ebitenutil.DrawRect(screen, 0, sy, rulerLeftW, 1, ColorMenuBorder)
+			drawTextAt(screen, face, fmt.Sprint(y), 2, int(sy)+1, ColorTextDim)
+		} else {
+			ebitenutil.DrawRect(screen, rulerLeftW-5, sy, 5, 1, ColorMenuBorder)
+		}
+	}
+	ebitenutil.DrawRect(screen, 0, 0, rulerLeftW, rulerTopH, ColorMenuBg)
+
+	// the cursor, marked on both rulers and read out next to it
+	mx, my := g.frame.CursorPosition()
+	ebitenutil.DrawRect(screen, float64(mx), 0, 1, rulerTopH, ColorSelection)
+	ebitenutil.DrawRect(screen, 0, float64(my), rulerLeftW, 1, ColorSelection)
+	wx, wy := c.ScreenToWorld(mx, my)
+	label := fmt.Sprintf("%d, %d", wx, wy)
+	lx, ly := mx+14, my+14
+	ebitenutil.DrawRect(screen, float64(lx), float64(ly), float64(textWidth(face, label)+PanelInnerPadding*2), float64(lineHeight(face)+PanelInnerPadding), ColorMenuBg)
+	drawTextAt(screen, face, label, lx+PanelInnerPadding, ly+PanelInnerPadding/2, ColorText)
+}
+
+// floorTo rounds v down to a multiple of step, also for negative v.
+func floorTo(v, step int) int {
+	return int(math.Floor(float64(v)/float64(step))) * step
+}
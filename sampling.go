@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// defaultPreviewRows is the suggested number of rows for a CSV preview.
+const defaultPreviewRows = 1000
+
+// previewBadge marks the header of a panel holding only part of its file.
+const previewBadge = "PREVIEW"
+
+// previewCSV asks for a CSV file and a row count and adds a panel at x, y
+// with just the first rows of the file, which opens instantly however big
+// the file is.
+func previewCSV(g *Game, x, y int) {
+	path, err := dialog.File().Filter("CSV", csvFileTypes...).Title("Preview CSV").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	g.prompt.Open("Preview how many rows?", "Load Full File in the panel menu reads the rest", strconv.Itoa(defaultPreviewRows), func(g *Game, v string) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			g.events.Publish(LogMessage{Text: "row count must be a positive number"})
+			return
+		}
+		absPath, _ := filepath.Abs(path)
+		p := cellcanvas.NewBlankPanel(x, y, 1, 1)
+		sampled, err := cellcanvas.LoadPanelFile(absPath, &p, n)
+		if err != nil {
+			reportError(g, "Preview CSV", err)
+			return
+		}
+		p.Loaded = true
+		if sampled {
+			// keep the full path: the file is read again by Load Full File
+			// and on the next launch
+			p.Filename = absPath
+			p.SampleRows = n
+		} else {
+			p.Filename = filepath.Base(absPath)
+		}
+		i := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: i})
+		if sampled {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("previewing first %d rows of %s", n, filepath.Base(absPath))})
+		} else {
+			g.events.Publish(LogMessage{Text: "loaded whole file: " + filepath.Base(absPath)})
+		}
+	})
+}
+
+// loadFullFile replaces preview panel pi with its whole file, loading it in
+// the background.
+func loadFullFile(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].SampleRows == 0 {
+		g.events.Publish(LogMessage{Text: "Not a preview panel"})
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	path := p.Filename
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(defaultStatePath), path)
+	}
+	p.SampleRows = 0
+	p.Loaded = false
+	g.canvas.saveManager.ScheduleChecked(pi, path, 0, "", p.Delimiter)
+	g.events.Publish(LogMessage{Text: "loading full file: " + filepath.Base(path)})
+}
+
+// previewBlocksEdit reports (and explains) that panel pi cannot be edited
+// because it only holds a preview of its file.
+func previewBlocksEdit(g *Game, pi int) bool {
+	if g.canvas.Panels[pi].SampleRows == 0 {
+		return false
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is a preview; use Load Full File to edit it", pi+1)})
+	return true
+}
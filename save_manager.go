@@ -0,0 +1,138 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// loadResult is used to pass loaded CSV data back into the main loop.
+type loadResult struct {
+	idx      int
+	p        cellcanvas.Panel
+	err      error
+	filename string
+	// sampled is set when only the first rows of a preview were loaded
+	sampled bool
+	// want is the checksum the file had when last saved, checksum the one
+	// it has now; both are empty when the file was not checked
+	want, checksum string
+}
+
+// SaveManager coordinates background CSV loads and applies them safely
+// on the main thread. It owns the internal channel and does not expose it
+// to canvas.go so we keep canvas free of concurrency primitives.
+type SaveManager struct {
+	loadCh chan loadResult
+	// synchronous makes ScheduleLoad parse files inline and queue the result
+	// for the next ApplyPending. Input recording and replay use this so that
+	// load completion never races with recorded input.
+	synchronous bool
+	pending     []loadResult
+}
+
+// NewSaveManager creates and initializes a SaveManager.
+func NewSaveManager() *SaveManager {
+	return &SaveManager{loadCh: make(chan loadResult, 8)}
+}
+
+// ScheduleLoad starts a background load of CSV file and will send the
+// resulting loadResult on the manager's channel when done.
+func (sm *SaveManager) ScheduleLoad(idx int, path string) {
+	sm.ScheduleSample(idx, path, 0)
+}
+
+// ScheduleSample is ScheduleLoad for a preview: only the first sampleRows
+// records are read (all of them when sampleRows is 0).
+func (sm *SaveManager) ScheduleSample(idx int, path string, sampleRows int) {
+	sm.ScheduleChecked(idx, path, sampleRows, "", "")
+}
+
+// ScheduleChecked is ScheduleSample for a file saved with checksum want and
+// fields separated by delimiter (sniffed when empty): the file is also
+// checksummed, and a FileChangedOutside is published when it no longer
+// matches.
+func (sm *SaveManager) ScheduleChecked(idx int, path string, sampleRows int, want, delimiter string) {
+	load := func() loadResult {
+		tmp := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+		tmp.Delimiter = delimiter
+		sampled, err := cellcanvas.LoadPanelFile(path, &tmp, sampleRows)
+		r := loadResult{idx: idx, p: tmp, err: err, filename: filepath.Base(path), sampled: sampled, want: want}
+		if want != "" && err == nil {
+			r.checksum, _ = cellcanvas.FileChecksum(path)
+		}
+		return r
+	}
+	if sm.synchronous {
+		sm.pending = append(sm.pending, load())
+		return
+	}
+	// spawn a goroutine to do IO and parsing
+	go func() {
+		sm.loadCh <- load()
+	}()
+}
+
+// ApplyPending hands every completed load to the frame scheduler, which
+// installs them one per step so a burst of finished loads (or a huge panel
+// arriving together with others) is spread over several frames.
+func (sm *SaveManager) ApplyPending(c *Canvas, sched *FrameScheduler, logError func(op string, err error)) {
+	if sm == nil || sm.loadCh == nil {
+		return
+	}
+	queue := func(r loadResult) {
+		sched.Add(func() bool {
+			sm.apply(c, r, logError)
+			return true
+		})
+	}
+	pending := sm.pending
+	sm.pending = nil
+	for _, r := range pending {
+		queue(r)
+	}
+	for {
+		select {
+		case r := <-sm.loadCh:
+			queue(r)
+		default:
+			return
+		}
+	}
+}
+
+// apply installs a single load result into the canvas.
+func (sm *SaveManager) apply(c *Canvas, r loadResult, logError func(op string, err error)) {
+	if r.err == nil {
+		if r.idx >= 0 && r.idx < len(c.Panels) {
+			// keep placement and metadata, copy loaded content
+			p := &c.Panels[r.idx]
+			store := p.Paged
+			p.ReplaceContent(&r.p)
+			c.ReleaseStore(store)
+			p.NormalizeDates()
+			// previews keep pointing at the original file
+			if p.SampleRows == 0 {
+				p.Filename = r.filename
+			}
+			p.Loaded = true
+			if !r.sampled {
+				p.SampleRows = 0
+			}
+			p.Checksum = r.checksum
+			c.events.Publish(PanelLoaded{Panel: r.idx})
+			if r.checksum != "" && r.checksum != r.want {
+				c.events.Publish(FileChangedOutside{Panel: r.idx, File: r.filename})
+			}
+		}
+		return
+	}
+	if r.idx >= 0 && r.idx < len(c.Panels) {
+		c.Panels[r.idx].Filename = r.filename
+		// keep panel as not loaded (placeholder)
+		c.Panels[r.idx].Loaded = false
+	}
+	if logError != nil {
+		logError("Load "+r.filename, r.err)
+	}
+}
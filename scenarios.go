@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// maxScenarioCells caps the input cells one scenario records.
+const maxScenarioCells = 1000
+
+// promptSaveScenario asks for a name and saves the values of the selected
+// range under it.
+func promptSaveScenario(g *Game) {
+	pi := g.input.activePanel
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	col0, row0, col1, row1 := g.input.selectionRange()
+	n := (col1 - col0 + 1) * (row1 - row0 + 1)
+	if n > maxScenarioCells {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("select at most %d input cells for a scenario", maxScenarioCells)})
+		return
+	}
+	hint := fmt.Sprintf("name, e.g. Best case; saves %s:%s", cellcanvas.CellRef(col0, row0), cellcanvas.CellRef(col1, row1))
+	g.prompt.Open("Save Scenario", hint, "", func(g *Game, name string) {
+		name = strings.TrimSpace(name)
+		if name == "" || pi >= len(g.canvas.Panels) {
+			return
+		}
+		g.canvas.SaveScenario(name, pi, col0, row0, col1, row1)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("scenario %q saved (%d cells)", name, n)})
+	})
+}
+
+// pickScenario lets the user switch to a saved scenario, writing its
+// values back into their cells.
+func pickScenario(g *Game) {
+	if len(g.canvas.Scenarios) == 0 {
+		g.events.Publish(LogMessage{Text: "No saved scenarios (select input cells, then Save Scenario...)"})
+		return
+	}
+	names := make([]string, len(g.canvas.Scenarios))
+	for i, s := range g.canvas.Scenarios {
+		names[i] = fmt.Sprintf("%s (%d cells)", s.Name, len(s.Values))
+	}
+	g.picker.Open("Switch Scenario", names, func(g *Game, i int) {
+		if i >= len(g.canvas.Scenarios) {
+			return
+		}
+		applyScenario(g, &g.canvas.Scenarios[i])
+	})
+}
+
+// applyScenario writes the values of s into their cells.
+func applyScenario(g *Game, s *cellcanvas.Scenario) {
+	changed := 0
+	for _, v := range s.Values {
+		col, row, err := cellcanvas.ParseCellRef(v.Cell)
+		if err != nil || v.Panel >= len(g.canvas.Panels) {
+			continue
+		}
+		p := &g.canvas.Panels[v.Panel]
+		old := p.RawCell(col, row)
+		if old == v.Value {
+			continue
+		}
+		p.Cols, p.Rows = max(p.Cols, col+1), max(p.Rows, row+1)
+		p.SetCell(col, row, v.Value)
+		g.events.Publish(CellChanged{Panel: v.Panel, Col: col, Row: row, Old: old, New: v.Value})
+		changed++
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("scenario %q: %d cells changed", s.Name, changed)})
+}
+
+// pickDeleteScenario lets the user remove a saved scenario.
+func pickDeleteScenario(g *Game) {
+	if len(g.canvas.Scenarios) == 0 {
+		g.events.Publish(LogMessage{Text: "No saved scenarios"})
+		return
+	}
+	names := make([]string, len(g.canvas.Scenarios))
+	for i, s := range g.canvas.Scenarios {
+		names[i] = s.Name
+	}
+	g.picker.Open("Delete Scenario", names, func(g *Game, i int) {
+		g.canvas.DeleteScenario(names[i])
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("scenario %q deleted", names[i])})
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// defaultFrameBudget is how much of a 60 TPS frame (16.7ms) the scheduler
+// may spend on deferred work.
+const defaultFrameBudget = 4 * time.Millisecond
+
+// FrameScheduler runs deferred work in small steps so that no single frame
+// hitches: each Run executes queued steps in FIFO order until the per-frame
+// budget is used up, and always at least one so work keeps moving on slow
+// machines. A step returns true once its task is finished; unfinished tasks
+// stay at the front of the queue, so a step must make progress rather than
+// wait for other work queued behind it.
+type FrameScheduler struct {
+	budget time.Duration
+	tasks  []func() bool
+	// unbudgeted runs everything queued on each Run. Input recording and
+	// replay use this so results never depend on wall-clock timing.
+	unbudgeted bool
+}
+
+// NewFrameScheduler creates a scheduler with the default frame budget.
+func NewFrameScheduler() *FrameScheduler {
+	return &FrameScheduler{budget: defaultFrameBudget}
+}
+
+// Add queues a task; step is called once per slice of work until it
+// returns true.
+func (s *FrameScheduler) Add(step func() bool) {
+	s.tasks = append(s.tasks, step)
+}
+
+// Busy reports whether work is queued.
+func (s *FrameScheduler) Busy() bool {
+	return len(s.tasks) > 0
+}
+
+// Run spends up to the frame budget on queued tasks. Call it once per
+// Update.
+func (s *FrameScheduler) Run() {
+	start := time.Now()
+	for len(s.tasks) > 0 {
+		if s.tasks[0]() {
+			s.tasks[0] = nil
+			s.tasks = s.tasks[1:]
+		}
+		if !s.unbudgeted && time.Since(start) >= s.budget {
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+const (
+	// scratchCols and scratchRows are the size of a new scratch panel
+	scratchCols = 4
+	scratchRows = 12
+)
+
+// scratchBadge marks the header of the scratch panel.
+const scratchBadge = "NOT SAVED"
+
+// Scratch is the session's scratch panel: a panel for quick notes and for
+// values sent from other panels, shown and hidden with Ctrl+J. It is marked
+// Scratch, so it is not saved with the workspace; Keep Scratch Panel turns
+// it into an ordinary panel.
+type Scratch struct {
+	// hidden holds the scratch panel while it is off the canvas
+	hidden *cellcanvas.Panel
+}
+
+func NewScratch() *Scratch {
+	return &Scratch{}
+}
+
+// scratchIndex returns the index of the scratch panel on the canvas, or -1.
+func scratchIndex(c *Canvas) int {
+	for i := range c.Panels {
+		if c.Panels[i].Scratch {
+			return i
+		}
+	}
+	return -1
+}
+
+// Toggle hides the scratch panel when it is on the canvas and shows it
+// otherwise.
+func (s *Scratch) Toggle(g *Game) {
+	if i := scratchIndex(g.canvas); i >= 0 {
+		s.hide(g, i)
+		return
+	}
+	s.show(g)
+}
+
+// show puts the scratch panel, as it was left or a new blank one, near the
+// top-right corner of the screen and makes it active. It returns its index.
+func (s *Scratch) show(g *Game) int {
+	p := cellcanvas.NewBlankPanel(0, 0, scratchCols, scratchRows)
+	p.Name = "Scratch"
+	if s.hidden != nil {
+		p, s.hidden = *s.hidden, nil
+	}
+	p.Scratch = true
+	w, _ := g.viewSize()
+	p.X = int(-g.canvas.CamX) + w - p.Width() - cameraMargin
+	p.Y = int(-g.canvas.CamY) + PanelHeaderHeight + cameraMargin
+	i := g.canvas.AddPanel(p)
+	g.events.Publish(PanelAdded{Panel: i})
+	g.input.switchToPanel(g, i)
+	return i
+}
+
+// hide takes the scratch panel at index i off the canvas, keeping its
+// cells for the next show.
+func (s *Scratch) hide(g *Game, i int) {
+	if g.input.editing {
+		return
+	}
+	p := g.canvas.Panels[i]
+	s.hidden = &p
+	removePanel(g, i)
+}
+
+// sendToScratch appends the values of the selected range of panel pi to the
+// scratch panel, below a line naming where they came from, showing the
+// scratch panel if needed.
+func sendToScratch(g *Game, pi int) {
+	im := g.input
+	if pi < 0 || pi >= len(g.canvas.Panels) || pi != im.activePanel {
+		g.events.Publish(LogMessage{Text: "Select the cells to send first"})
+		return
+	}
+	if g.canvas.Panels[pi].Scratch {
+		return
+	}
+	src := &g.canvas.Panels[pi]
+	col0, row0, col1, row1 := im.selectionRange()
+	label := panelLabel(src, pi)
+	recs := [][]string{{fmt.Sprintf("%s!%s:%s at %s", label, cellcanvas.CellRef(col0, row0), cellcanvas.CellRef(col1, row1), time.Now().Format("15:04:05"))}}
+	for r := row0; r <= row1; r++ {
+		rec := make([]string, 0, col1-col0+1)
+		for c := col0; c <= col1; c++ {
+			rec = append(rec, src.GetCell(c, r))
+		}
+		recs = append(recs, rec)
+	}
+	si := scratchIndex(g.canvas)
+	if si < 0 {
+		si = g.scratch.show(g)
+	}
+	row := g.canvas.Panels[si].UsedRows()
+	if row > 0 {
+		// a blank line between captures
+		row++
+	}
+	pasteSmall(g, &pasteJob{Panel: si, Col: 0, Row: row, recs: recs, cols: cellcanvas.RecordsWidth(recs)})
+}
+
+// keepScratch makes the scratch panel at pi an ordinary panel that is
+// saved with the workspace. The next Ctrl+J starts a new scratch panel.
+func keepScratch(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) || !g.canvas.Panels[pi].Scratch {
+		g.events.Publish(LogMessage{Text: "Right-click the scratch panel to keep it"})
+		return
+	}
+	g.canvas.Panels[pi].Scratch = false
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is no longer a scratch panel and will be saved", pi+1)})
+}
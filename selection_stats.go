@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+)
+
+// selStatsChunk is how many cells one scheduler step aggregates.
+const selStatsChunk = 4096
+
+// selRange identifies a selected rectangle of cells.
+type selRange struct {
+	panel                  int
+	col0, row0, col1, row1 int
+}
+
+// SelectionStats shows count, sum and average of the selected range in the
+// status bar. The scan runs as a FrameScheduler task a chunk of cells at a
+// time, so million-cell selections never stall a frame; changing the
+// selection (or editing a cell in it) abandons the scan in progress and
+// starts over.
+type SelectionStats struct {
+	cur selRange
+	// gen increases on every restart; scan steps of an older generation
+	// finish immediately
+	gen     int
+	running bool
+	// progress through the range, row-major
+	nextRow, nextCol int
+
+	cells    int // non-empty cells
+	numbers  int
+	sum      float64
+	min, max float64
+	// num is the number locale of the selected panel
+	num cellcanvas.NumberLocale
+
+	// parts and y are the status line from the last Draw, for clicks
+	parts []statPart
+	y     int
+}
+
+// NewSelectionStats creates an idle aggregator.
+func NewSelectionStats() *SelectionStats {
+	return &SelectionStats{cur: selRange{panel: -1}}
+}
+
+// Update restarts the aggregation when the selected range changed.
+func (s *SelectionStats) Update(g *Game) {
+	r := selRange{panel: g.input.activePanel}
+	r.col0, r.row0, r.col1, r.row1 = g.input.selectionRange()
+	if r != s.cur {
+		s.cur = r
+		s.restart(g)
+	}
+}
+
+// Observe restarts the aggregation when a cell of the selected panel
+// changes or panels are removed from under it.
+func (s *SelectionStats) Observe(g *Game, e Event) {
+	switch ev := e.(type) {
+	case CellChanged:
+		if ev.Panel == s.cur.panel {
+			s.restart(g)
+		}
+	case PanelRemoved, WorkspaceLoaded:
+		s.cur.panel = -1
+		s.restart(g)
+	}
+}
+
+func (s *SelectionStats) restart(g *Game) {
+	s.gen++
+	s.running = false
+	s.cells, s.numbers, s.sum, s.min, s.max = 0, 0, 0, 0, 0
+	r := s.cur
+	if r.panel < 0 || r.panel >= len(g.canvas.Panels) || (r.col0 == r.col1 && r.row0 == r.row1) {
+		return
+	}
+	s.running = true
+	s.num = g.canvas.Panels[r.panel].Numbers()
+	s.nextRow, s.nextCol = r.row0, r.col0
+	gen := s.gen
+	g.canvas.scheduler.Add(func() bool {
+		if gen != s.gen {
+			return true
+		}
+		return s.step(g)
+	})
+}
+
+// step aggregates the next chunk of cells and reports whether the range is
+// done.
+func (s *SelectionStats) step(g *Game) bool {
+	r := s.cur
+	if r.panel >= len(g.canvas.Panels) {
+		s.running = false
+		return true
+	}
+	p := &g.canvas.Panels[r.panel]
+	row1, col1 := min(r.row1, p.Rows-1), min(r.col1, p.Cols-1)
+	for n := 0; n < selStatsChunk; n++ {
+		if s.nextRow > row1 || s.nextCol > col1 {
+			s.running = false
+			return true
+		}
+		if v := strings.TrimSpace(p.GetCell(s.nextCol, s.nextRow)); v != "" {
+			s.cells++
+			if f, err := p.Numbers().Parse(v); err == nil {
+				if s.numbers == 0 || f < s.min {
+					s.min = f
+				}
+				if s.numbers == 0 || f > s.max {
+					s.max = f
+				}
+				s.numbers++
+				s.sum += f
+			}
+		}
+		if s.nextCol++; s.nextCol > col1 {
+			s.nextCol = r.col0
+			s.nextRow++
+		}
+	}
+	return false
+}
+
+// statPart is one figure of the status line. Figures with a value can be
+// clicked to copy it.
+type statPart struct {
+	text, name, value string
+	// x0, x1 is the screen span from the last Draw
+	x0, x1 int
+}
+
+// Draw renders the statistics in the bottom-right corner. While the scan is
+// running the partial figures are shown next to a spinner. Sum, average,
+// minimum and maximum appear once the range has two numbers.
+func (s *SelectionStats) Draw(screen *ebiten.Image, face font.Face, tick int64) {
+	s.parts = s.parts[:0]
+	r := s.cur
+	if r.panel < 0 || (r.col0 == r.col1 && r.row0 == r.row1) {
+		return
+	}
+	if s.running {
+		s.parts = append(s.parts, statPart{text: string(`|/-\`[tick/8%4])})
+	}
+	s.parts = append(s.parts,
+		statPart{text: fmt.Sprintf("%dx%d", r.col1-r.col0+1, r.row1-r.row0+1)},
+		statPart{text: fmt.Sprintf("Count: %d", s.cells), name: "Count", value: strconv.Itoa(s.cells)})
+	if s.numbers >= 2 {
+		for _, f := range []struct {
+			name string
+			v    float64
+		}{{"Sum", s.sum}, {"Avg", s.sum / float64(s.numbers)}, {"Min", s.min}, {"Max", s.max}} {
+			v := formatStat(f.v, s.num)
+			s.parts = append(s.parts, statPart{text: f.name + ": " + v, name: f.name, value: v})
+		}
+	}
+	gap := font.MeasureString(face, "  ").Round()
+	x := screen.Bounds().Dx() - 8 + gap
+	for i := len(s.parts) - 1; i >= 0; i-- {
+		pt := &s.parts[i]
+		pt.x1 = x - gap
+		pt.x0 = pt.x1 - font.MeasureString(face, pt.text).Round()
+		x = pt.x0
+	}
+	s.y = screen.Bounds().Dy() - 14
+	for _, pt := range s.parts {
+		drawTextAt(screen, face, pt.text, pt.x0, s.y, ColorText)
+	}
+}
+
+// HandleClick copies the figure clicked in the status line to the
+// clipboard and reports whether a figure was hit.
+func (s *SelectionStats) HandleClick(g *Game) bool {
+	if len(s.parts) == 0 || !g.frame.MouseJustPressed(ebiten.MouseButtonLeft) {
+		return false
+	}
+	mx, my := g.frame.CursorPosition()
+	if my < s.y-2 || my >= s.y+16 {
+		return false
+	}
+	for _, pt := range s.parts {
+		if pt.value == "" || mx < pt.x0 || mx >= pt.x1 {
+			continue
+		}
+		if err := clipboard.WriteAll(pt.value); err != nil {
+			g.events.Publish(LogMessage{Text: "copy failed: " + err.Error()})
+		} else {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("copied %s %s", pt.name, pt.value)})
+		}
+		return true
+	}
+	return false
+}
+
+// formatStat writes f in l, the number locale of the panel it is from, so
+// it can be pasted back into that panel.
+func formatStat(f float64, l cellcanvas.NumberLocale) string {
+	return l.Format(f, 10)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"gopkg.in/yaml.v3"
+)
+
+// settingsPath holds app-wide preferences. Unlike state.yml it describes
+// how the app looks, not what is on the canvas, and is only read.
+const settingsPath = "settings.yml"
+
+// Settings are the global preferences read from settings.yml. Missing keys
+// keep their defaults.
+type Settings struct {
+	// Gridlines is the style for panels without their own (solid,
+	// dotted, horizontal or none)
+	Gridlines string `yaml:"gridlines"`
+	// Paper is the page size for page-break preview and PDF export
+	// (a4 or letter)
+	Paper     string `yaml:"paper"`
+	Landscape bool   `yaml:"landscape"`
+	// OutlierK is the default threshold, in standard deviations, offered
+	// when outlier highlighting is turned on
+	OutlierK float64 `yaml:"outlier_k"`
+	// RaggedRows is what CSV loads do with rows that have more or fewer
+	// fields than the first (pad, truncate or skip)
+	RaggedRows cellcanvas.RaggedPolicy `yaml:"ragged_rows"`
+	// NumberLocale is how numbers are written in panels without a locale
+	// of their own: plain (1234.5), en (1,234.5), de (1.234,5), fr
+	// (1 234,5) or ch (1'234.5)
+	NumberLocale string `yaml:"number_locale"`
+	// DoubleClickMs is the longest gap between the clicks of a double
+	// click, in milliseconds
+	DoubleClickMs int `yaml:"double_click_ms"`
+	// EditTrigger is the click that starts editing a cell (double-click
+	// or single-click)
+	EditTrigger string `yaml:"edit_trigger"`
+	// RenameTrigger is the click on a panel's name button that renames
+	// the panel (double-click or single-click)
+	RenameTrigger string `yaml:"rename_trigger"`
+	// TypeToEdit starts editing the selected cell when a character is
+	// typed, replacing its value
+	TypeToEdit bool `yaml:"type_to_edit"`
+	// EditExits says whether a click on another cell, Tab and the arrow
+	// keys commit or cancel a cell edit (commit, cancel; keep for Tab and
+	// arrows)
+	EditExits editExits `yaml:"edit_exits"`
+	// NudgeGrid is the grid, in pixels, Ctrl+Shift+arrows move panels
+	// along; 0 moves them one pixel at a time
+	NudgeGrid int `yaml:"nudge_grid"`
+	// KeepPanelsInView stops panning once only a sliver of the panels
+	// is left on screen
+	KeepPanelsInView bool `yaml:"keep_panels_in_view"`
+}
+
+// defaultSettings applies when settings.yml is missing.
+func defaultSettings() Settings {
+	return Settings{Gridlines: GridSolid, Paper: "a4", OutlierK: cellcanvas.DefaultOutlierK, RaggedRows: cellcanvas.RaggedPad, NumberLocale: "plain", DoubleClickMs: 400, EditTrigger: EditOnDoubleClick, RenameTrigger: EditOnDoubleClick, TypeToEdit: true, EditExits: defaultEditExits()}
+}
+
+// PageSize returns the configured paper size.
+func (s Settings) PageSize() cellcanvas.PageSize {
+	size := cellcanvas.PageA4
+	if strings.EqualFold(s.Paper, "letter") {
+		size = cellcanvas.PageLetter
+	}
+	if s.Landscape {
+		size = size.Landscape()
+	}
+	return size
+}
+
+// LoadSettings reads path over the defaults. A missing file is not an
+// error; a broken one is logged and ignored.
+func LoadSettings(path string) Settings {
+	s := defaultSettings()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("settings: %v", err)
+		}
+		return s
+	}
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		log.Printf("settings: %s: %v", path, err)
+		return defaultSettings()
+	}
+	switch s.RaggedRows {
+	case cellcanvas.RaggedPad, cellcanvas.RaggedTruncate, cellcanvas.RaggedSkip:
+	default:
+		log.Printf("settings: unknown ragged_rows %q; padding", s.RaggedRows)
+		s.RaggedRows = cellcanvas.RaggedPad
+	}
+	if _, ok := cellcanvas.LookupNumberLocale(s.NumberLocale); !ok {
+		log.Printf("settings: unknown number_locale %q; using plain", s.NumberLocale)
+		s.NumberLocale = "plain"
+	}
+	switch s.EditTrigger {
+	case EditOnDoubleClick, EditOnClick:
+	default:
+		log.Printf("settings: unknown edit_trigger %q; using double-click", s.EditTrigger)
+		s.EditTrigger = EditOnDoubleClick
+	}
+	switch s.RenameTrigger {
+	case EditOnDoubleClick, EditOnClick:
+	default:
+		log.Printf("settings: unknown rename_trigger %q; using double-click", s.RenameTrigger)
+		s.RenameTrigger = EditOnDoubleClick
+	}
+	def := defaultEditExits()
+	for _, x := range []struct {
+		name     string
+		v        *string
+		def      string
+		keepable bool
+	}{
+		{"click", &s.EditExits.Click, def.Click, false},
+		{"tab", &s.EditExits.Tab, def.Tab, true},
+		{"arrows", &s.EditExits.Arrows, def.Arrows, true},
+	} {
+		switch *x.v {
+		case EditCommit, EditCancel:
+		case EditKeep:
+			if x.keepable {
+				break
+			}
+			fallthrough
+		default:
+			log.Printf("settings: unknown edit_exits %s %q; using %s", x.name, *x.v, x.def)
+			*x.v = x.def
+		}
+	}
+	if s.DoubleClickMs <= 0 {
+		s.DoubleClickMs = 400
+	}
+	if s.NudgeGrid < 0 {
+		log.Printf("settings: negative nudge_grid %d; nudging by one pixel", s.NudgeGrid)
+		s.NudgeGrid = 0
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// importGap is the horizontal space left between panels created from the
+// sheets of one imported spreadsheet.
+const importGap = 40
+
+// importODS asks for an .ods file and adds one panel per sheet, laid out
+// left to right starting at world coordinates x, y.
+func importODS(g *Game, x, y int) {
+	path, err := dialog.File().Filter("OpenDocument Spreadsheet", "ods").Title("Import ODS").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	panels, err := cellcanvas.ReadODS(path)
+	if err != nil {
+		reportError(g, "Import ODS", err)
+		return
+	}
+	for _, p := range panels {
+		p.X, p.Y = x, y
+		i := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: i})
+		x += p.Width() + importGap
+	}
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("imported %d sheet(s) from %s", len(panels), filepath.Base(path))})
+}
+
+// importJSON asks for a .json file holding an array of objects and adds it
+// as a panel at world coordinates x, y.
+func importJSON(g *Game, x, y int) {
+	path, err := dialog.File().Filter("JSON", "json").Title("Import JSON").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	p, err := cellcanvas.ReadJSON(path)
+	if err != nil {
+		reportError(g, "Import JSON", err)
+		return
+	}
+	p.X, p.Y = x, y
+	i := g.canvas.AddPanel(p)
+	g.events.Publish(PanelAdded{Panel: i})
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("imported %d object(s) with %d key(s) from %s as Panel %d", p.Rows-1, p.Cols, filepath.Base(path), i+1)})
+}
+
+// importMarkdown asks for a Markdown file and adds its first table as a
+// panel at world coordinates x, y.
+func importMarkdown(g *Game, x, y int) {
+	path, err := dialog.File().Filter("Markdown", "md", "markdown").Title("Import Markdown Table").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	p, err := cellcanvas.ReadMarkdown(path)
+	if err != nil {
+		reportError(g, "Import Markdown", err)
+		return
+	}
+	p.X, p.Y = x, y
+	i := g.canvas.AddPanel(p)
+	g.events.Publish(PanelAdded{Panel: i})
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("imported a %d x %d table from %s as Panel %d", p.Rows, p.Cols, filepath.Base(path), i+1)})
+}
+
+// importSQLite asks for a SQLite database file, lists its tables and adds
+// the one picked as a panel at world coordinates x, y.
+func importSQLite(g *Game, x, y int) {
+	path, err := dialog.File().Filter("SQLite database", "sqlite", "sqlite3", "db").Title("Import SQLite Table").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file open failed: %v", err)
+		}
+		return
+	}
+	tables, err := cellcanvas.SQLiteTables(path)
+	if err != nil {
+		reportError(g, "Import SQLite", err)
+		return
+	}
+	if len(tables) == 0 {
+		g.events.Publish(LogMessage{Text: filepath.Base(path) + " has no tables"})
+		return
+	}
+	g.picker.Open("Table of "+filepath.Base(path), tables, func(g *Game, i int) {
+		p, err := cellcanvas.ReadSQLiteTable(path, tables[i])
+		if err != nil {
+			reportError(g, "Import SQLite", err)
+			return
+		}
+		p.X, p.Y = x, y
+		pi := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: pi})
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("imported %d row(s) of table %s from %s as Panel %d", p.Rows-1, tables[i], filepath.Base(path), pi+1)})
+	})
+}
+
+// exportPanelJSON asks for a destination and writes panel pi as a JSON
+// array of objects keyed by its header row.
+func exportPanelJSON(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "No panel to export"})
+		return
+	}
+	path, err := dialog.File().Filter("JSON", "json").Title(fmt.Sprintf("Export Panel %d As", pi+1)).Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file save failed: %v", err)
+		}
+		return
+	}
+	if filepath.Ext(path) == "" {
+		path += ".json"
+	}
+	if err := cellcanvas.WriteJSON(path, &g.canvas.Panels[pi]); err != nil {
+		reportError(g, "Export JSON", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: "exported: " + filepath.Base(path)})
+}
+
+// exportWorkspace asks for a destination and writes every panel as a sheet
+// of one spreadsheet file using write (cellcanvas.WriteODS or WriteXLSX).
+func exportWorkspace(g *Game, kind, ext string, write func(string, []cellcanvas.Panel) error) {
+	if len(g.canvas.Panels) == 0 {
+		g.events.Publish(LogMessage{Text: "No panels to export"})
+		return
+	}
+	exportSheets(g, "Export Workspace As", g.canvas.Panels, kind, ext, write)
+}
+
+// exportPanel asks for a destination and writes panel pi alone as a
+// spreadsheet file using write. The sheet is named as in a workspace
+// export, an unnamed panel without a file by its number in the workspace.
+func exportPanel(g *Game, pi int, kind, ext string, write func(string, []cellcanvas.Panel) error) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		g.events.Publish(LogMessage{Text: "No panel to export"})
+		return
+	}
+	p := g.canvas.Panels[pi]
+	p.Name = cellcanvas.SheetName(&p, pi)
+	exportSheets(g, fmt.Sprintf("Export Panel %d As", pi+1), []cellcanvas.Panel{p}, kind, ext, write)
+}
+
+// exportSheets asks for a destination under title and writes panels to it,
+// one sheet each, using write.
+func exportSheets(g *Game, title string, panels []cellcanvas.Panel, kind, ext string, write func(string, []cellcanvas.Panel) error) {
+	path, err := dialog.File().Filter(kind, ext).Title(title).Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file save failed: %v", err)
+		}
+		return
+	}
+	if filepath.Ext(path) == "" {
+		path += "." + ext
+	}
+	if err := write(path, panels); err != nil {
+		reportError(g, "Export "+ext, err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: "exported: " + filepath.Base(path)})
+}
+
+// exportAuditTrail saves a copy of the audit file as CSV.
+func exportAuditTrail(g *Game) {
+	if g.audit == nil {
+		g.events.Publish(LogMessage{Text: "Audit trail is off (start with -audit)"})
+		return
+	}
+	path, err := dialog.File().Filter("CSV", "csv").Title("Export Audit Trail As").Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			log.Printf("file save failed: %v", err)
+		}
+		return
+	}
+	if filepath.Ext(path) == "" {
+		path += ".csv"
+	}
+	if err := g.audit.Export(path); err != nil {
+		reportError(g, "Export audit trail", err)
+		return
+	}
+	g.events.Publish(LogMessage{Text: "exported: " + filepath.Base(path)})
+}
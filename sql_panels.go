@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// StartSQL reruns the query of SQL panel pi on the frame scheduler,
+// replacing a run already going for it. A database table is read afresh; a
+// panel table still loading puts the panel in waitSQL until a panel loads,
+// rather than holding up the scheduler.
+func (d *DerivedPanels) StartSQL(g *Game, pi int) {
+	d.jobs[pi]++
+	delete(d.waitSQL, pi)
+	if pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].SQL == nil {
+		return
+	}
+	if src, _, err := g.canvas.SQLTable(pi); err == nil && !src.Loaded {
+		d.waitSQL[pi] = true
+		return
+	}
+	job, gen := d.jobs[pi], d.gen
+	var run *cellcanvas.QueryRun
+	g.canvas.scheduler.Add(func() bool {
+		if gen != d.gen || job != d.jobs[pi] || pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].SQL == nil {
+			return true
+		}
+		if run == nil {
+			src, text, err := g.canvas.SQLTable(pi)
+			if err == nil && !src.Loaded {
+				d.waitSQL[pi] = true
+				return true
+			}
+			if err == nil {
+				run, err = cellcanvas.StartQuery(text, src)
+			}
+			if err != nil {
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d SQL: %v", pi+1, err)})
+				return true
+			}
+		}
+		if !run.Step(queryChunk) {
+			return false
+		}
+		g.canvas.Panels[pi].SetQueryResult(run.Result())
+		g.events.Publish(PanelLoaded{Panel: pi})
+		return true
+	})
+}
+
+// newSQLPanel asks for a SQL query over the panels, or over a SQLite
+// database when one is attached, and places its result as a new panel at
+// canvas position x, y.
+func newSQLPanel(g *Game, x, y int) {
+	items := []string{"Query Panels...", "Query a SQLite Database..."}
+	g.picker.Open("New SQL Panel", items, func(g *Game, i int) {
+		if i == 0 {
+			promptNewSQL(g, x, y, "", "e.g. SELECT region, sum(sales) AS total FROM [Panel name] GROUP BY region")
+			return
+		}
+		path, err := dialog.File().Filter("SQLite database", "sqlite", "sqlite3", "db").Title("Attach SQLite Database").Load()
+		if err != nil {
+			if err != dialog.ErrCancelled {
+				log.Printf("file open failed: %v", err)
+			}
+			return
+		}
+		tables, err := cellcanvas.SQLiteTables(path)
+		if err != nil {
+			reportError(g, "Attach SQLite", err)
+			return
+		}
+		promptNewSQL(g, x, y, path, "tables: "+strings.Join(tables, ", "))
+	})
+}
+
+// promptNewSQL asks for the query of a new SQL panel over database, or
+// over the panels when it is empty.
+func promptNewSQL(g *Game, x, y int, database, hint string) {
+	title := "SQL over the panels"
+	if database != "" {
+		title = "SQL over " + filepath.Base(database)
+	}
+	g.prompt.Open(title, hint, "SELECT * FROM ", func(g *Game, text string) {
+		if _, _, err := cellcanvas.SplitFrom(text); err != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("SQL: %v", err)})
+			return
+		}
+		p := cellcanvas.NewBlankPanel(x, y, 1, 1)
+		p.SQL = &cellcanvas.SQLQuery{Database: database, Text: text}
+		p.Name = "SQL: " + p.SQL.Title()
+		i := g.canvas.AddPanel(p)
+		g.events.Publish(PanelAdded{Panel: i})
+		g.derived.StartSQL(g, i)
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d shows a SQL query over %s", i+1, p.SQL.Title())})
+	})
+}
+
+// editSQL changes the query of SQL panel pi. An empty query turns it back
+// into a plain panel that keeps its last result.
+func editSQL(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	q := g.canvas.Panels[pi].SQL
+	if q == nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is not a SQL panel; use New SQL Panel...", pi+1)})
+		return
+	}
+	g.prompt.Open(fmt.Sprintf("SQL of Panel %d", pi+1), "empty keeps the current result as a plain panel", q.Text, func(g *Game, text string) {
+		if pi >= len(g.canvas.Panels) || g.canvas.Panels[pi].SQL == nil {
+			return
+		}
+		p := &g.canvas.Panels[pi]
+		if text == "" {
+			p.SQL = nil
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is no longer a SQL panel", pi+1)})
+			return
+		}
+		if _, _, err := cellcanvas.SplitFrom(text); err != nil {
+			g.events.Publish(LogMessage{Text: fmt.Sprintf("SQL: %v", err)})
+			return
+		}
+		p.SQL.Text = text
+		g.derived.StartSQL(g, pi)
+	})
+}
+
+// refreshSQL reruns the query of SQL panel pi, reading its database again.
+func refreshSQL(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	if g.canvas.Panels[pi].SQL == nil {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is not a SQL panel", pi+1)})
+		return
+	}
+	g.derived.StartSQL(g, pi)
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("refreshing Panel %d", pi+1)})
+}
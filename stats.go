@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// UsageStats accumulates local-only usage counters from the event bus.
+// Nothing here ever leaves the machine; the numbers are only shown in the
+// optional statistics overlay.
+type UsageStats struct {
+	visible bool
+	start   time.Time
+
+	cellsEdited   int
+	panelsCreated int
+	// largest panel observed this session, tracked by cell area
+	largestCells int
+	largestName  string
+}
+
+// NewUsageStats creates a stats tracker whose session clock starts now.
+func NewUsageStats() *UsageStats {
+	return &UsageStats{start: time.Now()}
+}
+
+// Observe folds a single event into the counters. The canvas is consulted
+// for the dimensions of the panel the event refers to.
+func (s *UsageStats) Observe(e Event, c *Canvas) {
+	panel := -1
+	switch ev := e.(type) {
+	case CellChanged:
+		s.cellsEdited++
+		panel = ev.Panel
+	case PanelAdded:
+		s.panelsCreated++
+		panel = ev.Panel
+	}
+	if panel < 0 || panel >= len(c.Panels) {
+		return
+	}
+	p := &c.Panels[panel]
+	if area := p.Rows * p.Cols; area > s.largestCells {
+		s.largestCells = area
+		s.largestName = fmt.Sprintf("Panel %d (%dx%d)", panel+1, p.Cols, p.Rows)
+		if p.Name != "" {
+			s.largestName = fmt.Sprintf("%s (%dx%d)", p.Name, p.Cols, p.Rows)
+		}
+	}
+}
+
+// Toggle shows or hides the statistics overlay.
+func (s *UsageStats) Toggle() {
+	s.visible = !s.visible
+}
+
+// Draw renders the statistics overlay in the top-right corner when visible.
+func (s *UsageStats) Draw(screen *ebiten.Image, face font.Face) {
+	if !s.visible {
+		return
+	}
+	largest := s.largestName
+	if largest == "" {
+		largest = "-"
+	}
+	lines := []string{
+		"Usage statistics (local only)",
+		fmt.Sprintf("Time in app:     %s", time.Since(s.start).Truncate(time.Second)),
+		fmt.Sprintf("Cells edited:    %d", s.cellsEdited),
+		fmt.Sprintf("Panels created:  %d", s.panelsCreated),
+		fmt.Sprintf("Largest panel:   %s", largest),
+	}
+	boxW := 280
+	boxH := len(lines)*16 + 12
+	x := screen.Bounds().Dx() - boxW - 8
+	y := 44
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(boxW), float64(boxH), ColorLogBg)
+	for i, line := range lines {
+		drawTextAt(screen, face, line, x+8, y+6+i*16, ColorTextDim)
+	}
+}
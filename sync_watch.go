@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+)
+
+// syncCheckInterval is how often the workspace files are checked for
+// changes made by other programs, such as a Dropbox or Syncthing client
+// bringing in another machine's save.
+const syncCheckInterval = 2 * time.Second
+
+// errChangedOnDisk is returned by SaveState while files changed on disk
+// have not been merged, reloaded or accepted for overwriting.
+var errChangedOnDisk = errors.New("changed on disk since it was opened; right-click → Disk Changes... to merge, reload or overwrite")
+
+// fileStamp identifies the version of a file on disk; the zero value
+// stands for a missing file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// SyncWatch notices when the workspace's state file or panel CSVs are
+// changed by another program while the workspace is open. Saving is then
+// refused, so it never silently overwrites the other version, until the
+// user merges the changed panels, reloads the workspace or keeps their own
+// version.
+type SyncWatch struct {
+	statePath string
+	// stamps holds the files as they were last loaded or saved
+	stamps map[string]fileStamp
+	// changed lists the files found changed since, in the order found
+	changed []string
+	// dirty holds the cells edited since the last load or save; whole
+	// the panels changed in bulk (pastes, appends, resizes), whose edits
+	// are not known cell by cell
+	dirty map[historyKey]bool
+	whole map[int]bool
+	// loading holds the panels whose first load after opening is pending,
+	// so that load is not taken for a bulk change
+	loading   map[int]bool
+	lastCheck time.Time
+	// ask is set when changes were found while another dialog was open
+	ask bool
+}
+
+func NewSyncWatch() *SyncWatch {
+	return &SyncWatch{stamps: make(map[string]fileStamp), dirty: make(map[historyKey]bool), whole: make(map[int]bool), loading: make(map[int]bool)}
+}
+
+// panelPath resolves a panel's file name against the state file's folder.
+func panelPath(statePath, filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(filepath.Dir(statePath), filename)
+}
+
+// watched reports whether panel p's file is written back on save, and so
+// is worth watching. Previews are never written, derived panels are
+// recomputed and scratch panels are not saved.
+func watched(p *cellcanvas.Panel) bool {
+	return p.Filename != "" && p.SampleRows == 0 && p.Query == nil && !p.Scratch
+}
+
+// files returns the files a save of the workspace writes.
+func (sw *SyncWatch) files(c *Canvas) []string {
+	if c.password != "" {
+		return []string{encryptedPath(sw.statePath)}
+	}
+	paths := []string{sw.statePath}
+	seen := map[string]bool{sw.statePath: true}
+	for i := range c.Panels {
+		p := &c.Panels[i]
+		if !watched(p) {
+			continue
+		}
+		if path := panelPath(sw.statePath, p.Filename); !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// restamp records the workspace files as they are now and forgets any
+// changes found.
+func (sw *SyncWatch) restamp(g *Game) {
+	sw.stamps = make(map[string]fileStamp)
+	for _, path := range sw.files(g.canvas) {
+		sw.stamps[path] = statFile(path)
+	}
+	sw.changed, sw.ask = nil, false
+	g.canvas.changedOnDisk = nil
+}
+
+// Observe restamps the files on open and save and keeps track of local
+// edits for merging.
+func (sw *SyncWatch) Observe(g *Game, e Event) {
+	switch ev := e.(type) {
+	case WorkspaceLoaded:
+		sw.statePath = ev.Path
+		sw.dirty, sw.whole, sw.loading = make(map[historyKey]bool), make(map[int]bool), make(map[int]bool)
+		for i := range g.canvas.Panels {
+			if !g.canvas.Panels[i].Loaded {
+				sw.loading[i] = true
+			}
+		}
+		sw.restamp(g)
+	case WorkspaceSaved:
+		sw.statePath = ev.Path
+		sw.dirty, sw.whole = make(map[historyKey]bool), make(map[int]bool)
+		sw.restamp(g)
+	case PanelAdded:
+		if sw.statePath == "" || ev.Panel >= len(g.canvas.Panels) || !watched(&g.canvas.Panels[ev.Panel]) {
+			return
+		}
+		if path := panelPath(sw.statePath, g.canvas.Panels[ev.Panel].Filename); !sliceHas(sw.changed, path) {
+			sw.stamps[path] = statFile(path)
+		}
+	case CellChanged:
+		sw.dirty[historyKey{ev.Panel, ev.Col, ev.Row}] = true
+	case PanelLoaded:
+		if sw.loading[ev.Panel] {
+			delete(sw.loading, ev.Panel)
+			return
+		}
+		sw.whole[ev.Panel] = true
+	case RowsAppended:
+		sw.whole[ev.Panel] = true
+	case PanelResized:
+		sw.whole[ev.Panel] = true
+	case PanelRemoved:
+		dirty := make(map[historyKey]bool, len(sw.dirty))
+		for k := range sw.dirty {
+			switch {
+			case k.panel == ev.Panel:
+				continue
+			case k.panel > ev.Panel:
+				k.panel--
+			}
+			dirty[k] = true
+		}
+		sw.dirty = dirty
+		sw.whole = shiftPanelSet(sw.whole, ev.Panel)
+		sw.loading = shiftPanelSet(sw.loading, ev.Panel)
+	}
+}
+
+// shiftPanelSet returns set without panel, the panels after it moved up.
+func shiftPanelSet(set map[int]bool, panel int) map[int]bool {
+	out := make(map[int]bool, len(set))
+	for i := range set {
+		switch {
+		case i == panel:
+		case i > panel:
+			out[i-1] = true
+		default:
+			out[i] = true
+		}
+	}
+	return out
+}
+
+func sliceHas(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Update checks the files every syncCheckInterval and asks what to do when
+// one of them changed.
+func (sw *SyncWatch) Update(g *Game) {
+	now := time.Now()
+	if sw.statePath == "" || now.Sub(sw.lastCheck) < syncCheckInterval {
+		if sw.ask && g.focus() == FocusCanvas {
+			sw.Ask(g)
+		}
+		return
+	}
+	sw.lastCheck = now
+	found := false
+	paths := make([]string, 0, len(sw.stamps))
+	for path := range sw.stamps {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if !sliceHas(sw.changed, path) && statFile(path) != sw.stamps[path] {
+			sw.changed = append(sw.changed, path)
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+	names := make([]string, len(sw.changed))
+	for i, path := range sw.changed {
+		names[i] = filepath.Base(path)
+	}
+	g.canvas.changedOnDisk = names
+	g.events.Publish(LogMessage{Text: strings.Join(names, ", ") + " changed on disk"})
+	sw.ask = true
+	if g.focus() == FocusCanvas {
+		sw.Ask(g)
+	}
+}
+
+// syncChoices are the answers to files changing on disk.
+var syncChoices = []string{
+	"Merge: load the changed panels, keep the cells I edited",
+	"Reload the workspace from disk, dropping my changes",
+	"Keep mine: overwrite them on the next save",
+}
+
+// Ask offers to merge, reload or overwrite the files changed on disk.
+// Escape leaves saving blocked; Disk Changes... asks again.
+func (sw *SyncWatch) Ask(g *Game) {
+	sw.ask = false
+	if len(sw.changed) == 0 {
+		g.events.Publish(LogMessage{Text: "no workspace files changed on disk"})
+		return
+	}
+	title := fmt.Sprintf("%s changed on disk", strings.Join(g.canvas.changedOnDisk, ", "))
+	g.power.redraw = true
+	g.picker.Open(title, syncChoices, func(g *Game, i int) {
+		switch i {
+		case 0:
+			sw.merge(g)
+		case 1:
+			statePath := sw.statePath
+			sw.restamp(g)
+			openWorkspace(g, statePath)
+		case 2:
+			sw.restamp(g)
+			g.events.Publish(LogMessage{Text: "the next save overwrites the files changed on disk"})
+		}
+	})
+}
+
+// merge reloads each changed panel CSV and puts the cells edited here
+// since the last save back on top, so both sides' edits survive; where
+// both edited a cell, the edit made here wins. Panels changed in bulk here
+// cannot be merged cell by cell and keep their local version, as does the
+// layout when state.yml changed.
+func (sw *SyncWatch) merge(g *Game) {
+	merged, kept := 0, 0
+	for _, path := range sw.changed {
+		if path == sw.statePath {
+			continue
+		}
+		for i := range g.canvas.Panels {
+			p := &g.canvas.Panels[i]
+			if !watched(p) || !p.Loaded || panelPath(sw.statePath, p.Filename) != path {
+				continue
+			}
+			if sw.whole[i] {
+				kept++
+				g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d was changed in bulk here; keeping its local version", i+1)})
+				continue
+			}
+			tmp := cellcanvas.NewBlankPanel(0, 0, 1, 1)
+			tmp.Delimiter = p.Delimiter
+			if err := cellcanvas.LoadPanelCSV(path, &tmp); err != nil {
+				reportError(g, "Merge "+filepath.Base(path), err)
+				kept++
+				continue
+			}
+			for k := range sw.dirty {
+				if k.panel != i {
+					continue
+				}
+				tmp.Cols, tmp.Rows = max(tmp.Cols, k.col+1), max(tmp.Rows, k.row+1)
+				tmp.SetCell(k.col, k.row, p.RawCell(k.col, k.row))
+			}
+			store := p.Paged
+			p.ReplaceContent(&tmp)
+			g.canvas.ReleaseStore(store)
+			p.NormalizeDates()
+			// the merge is not a bulk change of the local version
+			sw.loading[i] = true
+			g.events.Publish(PanelLoaded{Panel: i})
+			g.events.Publish(BulkEdit{Panel: i, Action: "merge file", Detail: filepath.Base(path)})
+			merged++
+		}
+	}
+	sw.restamp(g)
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("merged %d panel(s) from disk, kept %d local", merged, kept)})
+}
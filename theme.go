@@ -4,23 +4,63 @@ import "image/color"
 
 // Color Palette
 var (
-	ColorBackground     = color.RGBA{0x12, 0x12, 0x14, 0xff} // Main window background
-	ColorPanelBg        = color.RGBA{0x22, 0x22, 0x2a, 0xff} // Panel body background
-	ColorPanelHeaderBtn = color.RGBA{0x11, 0x11, 0x16, 0xff} // Panel header button background
-	ColorPanelBorder    = color.RGBA{0x44, 0x44, 0x50, 0xff} // Panel border
-	ColorPanelLoading   = color.RGBA{0x0f, 0x0f, 0x12, 0xff} // Loading placeholder background
-	ColorCellBg         = color.RGBA{0x18, 0x18, 0x1c, 0xff} // Cell background
-	ColorSelection      = color.RGBA{0x66, 0x88, 0xff, 0xff} // Selection border (opaque)
-	ColorResizeHandle   = color.RGBA{0x55, 0x55, 0x66, 0xff} // Resize handle
-	ColorText           = color.White                        // Standard text
-	ColorTextDim        = color.RGBA{0xdd, 0xdd, 0xdd, 0xff} // Dimmed text (logs)
-	ColorOverlayBg      = color.RGBA{0x11, 0x11, 0x16, 0xff} // Top overlay background
-	ColorLogBg          = color.RGBA{0x0c, 0x0c, 0x0e, 0xee} // Click log background
-	ColorMenuBg         = color.RGBA{0x10, 0x10, 0x12, 0xff} // Context menu background
-	ColorMenuBorder     = color.RGBA{0x44, 0x44, 0x50, 0xff} // Context menu border
-	ColorMenuHighlight  = color.RGBA{0x33, 0x55, 0xff, 0xff} // Context menu hover highlight
+	ColorBackground      = color.RGBA{0x12, 0x12, 0x14, 0xff} // Main window background
+	ColorPanelBg         = color.RGBA{0x22, 0x22, 0x2a, 0xff} // Panel body background
+	ColorPanelHeaderBtn  = color.RGBA{0x11, 0x11, 0x16, 0xff} // Panel header button background
+	ColorPanelBorder     = color.RGBA{0x44, 0x44, 0x50, 0xff} // Panel border
+	ColorPanelLoading    = color.RGBA{0x0f, 0x0f, 0x12, 0xff} // Loading placeholder background
+	ColorCellBg          = color.RGBA{0x18, 0x18, 0x1c, 0xff} // Cell background
+	ColorZebraRow        = color.RGBA{0x1e, 0x1e, 0x25, 0xff} // Alternate row background (zebra striping)
+	ColorSelection       = color.RGBA{0x66, 0x88, 0xff, 0xff} // Selection border (opaque)
+	ColorSelectionRange  = color.RGBA{0x1a, 0x22, 0x40, 0x40} // Selected range tint (premultiplied)
+	ColorCellBorder      = color.RGBA{0xcc, 0xcc, 0xd4, 0xff} // Explicit cell borders
+	ColorPageBreak       = color.RGBA{0x33, 0x66, 0xff, 0xff} // Page-break preview lines
+	ColorOutsidePrint    = color.RGBA{0x00, 0x00, 0x00, 0xff} // Dims cells outside the print area
+	ColorPreviewBadge    = color.RGBA{0xff, 0xcc, 0x44, 0xff} // Header badge of sampled (preview) panels
+	ColorOutlier         = color.RGBA{0xff, 0x88, 0x22, 0xff} // Warning tint of outlier cells
+	ColorTracePrecedent  = color.RGBA{0x66, 0x88, 0xff, 0xff} // Formula trace: cells a formula refers to
+	ColorTraceDependent  = color.RGBA{0xe0, 0x6c, 0x75, 0xff} // Formula trace: formulas referring to a cell
+	ColorError           = color.RGBA{0xe0, 0x6c, 0x75, 0xff} // Error dialog title and accent
+	ColorNameButtonHover = color.RGBA{0x2a, 0x2a, 0x40, 0xff} // Panel name button under the cursor
+	ColorPlaceholder     = color.RGBA{0x88, 0x88, 0x99, 0xff} // Placeholder text such as "(unnamed)"
+	ColorEditPending     = color.RGBA{0x3a, 0x32, 0x18, 0xff} // Background of a cell with an uncommitted edit
+	ColorEditText        = color.RGBA{0xff, 0xe0, 0x99, 0xff} // Text of an uncommitted edit
+	ColorResizeHandle    = color.RGBA{0x55, 0x55, 0x66, 0xff} // Resize handle
+	ColorResizeGhost     = color.RGBA{0x66, 0x88, 0xff, 0xff} // Outline of the size a resize drag will give
+	ColorDensity         = color.RGBA{0x66, 0x88, 0xff, 0xff} // Zoomed-out cell density blocks
+	ColorScrollTrack     = color.RGBA{0x22, 0x22, 0x2a, 0xff} // Panel scrollbar track
+	ColorScrollThumb     = color.RGBA{0x66, 0x66, 0x7a, 0xff} // Panel scrollbar thumb
+	ColorText            = color.White                        // Standard text
+	ColorTextDim         = color.RGBA{0xdd, 0xdd, 0xdd, 0xff} // Dimmed text (logs)
+	ColorOverlayBg       = color.RGBA{0x11, 0x11, 0x16, 0xff} // Top overlay background
+	ColorImageBackdrop   = color.RGBA{0x00, 0x00, 0x00, 0xc0} // Dims the canvas behind the image viewer
+	ColorStarFilled      = color.RGBA{0xff, 0xc8, 0x3c, 0xff} // Filled stars of a rating cell
+	ColorStarEmpty       = color.RGBA{0x44, 0x44, 0x50, 0xff} // Unfilled stars of a rating cell
+	ColorLogBg           = color.RGBA{0x0c, 0x0c, 0x0e, 0xee} // Click log background
+	ColorMenuBg          = color.RGBA{0x10, 0x10, 0x12, 0xff} // Context menu background
+	ColorMenuBorder      = color.RGBA{0x44, 0x44, 0x50, 0xff} // Context menu border
+	ColorMenuHighlight   = color.RGBA{0x33, 0x55, 0xff, 0xff} // Context menu hover highlight
 )
 
+// AccentPalette lists the colors offered for panel color coding. The hex
+// value is what gets stored in state.yml.
+var AccentPalette = []struct {
+	Name string
+	Hex  string
+}{
+	{"Red", "#e06c75"},
+	{"Orange", "#d19a66"},
+	{"Yellow", "#e5c07b"},
+	{"Green", "#98c379"},
+	{"Teal", "#56b6c2"},
+	{"Blue", "#61afef"},
+	{"Purple", "#c678dd"},
+	{"Gray", "#8a8f98"},
+}
+
+// accentHeaderAlpha is the opacity of a panel's accent over its header.
+const accentHeaderAlpha = 0.3
+
 // Layout Constants
 const (
 	PanelPaddingX     = 4
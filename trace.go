@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// traceArrowHead is the length of the arrowheads drawn by formula tracing.
+const traceArrowHead = 8
+
+// FormulaTrace draws arrows from the cells the selected formula refers to
+// (its precedents) and to the formulas that refer to the selected cell (its
+// dependents). Ctrl+T toggles it.
+type FormulaTrace struct {
+	on bool
+	// at is the traced cell; the references are recomputed when the
+	// selection moves or the panel changes
+	at         [3]int
+	stale      bool
+	prec, deps []cellcanvas.CellRange
+}
+
+func NewFormulaTrace() *FormulaTrace {
+	return &FormulaTrace{}
+}
+
+// Observe recomputes the trace after edits and drops it when panels go
+// away.
+func (t *FormulaTrace) Observe(e Event) {
+	switch e.(type) {
+	case CellChanged, PanelLoaded, PanelRemoved, WorkspaceLoaded:
+		t.stale = true
+	}
+}
+
+func toggleTrace(g *Game) {
+	t := g.trace
+	t.on = !t.on
+	t.stale = true
+	if t.on {
+		g.events.Publish(LogMessage{Text: "formula trace on"})
+	} else {
+		g.events.Publish(LogMessage{Text: "formula trace off"})
+	}
+}
+
+// Update follows the selection.
+func (t *FormulaTrace) Update(g *Game) {
+	if !t.on {
+		return
+	}
+	at := [3]int{g.input.activePanel, g.input.selCol, g.input.selRow}
+	if at == t.at && !t.stale {
+		return
+	}
+	t.at, t.stale = at, false
+	t.prec, t.deps = nil, nil
+	if at[0] < 0 || at[0] >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[at[0]]
+	t.prec = p.Precedents(at[1], at[2])
+	t.deps = p.Dependents(at[1], at[2])
+	g.power.redraw = true
+}
+
+// Draw outlines the precedents and dependents and connects them to the
+// traced cell.
+func (t *FormulaTrace) Draw(screen *ebiten.Image, c *Canvas) {
+	if !t.on || t.at[0] < 0 || t.at[0] >= len(c.Panels) || len(t.prec)+len(t.deps) == 0 {
+		return
+	}
+	p := &c.Panels[t.at[0]]
+	if !p.CellVisible(t.at[1], t.at[2]) {
+		return
+	}
+	b := c.Bounds(p)
+	cx, cy := traceCenter(p, b, cellcanvas.CellRange{Col0: t.at[1], Row0: t.at[2], Col1: t.at[1], Row1: t.at[2]})
+	for _, r := range t.prec {
+		if !rangeVisible(p, r) {
+			continue
+		}
+		traceBox(screen, p, b, r, ColorTracePrecedent)
+		x, y := traceCenter(p, b, r)
+		traceArrow(screen, x, y, cx, cy, ColorTracePrecedent)
+	}
+	for _, r := range t.deps {
+		if !rangeVisible(p, r) {
+			continue
+		}
+		traceBox(screen, p, b, r, ColorTraceDependent)
+		x, y := traceCenter(p, b, r)
+		traceArrow(screen, cx, cy, x, y, ColorTraceDependent)
+	}
+}
+
+// rangeVisible reports whether part of r is in p's window.
+func rangeVisible(p *cellcanvas.Panel, r cellcanvas.CellRange) bool {
+	r0, r1 := p.VisibleRows()
+	c0, c1 := p.VisibleCols()
+	return r.Row0 < r1 && r.Row1 >= r0 && r.Col0 < c1 && r.Col1 >= c0
+}
+
+// traceCenter returns the screen position of the middle of r.
+func traceCenter(p *cellcanvas.Panel, b PanelBounds, r cellcanvas.CellRange) (float32, float32) {
+	x0, y0 := b.GridX+p.ColX(r.Col0), b.GridY+p.RowY(r.Row0)
+	x1, y1 := b.GridX+p.ColX(r.Col1+1), b.GridY+p.RowY(r.Row1+1)
+	return float32(x0+x1) / 2, float32(y0+y1) / 2
+}
+
+func traceBox(screen *ebiten.Image, p *cellcanvas.Panel, b PanelBounds, r cellcanvas.CellRange, clr color.Color) {
+	x := float32(b.GridX + p.ColX(r.Col0))
+	y := float32(b.GridY + p.RowY(r.Row0))
+	w := float32(p.ColX(r.Col1+1) - p.ColX(r.Col0))
+	h := float32(p.RowY(r.Row1+1) - p.RowY(r.Row0))
+	vector.StrokeRect(screen, x, y, w, h, 1, clr, false)
+}
+
+// traceArrow draws a line from x0,y0 to x1,y1 ending in an arrowhead.
+func traceArrow(screen *ebiten.Image, x0, y0, x1, y1 float32, clr color.Color) {
+	if x0 == x1 && y0 == y1 {
+		return
+	}
+	vector.StrokeLine(screen, x0, y0, x1, y1, 1.5, clr, true)
+	a := math.Atan2(float64(y1-y0), float64(x1-x0))
+	var path vector.Path
+	path.MoveTo(x1, y1)
+	for _, d := range []float64{-0.4, 0.4} {
+		path.LineTo(x1-traceArrowHead*float32(math.Cos(a+d)), y1-traceArrowHead*float32(math.Sin(a+d)))
+	}
+	path.Close()
+	var op vector.DrawPathOptions
+	op.AntiAlias = true
+	op.ColorScale.ScaleWithColor(clr)
+	vector.FillPath(screen, &path, nil, &op)
+}
@@ -5,23 +5,54 @@ import (
 	"log"
 	"os"
 	"time"
+	"unicode"
 
+	"github.com/example/cellchain/cellcanvas"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/opentype"
 )
 
+// Edit triggers: how a click on a cell starts editing it (Enter and F2
+// always do).
+const (
+	EditOnDoubleClick = "double-click"
+	EditOnClick       = "single-click"
+)
+
+// Edit exits: what a click on another cell, Tab or an arrow key does to a
+// cell being edited. Enter always commits and Esc always reverts.
+const (
+	EditCommit = "commit" // commit the edit, then move
+	EditCancel = "cancel" // revert the edit, then move
+	EditKeep   = "keep"   // keep editing: arrows move the caret, Tab is ignored
+)
+
+// editExits says what ends a cell edit (see EditCommit).
+type editExits struct {
+	Click  string `yaml:"click"`
+	Tab    string `yaml:"tab"`
+	Arrows string `yaml:"arrows"`
+}
+
 type UI struct {
 	face font.Face
 	// double-click tracking (moved here from Canvas)
 	lastClickPanel int
 	lastClickRow   int
 	lastClickCol   int
-	lastClickTime  int64 // unix ms
+	lastClickTime  int64 // session ms (InputFrame.TimeMs)
 	dblClickMs     int64
+	// editTrigger is EditOnDoubleClick or EditOnClick
+	editTrigger string
+	// renameTrigger is the click on a panel's name button that renames it
+	// (EditOnDoubleClick or EditOnClick)
+	renameTrigger string
+	// typeToEdit starts editing when a character is typed over a cell
+	typeToEdit bool
+	exits      editExits
 	// recent mouse click log (most-recent first)
 	clickLog []string
 	// double-click tracking for header name button
@@ -36,6 +67,10 @@ func NewUI() *UI {
 	ui.lastClickCol = -1
 	ui.lastClickTime = 0
 	ui.dblClickMs = 400
+	ui.editTrigger = EditOnDoubleClick
+	ui.renameTrigger = EditOnDoubleClick
+	ui.typeToEdit = true
+	ui.exits = defaultEditExits()
 
 	ui.clickLog = []string{}
 	ui.lastClickHeaderPanel = -1
@@ -71,18 +106,20 @@ func (ui *UI) Update(g *Game) {
 
 	// Early return if not editing
 	if !g.input.editing && !g.input.editingPanelName {
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-			if g.input.activePanel >= 0 && g.input.activePanel < len(g.canvas.panels) {
-				g.input.editing = true
-				g.input.editBuffer = g.canvas.panels[g.input.activePanel].GetCell(g.input.selCol, g.input.selRow)
-				g.input.editCursor = len([]rune(g.input.editBuffer))
-				g.input.blinkCounter = 0
-				g.input.caretVisible = true
-			}
+		// F2 edits like in Excel; Shift+F2 renames the panel
+		if g.frame.ShiftPressed() && g.frame.KeyJustPressed(ebiten.KeyF2) {
+			ui.beginPanelRename(g, g.input.activePanel)
+		} else if g.frame.KeyJustPressed(ebiten.KeyEnter) || g.frame.KeyJustPressed(ebiten.KeyF2) {
+			ui.beginCellEdit(g, g.input.activePanel, g.input.selCol, g.input.selRow)
+		} else if ui.typeToEdit {
+			ui.typeToReplace(g)
 		}
 		return
 	}
 
+	if !g.input.editingPanelName && ui.handleEditExit(g) {
+		return
+	}
 	ui.handleCaretBlink(g)
 	ui.handleTextInput(g)
 	ui.handleEditingNavigation(g)
@@ -91,28 +128,25 @@ func (ui *UI) Update(g *Game) {
 
 // handleClickLogging logs mouse clicks with panel/cell information
 func (ui *UI) handleClickLogging(g *Game) {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+	if g.frame.MouseJustPressed(ebiten.MouseButtonLeft) || g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
 		btn := "L"
-		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		if g.frame.MouseJustPressed(ebiten.MouseButtonRight) {
 			btn = "R"
 		}
-		mx, my := ebiten.CursorPosition()
+		mx, my := g.canvas.ToView(g.frame.CursorPosition())
 		// try to detect panel/cell under cursor
 		found := false
-		for i := len(g.canvas.panels) - 1; i >= 0; i-- {
-			p := g.canvas.panels[i]
-			baseX := int(float64(p.X) + g.canvas.camX)
-			baseY := int(float64(p.Y) + g.canvas.camY)
-			w := p.Cols * p.CellW
-			h := p.Rows * p.CellH
-			if mx >= baseX && mx <= baseX+w && my >= baseY && my <= baseY+h {
+		for i := len(g.canvas.Panels) - 1; i >= 0; i-- {
+			p := g.canvas.Panels[i]
+			b := g.canvas.Bounds(&p)
+			if mx >= b.ContentX && mx <= b.ContentX+b.ContentW && my >= b.ContentY && my <= b.ContentY+b.ContentH {
 				if !p.Loaded {
 					ui.addClickLog(fmt.Sprintf("%s click @ %d,%d  panel=%d (loading)", btn, mx, my, i))
 					found = true
 					break
 				}
-				col := (mx - baseX) / p.CellW
-				row := (my - baseY) / p.CellH
+				col := p.ColAt(mx - b.GridX)
+				row := p.RowAt(my - b.GridY)
 				ui.addClickLog(fmt.Sprintf("%s click @ %d,%d  panel=%d row=%d col=%d", btn, mx, my, i, row, col))
 				found = true
 				break
@@ -124,26 +158,82 @@ func (ui *UI) handleClickLogging(g *Game) {
 	}
 }
 
-// handleShortcuts processes global keyboard shortcuts (Ctrl+S, Ctrl+O)
+// handleShortcuts processes global keyboard shortcuts (Ctrl+S, Ctrl+O,
+// Ctrl+J, Ctrl+K)
 func (ui *UI) handleShortcuts(g *Game) {
-	ctrlPressed := ebiten.IsKeyPressed(ebiten.KeyControlLeft) || ebiten.IsKeyPressed(ebiten.KeyControlRight)
-	if ctrlPressed && inpututil.IsKeyJustPressed(ebiten.KeyS) {
-		// default state file
-		statePath := "state.yml"
+	ctrlPressed := g.frame.CtrlPressed()
+	if ctrlPressed && g.frame.KeyJustPressed(ebiten.KeyS) {
+		statePath := defaultStatePath
 		if err := g.canvas.SaveState(statePath); err != nil {
-			log.Printf("Save failed: %v", err)
+			reportError(g, "Save workspace", err)
 		} else {
 			log.Printf("Saved to %s", statePath)
+			g.events.Publish(WorkspaceSaved{Path: statePath})
 		}
 	}
-	if ctrlPressed && inpututil.IsKeyJustPressed(ebiten.KeyO) {
-		statePath := "state.yml"
-		if err := g.canvas.LoadState(statePath); err != nil {
-			log.Printf("Open failed: %v", err)
-		} else {
-			log.Printf("Loaded from %s", statePath)
+	if ctrlPressed && g.frame.KeyJustPressed(ebiten.KeyO) {
+		// asks for the password first if the workspace is encrypted
+		openWorkspace(g, defaultStatePath)
+	}
+	if ctrlPressed && g.frame.KeyJustPressed(ebiten.KeyJ) {
+		g.scratch.Toggle(g)
+	}
+	if ctrlPressed && g.frame.KeyJustPressed(ebiten.KeyK) {
+		g.contents.Toggle(g)
+	}
+}
+
+func defaultEditExits() editExits {
+	return editExits{Click: EditCommit, Tab: EditCommit, Arrows: EditKeep}
+}
+
+// handleEditExit ends a cell edit on a click outside the cell, Tab or an
+// arrow key, as configured in ui.exits, and moves the selection for the
+// keys. It reports whether the edit ended.
+func (ui *UI) handleEditExit(g *Game) bool {
+	im := g.input
+	f := g.frame
+	var action string
+	dRow, dCol := 0, 0
+	switch {
+	case f.MouseJustPressed(ebiten.MouseButtonLeft):
+		// clicks on another cell already ended the edit (OnCellClick);
+		// this is a click on the canvas or a panel header
+		if pi, col, row, ok := g.canvas.CellAt(f.CursorPosition()); ok && pi == im.activePanel && col == im.selCol && row == im.selRow {
+			return false
 		}
+		action = ui.exits.Click
+	case f.KeyJustPressed(ebiten.KeyTab):
+		action, dCol = ui.exits.Tab, 1
+		if f.ShiftPressed() {
+			dCol = -1
+		}
+	case f.KeyJustPressed(ebiten.KeyArrowUp):
+		action, dRow = ui.exits.Arrows, -1
+	case f.KeyJustPressed(ebiten.KeyArrowDown):
+		action, dRow = ui.exits.Arrows, 1
+	case f.KeyJustPressed(ebiten.KeyArrowLeft):
+		action, dCol = ui.exits.Arrows, -1
+	case f.KeyJustPressed(ebiten.KeyArrowRight):
+		action, dCol = ui.exits.Arrows, 1
+	default:
+		return false
+	}
+	switch action {
+	case EditCommit:
+		ui.commitCellEdit(g)
+	case EditCancel:
+		im.editing = false
+	default:
+		return false
+	}
+	if pi := im.activePanel; (dRow != 0 || dCol != 0) && pi >= 0 && pi < len(g.canvas.Panels) {
+		p := &g.canvas.Panels[pi]
+		row := max(0, min(im.selRow+dRow, p.Rows-1))
+		col := max(0, min(im.selCol+dCol, p.Cols-1))
+		im.setSelection(g, pi, row, col)
 	}
+	return true
 }
 
 // handleCaretBlink updates the caret blink timer
@@ -156,7 +246,7 @@ func (ui *UI) handleCaretBlink(g *Game) {
 
 // handleTextInput processes typed characters and inserts them at cursor position
 func (ui *UI) handleTextInput(g *Game) {
-	for _, r := range ebiten.InputChars() {
+	for _, r := range g.frame.InputChars() {
 		if r == '\b' {
 			if g.input.editingPanelName {
 				if g.input.editPanelCursor > 0 {
@@ -195,7 +285,7 @@ func (ui *UI) handleTextInput(g *Game) {
 
 // handleEditingNavigation processes arrow keys, backspace, delete, home, end
 func (ui *UI) handleEditingNavigation(g *Game) {
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+	if g.frame.KeyJustPressed(ebiten.KeyArrowLeft) {
 		if g.input.editingPanelName {
 			if g.input.editPanelCursor > 0 {
 				g.input.editPanelCursor--
@@ -208,7 +298,7 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 			}
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+	if g.frame.KeyJustPressed(ebiten.KeyArrowRight) {
 		if g.input.editingPanelName {
 			if g.input.editPanelCursor < len([]rune(g.input.editPanelBuffer)) {
 				g.input.editPanelCursor++
@@ -221,7 +311,7 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 			}
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+	if g.frame.KeyJustPressed(ebiten.KeyBackspace) {
 		if g.input.editingPanelName {
 			if g.input.editPanelCursor > 0 {
 				rs := []rune(g.input.editPanelBuffer)
@@ -240,7 +330,7 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 			}
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyDelete) {
+	if g.frame.KeyJustPressed(ebiten.KeyDelete) {
 		if g.input.editingPanelName {
 			rs := []rune(g.input.editPanelBuffer)
 			if g.input.editPanelCursor < len(rs) {
@@ -257,7 +347,7 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 			}
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+	if g.frame.KeyJustPressed(ebiten.KeyHome) {
 		if g.input.editingPanelName {
 			g.input.editPanelCursor = 0
 		} else {
@@ -265,7 +355,7 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 		}
 		ui.resetCaret(g)
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnd) {
+	if g.frame.KeyJustPressed(ebiten.KeyEnd) {
 		if g.input.editingPanelName {
 			g.input.editPanelCursor = len([]rune(g.input.editPanelBuffer))
 		} else {
@@ -277,35 +367,109 @@ func (ui *UI) handleEditingNavigation(g *Game) {
 
 // handleCommitCancel processes Enter and Escape keys to commit or cancel editing
 func (ui *UI) handleCommitCancel(g *Game) {
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+	if g.frame.KeyJustPressed(ebiten.KeyEnter) {
 		if g.input.editingPanelName {
-			if g.input.editPanelIndex >= 0 && g.input.editPanelIndex < len(g.canvas.panels) {
-				oldName := g.canvas.panels[g.input.editPanelIndex].Name
-				g.canvas.panels[g.input.editPanelIndex].Name = g.input.editPanelBuffer
-				if ui != nil && oldName != g.canvas.panels[g.input.editPanelIndex].Name {
-					if g.canvas.panels[g.input.editPanelIndex].Name == "" {
+			if g.input.editPanelIndex >= 0 && g.input.editPanelIndex < len(g.canvas.Panels) {
+				oldName := g.canvas.Panels[g.input.editPanelIndex].Name
+				g.canvas.Panels[g.input.editPanelIndex].Name = g.input.editPanelBuffer
+				if ui != nil && oldName != g.canvas.Panels[g.input.editPanelIndex].Name {
+					if g.canvas.Panels[g.input.editPanelIndex].Name == "" {
 						ui.addClickLog(fmt.Sprintf("Panel %d name cleared", g.input.editPanelIndex+1))
 					} else {
-						ui.addClickLog(fmt.Sprintf("Panel %d named: %s", g.input.editPanelIndex+1, g.canvas.panels[g.input.editPanelIndex].Name))
+						ui.addClickLog(fmt.Sprintf("Panel %d named: %s", g.input.editPanelIndex+1, g.canvas.Panels[g.input.editPanelIndex].Name))
 					}
 				}
 			}
 			g.input.editingPanelName = false
 		} else {
-			if g.input.activePanel >= 0 && g.input.activePanel < len(g.canvas.panels) {
-				g.canvas.panels[g.input.activePanel].SetCell(g.input.selCol, g.input.selRow, g.input.editBuffer)
-			}
-			g.input.editing = false
+			ui.commitCellEdit(g)
 		}
 	}
-	// Only cancel editing with ESC if context menu is not visible
-	// (context menu handles ESC first to close itself)
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) && !g.contextMenu.visible {
+	if g.frame.KeyJustPressed(ebiten.KeyEscape) {
 		g.input.editing = false
 		g.input.editingPanelName = false
 	}
 }
 
+// commitCellEdit writes the edit buffer into the selected cell, ends cell
+// editing, and publishes a CellChanged event when the value differs. In a
+// column with a unit, text that is not a number is rejected.
+func (ui *UI) commitCellEdit(g *Game) {
+	g.input.editing = false
+	pi := g.input.activePanel
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	col, row := g.input.selCol, g.input.selRow
+	val, err := p.UnitValue(col, row, g.input.editBuffer)
+	if err != nil {
+		g.events.Publish(LogMessage{Text: err.Error()})
+		return
+	}
+	old := p.RawCell(col, row)
+	p.SetCell(col, row, val)
+	if old != val {
+		// last write wins; a remote user's pending edit is noted in the history
+		other := conflictWith(g, pi, col, row, hostUser)
+		g.events.Publish(CellChanged{Panel: pi, Col: col, Row: row, Old: old, New: val, Conflict: other})
+		if other != "" {
+			g.events.Publish(LogMessage{Text: lockMessage(hostUser, other, pi, col, row)})
+		}
+	}
+}
+
+// beginCellEdit starts editing a cell of panel with its current value,
+// unless the panel or column does not allow it. It reports whether editing
+// started.
+func (ui *UI) beginCellEdit(g *Game, panel, col, row int) bool {
+	if panel < 0 || panel >= len(g.canvas.Panels) || previewBlocksEdit(g, panel) || queryBlocksEdit(g, panel) || computedBlocksEdit(g, panel, col, row) {
+		return false
+	}
+	if other := g.locks.Holder(panel, col, row, time.Now()); other != "" {
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("%s is editing this cell too; the last value written wins", other)})
+	}
+	g.input.editing = true
+	g.input.editBuffer = g.canvas.Panels[panel].RawCell(col, row)
+	g.input.editCursor = len([]rune(g.input.editBuffer))
+	ui.resetCaret(g)
+	return true
+}
+
+// beginPanelRename starts editing the name of panel.
+func (ui *UI) beginPanelRename(g *Game, panel int) {
+	if panel < 0 || panel >= len(g.canvas.Panels) {
+		return
+	}
+	g.input.editingPanelName = true
+	g.input.editPanelIndex = panel
+	g.input.editPanelBuffer = g.canvas.Panels[panel].Name
+	g.input.editPanelCursor = len([]rune(g.input.editPanelBuffer))
+	ui.resetCaret(g)
+}
+
+// typeToReplace starts editing the active cell when printable characters
+// are typed over it, replacing its value with them as Excel does. Nothing
+// happens while Ctrl, Alt or Meta is held (shortcuts) or for a leading
+// space, which is held to pan.
+func (ui *UI) typeToReplace(g *Game) {
+	f := g.frame
+	chars := f.InputChars()
+	if len(chars) == 0 || chars[0] == ' ' || f.CtrlPressed() || f.AltPressed() ||
+		f.KeyPressed(ebiten.KeyMetaLeft) || f.KeyPressed(ebiten.KeyMetaRight) {
+		return
+	}
+	for _, r := range chars {
+		if !unicode.IsPrint(r) {
+			return
+		}
+	}
+	if ui.beginCellEdit(g, g.input.activePanel, g.input.selCol, g.input.selRow) {
+		g.input.editBuffer = string(chars)
+		g.input.editCursor = len(chars)
+	}
+}
+
 // resetCaret resets the caret blink timer and makes it visible
 func (ui *UI) resetCaret(g *Game) {
 	g.input.blinkCounter = 0
@@ -314,26 +478,26 @@ func (ui *UI) resetCaret(g *Game) {
 
 // OnCellClick handles click events on a cell and detects double-clicks to begin editing.
 // Single-click commits any active edits and selects the cell.
-// Double-click starts editing the cell.
+// Double-click starts editing the cell (a single click does in EditOnClick mode).
 func (ui *UI) OnCellClick(g *Game, panel, row, col int) {
-	// First, commit any active cell edit
+	// First, end any active cell edit as configured for clicks
 	if g.input.editing && !g.input.editingPanelName {
-		if g.input.activePanel >= 0 && g.input.activePanel < len(g.canvas.panels) {
-			g.canvas.panels[g.input.activePanel].SetCell(g.input.selCol, g.input.selRow, g.input.editBuffer)
+		if ui.exits.Click == EditCancel {
+			g.input.editing = false
+		} else {
+			ui.commitCellEdit(g)
 		}
-		g.input.editing = false
 	}
 
-	now := time.Now().UnixNano() / 1e6
+	now := g.frame.TimeMs
+	if ui.editTrigger == EditOnClick && !g.frame.ShiftPressed() {
+		// single-click mode: every plain click edits
+		ui.beginCellEdit(g, panel, col, row)
+		return
+	}
 	if ui.lastClickPanel == panel && ui.lastClickRow == row && ui.lastClickCol == col && now-ui.lastClickTime <= ui.dblClickMs {
 		// double-click: start editing
-		if panel >= 0 && panel < len(g.canvas.panels) {
-			g.input.editing = true
-			g.input.editBuffer = g.canvas.panels[panel].GetCell(col, row)
-			g.input.editCursor = len([]rune(g.input.editBuffer))
-			g.input.blinkCounter = 0
-			g.input.caretVisible = true
-		}
+		ui.beginCellEdit(g, panel, col, row)
 		// reset last click to avoid immediate retrigger
 		ui.lastClickPanel = -1
 	} else {
@@ -348,17 +512,11 @@ func (ui *UI) OnCellClick(g *Game, panel, row, col int) {
 // OnPanelNameClick handles clicks on the header name button and detects double-clicks
 // to start editing the panel name.
 func (ui *UI) OnPanelNameClick(g *Game, panel int) {
-	now := time.Now().UnixNano() / 1e6
-	if ui.lastClickHeaderPanel == panel && now-ui.lastClickHeaderTime <= ui.dblClickMs {
-		// double-click: start editing panel name
-		if panel >= 0 && panel < len(g.canvas.panels) {
-			g.input.editingPanelName = true
-			g.input.editPanelIndex = panel
-			g.input.editPanelBuffer = g.canvas.panels[panel].Name
-			g.input.editPanelCursor = len([]rune(g.input.editPanelBuffer))
-			g.input.blinkCounter = 0
-			g.input.caretVisible = true
-		}
+	now := g.frame.TimeMs
+	if ui.renameTrigger == EditOnClick || ui.lastClickHeaderPanel == panel && now-ui.lastClickHeaderTime <= ui.dblClickMs {
+		// double-click (or any click in single-click mode): start editing
+		// panel name
+		ui.beginPanelRename(g, panel)
 		// reset last click to avoid immediate retrigger
 		ui.lastClickHeaderPanel = -1
 	} else {
@@ -367,6 +525,19 @@ func (ui *UI) OnPanelNameClick(g *Game, panel int) {
 	}
 }
 
+// OnEvent reacts to bus events addressed to the UI: log messages and clicks
+// detected by the InputManager.
+func (ui *UI) OnEvent(g *Game, e Event) {
+	switch ev := e.(type) {
+	case LogMessage:
+		ui.addClickLog(ev.Text)
+	case CellClicked:
+		ui.OnCellClick(g, ev.Panel, ev.Row, ev.Col)
+	case PanelNameClicked:
+		ui.OnPanelNameClick(g, ev.Panel)
+	}
+}
+
 // addClickLog prepends a timestamped entry to the click log and keeps it bounded.
 func (ui *UI) addClickLog(s string) {
 	ts := time.Now().Format("15:04:05.000")
@@ -378,14 +549,37 @@ func (ui *UI) addClickLog(s string) {
 	}
 }
 
-// Draw renders HUD and editing text overlay
+// drawCellEdit shows the value being typed over the selected cell. It is
+// drawn with the canvas, on the zoomed view.
+func (ui *UI) drawCellEdit(screen *ebiten.Image, g *Game) {
+	if !g.input.editing || g.input.editingPanelName || g.input.activePanel < 0 || g.input.activePanel >= len(g.canvas.Panels) {
+		return
+	}
+	p := g.canvas.Panels[g.input.activePanel]
+	b := g.canvas.Bounds(&p)
+	sx := b.GridX + p.ColX(g.input.selCol)
+	sy := b.GridY + p.RowY(g.input.selRow)
+	face := ui.face
+	if f := panelFonts.face(&p); f != nil {
+		face = f
+	}
+	// the pending value is tinted until it is committed; the inset keeps
+	// the selection border visible
+	ebitenutil.DrawRect(screen, float64(sx+2), float64(sy+2), float64(p.ColWidth(g.input.selCol)-5), float64(p.RowHeight(g.input.selRow)-5), ColorEditPending)
+	drawTextAt(screen, face, g.input.editBuffer, sx+PanelInnerPadding, sy+PanelInnerPadding, ColorEditText)
+}
+
+// Draw renders the HUD and the editing bar
 func (ui *UI) Draw(screen *ebiten.Image, g *Game) {
 	// Use the actual logical screen height so the HUD sits at the bottom
 	// even when the window is resized.
 	screenH := screen.Bounds().Dy()
 	drawTextAt(screen, ui.face, "Right-drag to pan - Left-drag title to move - Drag corner to resize", 8, screenH-42, ColorText)
 	drawTextAt(screen, ui.face, "Press Ctrl+S to Save - Press Ctrl+O to Open", 8, screenH-28, ColorText)
-	drawTextAt(screen, ui.face, "Arrows to move - Enter to edit - Tab switch panel", 8, screenH-14, ColorText)
+	drawTextAt(screen, ui.face, "Arrows to move - Shift+Arrows select - Enter/F2 to edit - Tab/Shift+Tab switch panel - Ctrl+Tab list - Home show all", 8, screenH-14, ColorText)
+	if g.canvas.readOnly {
+		drawTextAt(screen, ui.face, "READ-ONLY: workspace is open in another instance", 8, screenH-56, ColorTextDim)
+	}
 
 	if g.input.editing { // only show top overlay when editing a cell; panel name edits render inline
 		// top text bar background
@@ -398,7 +592,7 @@ func (ui *UI) Draw(screen *ebiten.Image, g *Game) {
 		if g.input.editingPanelName {
 			label = fmt.Sprintf("Edit Panel%d Name : ", g.input.editPanelIndex)
 		} else {
-			label = fmt.Sprintf("Edit Panel%d Cell-%s%d : ", g.input.activePanel, ColToLetters(g.input.selCol), g.input.selRow+1)
+			label = fmt.Sprintf("Edit Panel%d Cell-%s%d : ", g.input.activePanel, cellcanvas.ColToLetters(g.input.selCol), g.input.selRow+1)
 		}
 
 		// render the full bracketed string
@@ -447,17 +641,6 @@ func (ui *UI) Draw(screen *ebiten.Image, g *Game) {
 				ebitenutil.DrawRect(screen, float64(padding+caretX), float64(caretY), 2, float64(caretH), ColorText)
 			}
 		}
-
-		// position editing text over the selected cell (visual feedback)
-		// Position editing text over the selected cell only for normal cell edits.
-		if !g.input.editingPanelName {
-			if g.input.activePanel >= 0 && g.input.activePanel < len(g.canvas.panels) {
-				p := g.canvas.panels[g.input.activePanel]
-				sx := float64(p.X) + g.canvas.camX + float64(g.input.selCol*p.CellW)
-				sy := float64(p.Y) + g.canvas.camY + float64(g.input.selRow*p.CellH)
-				drawTextAt(screen, ui.face, g.input.editBuffer, int(sx)+PanelInnerPadding, int(sy)+PanelInnerPadding, ColorText)
-			}
-		}
 	}
 
 	// Draw recent mouse click log at bottom-right
@@ -475,6 +658,8 @@ func (ui *UI) Draw(screen *ebiten.Image, g *Game) {
 		}
 	}
 
+	g.stats.Draw(screen, ui.face)
+
 	// draw right-click context menu if visible
 	g.contextMenu.Draw(screen, ui.face)
 }
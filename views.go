@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// viewGap is the space left between a panel and a new linked view of it.
+const viewGap = 40
+
+// addLinkedView places a linked view of panel pi to its right.
+func addLinkedView(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	src := &g.canvas.Panels[pi]
+	i := g.canvas.AddView(pi, src.X+src.Width()+viewGap, src.Y)
+	g.events.Publish(PanelAdded{Panel: i})
+	g.events.Publish(LogMessage{Text: fmt.Sprintf("Panel %d is a view of %s", i+1, g.canvas.Panels[i].Filename)})
+}
+
+// syncViews propagates changes to a panel's data to its linked views.
+// They share one cell map, so only edits that replace it or change the
+// grid size need copying.
+func syncViews(c *Canvas, e Event) {
+	switch e := e.(type) {
+	case CellChanged:
+		c.SyncViews(e.Panel)
+	case PanelResized:
+		c.SyncViews(e.Panel)
+	case PanelLoaded:
+		c.SyncViews(e.Panel)
+	case RowsAppended:
+		c.SyncViews(e.Panel)
+	}
+}
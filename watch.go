@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// Watch window geometry and the characters shown per value.
+const (
+	watchW        = 280
+	watchRowH     = 18
+	watchMargin   = 8
+	maxWatchValue = 24
+	// watchTop and watchBottom keep clear of the top text bar and the
+	// selection statistics
+	watchTop    = 40
+	watchBottom = 28
+)
+
+// Corners the watch window can be docked to, in the order clicking its
+// title cycles through them.
+const (
+	dockTopRight = iota
+	dockBottomRight
+	dockBottomLeft
+	dockTopLeft
+	dockCorners
+)
+
+// WatchWindow shows the live values of the pinned cells (see AddWatch) in
+// a small overlay docked to a screen corner. Clicking its title moves it to
+// the next corner, clicking an entry jumps to the cell and right-clicking
+// one unpins it.
+type WatchWindow struct {
+	dock int
+	// box geometry from the last Draw, used for hit testing
+	x, y, h int
+}
+
+func NewWatchWindow() *WatchWindow {
+	return &WatchWindow{}
+}
+
+// HandleInput handles clicks on the window and reports whether it took
+// them.
+func (ww *WatchWindow) HandleInput(g *Game) bool {
+	if len(g.canvas.Watches) == 0 || ww.h == 0 {
+		return false
+	}
+	left := g.frame.MouseJustPressed(ebiten.MouseButtonLeft)
+	right := g.frame.MouseJustPressed(ebiten.MouseButtonRight)
+	if !left && !right {
+		return false
+	}
+	mx, my := g.frame.CursorPosition()
+	if mx < ww.x || mx >= ww.x+watchW || my < ww.y || my >= ww.y+ww.h {
+		return false
+	}
+	i := (my-ww.y)/watchRowH - 1
+	switch {
+	case i < 0:
+		if left {
+			ww.dock = (ww.dock + 1) % dockCorners
+		}
+	case i >= len(g.canvas.Watches):
+	case right:
+		g.events.Publish(LogMessage{Text: "unwatched " + watchLabel(g, i)})
+		g.canvas.RemoveWatch(i)
+	default:
+		wt := g.canvas.Watches[i]
+		if col, row, err := cellcanvas.ParseCellRef(wt.Cell); err == nil && wt.Panel < len(g.canvas.Panels) {
+			goToCell(g, wt.Panel, col, row)
+		}
+	}
+	g.power.redraw = true
+	return true
+}
+
+// Draw renders the window in its corner; nothing is drawn without watches.
+func (ww *WatchWindow) Draw(screen *ebiten.Image, g *Game, face font.Face) {
+	watches := g.canvas.Watches
+	if len(watches) == 0 {
+		ww.h = 0
+		return
+	}
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	ww.h = (len(watches) + 1) * watchRowH
+	ww.x, ww.y = sw-watchW-watchMargin, watchTop
+	switch ww.dock {
+	case dockBottomRight:
+		ww.y = sh - ww.h - watchBottom
+	case dockBottomLeft:
+		ww.x, ww.y = watchMargin, sh-ww.h-watchBottom
+	case dockTopLeft:
+		ww.x = watchMargin
+	}
+	ebitenutil.DrawRect(screen, float64(ww.x), float64(ww.y), watchW, float64(ww.h), ColorMenuBg)
+	ebitenutil.DrawRect(screen, float64(ww.x), float64(ww.y), watchW, watchRowH, ColorOverlayBg)
+	ebitenutil.DrawRect(screen, float64(ww.x), float64(ww.y+watchRowH-1), watchW, 1, ColorMenuBorder)
+	drawTextAt(screen, face, fmt.Sprintf("Watch (%d)", len(watches)), ww.x+PanelInnerPadding, ww.y+1, ColorText)
+
+	for i := range watches {
+		y := ww.y + (i+1)*watchRowH + 1
+		v, ok := g.canvas.WatchValue(i)
+		if !ok {
+			v = "#REF!"
+		}
+		v = strings.ReplaceAll(v, "\n", " ")
+		if r := []rune(v); len(r) > maxWatchValue {
+			v = string(r[:maxWatchValue]) + "..."
+		}
+		drawTextAt(screen, face, watchLabel(g, i), ww.x+PanelInnerPadding, y, ColorTextDim)
+		drawTextAt(screen, face, v, ww.x+watchW-PanelInnerPadding-textWidth(face, v), y, ColorText)
+	}
+}
+
+// watchLabel names watch i by its panel and cell, e.g. "Sales!B7".
+func watchLabel(g *Game, i int) string {
+	wt := g.canvas.Watches[i]
+	name := fmt.Sprintf("Panel %d", wt.Panel+1)
+	if wt.Panel < len(g.canvas.Panels) && g.canvas.Panels[wt.Panel].Name != "" {
+		name = g.canvas.Panels[wt.Panel].Name
+	}
+	return name + "!" + wt.Cell
+}
+
+// toggleWatch pins the cell of panel pi to the watch window, or unpins it
+// when it is already watched.
+func toggleWatch(g *Game, pi, col, row int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	if i := g.canvas.FindWatch(pi, col, row); i >= 0 {
+		g.events.Publish(LogMessage{Text: "unwatched " + watchLabel(g, i)})
+		g.canvas.RemoveWatch(i)
+		return
+	}
+	g.canvas.AddWatch(pi, col, row)
+	g.events.Publish(LogMessage{Text: "watching " + watchLabel(g, len(g.canvas.Watches)-1)})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/example/cellchain/cellcanvas"
+	"github.com/sqweek/dialog"
+)
+
+// lockRefreshInterval is how often the lock heartbeat is rewritten; it must
+// stay well below cellcanvas.LockStaleAfter.
+const lockRefreshInterval = 10 * time.Second
+
+// errReadOnly is returned by SaveState while the workspace is open
+// read-only because another instance owns it.
+var errReadOnly = errors.New("workspace is open read-only (locked by another instance)")
+
+// acquireWorkspaceLock takes the single-writer lock on statePath. When
+// another live instance holds it the user chooses between taking it over
+// and opening the workspace read-only.
+func acquireWorkspaceLock(g *Game, statePath string) {
+	lock, holder, err := cellcanvas.AcquireLock(statePath, false)
+	if errors.Is(err, cellcanvas.ErrLocked) {
+		takeover := dialog.Message("%s is already open in another CellCanvas instance:\n\n%s, last seen %s\n\nTake it over? The other instance will become read-only.\nChoose No to open it read-only here.",
+			statePath, holder, holder.Updated.Format("15:04:05")).Title("Workspace In Use").YesNo()
+		if !takeover {
+			g.canvas.readOnly = true
+			log.Printf("workspace locked by %s; opened read-only", holder)
+			return
+		}
+		lock, _, err = cellcanvas.AcquireLock(statePath, true)
+	}
+	if err != nil {
+		// could not write a lock (e.g. read-only directory); carry on unlocked
+		log.Printf("workspace lock: %v", err)
+		return
+	}
+	g.lock = lock
+	g.lockRefreshed = time.Now()
+}
+
+// refreshWorkspaceLock keeps the lock heartbeat alive and drops to
+// read-only if another instance took the workspace over.
+func (g *Game) refreshWorkspaceLock() {
+	if g.lock == nil || time.Since(g.lockRefreshed) < lockRefreshInterval {
+		return
+	}
+	g.lockRefreshed = time.Now()
+	owner, err := g.lock.Refresh()
+	if errors.Is(err, cellcanvas.ErrLockLost) {
+		g.lock = nil
+		g.canvas.readOnly = true
+		g.events.Publish(LogMessage{Text: fmt.Sprintf("workspace taken over by %s; now read-only", owner)})
+		return
+	}
+	if err != nil {
+		log.Printf("workspace lock: %v", err)
+	}
+}
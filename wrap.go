@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/example/cellchain/cellcanvas"
+	"golang.org/x/image/font"
+)
+
+// Word wrap. Wrapped panels break cell text at word boundaries to fit the
+// column and grow each row to its tallest cell. Row heights are derived from
+// the content, so they are refitted whenever it changes rather than saved.
+// autoHeightMaxRows is the largest panel whose rows are auto-sized; bigger
+// (typically paged) panels wrap inside fixed-height rows because fitting
+// would read every cell.
+const autoHeightMaxRows = 100000
+
+// wrapText breaks s into lines at most width pixels wide in face (nil for
+// the debug font), splitting words only when a single word is too long.
+func wrapText(face font.Face, s string, width int) string {
+	width = max(width, 1)
+	spaceW := textWidth(face, " ")
+	var b strings.Builder
+	for i, para := range strings.Split(s, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		lineW := 0 // width of the current line; 0 at its start
+		for _, word := range strings.Fields(para) {
+			wordW := textWidth(face, word)
+			if lineW > 0 {
+				if lineW+spaceW+wordW <= width {
+					b.WriteByte(' ')
+					lineW += spaceW
+				} else {
+					b.WriteByte('\n')
+					lineW = 0
+				}
+			}
+			if lineW == 0 && wordW > width {
+				// hard-break a word longer than the line
+				for _, r := range word {
+					rw := textWidth(face, string(r))
+					if lineW > 0 && lineW+rw > width {
+						b.WriteByte('\n')
+						lineW = 0
+					}
+					b.WriteRune(r)
+					lineW += rw
+				}
+				continue
+			}
+			b.WriteString(word)
+			lineW += wordW
+		}
+	}
+	return b.String()
+}
+
+// cellText returns the text drawn for a cell: wrapped to its column on
+// wrapping panels.
+func cellText(p *cellcanvas.Panel, face font.Face, col int, s string) string {
+	if !p.Wrap || s == "" {
+		return s
+	}
+	return wrapText(face, s, p.ColWidth(col)-2*PanelInnerPadding)
+}
+
+// fitRowHeights sizes every row of a wrapping panel to its tallest cell and
+// resets other panels to the default height.
+func fitRowHeights(p *cellcanvas.Panel) {
+	if !p.Wrap || p.Rows > autoHeightMaxRows {
+		p.SetRowHeights(nil)
+		return
+	}
+	face := panelFonts.face(p)
+	lh := lineHeight(face)
+	heights := make([]int, p.Rows)
+	tall := false
+	for row := 0; row < p.Rows; row++ {
+		lines := 1
+		for col := 0; col < p.Cols; col++ {
+			if v := p.DisplayCell(col, row); v != "" {
+				lines = max(lines, strings.Count(cellText(p, face, col, v), "\n")+1)
+			}
+		}
+		// text starts PanelInnerPadding below the top of the cell
+		if h := lines*lh + PanelInnerPadding + 2; h > p.CellH {
+			heights[row] = h
+			tall = true
+		}
+	}
+	if !tall {
+		heights = nil
+	}
+	p.SetRowHeights(heights)
+}
+
+// refitRows keeps cell sizes in step with panel fonts, and row heights with
+// content and column widths.
+func refitRows(c *Canvas, e Event) {
+	panel := -1
+	switch ev := e.(type) {
+	case CellChanged:
+		panel = ev.Panel
+	case ColumnResized:
+		panel = ev.Panel
+	case PanelResized:
+		panel = ev.Panel
+	case PanelLoaded:
+		panel = ev.Panel
+	case RowsAppended:
+		panel = ev.Panel
+	case PanelAdded:
+		panel = ev.Panel
+	case WorkspaceLoaded:
+		for i := range c.Panels {
+			applyPanelFont(&c.Panels[i])
+			fitRowHeights(&c.Panels[i])
+		}
+		return
+	}
+	if panel >= 0 && panel < len(c.Panels) {
+		applyPanelFont(&c.Panels[panel])
+		fitRowHeights(&c.Panels[panel])
+	}
+}
+
+// toggleWrap switches word wrap for panel pi.
+func toggleWrap(g *Game, pi int) {
+	if pi < 0 || pi >= len(g.canvas.Panels) {
+		return
+	}
+	p := &g.canvas.Panels[pi]
+	p.Wrap = !p.Wrap
+	fitRowHeights(p)
+	state := "off"
+	if p.Wrap {
+		state = "on"
+	}
+	g.events.Publish(LogMessage{Text: "word wrap " + state})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Camera zoom. The canvas is drawn at its natural size on a view image of
+// the screen's size divided by the zoom, which is then scaled onto the
+// screen; panel bounds, the camera and every hit test work in view pixels.
+// Below overviewZoom, where cell text is unreadable and the view image
+// would get large, panels are drawn straight onto the screen as outlines
+// with density blocks for their cells.
+const (
+	minZoom = 0.1
+	maxZoom = 4
+	// zoomStep is the factor one wheel notch zooms by
+	zoomStep     = 1.15
+	overviewZoom = 0.5
+	// zooms this close to 1 snap to it, where text is sharpest
+	zoomSnap = 0.04
+)
+
+// zoom returns the camera zoom factor.
+func (c *Canvas) zoom() float64 {
+	if c.Zoom <= 0 {
+		return 1
+	}
+	return c.Zoom
+}
+
+// ToView converts screen position x, y to the view the canvas is drawn on.
+func (c *Canvas) ToView(x, y int) (int, int) {
+	z := c.zoom()
+	return int(math.Floor(float64(x) / z)), int(math.Floor(float64(y) / z))
+}
+
+// ScreenToWorld converts screen position x, y to canvas coordinates, where
+// panels are placed.
+func (c *Canvas) ScreenToWorld(x, y int) (int, int) {
+	z := c.zoom()
+	return int(float64(x)/z - c.CamX), int(float64(y)/z - c.CamY)
+}
+
+// SetZoom sets the zoom to z, within minZoom and maxZoom, keeping the
+// canvas point under screen position x, y where it is.
+func (c *Canvas) SetZoom(z float64, x, y int) {
+	z = math.Min(math.Max(z, minZoom), maxZoom)
+	if math.Abs(z-1) < zoomSnap {
+		z = 1
+	}
+	old := c.zoom()
+	c.CamX += float64(x)/z - float64(x)/old
+	c.CamY += float64(y)/z - float64(y)/old
+	c.Zoom = z
+}
+
+// viewSize returns the size of the screen in view pixels.
+func (g *Game) viewSize() (w, h int) {
+	z := g.canvas.zoom()
+	return int(float64(g.power.layoutW) / z), int(float64(g.power.layoutH) / z)
+}
+
+// handleZoomInput zooms around the cursor with Ctrl+wheel; Ctrl+0 goes
+// back to 100%.
+func handleZoomInput(g *Game) {
+	f := g.frame
+	if !f.CtrlPressed() {
+		return
+	}
+	mx, my := f.CursorPosition()
+	if _, dy := f.Wheel(); dy != 0 {
+		g.canvas.SetZoom(g.canvas.zoom()*math.Pow(zoomStep, dy), mx, my)
+	} else if f.KeyJustPressed(ebiten.Key0) {
+		g.canvas.SetZoom(1, mx, my)
+	} else {
+		return
+	}
+	g.power.redraw = true
+}
+
+// viewImage returns the image to draw the canvas on this frame, cleared:
+// screen itself at 100%, else an offscreen image the size of the view.
+func (r *Renderer) viewImage(screen *ebiten.Image, z float64) *ebiten.Image {
+	if z == 1 {
+		return screen
+	}
+	w := int(math.Ceil(float64(screen.Bounds().Dx()) / z))
+	h := int(math.Ceil(float64(screen.Bounds().Dy()) / z))
+	if r.view == nil || r.view.Bounds().Dx() != w || r.view.Bounds().Dy() != h {
+		if r.view != nil {
+			r.view.Deallocate()
+		}
+		r.view = ebiten.NewImage(w, h)
+	}
+	r.view.Fill(ColorBackground)
+	return r.view
+}
+
+// presentView draws view, as returned by viewImage, scaled onto screen.
+func (r *Renderer) presentView(screen, view *ebiten.Image, z float64) {
+	if view == screen {
+		return
+	}
+	var op ebiten.DrawImageOptions
+	op.GeoM.Scale(z, z)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(view, &op)
+}
+
+// DrawOverview draws the canvas straight onto screen below overviewZoom:
+// each panel's frame and accent, its cells as density blocks and its name
+// where it fits. The active panel's frame is highlighted.
+func (r *Renderer) DrawOverview(screen *ebiten.Image, c *Canvas, active int) {
+	z := c.zoom()
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	for pi := range c.Panels {
+		p := &c.Panels[pi]
+		b := scaleBounds(c.Bounds(p), z)
+		if b.TotalX > sw || b.TotalX+b.TotalW < 0 || b.TotalY > sh || b.TotalY+b.TotalH < 0 {
+			continue
+		}
+		r.drawPanelBackground(screen, b)
+		if accent, ok := parseHexColor(p.Accent); ok {
+			r.tintRect(screen, float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(b.ContentY-b.TotalY), accent, accentHeaderAlpha)
+		}
+		switch {
+		case !p.Loaded:
+			r.fillRect(screen, float64(b.ContentX), float64(b.ContentY), float64(b.ContentW), float64(b.ContentH), ColorPanelLoading)
+		case p.Rows > 0 && p.Cols > 0:
+			r.drawPanelDensity(screen, p, b)
+		}
+		r.drawPanelBorder(screen, p, b)
+		if pi == active {
+			r.drawFrame(screen, b, ColorSelection)
+		}
+		label := p.Name
+		if label == "" {
+			label = r.panelTitle(pi)
+		}
+		if textWidth(nil, label) <= b.TotalW {
+			drawTextAt(screen, nil, label, b.TotalX, b.TotalY-lineHeight(nil), ColorTextDim)
+		}
+	}
+}
+
+// drawFrame outlines the whole of a panel in clr.
+func (r *Renderer) drawFrame(screen *ebiten.Image, b PanelBounds, c color.Color) {
+	x, y, w, h := float64(b.TotalX), float64(b.TotalY), float64(b.TotalW), float64(b.TotalH)
+	r.fillRect(screen, x, y, w, 2, c)
+	r.fillRect(screen, x, y+h-2, w, 2, c)
+	r.fillRect(screen, x, y, 2, h, c)
+	r.fillRect(screen, x+w-2, y, 2, h, c)
+}
+
+// scaleBounds scales bounds in view pixels to screen pixels at zoom z.
+func scaleBounds(b PanelBounds, z float64) PanelBounds {
+	s := func(v int) int { return int(math.Round(float64(v) * z)) }
+	return PanelBounds{
+		ContentX: s(b.ContentX), ContentY: s(b.ContentY),
+		ContentW: max(s(b.ContentW), 1), ContentH: max(s(b.ContentH), 1),
+		TotalX: s(b.TotalX), TotalY: s(b.TotalY),
+		TotalW: max(s(b.TotalW), 1), TotalH: max(s(b.TotalH), 1),
+		GridX: s(b.GridX), GridY: s(b.GridY),
+	}
+}